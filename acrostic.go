@@ -0,0 +1,116 @@
+package gomarkov
+
+import (
+	"unicode"
+	"unicode/utf8"
+)
+
+// Acrostic is a Constraint making the first letters of successive words spell
+// Word, ignoring case and anything in Word that isn't a letter. Tokens that don't
+// start with a letter, such as punctuation, are allowed anywhere and don't count.
+type Acrostic struct {
+	Word string
+	// Complete makes the sequence end where the chain can end it right after the
+	// last letter is spelled, instead of stopping there
+	Complete bool
+}
+
+// letters returns the letters to spell in lower case
+func (a Acrostic) letters() []rune {
+	var letters []rune
+	for _, r := range a.Word {
+		if unicode.IsLetter(r) {
+			letters = append(letters, unicode.ToLower(r))
+		}
+	}
+	return letters
+}
+
+// initial returns the first letter of a token in lower case, with ok false if it
+// doesn't start with a letter
+func initial(token string) (rune, bool) {
+	r, _ := utf8.DecodeRuneInString(token)
+	if !unicode.IsLetter(r) {
+		return 0, false
+	}
+	return unicode.ToLower(r), true
+}
+
+// spelled counts the words of tokens
+func spelled(tokens []string) int {
+	n := 0
+	for _, token := range tokens {
+		if _, ok := initial(token); ok {
+			n++
+		}
+	}
+	return n
+}
+
+func (a Acrostic) Allow(tokens []string, next string) bool {
+	letters := a.letters()
+	n := spelled(tokens)
+	if next == EndToken {
+		return a.Complete && n == len(letters)
+	}
+	if next == StartToken {
+		return false
+	}
+	r, ok := initial(next)
+	if !ok {
+		return len(tokens) > 0
+	}
+	return n < len(letters) && r == letters[n]
+}
+
+func (a Acrostic) Done(tokens []string) bool {
+	return !a.Complete && spelled(tokens) == len(a.letters())
+}
+
+// GenerateAcrostic generates a sequence from the start whose words' first letters
+// spell word, see Acrostic. If the chain can't spell it within the search limit,
+// the error is ErrUnsatisfiable.
+func (chain *Chain) GenerateAcrostic(word string, opts ...SearchOption) ([]string, error) {
+	return chain.GenerateConstrained(NGram(boundary(StartToken, chain.Order)), Acrostic{Word: word}, opts...)
+}
+
+// firstLetter is a Constraint for whole sequences starting with a letter and at
+// most maxLength tokens long if maxLength is positive
+type firstLetter struct {
+	letter    rune
+	maxLength int
+}
+
+func (f firstLetter) Allow(tokens []string, next string) bool {
+	switch {
+	case next == EndToken:
+		return len(tokens) > 0
+	case next == StartToken:
+		return false
+	case len(tokens) == 0:
+		r, ok := initial(next)
+		return ok && r == f.letter
+	}
+	return f.maxLength <= 0 || len(tokens) < f.maxLength
+}
+
+func (f firstLetter) Done(tokens []string) bool {
+	return false
+}
+
+// GenerateAcrosticLines generates a whole sequence for each letter of word, each
+// starting with a word beginning with that letter, ignoring case. Lines are at most
+// maxLength tokens if it is positive. If a line can't be found within the search
+// limit, the error is ErrUnsatisfiable.
+func (chain *Chain) GenerateAcrosticLines(word string, maxLength int, opts ...SearchOption) ([][]string, error) {
+	seed := NGram(boundary(StartToken, chain.Order))
+	var lines [][]string
+	for _, r := range (Acrostic{Word: word}).letters() {
+		line, err := chain.GenerateConstrained(seed, firstLetter{r, maxLength}, opts...)
+		if err != nil {
+			return nil, err
+		}
+		lines = append(lines, line)
+	}
+	return lines, nil
+}
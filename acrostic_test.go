@@ -0,0 +1,89 @@
+package gomarkov
+
+import (
+	"errors"
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// initials returns the first letters of the words in tokens
+func initials(tokens []string) string {
+	var b strings.Builder
+	for _, token := range tokens {
+		if r, ok := initial(token); ok {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+func acrosticChain() *Chain {
+	chain := NewChain(1)
+	for _, s := range []string{
+		"happy cats eat fish",
+		"every lazy dog sleeps",
+		"lovely old owls hoot , loudly",
+		"old hens lay eggs",
+		"dogs over here",
+	} {
+		for i := 0; i < 10; i++ {
+			chain.Add(strings.Fields(s))
+		}
+	}
+	return chain
+}
+
+func TestChain_GenerateAcrostic(t *testing.T) {
+	chain := acrosticChain()
+	prng := rand.New(rand.NewSource(1))
+	// Punctuation in the word is skipped, and between words it doesn't count
+	for _, word := range []string{"Lohle", "oh-le!", "loohl"} {
+		got, err := chain.GenerateAcrostic(word, SearchRand(prng))
+		if err != nil {
+			t.Fatalf("Chain.GenerateAcrostic(%q) error = %v", word, err)
+		}
+		want := strings.ToLower(strings.NewReplacer("-", "", "!", "").Replace(word))
+		if initials(got) != want {
+			t.Errorf("Chain.GenerateAcrostic(%q) = %v, spelling %q", word, got, initials(got))
+		}
+	}
+	if _, err := chain.GenerateAcrostic("xyz"); !errors.Is(err, ErrUnsatisfiable) {
+		t.Errorf("Chain.GenerateAcrostic(xyz) error = %v, want %v", err, ErrUnsatisfiable)
+	}
+}
+
+func TestAcrostic_Complete(t *testing.T) {
+	chain := acrosticChain()
+	seed := NGram{StartToken}
+	prng := rand.New(rand.NewSource(1))
+
+	// Sequences spelling looh go on after hoot, so they can't end there
+	if _, err := chain.GenerateConstrained(seed, Acrostic{Word: "looh", Complete: true}, SearchRand(prng)); !errors.Is(err, ErrUnsatisfiable) {
+		t.Errorf("Chain.GenerateConstrained(Acrostic) error = %v, want %v", err, ErrUnsatisfiable)
+	}
+	got, err := chain.GenerateConstrained(seed, Acrostic{Word: "loohl", Complete: true}, SearchRand(prng))
+	if err != nil {
+		t.Fatalf("Chain.GenerateConstrained(Acrostic) error = %v", err)
+	}
+	if want := strings.Fields("lovely old owls hoot , loudly"); !reflect.DeepEqual(got, want) {
+		t.Errorf("Chain.GenerateConstrained(Acrostic) = %v, want %v", got, want)
+	}
+}
+
+func TestChain_GenerateAcrosticLines(t *testing.T) {
+	chain := acrosticChain()
+	lines, err := chain.GenerateAcrosticLines("Hold", 6, SearchRand(rand.New(rand.NewSource(1))))
+	if err != nil {
+		t.Fatalf("Chain.GenerateAcrosticLines() error = %v", err)
+	}
+	if len(lines) != 4 {
+		t.Fatalf("Chain.GenerateAcrosticLines() = %d lines, want 4", len(lines))
+	}
+	for i, line := range lines {
+		if len(line) > 6 || line[0][0] != "hold"[i] {
+			t.Errorf("line %d = %v, want at most 6 tokens starting with %c", i, line, "hold"[i])
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package gomarkov
+
+import (
+	"math"
+	"sort"
+	"sync"
+)
+
+// aliasTable samples a row in constant time using Walker's alias method. Counts are
+// scaled by the row length so the table is built and sampled with exact integer
+// arithmetic from a single Intn call.
+type aliasTable struct {
+	keys  []int
+	prob  []int
+	alias []int
+	total int
+}
+
+// newAliasTable builds the alias table of a row, falling back to a cumulative
+// distribution for rows too large to scale without overflow
+func newAliasTable(row map[int]int) distribution {
+	keys := make([]int, 0, len(row))
+	total := 0
+	for key, count := range row {
+		keys = append(keys, key)
+		total += count
+	}
+	if total == 0 || total > math.MaxInt/len(keys) {
+		return sparseArray(row).cumulative()
+	}
+	sort.Ints(keys)
+
+	n := len(keys)
+	a := &aliasTable{
+		keys:  keys,
+		prob:  make([]int, n),
+		alias: make([]int, n),
+		total: total,
+	}
+	// Scaled counts sum to n*total, so the average bucket holds exactly total
+	scaled := make([]int, n)
+	var small, large []int
+	for i, key := range keys {
+		scaled[i] = row[key] * n
+		if scaled[i] < total {
+			small = append(small, i)
+		} else {
+			large = append(large, i)
+		}
+	}
+	for len(small) > 0 && len(large) > 0 {
+		s, l := small[len(small)-1], large[len(large)-1]
+		small = small[:len(small)-1]
+		a.prob[s] = scaled[s]
+		a.alias[s] = l
+		scaled[l] -= total - scaled[s]
+		if scaled[l] < total {
+			large = large[:len(large)-1]
+			small = append(small, l)
+		}
+	}
+	for _, i := range append(small, large...) {
+		a.prob[i] = total
+		a.alias[i] = i
+	}
+	return a
+}
+
+func (a *aliasTable) draw(prng PRNG) (int, bool) {
+	r := prng.Intn(len(a.keys) * a.total)
+	i := r / a.total
+	if r%a.total < a.prob[i] {
+		return a.keys[i], true
+	}
+	return a.keys[a.alias[i]], true
+}
+
+// aliasCache lazily builds and keeps the alias tables of an immutable store
+type aliasCache struct {
+	lock   sync.RWMutex
+	tables map[int]distribution
+}
+
+func (c *aliasCache) get(current int, getRow func(int) (map[int]int, error)) (distribution, error) {
+	c.lock.RLock()
+	table, ok := c.tables[current]
+	c.lock.RUnlock()
+	if ok {
+		return table, nil
+	}
+	row, err := getRow(current)
+	if err != nil {
+		return nil, err
+	}
+	table = newAliasTable(row)
+	c.lock.Lock()
+	if c.tables == nil {
+		c.tables = make(map[int]distribution)
+	}
+	c.tables[current] = table
+	c.lock.Unlock()
+	return table, nil
+}
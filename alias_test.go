@@ -0,0 +1,72 @@
+package gomarkov
+
+import (
+	"bytes"
+	"testing"
+)
+
+// sequenceRand returns successive values from a slice, wrapped into range
+type sequenceRand struct {
+	values []int
+}
+
+func (s *sequenceRand) Intn(n int) int {
+	v := s.values[0]
+	s.values = s.values[1:]
+	return v % n
+}
+
+func Test_aliasTable_draw(t *testing.T) {
+	tests := []struct {
+		name string
+		row  map[int]int
+	}{
+		{"Single key", map[int]int{7: 3}},
+		{"Uniform", map[int]int{1: 2, 2: 2, 3: 2}},
+		{"Skewed", map[int]int{1: 1, 2: 3, 5: 10, 9: 4}},
+		{"Coprime counts", map[int]int{4: 7, 8: 11, 15: 13}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := newAliasTable(tt.row).(*aliasTable)
+			// Every value of the single draw hits a key exactly count*n times
+			n := len(tt.row)
+			got := map[int]int{}
+			for r := 0; r < n*a.total; r++ {
+				next, _ := a.draw(&sequenceRand{[]int{r}})
+				got[next]++
+			}
+			for key, count := range tt.row {
+				if got[key] != count*n {
+					t.Errorf("aliasTable.draw() hit %v %d times, want %d", key, got[key], count*n)
+				}
+			}
+		})
+	}
+}
+
+func TestLoadCompiled_Generate(t *testing.T) {
+	var buf bytes.Buffer
+	compiled := NewChain(1)
+	compiled.Add([]string{"a", "b"})
+	compiled.Add([]string{"a", "c"})
+	compiled.Add([]string{"a", "c"})
+	if err := compiled.Compile(&buf); err != nil {
+		t.Fatalf("Chain.Compile() error = %v", err)
+	}
+	chain, err := LoadCompiled(buf.Bytes())
+	if err != nil {
+		t.Fatalf("LoadCompiled() error = %v", err)
+	}
+	got := map[string]int{}
+	for r := 0; r < 6; r++ {
+		next, err := chain.GenerateDeterministic(NGram{"a"}, &sequenceRand{[]int{r}})
+		if err != nil {
+			t.Fatalf("Chain.GenerateDeterministic() error = %v", err)
+		}
+		got[next]++
+	}
+	if got["b"] != 2 || got["c"] != 4 {
+		t.Errorf("Chain.GenerateDeterministic() drew %v, want b 2 times and c 4 times", got)
+	}
+}
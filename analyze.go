@@ -0,0 +1,184 @@
+package gomarkov
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// TokenIssue is why AnalyzeCorpus reports a token as suspicious
+type TokenIssue string
+
+// Issues reported by AnalyzeCorpus
+const (
+	// IssueEmpty is an empty token, usually left by splitting on single spaces
+	IssueEmpty TokenIssue = "empty"
+	// IssueBoundary is a token equal to StartToken or EndToken, which the chain
+	// would mistake for the start or end of a sequence
+	IssueBoundary TokenIssue = "boundary token"
+	// IssueControl is a token holding control characters, such as a stray \r
+	IssueControl TokenIssue = "control character"
+	// IssueInvalidUTF8 is a token that isn't valid UTF-8
+	IssueInvalidUTF8 TokenIssue = "invalid UTF-8"
+	// IssueSpace is a token holding whitespace, which the tokenizer should have split on
+	IssueSpace TokenIssue = "whitespace"
+	// IssueSeparator is a token holding the "_" joining n-gram keys, which makes
+	// the states of chains of order 2 or more ambiguous to export
+	IssueSeparator TokenIssue = "key separator"
+	// IssueLong is a token longer than the limit set with AnalyzeMaxTokenLength
+	IssueLong TokenIssue = "too long"
+)
+
+// SuspiciousToken is a token AnalyzeCorpus found likely to be a corpus error
+type SuspiciousToken struct {
+	Token string
+	Issue TokenIssue
+	// Count is the number of times the token appears
+	Count int
+	// First is the index of the first sequence holding it
+	First int
+}
+
+// GrowthPoint is the size of the vocabulary after the first Sequences sequences
+type GrowthPoint struct {
+	Sequences  int
+	Vocabulary int
+}
+
+// CorpusReport describes a corpus before training on it, see AnalyzeCorpus
+type CorpusReport struct {
+	Sequences int
+	Tokens    int
+	// Vocabulary is the number of distinct tokens
+	Vocabulary int
+	// Empty is the number of sequences without tokens, which training skips
+	Empty int
+	// Duplicates is the number of sequences identical to an earlier one
+	Duplicates int
+	// Lengths counts sequences by their number of tokens
+	Lengths map[int]int
+	// TokenLengths counts token occurrences by their length in runes
+	TokenLengths map[int]int
+	// Suspicious lists tokens with an issue, most frequent first
+	Suspicious []SuspiciousToken
+	// Growth follows the vocabulary size through the corpus. A curve still rising
+	// steeply at the end means the corpus is too small for its vocabulary.
+	Growth []GrowthPoint
+}
+
+// MeanLength returns the average number of tokens in a sequence
+func (r CorpusReport) MeanLength() float64 {
+	if r.Sequences == 0 {
+		return 0
+	}
+	return float64(r.Tokens) / float64(r.Sequences)
+}
+
+// DuplicateRate returns the fraction of sequences identical to an earlier one
+func (r CorpusReport) DuplicateRate() float64 {
+	if r.Sequences == 0 {
+		return 0
+	}
+	return float64(r.Duplicates) / float64(r.Sequences)
+}
+
+// AnalyzeOption configures AnalyzeCorpus
+type AnalyzeOption func(*analyzeOptions)
+
+type analyzeOptions struct {
+	maxTokenLength int
+	growthPoints   int
+}
+
+// AnalyzeMaxTokenLength reports tokens longer than n runes, 40 by default
+func AnalyzeMaxTokenLength(n int) AnalyzeOption {
+	return func(o *analyzeOptions) {
+		o.maxTokenLength = n
+	}
+}
+
+// AnalyzeGrowthPoints sets how many points of the vocabulary growth curve are
+// reported, 20 by default
+func AnalyzeGrowthPoints(n int) AnalyzeOption {
+	return func(o *analyzeOptions) {
+		o.growthPoints = max(n, 1)
+	}
+}
+
+// AnalyzeCorpus reports on the quality of a tokenized corpus: its length
+// distributions, duplicates, vocabulary growth and suspicious tokens. Chains that
+// generate garbage are more often the fault of their corpus than of the chain, and
+// the report surfaces the usual culprits before training.
+func AnalyzeCorpus(sequences [][]string, opts ...AnalyzeOption) CorpusReport {
+	o := analyzeOptions{maxTokenLength: 40, growthPoints: 20}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	r := CorpusReport{
+		Sequences:    len(sequences),
+		Lengths:      make(map[int]int),
+		TokenLengths: make(map[int]int),
+	}
+	step := max((len(sequences)+o.growthPoints-1)/o.growthPoints, 1)
+	vocab := make(map[string]struct{})
+	seen := make(map[string]struct{})
+	suspicious := make(map[string]*SuspiciousToken)
+	for i, sequence := range sequences {
+		r.Tokens += len(sequence)
+		r.Lengths[len(sequence)]++
+		if len(sequence) == 0 {
+			r.Empty++
+		}
+		key := sequenceKey(sequence)
+		if _, ok := seen[key]; ok {
+			r.Duplicates++
+		} else {
+			seen[key] = struct{}{}
+		}
+		for _, token := range sequence {
+			r.TokenLengths[utf8.RuneCountInString(token)]++
+			vocab[token] = struct{}{}
+			if s, ok := suspicious[token]; ok {
+				s.Count++
+			} else if issue, bad := tokenIssue(token, o.maxTokenLength); bad {
+				suspicious[token] = &SuspiciousToken{Token: token, Issue: issue, Count: 1, First: i}
+			}
+		}
+		if (i+1)%step == 0 || i == len(sequences)-1 {
+			r.Growth = append(r.Growth, GrowthPoint{Sequences: i + 1, Vocabulary: len(vocab)})
+		}
+	}
+	r.Vocabulary = len(vocab)
+	for _, s := range suspicious {
+		r.Suspicious = append(r.Suspicious, *s)
+	}
+	sort.Slice(r.Suspicious, func(a, b int) bool {
+		if r.Suspicious[a].Count != r.Suspicious[b].Count {
+			return r.Suspicious[a].Count > r.Suspicious[b].Count
+		}
+		return r.Suspicious[a].Token < r.Suspicious[b].Token
+	})
+	return r
+}
+
+// tokenIssue returns the most serious issue of a token, if it has any
+func tokenIssue(token string, maxLength int) (TokenIssue, bool) {
+	switch {
+	case token == "":
+		return IssueEmpty, true
+	case token == StartToken || token == EndToken:
+		return IssueBoundary, true
+	case !utf8.ValidString(token):
+		return IssueInvalidUTF8, true
+	case strings.IndexFunc(token, unicode.IsControl) >= 0:
+		return IssueControl, true
+	case strings.IndexFunc(token, unicode.IsSpace) >= 0:
+		return IssueSpace, true
+	case strings.Contains(token, "_"):
+		return IssueSeparator, true
+	case maxLength > 0 && utf8.RuneCountInString(token) > maxLength:
+		return IssueLong, true
+	}
+	return "", false
+}
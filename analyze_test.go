@@ -0,0 +1,52 @@
+package gomarkov
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestAnalyzeCorpus(t *testing.T) {
+	corpus := [][]string{
+		{"the", "cat", "sat"},
+		{"the", "cat", "sat"},
+		{},
+		{"end\r", "$", "snake_case"},
+		{"a", "", "supercalifragilistic", "end\r"},
+		{"two words", "\xff"},
+	}
+	r := AnalyzeCorpus(corpus, AnalyzeMaxTokenLength(10), AnalyzeGrowthPoints(3))
+	if r.Sequences != 6 || r.Tokens != 15 || r.Vocabulary != 11 || r.Empty != 1 || r.Duplicates != 1 {
+		t.Errorf("AnalyzeCorpus() = %+v", r)
+	}
+	if want := map[int]int{0: 1, 2: 1, 3: 3, 4: 1}; !reflect.DeepEqual(r.Lengths, want) {
+		t.Errorf("AnalyzeCorpus() lengths = %v, want %v", r.Lengths, want)
+	}
+	if r.TokenLengths[3] != 6 || r.TokenLengths[0] != 1 {
+		t.Errorf("AnalyzeCorpus() token lengths = %v", r.TokenLengths)
+	}
+	if got := r.DuplicateRate(); got != 1.0/6 {
+		t.Errorf("CorpusReport.DuplicateRate() = %v, want %v", got, 1.0/6)
+	}
+	if got := r.MeanLength(); got != 2.5 {
+		t.Errorf("CorpusReport.MeanLength() = %v, want 2.5", got)
+	}
+	want := []SuspiciousToken{
+		{"end\r", IssueControl, 2, 3},
+		{"", IssueEmpty, 1, 4},
+		{"$", IssueBoundary, 1, 3},
+		{"snake_case", IssueSeparator, 1, 3},
+		{"supercalifragilistic", IssueLong, 1, 4},
+		{"two words", IssueSpace, 1, 5},
+		{"\xff", IssueInvalidUTF8, 1, 5},
+	}
+	if !reflect.DeepEqual(r.Suspicious, want) {
+		t.Errorf("AnalyzeCorpus() suspicious = %+v, want %+v", r.Suspicious, want)
+	}
+	if want := []GrowthPoint{{2, 3}, {4, 6}, {6, 11}}; !reflect.DeepEqual(r.Growth, want) {
+		t.Errorf("AnalyzeCorpus() growth = %v, want %v", r.Growth, want)
+	}
+
+	if empty := AnalyzeCorpus(nil); empty.Sequences != 0 || empty.MeanLength() != 0 || empty.DuplicateRate() != 0 || len(empty.Growth) != 0 {
+		t.Errorf("AnalyzeCorpus(nil) = %+v", empty)
+	}
+}
@@ -0,0 +1,202 @@
+package gomarkov
+
+import "sort"
+
+// flipBoundary swaps the start and end tokens, as reversing a sequence does
+func flipBoundary(token string) string {
+	switch token {
+	case StartToken:
+		return EndToken
+	case EndToken:
+		return StartToken
+	}
+	return token
+}
+
+// reversed returns tokens in reverse order with the boundaries flipped
+func reversed(tokens []string) NGram {
+	r := make(NGram, len(tokens))
+	for i, token := range tokens {
+		r[len(tokens)-1-i] = flipBoundary(token)
+	}
+	return r
+}
+
+// Reverse returns a chain of the same order trained on every sequence of this one
+// read backwards, built from the counts without retraining. Generating from its
+// start produces sequences in reverse, ending where this chain's sequences start.
+func (chain *Chain) Reverse() (*Chain, error) {
+	reverse := NewChain(chain.Order)
+	window := make([]string, chain.Order+1)
+	var addErr error
+	err := chain.ForEachTransition(func(current NGram, t Transition) bool {
+		if chain.Order == 0 {
+			// Token frequencies don't depend on the direction sequences are read in
+			addErr = reverse.addTransition(current, t.Next, t.Count)
+			return addErr == nil
+		}
+		copy(window, current)
+		window[chain.Order] = t.Next
+		r := reversed(window)
+		addErr = reverse.addTransition(r[:chain.Order], r[chain.Order], t.Count)
+		return addErr == nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if addErr != nil {
+		return nil, addErr
+	}
+	return reverse, nil
+}
+
+// Bidirectional pairs a chain with its reverse, for generating sequences around a
+// seed in the middle
+type Bidirectional struct {
+	Forward  *Chain
+	Backward *Chain
+}
+
+// NewBidirectional creates a pair of empty chains, the forward one with opts
+func NewBidirectional(order int, opts ...ChainOption) *Bidirectional {
+	return &Bidirectional{Forward: NewChain(order, opts...), Backward: NewChain(order)}
+}
+
+// Add trains both chains on a sequence
+func (b *Bidirectional) Add(input []string) error {
+	if err := b.Forward.Add(input); err != nil {
+		return err
+	}
+	backward := make([]string, len(input))
+	for i, token := range input {
+		backward[len(input)-1-i] = token
+	}
+	return b.Backward.Add(backward)
+}
+
+// AroundOption configures GenerateAround
+type AroundOption func(*aroundOptions)
+
+type aroundOptions struct {
+	maxLength int
+}
+
+// AroundMaxLength limits how many tokens GenerateAround grows the seed by on either
+// side. Without a limit, a chain that cycles can keep generating for a long time
+// before it reaches the start or end of a sequence.
+func AroundMaxLength(n int) AroundOption {
+	return func(o *aroundOptions) {
+		o.maxLength = n
+	}
+}
+
+// GenerateAround generates a whole sequence containing seed, growing it backwards to
+// the start of a sequence and forwards to its end. A seed shorter than the order is
+// first extended to a state of the chain starting with it, drawn by how often the
+// state was seen, and an empty seed of a chain of order 0 is only grown forwards.
+// The result includes the seed but not the boundary tokens.
+func (b *Bidirectional) GenerateAround(seed NGram, opts ...AroundOption) ([]string, error) {
+	prng := b.Forward.prng
+	if prng == nil {
+		prng = defaultPrng
+	}
+	return b.GenerateAroundDeterministic(seed, prng, opts...)
+}
+
+// GenerateAroundDeterministic is GenerateAround using the given PRNG
+func (b *Bidirectional) GenerateAroundDeterministic(seed NGram, prng PRNG, opts ...AroundOption) ([]string, error) {
+	var o aroundOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	order := b.Forward.Order
+	if b.Backward.Order != order {
+		return nil, ErrOrderMismatch
+	}
+	middle := append(NGram{}, seed...)
+	if len(middle) < order {
+		state, err := b.extend(middle, prng)
+		if err != nil {
+			return nil, err
+		}
+		middle = state
+	}
+	before := NGram{}
+	if len(middle) > 0 && middle[0] != StartToken {
+		var err error
+		before, err = b.Backward.GenerateSequenceDeterministic(reversed(middle[:order]), o.maxLength, prng)
+		if err != nil {
+			return nil, err
+		}
+	}
+	after := NGram{}
+	if len(middle) == 0 || middle[len(middle)-1] != EndToken {
+		var err error
+		after, err = b.Forward.GenerateSequenceDeterministic(middle[len(middle)-order:], o.maxLength, prng)
+		if err != nil {
+			return nil, err
+		}
+	}
+	tokens := make([]string, 0, len(before)+len(middle)+len(after))
+	for i := len(before) - 1; i >= 0; i-- {
+		tokens = append(tokens, before[i])
+	}
+	for _, token := range middle {
+		if token != StartToken && token != EndToken {
+			tokens = append(tokens, token)
+		}
+	}
+	return append(tokens, after...), nil
+}
+
+// extend draws a state of the forward chain starting with prefix, weighted by its
+// transition counts
+func (b *Bidirectional) extend(prefix NGram, prng PRNG) (NGram, error) {
+	weights := make(map[string]int)
+	states := make(map[string]NGram)
+	err := b.Forward.ForEachTransition(func(current NGram, t Transition) bool {
+		for i, token := range prefix {
+			if current[i] != token {
+				return true
+			}
+		}
+		key := current.key()
+		if _, ok := states[key]; !ok {
+			states[key] = append(NGram{}, current...)
+		}
+		weights[key] += t.Count
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(states) == 0 {
+		return nil, &UnknownNGramError{NGram: prefix}
+	}
+	// Draw in key order so the result only depends on the PRNG
+	keys := make([]string, 0, len(states))
+	total := 0
+	for key := range states {
+		keys = append(keys, key)
+		total += weights[key]
+	}
+	sort.Strings(keys)
+	randN := prng.Intn(total)
+	for _, key := range keys {
+		if randN -= weights[key]; randN < 0 {
+			return states[key], nil
+		}
+	}
+	return states[keys[len(keys)-1]], nil
+}
+
+// GenerateAround generates a whole sequence containing seed, see
+// Bidirectional.GenerateAround. It reverses the chain on every call, so build a
+// Bidirectional with Reverse to generate many.
+func (chain *Chain) GenerateAround(seed NGram, opts ...AroundOption) ([]string, error) {
+	reverse, err := chain.Reverse()
+	if err != nil {
+		return nil, err
+	}
+	return (&Bidirectional{Forward: chain, Backward: reverse}).GenerateAround(seed, opts...)
+}
@@ -0,0 +1,95 @@
+package gomarkov
+
+import (
+	"errors"
+	"math"
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+var aroundCorpus = []string{
+	"the quick brown fox jumps over the lazy dog",
+	"a lazy cat sleeps all day",
+	"the brown dog barks at the quick cat",
+}
+
+func TestChain_Reverse(t *testing.T) {
+	for _, order := range []int{1, 2, 3} {
+		b := NewBidirectional(order)
+		for _, s := range aroundCorpus {
+			b.Add(strings.Fields(s))
+		}
+		reverse, err := b.Forward.Reverse()
+		if err != nil {
+			t.Fatalf("Chain.Reverse() error = %v", err)
+		}
+		if got, want := transitionCounts(t, reverse), transitionCounts(t, b.Backward); !reflect.DeepEqual(got, want) {
+			t.Errorf("order %d: Chain.Reverse() = %v, want %v", order, got, want)
+		}
+	}
+}
+
+func TestBidirectional_GenerateAround(t *testing.T) {
+	b := NewBidirectional(2)
+	for _, s := range aroundCorpus {
+		b.Add(strings.Fields(s))
+	}
+	prng := rand.New(rand.NewSource(1))
+	for _, seed := range []NGram{{"lazy"}, {"brown", "dog"}, {"jumps", "over", "the"}, {"the"}} {
+		for i := 0; i < 20; i++ {
+			got, err := b.GenerateAroundDeterministic(seed, prng)
+			if err != nil {
+				t.Fatalf("Bidirectional.GenerateAround(%v) error = %v", seed, err)
+			}
+			if !strings.Contains(" "+strings.Join(got, " ")+" ", " "+strings.Join(seed, " ")+" ") {
+				t.Errorf("Bidirectional.GenerateAround(%v) = %v, want it to contain the seed", seed, got)
+			}
+			if p, _ := b.Forward.LogProbability(got); math.IsInf(p, -1) {
+				t.Errorf("Bidirectional.GenerateAround(%v) = %v, which the chain can't generate", seed, got)
+			}
+		}
+	}
+
+	var unknown *UnknownNGramError
+	if _, err := b.GenerateAround(NGram{"unicorn"}); !errors.As(err, &unknown) {
+		t.Errorf("Bidirectional.GenerateAround(unicorn) error = %v, want an UnknownNGramError", err)
+	}
+}
+
+func TestChain_GenerateAround(t *testing.T) {
+	chain := NewChain(1)
+	chain.Add(strings.Fields("a b c"))
+	got, err := chain.GenerateAround(NGram{"b"})
+	if err != nil {
+		t.Fatalf("Chain.GenerateAround() error = %v", err)
+	}
+	if want := strings.Fields("a b c"); !reflect.DeepEqual(got, want) {
+		t.Errorf("Chain.GenerateAround() = %v, want %v", got, want)
+	}
+
+	long := NewChain(1)
+	long.Add(strings.Fields("a b c d e f g"))
+	got, err = long.GenerateAround(NGram{"d"}, AroundMaxLength(2))
+	if err != nil {
+		t.Fatalf("Chain.GenerateAround() error = %v", err)
+	}
+	if want := strings.Fields("b c d e f"); !reflect.DeepEqual(got, want) {
+		t.Errorf("Chain.GenerateAround() limited to 2 = %v, want %v", got, want)
+	}
+
+	// An empty seed of a unigram is grown forwards only
+	unigram := NewBidirectional(0)
+	unigram.Add(strings.Fields("a a"))
+	got, err = unigram.GenerateAround(NGram{}, AroundMaxLength(10))
+	if err != nil {
+		t.Fatalf("Bidirectional.GenerateAround() of order 0 error = %v", err)
+	}
+	for _, token := range got {
+		if token != "a" {
+			t.Errorf("Bidirectional.GenerateAround() of order 0 = %v, want only a", got)
+			break
+		}
+	}
+}
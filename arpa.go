@@ -0,0 +1,355 @@
+package gomarkov
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ARPA boundary tokens used in place of StartToken and EndToken
+const (
+	arpaStart = "<s>"
+	arpaEnd   = "</s>"
+)
+
+// arpaDiscount is subtracted from every observed count above the unigrams to free
+// probability mass for backing off to shorter n-grams
+const arpaDiscount = 0.5
+
+// arpaModel holds the n-gram counts of each order, keyed by space joined tokens
+type arpaModel struct {
+	counts []map[string]float64
+}
+
+// arpaModel derives the counts of every n-gram up to order+1 from the chain's
+// transitions. Runs of start tokens are collapsed and end token padding is dropped,
+// so each sentence contributes a single <s> and </s>.
+func (chain *Chain) arpaModel() (*arpaModel, error) {
+	spoolMap, freqMat, err := chain.exportCounts("ARPA models")
+	if err != nil {
+		return nil, err
+	}
+	states := make(map[int]string, len(spoolMap))
+	for state, index := range spoolMap {
+		states[index] = state
+	}
+	m := &arpaModel{counts: make([]map[string]float64, chain.Order+2)}
+	for k := range m.counts {
+		m.counts[k] = make(map[string]float64)
+	}
+	for current, row := range freqMat {
+		ngram, ok := splitKey(states[current], chain.Order)
+		if !ok {
+			return nil, fmt.Errorf("Cannot split state %q into %d tokens", states[current], chain.Order)
+		}
+		padded := false
+		for _, token := range ngram {
+			padded = padded || token == EndToken
+		}
+		if padded {
+			continue
+		}
+		for next, count := range row {
+			tokens := append(append([]string{}, ngram...), states[next])
+			for i, token := range tokens {
+				if strings.ContainsAny(token, " \t\n") {
+					return nil, fmt.Errorf("Token %q cannot be written in ARPA format", token)
+				}
+				tokens[i] = toARPA(token)
+			}
+			for k := 1; k <= len(tokens); k++ {
+				gram := tokens[len(tokens)-k:]
+				if k > 1 && gram[0] == arpaStart && gram[1] == arpaStart {
+					// Same as the shorter n-gram with a single start token
+					continue
+				}
+				m.counts[k][strings.Join(gram, " ")] += float64(count)
+			}
+		}
+	}
+	return m, nil
+}
+
+// probabilities computes the log10 probability of every n-gram and log10 backoff
+// weight of every n-gram used as a context, using absolute discounting
+func (m *arpaModel) probabilities() (logProbs, backoffs []map[string]float64) {
+	n := len(m.counts) - 1
+	logProbs = make([]map[string]float64, n+1)
+	backoffs = make([]map[string]float64, n+1)
+	probs := make([]map[string]float64, n+1)
+	for k := 1; k <= n; k++ {
+		logProbs[k] = make(map[string]float64)
+		backoffs[k] = make(map[string]float64)
+		probs[k] = make(map[string]float64)
+	}
+
+	total := 0.0
+	for _, count := range m.counts[1] {
+		total += count
+	}
+	for gram, count := range m.counts[1] {
+		probs[1][gram] = count / total
+	}
+	// The start token is never predicted but has to be listed as a context
+	if n > 1 {
+		probs[1][arpaStart] = 0
+	}
+
+	for k := 2; k <= n; k++ {
+		contextCounts := make(map[string]float64)
+		followers := make(map[string][]string)
+		for gram, count := range m.counts[k] {
+			context := gram[:strings.LastIndex(gram, " ")]
+			contextCounts[context] += count
+			followers[context] = append(followers[context], gram)
+		}
+		for gram, count := range m.counts[k] {
+			context := gram[:strings.LastIndex(gram, " ")]
+			probs[k][gram] = (count - arpaDiscount) / contextCounts[context]
+		}
+		for context, grams := range followers {
+			left := arpaDiscount * float64(len(grams)) / contextCounts[context]
+			lower := 0.0
+			for _, gram := range grams {
+				lower += probs[k-1][gram[strings.Index(gram, " ")+1:]]
+			}
+			weight := 1.0
+			if lower < 1 {
+				weight = left / (1 - lower)
+			}
+			backoffs[k-1][context] = math.Log10(weight)
+		}
+	}
+
+	for k := 1; k <= n; k++ {
+		for gram, p := range probs[k] {
+			if p > 0 {
+				logProbs[k][gram] = math.Log10(p)
+			} else {
+				logProbs[k][gram] = -99
+			}
+		}
+	}
+	return logProbs, backoffs
+}
+
+// ExportARPA writes the chain as a backoff language model in ARPA format, with
+// n-grams up to one more than the chain order, so it can be used by SRILM, KenLM
+// and speech toolchains. Backoff weights come from absolute discounting, which needs
+// counts, so chains holding float weights can't be exported.
+func (chain *Chain) ExportARPA(w io.Writer) error {
+	m, err := chain.arpaModel()
+	if err != nil {
+		return err
+	}
+	logProbs, backoffs := m.probabilities()
+	n := len(logProbs) - 1
+
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, "\\data\\")
+	for k := 1; k <= n; k++ {
+		fmt.Fprintf(bw, "ngram %d=%d\n", k, len(logProbs[k]))
+	}
+	for k := 1; k <= n; k++ {
+		fmt.Fprintf(bw, "\n\\%d-grams:\n", k)
+		grams := make([]string, 0, len(logProbs[k]))
+		for gram := range logProbs[k] {
+			grams = append(grams, gram)
+		}
+		sort.Strings(grams)
+		for _, gram := range grams {
+			fmt.Fprintf(bw, "%.6f\t%s", logProbs[k][gram], gram)
+			if backoff, ok := backoffs[k][gram]; ok {
+				fmt.Fprintf(bw, "\t%.6f", backoff)
+			}
+			fmt.Fprintln(bw)
+		}
+	}
+	fmt.Fprintln(bw, "\n\\end\\")
+	return bw.Flush()
+}
+
+// arpaCountScale converts ARPA probabilities into the integer counts of a Chain
+const arpaCountScale = 1e6
+
+// ARPAModel is a backoff language model read from an ARPA file, holding the log10
+// probabilities and backoff weights of n-grams of every order
+type ARPAModel struct {
+	logProbs []map[string]float64
+	backoffs []map[string]float64
+}
+
+var arpaCountLine = regexp.MustCompile(`^ngram (\d+)=(\d+)$`)
+var arpaSectionLine = regexp.MustCompile(`^\\(\d+)-grams:$`)
+
+// ImportARPA reads a backoff language model in ARPA format, such as those built by
+// SRILM or KenLM
+func ImportARPA(r io.Reader) (*ARPAModel, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	expected := map[int]int{}
+	m := &ARPAModel{}
+	section := -1
+	ended := false
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		switch {
+		case text == "" || ended:
+			continue
+		case text == "\\data\\":
+			section = 0
+		case text == "\\end\\":
+			ended = true
+		case section == 0 && arpaCountLine.MatchString(text):
+			match := arpaCountLine.FindStringSubmatch(text)
+			k, _ := strconv.Atoi(match[1])
+			count, _ := strconv.Atoi(match[2])
+			expected[k] = count
+		case arpaSectionLine.MatchString(text):
+			section, _ = strconv.Atoi(arpaSectionLine.FindStringSubmatch(text)[1])
+			if _, ok := expected[section]; !ok || section < 1 {
+				return nil, fmt.Errorf("Line %d: unexpected %d-grams section", line, section)
+			}
+			for len(m.logProbs) <= section {
+				m.logProbs = append(m.logProbs, make(map[string]float64))
+				m.backoffs = append(m.backoffs, make(map[string]float64))
+			}
+		case section > 0:
+			fields := strings.Fields(text)
+			if len(fields) != section+1 && len(fields) != section+2 {
+				return nil, fmt.Errorf("Line %d: expected a %d-gram", line, section)
+			}
+			logProb, err := strconv.ParseFloat(fields[0], 64)
+			if err != nil {
+				return nil, fmt.Errorf("Line %d: invalid probability %q", line, fields[0])
+			}
+			gram := strings.Join(fields[1:section+1], " ")
+			m.logProbs[section][gram] = logProb
+			if len(fields) == section+2 {
+				backoff, err := strconv.ParseFloat(fields[section+1], 64)
+				if err != nil {
+					return nil, fmt.Errorf("Line %d: invalid backoff %q", line, fields[section+1])
+				}
+				m.backoffs[section][gram] = backoff
+			}
+		default:
+			return nil, fmt.Errorf("Line %d: unexpected %q", line, text)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if !ended {
+		return nil, errors.New("ARPA model is truncated")
+	}
+	if len(expected) == 0 {
+		return nil, errors.New("ARPA model has no n-grams")
+	}
+	for k, count := range expected {
+		if k >= len(m.logProbs) || len(m.logProbs[k]) != count {
+			return nil, fmt.Errorf("Expected %d %d-grams", count, k)
+		}
+	}
+	return m, nil
+}
+
+// Order returns the longest n-gram length of the model
+func (m *ARPAModel) Order() int {
+	return len(m.logProbs) - 1
+}
+
+// LogProb returns the log10 probability of next following the context, backing off
+// to shorter contexts for n-grams the model doesn't list. Leading start tokens in the
+// context are treated as a single sentence start.
+func (m *ARPAModel) LogProb(next string, context NGram) float64 {
+	tokens := make([]string, 0, len(context)+1)
+	for _, token := range context {
+		token = toARPA(token)
+		if token == arpaStart && len(tokens) > 0 && tokens[len(tokens)-1] == arpaStart {
+			continue
+		}
+		tokens = append(tokens, token)
+	}
+	tokens = append(tokens, toARPA(next))
+	if len(tokens) > m.Order() {
+		tokens = tokens[len(tokens)-m.Order():]
+	}
+	return m.logProb(tokens)
+}
+
+func (m *ARPAModel) logProb(tokens []string) float64 {
+	k := len(tokens)
+	if p, ok := m.logProbs[k][strings.Join(tokens, " ")]; ok {
+		return p
+	}
+	if k == 1 {
+		if p, ok := m.logProbs[1]["<unk>"]; ok {
+			return p
+		}
+		return -99
+	}
+	return m.backoffs[k-1][strings.Join(tokens[:k-1], " ")] + m.logProb(tokens[1:])
+}
+
+// Chain converts the model's longest n-grams into a chain of order Order()-1, with
+// counts proportional to their probabilities. Shorter n-grams following the sentence
+// start become the start transitions. Backoff mass has no counterpart in a chain and
+// is dropped, so each row is renormalized over the n-grams the model lists.
+func (m *ARPAModel) Chain() (*Chain, error) {
+	n := m.Order()
+	if n < 2 {
+		return nil, errors.New("ARPA model needs at least 2-grams to build a chain")
+	}
+	chain := NewChain(n - 1)
+	for k := 2; k <= n; k++ {
+		grams := make([]string, 0, len(m.logProbs[k]))
+		for gram := range m.logProbs[k] {
+			grams = append(grams, gram)
+		}
+		sort.Strings(grams)
+		for _, gram := range grams {
+			tokens := strings.Fields(gram)
+			if k < n && tokens[0] != arpaStart {
+				continue
+			}
+			count := int(math.Round(math.Pow(10, m.logProbs[k][gram]) * arpaCountScale))
+			if count == 0 {
+				continue
+			}
+			current := array(StartToken, n-k)
+			for _, token := range tokens[:k-1] {
+				current = append(current, fromARPA(token))
+			}
+			if err := chain.addEndTransition(current, fromARPA(tokens[k-1]), count); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return chain, nil
+}
+
+func fromARPA(token string) string {
+	switch token {
+	case arpaStart:
+		return StartToken
+	case arpaEnd:
+		return EndToken
+	}
+	return token
+}
+
+func toARPA(token string) string {
+	switch token {
+	case StartToken:
+		return arpaStart
+	case EndToken:
+		return arpaEnd
+	}
+	return token
+}
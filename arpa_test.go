@@ -0,0 +1,196 @@
+package gomarkov
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestChain_ExportARPA(t *testing.T) {
+	tests := []struct {
+		name  string
+		order int
+		data  [][]string
+		want  string
+	}{
+		{
+			"Order 1",
+			1,
+			[][]string{{"a", "b"}, {"a", "c"}},
+			`\data\
+ngram 1=5
+ngram 2=5
+
+\1-grams:
+-0.477121	</s>
+-99.000000	<s>	-0.425969
+-0.477121	a	-0.124939
+-0.778151	b	-0.124939
+-0.778151	c	-0.124939
+
+\2-grams:
+-0.124939	<s> a
+-0.602060	a b
+-0.602060	a c
+-0.301030	b </s>
+-0.301030	c </s>
+
+\end\
+`,
+		},
+		{
+			"Order 2 collapses boundary padding",
+			2,
+			[][]string{{"a", "b"}},
+			`\data\
+ngram 1=4
+ngram 2=3
+ngram 3=2
+
+\1-grams:
+-0.477121	</s>
+-99.000000	<s>	-0.124939
+-0.477121	a	-0.124939
+-0.477121	b	-0.124939
+
+\2-grams:
+-0.301030	<s> a	0.000000
+-0.301030	a b	0.000000
+-0.301030	b </s>
+
+\3-grams:
+-0.301030	<s> a b
+-0.301030	a b </s>
+
+\end\
+`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chain := NewChain(tt.order)
+			for _, data := range tt.data {
+				chain.Add(data)
+			}
+			var buf strings.Builder
+			if err := chain.ExportARPA(&buf); err != nil {
+				t.Fatalf("Chain.ExportARPA() error = %v", err)
+			}
+			if buf.String() != tt.want {
+				t.Errorf("Chain.ExportARPA() = %v, want %v", buf.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestChain_ExportARPA_Whitespace(t *testing.T) {
+	chain := NewChain(1)
+	chain.Add([]string{"two words"})
+	if err := chain.ExportARPA(&strings.Builder{}); err == nil {
+		t.Errorf("Chain.ExportARPA() expected an error for tokens with whitespace")
+	}
+}
+
+func TestImportARPA(t *testing.T) {
+	chain := NewChain(1)
+	chain.Add([]string{"a", "b"})
+	chain.Add([]string{"a", "c"})
+	var buf strings.Builder
+	if err := chain.ExportARPA(&buf); err != nil {
+		t.Fatalf("Chain.ExportARPA() error = %v", err)
+	}
+	m, err := ImportARPA(strings.NewReader(buf.String()))
+	if err != nil {
+		t.Fatalf("ImportARPA() error = %v", err)
+	}
+	if m.Order() != 2 {
+		t.Errorf("ARPAModel.Order() = %d, want 2", m.Order())
+	}
+
+	tests := []struct {
+		name    string
+		next    string
+		context NGram
+		want    float64
+	}{
+		{"Listed bigram", "b", NGram{"a"}, 0.25},
+		{"Start bigram", "a", NGram{StartToken}, 0.75},
+		{"Padded start", "a", NGram{StartToken, StartToken}, 0.75},
+		{"Backed off bigram", EndToken, NGram{"a"}, 0.25},
+		{"Unigram", "a", NGram{}, 1.0 / 3.0},
+		{"Unknown token", "zzz", NGram{"a"}, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := math.Pow(10, m.LogProb(tt.next, tt.context))
+			if math.Abs(got-tt.want) > 1e-5 {
+				t.Errorf("ARPAModel.LogProb() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	sum := 0.0
+	for _, token := range []string{"a", "b", "c", EndToken} {
+		sum += math.Pow(10, m.LogProb(token, NGram{"a"}))
+	}
+	if math.Abs(sum-1) > 1e-5 {
+		t.Errorf("ARPAModel.LogProb() does not sum to 1 over the vocabulary: %v", sum)
+	}
+}
+
+func TestARPAModel_Chain(t *testing.T) {
+	trained := NewChain(2)
+	trained.Add([]string{"a", "b"})
+	trained.Add([]string{"a", "c"})
+	var buf strings.Builder
+	trained.ExportARPA(&buf)
+	m, err := ImportARPA(strings.NewReader(buf.String()))
+	if err != nil {
+		t.Fatalf("ImportARPA() error = %v", err)
+	}
+	chain, err := m.Chain()
+	if err != nil {
+		t.Fatalf("ARPAModel.Chain() error = %v", err)
+	}
+	if chain.Order != 2 {
+		t.Errorf("ARPAModel.Chain() order = %d, want 2", chain.Order)
+	}
+	tests := []struct {
+		next    string
+		current NGram
+	}{
+		{"a", NGram{StartToken, StartToken}},
+		{"b", NGram{StartToken, "a"}},
+		{"c", NGram{StartToken, "a"}},
+		{EndToken, NGram{"a", "b"}},
+		{EndToken, NGram{"b", EndToken}},
+	}
+	for _, tt := range tests {
+		want, _ := trained.TransitionProbability(tt.next, tt.current)
+		got, err := chain.TransitionProbability(tt.next, tt.current)
+		if err != nil || math.Abs(got-want) > 1e-5 {
+			t.Errorf("Chain.TransitionProbability(%q, %v) = %v, %v, want %v", tt.next, tt.current, got, err, want)
+		}
+	}
+}
+
+func TestImportARPA_Invalid(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{"Empty", ""},
+		{"Truncated", "\\data\\\nngram 1=1\n\n\\1-grams:\n-1.0\ta\n"},
+		{"Count mismatch", "\\data\\\nngram 1=2\n\n\\1-grams:\n-1.0\ta\n\n\\end\\\n"},
+		{"Undeclared section", "\\data\\\nngram 1=1\n\n\\2-grams:\n-1.0\ta b\n\n\\end\\\n"},
+		{"Invalid probability", "\\data\\\nngram 1=1\n\n\\1-grams:\nx\ta\n\n\\end\\\n"},
+		{"Wrong arity", "\\data\\\nngram 1=1\n\n\\1-grams:\n-1.0\ta b c\n\n\\end\\\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ImportARPA(strings.NewReader(tt.input)); err == nil {
+				t.Errorf("ImportARPA() expected an error")
+			}
+		})
+	}
+}
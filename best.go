@@ -0,0 +1,109 @@
+package gomarkov
+
+import (
+	"errors"
+	"math"
+	"sort"
+	"time"
+)
+
+// BestOption configures BestSequence
+type BestOption func(*bestOptions)
+
+type bestOptions struct {
+	atMost bool
+}
+
+// BestAtMost finds the most probable sequence of up to the given length instead of
+// exactly that length. Shorter sequences are usually more probable, as every token
+// multiplies in another probability.
+func BestAtMost() BestOption {
+	return func(o *bestOptions) {
+		o.atMost = true
+	}
+}
+
+// bestPath is a partial sequence in BestSequence, sharing its earlier tokens with
+// the paths it was extended from
+type bestPath struct {
+	logProb float64
+	token   string
+	prev    *bestPath
+}
+
+// BestSequence returns the most probable sequence of exactly length tokens from the
+// start to the end, along with its log probability as LogProbability computes it.
+// Unlike generating, it is deterministic, summing up what the chain considers most
+// typical. It is found with the Viterbi algorithm, taking time proportional to the
+// length times the number of transitions. If the chain can't produce a sequence that
+// long, the error is ErrUnsatisfiable. Ties go to the tokens of the state visited first.
+func (chain *Chain) BestSequence(length int, opts ...BestOption) (tokens []string, logProb float64, err error) {
+	if chain.metrics != nil {
+		defer func(start time.Time) {
+			chain.observeGenerate(start, len(tokens), err)
+		}(time.Now())
+	}
+	var o bestOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if length < 1 {
+		return nil, 0, errors.New("Sequence length must be positive")
+	}
+	states, err := chain.stateRows()
+	if err != nil {
+		return nil, 0, err
+	}
+	if len(states) == 0 {
+		return nil, 0, ErrEmptyChain
+	}
+
+	// layer holds the most probable path of k tokens into each state
+	layer := map[string]*bestPath{NGram(boundary(StartToken, chain.Order)).key(): {}}
+	var best *bestPath
+	for k := 0; k <= length && len(layer) > 0; k++ {
+		keys := make([]string, 0, len(layer))
+		for key := range layer {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		next := make(map[string]*bestPath)
+		for _, key := range keys {
+			path, s := layer[key], states[key]
+			if s == nil {
+				continue
+			}
+			for _, t := range s.transitions {
+				if t.Probability == 0 {
+					continue
+				}
+				lp := path.logProb + math.Log(t.Probability)
+				if t.Next == EndToken {
+					if (k == length || o.atMost) && (best == nil || lp > best.logProb) {
+						best = &bestPath{logProb: lp, prev: path}
+					}
+					continue
+				}
+				if k == length {
+					continue
+				}
+				to := s.ngram.Shift(t.Next).key()
+				if p, ok := next[to]; !ok || lp > p.logProb {
+					next[to] = &bestPath{logProb: lp, token: t.Next, prev: path}
+				}
+			}
+		}
+		layer = next
+	}
+	if best == nil {
+		return nil, 0, ErrUnsatisfiable
+	}
+	// The last path only marks the end, and the first is the empty start
+	for p := best.prev; p.prev != nil; p = p.prev {
+		tokens = append(tokens, p.token)
+	}
+	for i, j := 0, len(tokens)-1; i < j; i, j = i+1, j-1 {
+		tokens[i], tokens[j] = tokens[j], tokens[i]
+	}
+	return tokens, best.logProb, nil
+}
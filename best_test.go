@@ -0,0 +1,69 @@
+package gomarkov
+
+import (
+	"errors"
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestChain_BestSequence(t *testing.T) {
+	corpus := [][]string{
+		{"a", "b", "c"}, {"a", "b", "c"}, {"a", "b", "c"},
+		{"a", "c"}, {"a", "c"},
+		{"d"},
+	}
+	tests := []struct {
+		name     string
+		order    int
+		length   int
+		opts     []BestOption
+		want     []string
+		wantProb float64
+		wantErr  error
+	}{
+		{"Single token", 1, 1, nil, []string{"d"}, 1.0 / 6, nil},
+		{"Two tokens", 1, 2, nil, []string{"a", "c"}, 1.0 / 3, nil},
+		{"Three tokens", 1, 3, nil, []string{"a", "b", "c"}, 0.5, nil},
+		{"Too long", 1, 4, nil, nil, 0, ErrUnsatisfiable},
+		{"At most two tokens", 1, 2, []BestOption{BestAtMost()}, []string{"a", "c"}, 1.0 / 3, nil},
+		{"At most four tokens", 1, 4, []BestOption{BestAtMost()}, []string{"a", "b", "c"}, 0.5, nil},
+		{"Order 2", 2, 3, nil, []string{"a", "b", "c"}, 0.5, nil},
+		{"Unigram", 0, 1, nil, []string{"a"}, 5.0 / 20 * 6.0 / 20, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chain := NewChain(tt.order)
+			chain.AddBatch(corpus)
+			got, logProb, err := chain.BestSequence(tt.length, tt.opts...)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Chain.BestSequence() error = %v, want %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Chain.BestSequence() = %v, want %v", got, tt.want)
+			}
+			if err != nil {
+				return
+			}
+			if math.Abs(logProb-math.Log(tt.wantProb)) > 1e-9 {
+				t.Errorf("Chain.BestSequence() log probability = %v, want %v", logProb, math.Log(tt.wantProb))
+			}
+			if scored, _ := chain.LogProbability(got); math.Abs(scored-logProb) > 1e-9 {
+				t.Errorf("Chain.LogProbability() of the best sequence = %v, want %v", scored, logProb)
+			}
+		})
+	}
+
+	// Exact lengths follow cycles as often as needed
+	loop := NewChain(1)
+	loop.AddBatch([][]string{{"x", "x"}, {"y"}})
+	if got, _, err := loop.BestSequence(4); err != nil || !reflect.DeepEqual(got, []string{"x", "x", "x", "x"}) {
+		t.Errorf("Chain.BestSequence(4) around a cycle = %v, %v", got, err)
+	}
+	if _, _, err := NewChain(1).BestSequence(2); !errors.Is(err, ErrEmptyChain) {
+		t.Errorf("Chain.BestSequence() of an empty chain error = %v, want ErrEmptyChain", err)
+	}
+	if _, _, err := loop.BestSequence(0); err == nil {
+		t.Errorf("Chain.BestSequence(0) succeeded")
+	}
+}
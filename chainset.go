@@ -0,0 +1,68 @@
+package gomarkov
+
+import (
+	"sort"
+	"sync"
+)
+
+// ChainSet holds a chain per label, such as one per author, channel or language,
+// each created on first use. It is safe for concurrent use.
+type ChainSet struct {
+	order  int
+	opts   []ChainOption
+	lock   sync.RWMutex
+	chains map[string]*Chain
+}
+
+// NewChainSet creates an empty set whose chains are created with NewChain(order,
+// opts...). Options holding state, such as WithLengthMatching, share it between
+// every chain of the set. It panics if order is negative.
+func NewChainSet(order int, opts ...ChainOption) *ChainSet {
+	if err := validateOrder(order); err != nil {
+		panic("gomarkov: " + err.Error())
+	}
+	return &ChainSet{order: order, opts: opts, chains: make(map[string]*Chain)}
+}
+
+// Chain returns the chain of a label, creating it if the set doesn't have one yet
+func (s *ChainSet) Chain(label string) *Chain {
+	s.lock.RLock()
+	chain, ok := s.chains[label]
+	s.lock.RUnlock()
+	if ok {
+		return chain
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if chain, ok := s.chains[label]; ok {
+		return chain
+	}
+	chain = NewChain(s.order, s.opts...)
+	s.chains[label] = chain
+	return chain
+}
+
+// Lookup returns the chain of a label and whether the set has one
+func (s *ChainSet) Lookup(label string) (*Chain, bool) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	chain, ok := s.chains[label]
+	return chain, ok
+}
+
+// Labels returns the labels of the set's chains in sorted order
+func (s *ChainSet) Labels() []string {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	labels := make([]string, 0, len(s.chains))
+	for label := range s.chains {
+		labels = append(labels, label)
+	}
+	sort.Strings(labels)
+	return labels
+}
+
+// Add adds a sequence to the chain of a label, see Chain.Add
+func (s *ChainSet) Add(label string, input []string) error {
+	return s.Chain(label).Add(input)
+}
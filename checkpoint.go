@@ -0,0 +1,164 @@
+package gomarkov
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// CheckpointOption configures a Checkpointer
+type CheckpointOption func(*Checkpointer)
+
+// CheckpointEvery saves a checkpoint once n sequences have been trained on since the
+// last one. Sequences added with a weight count that many times.
+func CheckpointEvery(n int) CheckpointOption {
+	return func(c *Checkpointer) {
+		c.every = n
+	}
+}
+
+// CheckpointInterval saves a checkpoint once d has passed since the last one. It is
+// checked as sequences are trained on, so an idle chain isn't saved again.
+func CheckpointInterval(d time.Duration) CheckpointOption {
+	return func(c *Checkpointer) {
+		c.interval = d
+	}
+}
+
+// CheckpointKeep sets how many checkpoints are kept, 3 by default. The newest is
+// always at the Checkpointer's path and older ones at path.1, path.2 and so on.
+func CheckpointKeep(n int) CheckpointOption {
+	return func(c *Checkpointer) {
+		c.keep = max(n, 1)
+	}
+}
+
+// Checkpointer periodically saves the JSON of a chain as it trains, so a long
+// training run that crashes can resume from its last checkpoint. Each checkpoint is
+// taken from a Snapshot and written in the background, so training doesn't wait for
+// it. A checkpoint that comes due while another is still being written is skipped.
+// Errors are kept and returned by Err and Close.
+type Checkpointer struct {
+	path     string
+	every    int
+	interval time.Duration
+	keep     int
+
+	lock sync.Mutex
+	// chain is the chain last trained, written once more by Close
+	chain *Chain
+	// pending is the number of sequences trained on since the last checkpoint
+	pending int
+	last    time.Time
+	saving  bool
+	err     error
+	wg      sync.WaitGroup
+}
+
+// NewCheckpointer creates a Checkpointer saving to path, see WithCheckpoints. Without
+// CheckpointEvery or CheckpointInterval only Close saves a checkpoint.
+func NewCheckpointer(path string, opts ...CheckpointOption) *Checkpointer {
+	c := &Checkpointer{path: path, keep: 3}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// trained records that chain has been trained on n more sequences, starting a
+// checkpoint if one is due
+func (c *Checkpointer) trained(chain *Chain, n int) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	now := time.Now()
+	if c.last.IsZero() {
+		c.last = now
+	}
+	c.chain = chain
+	c.pending += n
+	due := (c.every > 0 && c.pending >= c.every) || (c.interval > 0 && now.Sub(c.last) >= c.interval)
+	if !due || c.saving {
+		return
+	}
+	snapshot, err := chain.Snapshot()
+	if err != nil {
+		c.fail(err)
+		return
+	}
+	c.pending, c.last, c.saving = 0, now, true
+	c.wg.Add(1)
+	go func() {
+		defer c.wg.Done()
+		err := c.save(snapshot)
+		c.lock.Lock()
+		defer c.lock.Unlock()
+		c.saving = false
+		if err != nil {
+			c.fail(err)
+		}
+	}()
+}
+
+// fail records the first error, the caller must hold the lock
+func (c *Checkpointer) fail(err error) {
+	if c.err == nil {
+		c.err = fmt.Errorf("Checkpoint %s: %w", c.path, err)
+	}
+}
+
+// Err returns the first error saving a checkpoint, if any
+func (c *Checkpointer) Err() error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.err
+}
+
+// Close waits for a checkpoint being written, then saves a last one if anything has
+// been trained on since. It returns the first error saving a checkpoint and must be
+// called once training is done.
+func (c *Checkpointer) Close() error {
+	c.wg.Wait()
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.pending > 0 && c.chain != nil {
+		if err := c.save(c.chain); err != nil {
+			c.fail(err)
+		}
+		c.pending = 0
+	}
+	return c.err
+}
+
+// save writes the chain to a temporary file, rotates the older checkpoints and moves
+// the new one into place, so path always holds a whole model
+func (c *Checkpointer) save(chain *Chain) error {
+	data, err := chain.MarshalJSON()
+	if err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(filepath.Dir(c.path), filepath.Base(c.path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	for i := c.keep - 1; i >= 1; i-- {
+		from := c.path
+		if i > 1 {
+			from = fmt.Sprintf("%s.%d", c.path, i-1)
+		}
+		if err := os.Rename(from, fmt.Sprintf("%s.%d", c.path, i)); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+	return os.Rename(f.Name(), c.path)
+}
@@ -0,0 +1,62 @@
+package gomarkov
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestWithCheckpoints(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "model.json")
+	c := NewCheckpointer(path, CheckpointEvery(1), CheckpointKeep(2))
+	chain := NewChain(1, WithCheckpoints(c))
+	for _, input := range [][]string{{"a", "b"}, {"b", "c"}, {"c", "a"}, {"a", "c"}} {
+		if err := chain.Add(input); err != nil {
+			t.Fatal(err)
+		}
+	}
+	chain.AddBatch([][]string{{"b", "a"}, {"c", "b"}})
+	if err := c.Close(); err != nil {
+		t.Fatalf("Checkpointer.Close() error = %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var restored Chain
+	if err := restored.UnmarshalJSON(data); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := transitionCounts(t, &restored), transitionCounts(t, chain); !reflect.DeepEqual(got, want) {
+		t.Errorf("last checkpoint counts = %v, want %v", got, want)
+	}
+	if _, err := os.Stat(path + ".1"); err != nil {
+		t.Errorf("previous checkpoint missing: %v", err)
+	}
+	if _, err := os.Stat(path + ".2"); !os.IsNotExist(err) {
+		t.Errorf("CheckpointKeep(2) kept a third checkpoint, stat error = %v", err)
+	}
+	matches, _ := filepath.Glob(path + ".*.tmp")
+	if len(matches) > 0 {
+		t.Errorf("temporary files left behind: %v", matches)
+	}
+}
+
+func TestCheckpointer_Err(t *testing.T) {
+	c := NewCheckpointer(filepath.Join(t.TempDir(), "missing", "model.json"), CheckpointEvery(1))
+	chain := NewChain(1, WithCheckpoints(c))
+	chain.Add([]string{"a"})
+	if err := c.Close(); err == nil {
+		t.Errorf("Checkpointer.Close() saving to a missing directory succeeded")
+	}
+	if c.Err() == nil {
+		t.Errorf("Checkpointer.Err() = nil after a failed checkpoint")
+	}
+
+	idle := NewCheckpointer(filepath.Join(t.TempDir(), "model.json"))
+	if err := idle.Close(); err != nil {
+		t.Errorf("Checkpointer.Close() without training error = %v", err)
+	}
+}
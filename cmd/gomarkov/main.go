@@ -0,0 +1,319 @@
+// Command gomarkov trains, samples, scores and inspects markov chains from the
+// command line.
+//
+//	gomarkov train -order 2 -o model.json corpus.txt
+//	gomarkov generate -m model.json -n 5
+//	gomarkov score -m model.json < sentences.txt
+//	gomarkov inspect -m model.json -limit 10
+//
+// Every line of input is a document, split into words or, with -chars, characters.
+// Models are read and written as JSON, compiled (.gmkc), CSV, markovify or ARPA,
+// chosen with -format or from the file extension.
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/mb-14/gomarkov"
+)
+
+func main() {
+	if err := run(os.Args[1:], os.Stdin, os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, "gomarkov:", err)
+		os.Exit(1)
+	}
+}
+
+const usage = `usage: gomarkov <command> [flags] [files]
+
+commands:
+  train     train a chain from text and save it
+  generate  generate sequences from a chain
+  score     print the log probability of each input line
+  inspect   print statistics and the most frequent transitions
+
+Run gomarkov <command> -h for the flags of a command.`
+
+func run(args []string, stdin io.Reader, stdout io.Writer) error {
+	if len(args) == 0 {
+		return errors.New(usage)
+	}
+	commands := map[string]func([]string, io.Reader, io.Writer) error{
+		"train":    train,
+		"generate": generate,
+		"score":    score,
+		"inspect":  inspect,
+	}
+	command, ok := commands[args[0]]
+	if !ok {
+		return fmt.Errorf("unknown command %q\n%s", args[0], usage)
+	}
+	return command(args[1:], stdin, stdout)
+}
+
+// tokenFlags are the flags shared by every command reading text
+type tokenFlags struct {
+	chars bool
+	lower bool
+}
+
+func (t *tokenFlags) register(fs *flag.FlagSet) {
+	fs.BoolVar(&t.chars, "chars", false, "split text into characters instead of words")
+	fs.BoolVar(&t.lower, "lower", false, "lower case all text")
+}
+
+func (t *tokenFlags) tokenize(line string) []string {
+	if t.lower {
+		line = strings.ToLower(line)
+	}
+	if t.chars {
+		return strings.Split(line, "")
+	}
+	return strings.Fields(line)
+}
+
+func (t *tokenFlags) join(tokens []string) string {
+	if t.chars {
+		return strings.Join(tokens, "")
+	}
+	return strings.Join(tokens, " ")
+}
+
+// modelFlags are the flags locating a saved model
+type modelFlags struct {
+	path   string
+	format string
+	order  int
+}
+
+func (m *modelFlags) register(fs *flag.FlagSet, name string) {
+	fs.StringVar(&m.path, name, "model.json", "model file")
+	fs.StringVar(&m.format, "format", "", "model format: json, compiled, csv, markovify or arpa (default from the file extension)")
+}
+
+func (m *modelFlags) resolveFormat() string {
+	if m.format != "" {
+		return m.format
+	}
+	switch filepath.Ext(m.path) {
+	case ".gmkc":
+		return "compiled"
+	case ".csv":
+		return "csv"
+	case ".arpa":
+		return "arpa"
+	}
+	return "json"
+}
+
+func (m *modelFlags) load() (*gomarkov.Chain, error) {
+	format := m.resolveFormat()
+	if format == "compiled" {
+		return gomarkov.OpenCompiled(m.path)
+	}
+	f, err := os.Open(m.path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	r := bufio.NewReader(f)
+	switch format {
+	case "json":
+		data, err := io.ReadAll(r)
+		if err != nil {
+			return nil, err
+		}
+		var chain gomarkov.Chain
+		if err := chain.UnmarshalJSON(data); err != nil {
+			return nil, err
+		}
+		return &chain, nil
+	case "csv":
+		return gomarkov.ImportCSV(r, m.order)
+	case "markovify":
+		return gomarkov.ImportMarkovify(r, m.order)
+	case "arpa":
+		model, err := gomarkov.ImportARPA(r)
+		if err != nil {
+			return nil, err
+		}
+		return model.Chain()
+	}
+	return nil, fmt.Errorf("unknown model format %q", format)
+}
+
+func (m *modelFlags) save(chain *gomarkov.Chain) error {
+	f, err := os.Create(m.path)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(f)
+	switch format := m.resolveFormat(); format {
+	case "json":
+		var data []byte
+		if data, err = chain.MarshalJSON(); err == nil {
+			_, err = w.Write(data)
+		}
+	case "compiled":
+		err = chain.Compile(w)
+	case "csv":
+		err = chain.ExportCSV(w)
+	case "markovify":
+		err = chain.ExportMarkovify(w)
+	case "arpa":
+		err = chain.ExportARPA(w)
+	default:
+		err = fmt.Errorf("unknown model format %q", format)
+	}
+	if err == nil {
+		err = w.Flush()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// readLines calls fn with every line of the named files, or of stdin if there are none
+func readLines(files []string, stdin io.Reader, fn func(line string) error) error {
+	scan := func(r io.Reader) error {
+		scanner := bufio.NewScanner(r)
+		scanner.Buffer(nil, 1<<20)
+		for scanner.Scan() {
+			if err := fn(scanner.Text()); err != nil {
+				return err
+			}
+		}
+		return scanner.Err()
+	}
+	if len(files) == 0 {
+		return scan(stdin)
+	}
+	for _, name := range files {
+		f, err := os.Open(name)
+		if err != nil {
+			return err
+		}
+		err = scan(f)
+		f.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func train(args []string, stdin io.Reader, stdout io.Writer) error {
+	fs := flag.NewFlagSet("train", flag.ContinueOnError)
+	var tokens tokenFlags
+	var model modelFlags
+	tokens.register(fs)
+	model.register(fs, "o")
+	fs.IntVar(&model.order, "order", 2, "chain order")
+	dedup := fs.Bool("dedup", false, "drop duplicate lines")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	corpus := &gomarkov.CorpusBuilder{Tokenize: tokens.tokenize, Dedup: *dedup}
+	err := readLines(fs.Args(), stdin, func(line string) error {
+		corpus.AddDocument(line)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	chains, err := corpus.Train(model.order)
+	if err != nil {
+		return err
+	}
+	stats := corpus.Stats()
+	fmt.Fprintf(stdout, "trained on %d documents, %d tokens, %d distinct (%d duplicates and %d empty dropped)\n",
+		stats.Documents, stats.Tokens, stats.Vocabulary, stats.Duplicates, stats.Empty)
+	return model.save(chains[0])
+}
+
+func generate(args []string, stdin io.Reader, stdout io.Writer) error {
+	fs := flag.NewFlagSet("generate", flag.ContinueOnError)
+	var tokens tokenFlags
+	var model modelFlags
+	tokens.register(fs)
+	model.register(fs, "m")
+	fs.IntVar(&model.order, "order", 2, "chain order of CSV and markovify models")
+	n := fs.Int("n", 1, "number of sequences to generate")
+	maxLength := fs.Int("max", 100, "maximum number of tokens per sequence, 0 for no limit")
+	seed := fs.Int64("seed", 0, "random seed, 0 for a random one")
+	start := fs.String("start", "", "text the sequences continue from")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	chain, err := model.load()
+	if err != nil {
+		return err
+	}
+	defer chain.Close()
+	var prng gomarkov.PRNG = rand.New(rand.NewSource(rand.Int63()))
+	if *seed != 0 {
+		prng = rand.New(rand.NewSource(*seed))
+	}
+	prefix := tokens.tokenize(*start)
+	current := gomarkov.FromWords(*start, chain.Order, tokens.tokenize)
+	for i := 0; i < *n; i++ {
+		seq, err := chain.GenerateSequenceDeterministic(current, *maxLength, prng)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(stdout, tokens.join(append(prefix[:len(prefix):len(prefix)], seq...)))
+	}
+	return nil
+}
+
+func score(args []string, stdin io.Reader, stdout io.Writer) error {
+	fs := flag.NewFlagSet("score", flag.ContinueOnError)
+	var tokens tokenFlags
+	var model modelFlags
+	tokens.register(fs)
+	model.register(fs, "m")
+	fs.IntVar(&model.order, "order", 2, "chain order of CSV and markovify models")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	chain, err := model.load()
+	if err != nil {
+		return err
+	}
+	defer chain.Close()
+	return readLines(fs.Args(), stdin, func(line string) error {
+		logProb, err := chain.LogProbability(tokens.tokenize(line))
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(stdout, "%.4f\t%s\n", logProb, line)
+		return err
+	})
+}
+
+func inspect(args []string, stdin io.Reader, stdout io.Writer) error {
+	fs := flag.NewFlagSet("inspect", flag.ContinueOnError)
+	var model modelFlags
+	model.register(fs, "m")
+	fs.IntVar(&model.order, "order", 2, "chain order of CSV and markovify models")
+	limit := fs.Int("limit", 10, "number of states and transitions per state to list, 0 for all")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	chain, err := model.load()
+	if err != nil {
+		return err
+	}
+	defer chain.Close()
+	fmt.Fprintln(stdout, chain)
+	return chain.Dump(stdout, *limit)
+}
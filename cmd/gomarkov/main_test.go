@@ -0,0 +1,68 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRun(t *testing.T) {
+	dir := t.TempDir()
+	corpus := filepath.Join(dir, "corpus.txt")
+	text := "I want a cheese burger\nI want a cheese burger\n\nI want a cheese burger\n"
+	if err := os.WriteFile(corpus, []byte(text), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range []string{"model.json", "model.gmkc", "model.csv"} {
+		t.Run(name, func(t *testing.T) {
+			model := filepath.Join(dir, name)
+			var out bytes.Buffer
+			if err := run([]string{"train", "-order", "2", "-dedup", "-o", model, corpus}, nil, &out); err != nil {
+				t.Fatalf("train error = %v", err)
+			}
+			if want := "trained on 1 documents, 5 tokens, 5 distinct (2 duplicates and 1 empty dropped)\n"; out.String() != want {
+				t.Errorf("train output = %q, want %q", out.String(), want)
+			}
+
+			out.Reset()
+			if err := run([]string{"generate", "-m", model, "-n", "2", "-seed", "1"}, nil, &out); err != nil {
+				t.Fatalf("generate error = %v", err)
+			}
+			if want := "I want a cheese burger\nI want a cheese burger\n"; out.String() != want {
+				t.Errorf("generate output = %q, want %q", out.String(), want)
+			}
+
+			out.Reset()
+			if err := run([]string{"generate", "-m", model, "-start", "a cheese"}, nil, &out); err != nil {
+				t.Fatalf("generate error = %v", err)
+			}
+			if want := "a cheese burger\n"; out.String() != want {
+				t.Errorf("generate output = %q, want %q", out.String(), want)
+			}
+
+			out.Reset()
+			in := strings.NewReader("I want a cheese burger\n")
+			if err := run([]string{"score", "-m", model}, in, &out); err != nil {
+				t.Fatalf("score error = %v", err)
+			}
+			if want := "0.0000\tI want a cheese burger\n"; out.String() != want {
+				t.Errorf("score output = %q, want %q", out.String(), want)
+			}
+
+			out.Reset()
+			if err := run([]string{"inspect", "-m", model, "-limit", "1"}, nil, &out); err != nil {
+				t.Fatalf("inspect error = %v", err)
+			}
+			if !strings.HasPrefix(out.String(), "gomarkov.Chain{order: 2, states: 7, transitions: 7, vocab: 5}\n") {
+				t.Errorf("inspect output = %q", out.String())
+			}
+		})
+	}
+
+	if err := run([]string{"unknown"}, nil, &bytes.Buffer{}); err == nil {
+		t.Errorf("run() with an unknown command succeeded")
+	}
+}
@@ -0,0 +1,120 @@
+// Command langid trains language identification models and classifies text with
+// them, see the langid package.
+//
+//	langid train -order 3 -o langid.json en=english.txt fr=french.txt
+//	langid classify -m langid.json < lines.txt
+//
+// Each sample file holds text in one language. Classify prints the most likely
+// language of every input line.
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/mb-14/gomarkov/langid"
+)
+
+func main() {
+	if err := run(os.Args[1:], os.Stdin, os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, "langid:", err)
+		os.Exit(1)
+	}
+}
+
+const usage = `usage: langid <command> [flags] [arguments]
+
+commands:
+  train     train a classifier from lang=file samples and save it
+  classify  print the language of each input line
+
+Run langid <command> -h for the flags of a command.`
+
+func run(args []string, stdin io.Reader, stdout io.Writer) error {
+	if len(args) == 0 {
+		return errors.New(usage)
+	}
+	commands := map[string]func([]string, io.Reader, io.Writer) error{
+		"train":    train,
+		"classify": classify,
+	}
+	command, ok := commands[args[0]]
+	if !ok {
+		return fmt.Errorf("unknown command %q\n%s", args[0], usage)
+	}
+	return command(args[1:], stdin, stdout)
+}
+
+func train(args []string, stdin io.Reader, stdout io.Writer) error {
+	fs := flag.NewFlagSet("train", flag.ContinueOnError)
+	out := fs.String("o", "langid.json", "model file")
+	order := fs.Int("order", 3, "chain order")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		return errors.New("train needs at least one lang=file sample")
+	}
+	c := langid.New(*order)
+	for _, arg := range fs.Args() {
+		lang, path, ok := strings.Cut(arg, "=")
+		if !ok || lang == "" {
+			return fmt.Errorf("sample %q is not of the form lang=file", arg)
+		}
+		sample, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		if err := c.Train(lang, string(sample)); err != nil {
+			return err
+		}
+	}
+	data, err := json.Marshal(c)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(*out, data, 0644); err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(stdout, "trained %s\n", strings.Join(c.Languages(), ", "))
+	return err
+}
+
+func classify(args []string, stdin io.Reader, stdout io.Writer) error {
+	fs := flag.NewFlagSet("classify", flag.ContinueOnError)
+	model := fs.String("m", "langid.json", "model file")
+	verbose := fs.Bool("v", false, "print the score of every language")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	data, err := os.ReadFile(*model)
+	if err != nil {
+		return err
+	}
+	var c langid.Classifier
+	if err := json.Unmarshal(data, &c); err != nil {
+		return err
+	}
+	scanner := bufio.NewScanner(stdin)
+	scanner.Buffer(nil, 1<<20)
+	for scanner.Scan() {
+		line := scanner.Text()
+		lang, scores, err := c.Classify(line)
+		if err != nil {
+			return err
+		}
+		if *verbose {
+			for _, s := range scores {
+				fmt.Fprintf(stdout, "%s=%.4f ", s.Language, s.LogProbability)
+			}
+		}
+		fmt.Fprintf(stdout, "%s\t%s\n", lang, line)
+	}
+	return scanner.Err()
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRun(t *testing.T) {
+	dir := t.TempDir()
+	samples := map[string]string{
+		"en.txt": "the quick brown fox jumps over the lazy dog\nwhere is the library\nthe weather is nice today\n",
+		"de.txt": "der schnelle braune fuchs springt über den faulen hund\nwo ist die bibliothek\ndas wetter ist heute schön\n",
+	}
+	for name, text := range samples {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(text), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	model := filepath.Join(dir, "langid.json")
+
+	var out bytes.Buffer
+	err := run([]string{"train", "-order", "2", "-o", model, "en=" + filepath.Join(dir, "en.txt"), "de=" + filepath.Join(dir, "de.txt")}, nil, &out)
+	if err != nil {
+		t.Fatalf("train error = %v", err)
+	}
+	if want := "trained de, en\n"; out.String() != want {
+		t.Errorf("train output = %q, want %q", out.String(), want)
+	}
+
+	out.Reset()
+	if err := run([]string{"classify", "-m", model}, strings.NewReader("the dog is lazy\nder hund ist faul\n"), &out); err != nil {
+		t.Fatalf("classify error = %v", err)
+	}
+	if want := "en\tthe dog is lazy\nde\tder hund ist faul\n"; out.String() != want {
+		t.Errorf("classify output = %q, want %q", out.String(), want)
+	}
+
+	if err := run([]string{"train", "-o", model, "english.txt"}, nil, &out); err == nil {
+		t.Error("train accepted a sample without a language")
+	}
+	if err := run([]string{"detect"}, nil, &out); err == nil {
+		t.Error("run accepted an unknown command")
+	}
+}
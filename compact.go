@@ -0,0 +1,137 @@
+package gomarkov
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+)
+
+var (
+	errCompactOverflow      = errors.New("Compact store is limited to 32-bit indices")
+	errCompactCountOverflow = fmt.Errorf("%w, compact store counts and row totals are limited to 32 bits", ErrCountOverflow)
+)
+
+// NewCompactMemoryStore creates an in-memory Store holding state indices and
+// transition counts as 32-bit integers, roughly halving the footprint of large
+// chains compared to NewMemoryStore. Rows are converted on every lookup, so it
+// trades some generation speed for memory. Adding more than math.MaxUint32 states
+// returns an error, and pushing a count or the total of its row past math.MaxUint32
+// returns ErrCountOverflow.
+func NewCompactMemoryStore() Store {
+	return &compactStore{
+		stringMap:    make(map[string]uint32),
+		frequencyMat: make(map[uint32]map[uint32]uint32),
+		totals:       make(map[uint32]uint32),
+	}
+}
+
+// compactStore is a memoryStore using 32-bit integers and a slice for the reverse spool
+type compactStore struct {
+	stringMap    map[string]uint32
+	states       []string
+	frequencyMat map[uint32]map[uint32]uint32
+	// totals holds the sum of each row, which must fit as well
+	totals map[uint32]uint32
+	lock   sync.RWMutex
+}
+
+func (c *compactStore) AddState(state string) (int, error) {
+	c.lock.RLock()
+	index, ok := c.stringMap[state]
+	c.lock.RUnlock()
+	if ok {
+		return int(index), nil
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if index, ok := c.stringMap[state]; ok {
+		return int(index), nil
+	}
+	if uint64(len(c.states)) >= math.MaxUint32 {
+		return 0, errCompactOverflow
+	}
+	index = uint32(len(c.states))
+	c.stringMap[state] = index
+	c.states = append(c.states, state)
+	return int(index), nil
+}
+
+func (c *compactStore) LookupState(state string) (int, bool, error) {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	index, ok := c.stringMap[state]
+	return int(index), ok, nil
+}
+
+func (c *compactStore) rangeStates(fn func(state string, index int)) {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	for index, state := range c.states {
+		fn(state, index)
+	}
+}
+
+func (c *compactStore) LookupIndex(index int) (string, bool, error) {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	if index < 0 || index >= len(c.states) {
+		return "", false, nil
+	}
+	return c.states[index], true, nil
+}
+
+func (c *compactStore) IncrementTransition(current, next, delta int) error {
+	if current < 0 || uint64(current) > math.MaxUint32 || next < 0 || uint64(next) > math.MaxUint32 {
+		return errCompactOverflow
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	row := c.frequencyMat[uint32(current)]
+	count := int64(row[uint32(next)]) + int64(delta)
+	total := int64(c.totals[uint32(current)]) + int64(delta)
+	// Counts are widened back to ints, which are only 32 bits on some platforms.
+	// A count never exceeds its row total, so checking the total bounds both.
+	if count < 0 || total > math.MaxUint32 || total > math.MaxInt {
+		return errCompactCountOverflow
+	}
+	if row == nil {
+		row = make(map[uint32]uint32)
+		c.frequencyMat[uint32(current)] = row
+	}
+	row[uint32(next)] = uint32(count)
+	c.totals[uint32(current)] = uint32(total)
+	return nil
+}
+
+func (c *compactStore) GetRow(current int) (map[int]int, error) {
+	if current < 0 || uint64(current) > math.MaxUint32 {
+		return nil, nil
+	}
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return widen(c.frequencyMat[uint32(current)]), nil
+}
+
+func (c *compactStore) IterateRows(fn func(current int, row map[int]int) bool) error {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	for current, row := range c.frequencyMat {
+		if !fn(int(current), widen(row)) {
+			break
+		}
+	}
+	return nil
+}
+
+// widen converts a compact row to the form returned by Store
+func widen(row map[uint32]uint32) map[int]int {
+	if row == nil {
+		return nil
+	}
+	wide := make(map[int]int, len(row))
+	for next, count := range row {
+		wide[int(next)] = int(count)
+	}
+	return wide
+}
@@ -0,0 +1,51 @@
+package gomarkov
+
+import (
+	"errors"
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestNewCompactMemoryStore(t *testing.T) {
+	chain := NewChainWithStore(1, NewCompactMemoryStore())
+	chain.Add([]string{"I", "want", "a", "cheese", "burger"})
+	chain.Add([]string{"I", "want", "a", "chilled", "sprite"})
+	want := NewChain(1)
+	want.Add([]string{"I", "want", "a", "cheese", "burger"})
+	want.Add([]string{"I", "want", "a", "chilled", "sprite"})
+
+	for _, tt := range []struct {
+		next    string
+		current NGram
+	}{{"cheese", NGram{"a"}}, {"want", NGram{"I"}}, {"burger", NGram{"want"}}} {
+		got, err := chain.TransitionProbability(tt.next, tt.current)
+		if err != nil {
+			t.Fatalf("Chain.TransitionProbability() error = %v", err)
+		}
+		if expected, _ := want.TransitionProbability(tt.next, tt.current); got != expected {
+			t.Errorf("Chain.TransitionProbability(%v, %v) = %v, want %v", tt.next, tt.current, got, expected)
+		}
+	}
+	if got, want := transitionCounts(t, chain), transitionCounts(t, want); !reflect.DeepEqual(got, want) {
+		t.Errorf("transitions = %v, want %v", got, want)
+	}
+}
+
+func Test_compactStore_Overflow(t *testing.T) {
+	s := NewCompactMemoryStore()
+	// Counts are limited to 32 bits, or to an int on platforms where that's smaller
+	if err := s.IncrementTransition(0, 1, min(math.MaxUint32, math.MaxInt)); err != nil {
+		t.Fatalf("compactStore.IncrementTransition() error = %v", err)
+	}
+	if err := s.IncrementTransition(0, 1, 1); !errors.Is(err, ErrCountOverflow) {
+		t.Errorf("compactStore.IncrementTransition() error = %v, want %v", err, ErrCountOverflow)
+	}
+	// The row total overflows even though the new count fits
+	if err := s.IncrementTransition(0, 2, 1); !errors.Is(err, ErrCountOverflow) {
+		t.Errorf("compactStore.IncrementTransition() of a full row error = %v, want %v", err, ErrCountOverflow)
+	}
+	if err := s.IncrementTransition(-1, 1, 1); err != errCompactOverflow {
+		t.Errorf("compactStore.IncrementTransition() error = %v, want %v", err, errCompactOverflow)
+	}
+}
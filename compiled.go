@@ -0,0 +1,360 @@
+package gomarkov
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"sync/atomic"
+)
+
+// Compiled models are a flat, little-endian, offset-indexed file that can be
+// memory-mapped and served without decoding:
+//
+//	header       magic, version, order, state count, edge count, string blob length
+//	strOffsets   [states+1]uint32 offsets of each state into the string blob
+//	blob         state strings in sorted order, padded to 4 bytes
+//	rowOffsets   [states+1]uint32 offsets of each state's row into cols/counts
+//	cols         [edges]uint32 next state index, sorted within each row
+//	counts       [edges]uint32 transition count
+//
+// State indices are positions in the sorted string table, so lookups are binary searches.
+const (
+	compiledMagic      = "GMKC"
+	compiledVersion    = 1
+	compiledHeaderSize = 32
+)
+
+var errReadOnly = errors.New("Compiled chains are read-only")
+
+// Compile writes the chain in the compiled read-only format, see OpenCompiled. The
+// format holds counts, so chains holding float weights can't be compiled.
+func (chain *Chain) Compile(w io.Writer) error {
+	spoolMap, freqMat, err := chain.exportCounts("compiled models")
+	if err != nil {
+		return err
+	}
+	states := make([]string, 0, len(spoolMap))
+	for state := range spoolMap {
+		states = append(states, state)
+	}
+	sort.Strings(states)
+	remap := make(map[int]uint32, len(states))
+	blobLen := 0
+	for i, state := range states {
+		remap[spoolMap[state]] = uint32(i)
+		blobLen += len(state)
+	}
+	edges := 0
+	for _, row := range freqMat {
+		edges += len(row)
+	}
+	if uint64(len(states)) >= math.MaxUint32 || uint64(edges) >= math.MaxUint32 || uint64(blobLen) >= math.MaxUint32 {
+		return errors.New("Chain is too large to compile")
+	}
+
+	bw := bufio.NewWriter(w)
+	header := make([]byte, compiledHeaderSize)
+	copy(header, compiledMagic)
+	binary.LittleEndian.PutUint32(header[4:], compiledVersion)
+	binary.LittleEndian.PutUint32(header[8:], uint32(chain.Order))
+	binary.LittleEndian.PutUint32(header[12:], uint32(len(states)))
+	binary.LittleEndian.PutUint32(header[16:], uint32(edges))
+	binary.LittleEndian.PutUint32(header[20:], uint32(blobLen))
+	bw.Write(header)
+
+	offset := 0
+	writeUint32(bw, 0)
+	for _, state := range states {
+		offset += len(state)
+		writeUint32(bw, uint32(offset))
+	}
+	for _, state := range states {
+		bw.WriteString(state)
+	}
+	bw.Write(make([]byte, padding(blobLen)))
+
+	rows := make([]sparseArray, len(states))
+	for oldIndex, row := range freqMat {
+		rows[remap[oldIndex]] = row
+	}
+	offset = 0
+	writeUint32(bw, 0)
+	for _, row := range rows {
+		offset += len(row)
+		writeUint32(bw, uint32(offset))
+	}
+	counts := make([]uint32, 0, edges)
+	for _, row := range rows {
+		cols := make([]uint32, 0, len(row))
+		for next := range row {
+			cols = append(cols, remap[next])
+		}
+		sort.Slice(cols, func(a, b int) bool { return cols[a] < cols[b] })
+		rowCounts := make(map[uint32]int, len(row))
+		for next, count := range row {
+			rowCounts[remap[next]] = count
+		}
+		for _, col := range cols {
+			count := rowCounts[col]
+			if count < 0 || uint64(count) > math.MaxUint32 {
+				return fmt.Errorf("Transition count %d cannot be compiled", count)
+			}
+			writeUint32(bw, col)
+			counts = append(counts, uint32(count))
+		}
+	}
+	for _, count := range counts {
+		writeUint32(bw, count)
+	}
+	return bw.Flush()
+}
+
+// OpenCompiled memory-maps a file written by Compile and returns a read-only chain
+// serving directly from the mapped pages. Close the chain to release the mapping.
+func OpenCompiled(path string) (*Chain, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if info.Size() < compiledHeaderSize {
+		return nil, errors.New("Compiled model is truncated")
+	}
+	data, unmap, err := mapFile(f, int(info.Size()))
+	if err != nil {
+		return nil, err
+	}
+	chain, err := loadCompiled(data, unmap)
+	if err != nil {
+		unmap()
+		return nil, err
+	}
+	return chain, nil
+}
+
+// LoadCompiled returns a read-only chain serving from a compiled model held in memory
+func LoadCompiled(data []byte) (*Chain, error) {
+	return loadCompiled(data, nil)
+}
+
+func loadCompiled(data []byte, closer func() error) (*Chain, error) {
+	h, err := parseCompiledHeader(data)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) != h.size() {
+		return nil, errors.New("Compiled model is truncated")
+	}
+	if err := validateOrder(h.order); err != nil {
+		return nil, err
+	}
+	s, err := newCompiledStore(h, data[:h.colsOffset()])
+	if err != nil {
+		return nil, err
+	}
+	cols := h.colsOffset()
+	s.cols = data[cols : cols+4*h.edges]
+	s.counts = data[cols+4*h.edges:]
+	s.closer = closer
+	return NewChainWithStore(h.order, s), nil
+}
+
+// compiledHeader describes the layout of a compiled model
+type compiledHeader struct {
+	order, states, edges, blobLen int
+}
+
+func parseCompiledHeader(data []byte) (compiledHeader, error) {
+	if len(data) < compiledHeaderSize || string(data[:4]) != compiledMagic {
+		return compiledHeader{}, errors.New("Not a compiled model")
+	}
+	if version := binary.LittleEndian.Uint32(data[4:]); version != compiledVersion {
+		return compiledHeader{}, fmt.Errorf("Unsupported compiled model version %d", version)
+	}
+	return compiledHeader{
+		order:   int(binary.LittleEndian.Uint32(data[8:])),
+		states:  int(binary.LittleEndian.Uint32(data[12:])),
+		edges:   int(binary.LittleEndian.Uint32(data[16:])),
+		blobLen: int(binary.LittleEndian.Uint32(data[20:])),
+	}, nil
+}
+
+// colsOffset is where the rows start, everything before it is the state index
+func (h compiledHeader) colsOffset() int {
+	return compiledHeaderSize + 8*(h.states+1) + h.blobLen + padding(h.blobLen)
+}
+
+func (h compiledHeader) size() int {
+	return h.colsOffset() + 8*h.edges
+}
+
+// newCompiledStore sets up a compiledStore over the state index of a compiled
+// model, everything up to colsOffset, leaving the rows to the caller
+func newCompiledStore(h compiledHeader, index []byte) (*compiledStore, error) {
+	s := &compiledStore{states: h.states, names: make([]atomic.Pointer[string], h.states)}
+	rest := index[compiledHeaderSize:]
+	take := func(n int) []byte {
+		b := rest[:n]
+		rest = rest[n:]
+		return b
+	}
+	s.strOffsets = take(4 * (h.states + 1))
+	s.blob = take(h.blobLen)
+	take(padding(h.blobLen))
+	s.rowOffsets = take(4 * (h.states + 1))
+	for i := 0; i < h.states; i++ {
+		if s.strOffset(i) > s.strOffset(i+1) || s.rowOffset(i) > s.rowOffset(i+1) {
+			return nil, errors.New("Compiled model offsets are corrupt")
+		}
+	}
+	if s.strOffset(h.states) != h.blobLen || s.rowOffset(h.states) != h.edges {
+		return nil, errors.New("Compiled model offsets are corrupt")
+	}
+	return s, nil
+}
+
+// compiledStore is a read-only Store over a compiled model
+type compiledStore struct {
+	states     int
+	strOffsets []byte
+	blob       []byte
+	rowOffsets []byte
+	cols       []byte
+	counts     []byte
+	closer     func() error
+	aliases    aliasCache
+	// names caches states decoded by LookupIndex, so generating a token that has
+	// been seen before doesn't allocate
+	names []atomic.Pointer[string]
+}
+
+func (c *compiledStore) strOffset(i int) int {
+	return int(binary.LittleEndian.Uint32(c.strOffsets[4*i:]))
+}
+
+func (c *compiledStore) rowOffset(i int) int {
+	return int(binary.LittleEndian.Uint32(c.rowOffsets[4*i:]))
+}
+
+func (c *compiledStore) state(i int) []byte {
+	return c.blob[c.strOffset(i):c.strOffset(i+1)]
+}
+
+func (c *compiledStore) AddState(state string) (int, error) {
+	index, ok, _ := c.LookupState(state)
+	if !ok {
+		return 0, errReadOnly
+	}
+	return index, nil
+}
+
+func (c *compiledStore) LookupState(state string) (int, bool, error) {
+	i := sort.Search(c.states, func(i int) bool {
+		return string(c.state(i)) >= state
+	})
+	if i < c.states && string(c.state(i)) == state {
+		return i, true, nil
+	}
+	return 0, false, nil
+}
+
+// lookupNGram is LookupState for an n-gram, building its key on the stack
+func (c *compiledStore) lookupNGram(ngram NGram) (int, bool, error) {
+	var buf [128]byte
+	key := ngram.appendKey(buf[:0])
+	// sort.Search would move the buffer to the heap through its closure
+	lo, hi := 0, c.states
+	for lo < hi {
+		mid := int(uint(lo+hi) >> 1)
+		if string(c.state(mid)) < string(key) {
+			lo = mid + 1
+		} else {
+			hi = mid
+		}
+	}
+	if lo < c.states && string(c.state(lo)) == string(key) {
+		return lo, true, nil
+	}
+	return 0, false, nil
+}
+
+func (c *compiledStore) LookupIndex(index int) (string, bool, error) {
+	if index < 0 || index >= c.states {
+		return "", false, nil
+	}
+	if name := c.names[index].Load(); name != nil {
+		return *name, true, nil
+	}
+	name := string(c.state(index))
+	c.names[index].Store(&name)
+	return name, true, nil
+}
+
+func (c *compiledStore) IncrementTransition(current, next, delta int) error {
+	return errReadOnly
+}
+
+func (c *compiledStore) GetRow(current int) (map[int]int, error) {
+	if current < 0 || current >= c.states {
+		return nil, nil
+	}
+	start, end := c.rowOffset(current), c.rowOffset(current+1)
+	row := make(map[int]int, end-start)
+	for i := start; i < end; i++ {
+		next := int(binary.LittleEndian.Uint32(c.cols[4*i:]))
+		row[next] = int(binary.LittleEndian.Uint32(c.counts[4*i:]))
+	}
+	return row, nil
+}
+
+func (c *compiledStore) IterateRows(fn func(current int, row map[int]int) bool) error {
+	for current := 0; current < c.states; current++ {
+		if c.rowOffset(current) == c.rowOffset(current+1) {
+			continue
+		}
+		row, _ := c.GetRow(current)
+		if !fn(current, row) {
+			break
+		}
+	}
+	return nil
+}
+
+// distribution samples rows with alias tables, as compiled models never change
+func (c *compiledStore) distribution(current int) (distribution, error) {
+	return c.aliases.get(current, c.GetRow)
+}
+
+// snapshot returns the store itself since compiled models never change
+func (c *compiledStore) snapshot() Store {
+	return c
+}
+
+// Close releases the memory mapping, if any
+func (c *compiledStore) Close() error {
+	if c.closer == nil {
+		return nil
+	}
+	closer := c.closer
+	c.closer = nil
+	return closer()
+}
+
+func writeUint32(w io.Writer, v uint32) {
+	var b [4]byte
+	binary.LittleEndian.PutUint32(b[:], v)
+	w.Write(b[:])
+}
+
+func padding(n int) int {
+	return (4 - n%4) % 4
+}
@@ -0,0 +1,106 @@
+package gomarkov
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func compiledTestChain() *Chain {
+	chain := NewChain(1)
+	chain.Add([]string{"test", "data"})
+	chain.Add([]string{"test", "data"})
+	chain.Add([]string{"test", "node"})
+	return chain
+}
+
+func TestChain_Compile(t *testing.T) {
+	var buf bytes.Buffer
+	if err := compiledTestChain().Compile(&buf); err != nil {
+		t.Fatalf("Chain.Compile() error = %v", err)
+	}
+	chain, err := LoadCompiled(buf.Bytes())
+	if err != nil {
+		t.Fatalf("LoadCompiled() error = %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		next    string
+		current NGram
+		want    float64
+	}{
+		{"Start transition", "test", NGram{StartToken}, 1},
+		{"Common transition", "data", NGram{"test"}, 2.0 / 3.0},
+		{"Rare transition", "node", NGram{"test"}, 1.0 / 3.0},
+		{"End transition", EndToken, NGram{"node"}, 1},
+		{"Unknown state", "data", NGram{"unknown"}, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := chain.TransitionProbability(tt.next, tt.current)
+			if err != nil {
+				t.Fatalf("Chain.TransitionProbability() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Chain.TransitionProbability() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if next, err := chain.Generate(NGram{"data"}); err != nil || next != EndToken {
+		t.Errorf("Chain.Generate() = %q, %v, want %q", next, err, EndToken)
+	}
+	if err := chain.Add([]string{"more"}); err == nil {
+		t.Errorf("Chain.Add() on a compiled chain should fail")
+	}
+}
+
+func TestOpenCompiled(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "model.gmkc")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := compiledTestChain().Compile(f); err != nil {
+		t.Fatalf("Chain.Compile() error = %v", err)
+	}
+	f.Close()
+
+	chain, err := OpenCompiled(path)
+	if err != nil {
+		t.Fatalf("OpenCompiled() error = %v", err)
+	}
+	defer chain.Close()
+	if chain.Order != 1 {
+		t.Errorf("OpenCompiled() order = %d, want 1", chain.Order)
+	}
+	got, err := chain.TransitionProbability("data", NGram{"test"})
+	if err != nil || got != 2.0/3.0 {
+		t.Errorf("Chain.TransitionProbability() = %v, %v, want %v", got, err, 2.0/3.0)
+	}
+}
+
+func TestLoadCompiled_Invalid(t *testing.T) {
+	var buf bytes.Buffer
+	compiledTestChain().Compile(&buf)
+	valid := buf.Bytes()
+
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"Empty", []byte{}},
+		{"Bad magic", append([]byte("NOPE"), valid[4:]...)},
+		{"Truncated", valid[:len(valid)-4]},
+		{"Trailing data", append(append([]byte{}, valid...), 0, 0, 0, 0)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := LoadCompiled(tt.data); err == nil {
+				t.Errorf("LoadCompiled() expected an error")
+			}
+		})
+	}
+}
@@ -0,0 +1,149 @@
+package gomarkov
+
+import (
+	"errors"
+	"sort"
+	"time"
+)
+
+// Constraint guides GenerateConstrained through the chain
+type Constraint interface {
+	// Allow reports whether next may follow tokens, the tokens generated so far.
+	// next is EndToken where the sequence could end, and allowing it completes the
+	// search.
+	Allow(tokens []string, next string) bool
+	// Done reports whether tokens complete the search without reaching the end of
+	// a sequence
+	Done(tokens []string) bool
+}
+
+// SearchOption configures GenerateConstrained
+type SearchOption func(*searchOptions)
+
+type searchOptions struct {
+	prng  PRNG
+	limit int
+}
+
+// SearchRand makes the search draw from prng instead of the chain's PRNG
+func SearchRand(prng PRNG) SearchOption {
+	return func(o *searchOptions) {
+		o.prng = prng
+	}
+}
+
+// SearchLimit sets how many tokens the search may try before giving up, 100000 by
+// default
+func SearchLimit(n int) SearchOption {
+	return func(o *searchOptions) {
+		o.limit = n
+	}
+}
+
+// errSearchLimit stops a search that has tried too many tokens
+var errSearchLimit = errors.New("Search limit reached")
+
+// GenerateConstrained generates tokens following seed that satisfy a constraint,
+// trying the likely tokens first but drawn at random like Generate. Unlike
+// rejection sampling, tokens the constraint rules out are never followed, and when
+// a path runs into a dead end the search backtracks to try the next candidate of
+// an earlier token. If no sequence is found within the search limit, the error is
+// ErrUnsatisfiable.
+func (chain *Chain) GenerateConstrained(seed NGram, c Constraint, opts ...SearchOption) (tokens []string, err error) {
+	if chain.metrics != nil {
+		defer func(start time.Time) {
+			chain.observeGenerate(start, len(tokens), err)
+		}(time.Now())
+	}
+	o := searchOptions{prng: chain.prng, limit: 100000}
+	if o.prng == nil {
+		o.prng = defaultPrng
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if len(seed) != chain.Order {
+		return nil, ErrOrderMismatch
+	}
+	s := &search{chain: chain, c: c, opts: o}
+	ok, err := s.extend(append(NGram{}, seed...), nil)
+	if err != nil && err != errSearchLimit {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrUnsatisfiable
+	}
+	return s.result, nil
+}
+
+// search is the state of a GenerateConstrained call
+type search struct {
+	chain  *Chain
+	c      Constraint
+	opts   searchOptions
+	tried  int
+	result []string
+}
+
+// extend searches depth first for a completion of tokens, whose last Order tokens
+// including the seed are current
+func (s *search) extend(current NGram, tokens []string) (bool, error) {
+	if s.c.Done(tokens) {
+		s.result = tokens
+		return true, nil
+	}
+	if current.ended() {
+		return false, nil
+	}
+	row, err := s.chain.Row(current)
+	if errors.Is(err, ErrUnknownNGram) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	for _, next := range shuffle(row, s.opts.prng) {
+		if s.tried++; s.tried > s.opts.limit {
+			return false, errSearchLimit
+		}
+		if !s.c.Allow(tokens, next) {
+			continue
+		}
+		if next == EndToken {
+			s.result = tokens
+			return true, nil
+		}
+		ok, err := s.extend(current.Shift(next), append(tokens[:len(tokens):len(tokens)], next))
+		if ok || err != nil {
+			return ok, err
+		}
+	}
+	return false, nil
+}
+
+// shuffle returns the tokens of a row in random order, drawing each next token with
+// probability proportional to its count among those left
+func shuffle(row map[string]int, prng PRNG) []string {
+	tokens := make([]string, 0, len(row))
+	total := 0
+	for token, count := range row {
+		tokens = append(tokens, token)
+		total += count
+	}
+	// Sort first so the order only depends on the PRNG
+	sort.Strings(tokens)
+	for i := range tokens {
+		if total <= 0 {
+			break
+		}
+		randN := prng.Intn(total)
+		for j := i; j < len(tokens); j++ {
+			if randN -= row[tokens[j]]; randN < 0 {
+				tokens[i], tokens[j] = tokens[j], tokens[i]
+				break
+			}
+		}
+		total -= row[tokens[i]]
+	}
+	return tokens
+}
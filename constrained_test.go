@@ -0,0 +1,80 @@
+package gomarkov
+
+import (
+	"errors"
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// lengthConstraint accepts whole sequences of exactly n tokens without banned words
+type lengthConstraint struct {
+	n      int
+	banned string
+}
+
+func (c lengthConstraint) Allow(tokens []string, next string) bool {
+	if next == EndToken {
+		return len(tokens) == c.n
+	}
+	return len(tokens) < c.n && next != c.banned
+}
+
+func (c lengthConstraint) Done(tokens []string) bool {
+	return false
+}
+
+func constrainedChain() *Chain {
+	chain := NewChain(1)
+	for i := 0; i < 50; i++ {
+		chain.Add(strings.Fields("the cat sat"))
+		chain.Add(strings.Fields("the dog ran home"))
+	}
+	chain.Add(strings.Fields("the cat ran away fast"))
+	return chain
+}
+
+func TestChain_GenerateConstrained(t *testing.T) {
+	chain := constrainedChain()
+	seed := NGram{StartToken}
+	prng := rand.New(rand.NewSource(1))
+
+	// Five tokens must end with the one rare sequence, however unlikely it is
+	got, err := chain.GenerateConstrained(seed, lengthConstraint{n: 5}, SearchRand(prng))
+	if err != nil {
+		t.Fatalf("Chain.GenerateConstrained() error = %v", err)
+	}
+	if want := strings.Fields("ran away fast"); len(got) != 5 || !reflect.DeepEqual(got[2:], want) {
+		t.Errorf("Chain.GenerateConstrained() = %v, want %v", got, want)
+	}
+
+	// Four tokens either go the dog ran home or the cat ran home
+	for i := 0; i < 20; i++ {
+		got, err = chain.GenerateConstrained(seed, lengthConstraint{n: 4, banned: "dog"}, SearchRand(prng))
+		if err != nil {
+			t.Fatalf("Chain.GenerateConstrained() error = %v", err)
+		}
+		if want := strings.Fields("the cat ran home"); !reflect.DeepEqual(got, want) {
+			t.Errorf("Chain.GenerateConstrained() = %v, want %v", got, want)
+		}
+	}
+}
+
+func TestChain_GenerateConstrained_unsatisfiable(t *testing.T) {
+	chain := constrainedChain()
+	seed := NGram{StartToken}
+
+	_, err := chain.GenerateConstrained(seed, lengthConstraint{n: 6})
+	if !errors.Is(err, ErrUnsatisfiable) {
+		t.Errorf("Chain.GenerateConstrained() error = %v, want %v", err, ErrUnsatisfiable)
+	}
+	_, err = chain.GenerateConstrained(seed, lengthConstraint{n: 5}, SearchLimit(2))
+	if !errors.Is(err, ErrUnsatisfiable) {
+		t.Errorf("Chain.GenerateConstrained(SearchLimit) error = %v, want %v", err, ErrUnsatisfiable)
+	}
+	_, err = chain.GenerateConstrained(NGram{"the", "cat"}, lengthConstraint{n: 5})
+	if !errors.Is(err, ErrOrderMismatch) {
+		t.Errorf("Chain.GenerateConstrained() error = %v, want %v", err, ErrOrderMismatch)
+	}
+}
@@ -0,0 +1,130 @@
+package gomarkov
+
+import (
+	"strings"
+)
+
+// CorpusBuilder prepares a corpus for training. Documents are tokenized,
+// normalized and optionally deduplicated as they are added, then used to train
+// chains of one or more orders. The zero value splits documents into whitespace
+// separated words and keeps every document. A CorpusBuilder is not safe for
+// concurrent use, though the chains it trains are.
+type CorpusBuilder struct {
+	// Tokenize splits a document into tokens, strings.Fields when nil
+	Tokenize func(string) []string
+	// Normalize is applied to every token, such as strings.ToLower. Tokens it maps
+	// to the empty string are dropped.
+	Normalize func(string) string
+	// Dedup drops documents whose normalized tokens match an earlier document
+	Dedup bool
+
+	docs  [][]string
+	seen  map[string]struct{}
+	vocab map[string]struct{}
+	stats CorpusStats
+}
+
+// CorpusStats describes the documents added to a CorpusBuilder
+type CorpusStats struct {
+	// Documents is the number of documents kept for training
+	Documents int
+	// Duplicates is the number of documents dropped by Dedup
+	Duplicates int
+	// Empty is the number of documents dropped for having no tokens
+	Empty int
+	// Tokens is the total number of tokens in kept documents
+	Tokens int
+	// Vocabulary is the number of distinct tokens in kept documents
+	Vocabulary int
+}
+
+// MeanLength returns the average number of tokens in a kept document
+func (s CorpusStats) MeanLength() float64 {
+	if s.Documents == 0 {
+		return 0
+	}
+	return float64(s.Tokens) / float64(s.Documents)
+}
+
+// AddDocument tokenizes and adds a document, reporting whether it was kept
+func (b *CorpusBuilder) AddDocument(text string) bool {
+	tokenize := b.Tokenize
+	if tokenize == nil {
+		tokenize = strings.Fields
+	}
+	return b.AddTokens(tokenize(text))
+}
+
+// AddTokens adds an already tokenized document, reporting whether it was kept.
+// tokens is not modified or retained.
+func (b *CorpusBuilder) AddTokens(tokens []string) bool {
+	doc := make([]string, 0, len(tokens))
+	for _, token := range tokens {
+		if b.Normalize != nil {
+			token = b.Normalize(token)
+		}
+		if token != "" {
+			doc = append(doc, token)
+		}
+	}
+	if len(doc) == 0 {
+		b.stats.Empty++
+		return false
+	}
+	if b.Dedup {
+		if b.seen == nil {
+			b.seen = make(map[string]struct{})
+		}
+		key := sequenceKey(doc)
+		if _, ok := b.seen[key]; ok {
+			b.stats.Duplicates++
+			return false
+		}
+		b.seen[key] = struct{}{}
+	}
+	if b.vocab == nil {
+		b.vocab = make(map[string]struct{})
+	}
+	for _, token := range doc {
+		b.vocab[token] = struct{}{}
+	}
+	b.docs = append(b.docs, doc)
+	b.stats.Documents++
+	b.stats.Tokens += len(doc)
+	b.stats.Vocabulary = len(b.vocab)
+	return true
+}
+
+// Stats returns statistics of the documents added so far
+func (b *CorpusBuilder) Stats() CorpusStats {
+	return b.stats
+}
+
+// Documents returns the kept documents as normalized tokens. They must not be modified.
+func (b *CorpusBuilder) Documents() [][]string {
+	return b.docs
+}
+
+// Train returns a new chain for each of the given orders, trained on every kept
+// document and presized for the corpus vocabulary
+func (b *CorpusBuilder) Train(orders ...int) ([]*Chain, error) {
+	chains := make([]*Chain, len(orders))
+	for i, order := range orders {
+		chains[i] = NewChain(order, WithExpectedVocab(b.stats.Vocabulary))
+	}
+	if err := b.TrainInto(chains...); err != nil {
+		return nil, err
+	}
+	return chains, nil
+}
+
+// TrainInto adds every kept document to each of the given chains, which may use
+// any store and already hold counts
+func (b *CorpusBuilder) TrainInto(chains ...*Chain) error {
+	for _, chain := range chains {
+		if err := chain.AddBatch(b.docs); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,59 @@
+package gomarkov
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCorpusBuilder(t *testing.T) {
+	b := &CorpusBuilder{Normalize: strings.ToLower, Dedup: true}
+	docs := []string{
+		"I want a cheese burger",
+		"i WANT a cheese burger",
+		"   ",
+		"I want a chilled sprite",
+	}
+	kept := []bool{true, false, false, true}
+	for i, doc := range docs {
+		if got := b.AddDocument(doc); got != kept[i] {
+			t.Errorf("CorpusBuilder.AddDocument(%q) = %v, want %v", doc, got, kept[i])
+		}
+	}
+	want := CorpusStats{Documents: 2, Duplicates: 1, Empty: 1, Tokens: 10, Vocabulary: 7}
+	if got := b.Stats(); got != want {
+		t.Errorf("CorpusBuilder.Stats() = %+v, want %+v", got, want)
+	}
+	if got := b.Stats().MeanLength(); got != 5 {
+		t.Errorf("CorpusStats.MeanLength() = %v, want 5", got)
+	}
+
+	chains, err := b.Train(1, 2)
+	if err != nil {
+		t.Fatalf("CorpusBuilder.Train() error = %v", err)
+	}
+	for i, order := range []int{1, 2} {
+		want := NewChain(order)
+		want.Add([]string{"i", "want", "a", "cheese", "burger"})
+		want.Add([]string{"i", "want", "a", "chilled", "sprite"})
+		if chains[i].Order != order {
+			t.Errorf("CorpusBuilder.Train() chain %d has order %d, want %d", i, chains[i].Order, order)
+		}
+		if !reflect.DeepEqual(transitionCounts(t, chains[i]), transitionCounts(t, want)) {
+			t.Errorf("CorpusBuilder.Train() order %d counts differ from Chain.Add()", order)
+		}
+	}
+}
+
+func TestCorpusBuilder_Tokenize(t *testing.T) {
+	var b CorpusBuilder
+	b.Tokenize = func(s string) []string { return strings.Split(s, "") }
+	b.AddDocument("abba")
+	b.AddDocument("abba")
+	if got := b.Stats(); got.Documents != 2 || got.Vocabulary != 2 || got.Tokens != 8 {
+		t.Errorf("CorpusBuilder.Stats() = %+v, want 2 documents, 8 tokens and 2 distinct", got)
+	}
+	if got := b.Documents()[0]; !reflect.DeepEqual(got, []string{"a", "b", "b", "a"}) {
+		t.Errorf("CorpusBuilder.Documents()[0] = %v", got)
+	}
+}
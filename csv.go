@@ -0,0 +1,251 @@
+package gomarkov
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// csvHeader returns the columns of the transitions CSV for a chain of the given order
+func csvHeader(order int) []string {
+	header := make([]string, 0, order+3)
+	for i := 1; i <= order; i++ {
+		header = append(header, "current_"+strconv.Itoa(i))
+	}
+	return append(header, "next", "count", "probability")
+}
+
+// ExportCSV writes every transition of the chain as a CSV row with one column per
+// token of the current n-gram, followed by the next token, count and probability.
+// Chains holding float weights have no counts to write and can't be exported.
+func (chain *Chain) ExportCSV(w io.Writer) error {
+	spoolMap, freqMat, err := chain.exportCounts("CSV exports")
+	if err != nil {
+		return err
+	}
+	states := make(map[int]string, len(spoolMap))
+	for state, index := range spoolMap {
+		states[index] = state
+	}
+	currents := make([]int, 0, len(freqMat))
+	for current := range freqMat {
+		currents = append(currents, current)
+	}
+	sort.Slice(currents, func(a, b int) bool {
+		return states[currents[a]] < states[currents[b]]
+	})
+
+	cw := csv.NewWriter(w)
+	if err := cw.Write(csvHeader(chain.Order)); err != nil {
+		return err
+	}
+	for _, current := range currents {
+		ngram, ok := splitKey(states[current], chain.Order)
+		if !ok {
+			return fmt.Errorf("Cannot split state %q into %d tokens", states[current], chain.Order)
+		}
+		row := freqMat[current]
+		sum := float64(row.sum())
+		nexts := row.orderedKeys()
+		sort.Slice(nexts, func(a, b int) bool {
+			return states[nexts[a]] < states[nexts[b]]
+		})
+		for _, next := range nexts {
+			record := append(append([]string{}, ngram...),
+				states[next],
+				strconv.Itoa(row[next]),
+				strconv.FormatFloat(float64(row[next])/sum, 'g', -1, 64),
+			)
+			if err := cw.Write(record); err != nil {
+				return err
+			}
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// ImportCSV builds a chain from pre-counted transitions, one per row: order columns
+// holding the current n-gram, then the next token and the count. Any further columns,
+// such as the probability written by ExportCSV, are ignored, as is a header row.
+// Counts are imported as-is, so boundary tokens must already be present in the data.
+func ImportCSV(r io.Reader, order int) (*Chain, error) {
+	if err := validateOrder(order); err != nil {
+		return nil, err
+	}
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	chain := NewChain(order)
+	for line := 1; ; line++ {
+		record, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(record) < order+2 {
+			return nil, fmt.Errorf("Line %d: expected at least %d columns, got %d", line, order+2, len(record))
+		}
+		count, err := strconv.Atoi(record[order+1])
+		if err != nil {
+			if line == 1 {
+				continue
+			}
+			return nil, fmt.Errorf("Line %d: invalid count %q", line, record[order+1])
+		}
+		if count < 0 {
+			return nil, fmt.Errorf("Line %d: negative count %d", line, count)
+		}
+		if err := chain.addTransition(NGram(record[:order]), record[order], count); err != nil {
+			return nil, err
+		}
+	}
+	return chain, nil
+}
+
+// CSVOption configures TrainCSV
+type CSVOption func(*csvOptions)
+
+type csvOptions struct {
+	tokenize func(string) []string
+	weight   string
+	comma    rune
+	noHeader bool
+}
+
+// CSVTokenizer splits the text of each row into tokens, strings.Fields by default
+func CSVTokenizer(tokenize func(string) []string) CSVOption {
+	return func(o *csvOptions) {
+		o.tokenize = tokenize
+	}
+}
+
+// CSVWeight trains each row as many times as the non-negative integer in the given
+// column, see Chain.AddWeighted. Rows with an empty weight have a weight of 1.
+func CSVWeight(column string) CSVOption {
+	return func(o *csvOptions) {
+		o.weight = column
+	}
+}
+
+// CSVComma sets the field delimiter, such as ';' or '\t', instead of ','
+func CSVComma(comma rune) CSVOption {
+	return func(o *csvOptions) {
+		o.comma = comma
+	}
+}
+
+// CSVNoHeader reads every row as data. Columns are then named by their zero-based
+// index, "0" being the first.
+func CSVNoHeader() CSVOption {
+	return func(o *csvOptions) {
+		o.noHeader = true
+	}
+}
+
+// TrainCSV trains the chain on one column of a CSV corpus, such as a spreadsheet
+// export, streaming rows from r. column names a column of the header row, which is
+// read first, and each row's cell in it is tokenized and trained on. Quoted cells
+// may span lines. Rows whose cell is empty are skipped.
+func (chain *Chain) TrainCSV(r io.Reader, column string, opts ...CSVOption) error {
+	return readCSV(r, []string{column}, opts, func(cells, tokens []string, weight int) error {
+		return chain.AddWeighted(tokens, weight)
+	})
+}
+
+// TrainCSV trains the set on one column of a CSV corpus like Chain.TrainCSV, adding
+// each row to the chain of the label in labelColumn. Rows without a label are skipped.
+func (s *ChainSet) TrainCSV(r io.Reader, column, labelColumn string, opts ...CSVOption) error {
+	return readCSV(r, []string{column, labelColumn}, opts, func(cells, tokens []string, weight int) error {
+		if cells[1] == "" {
+			return nil
+		}
+		return s.Chain(cells[1]).AddWeighted(tokens, weight)
+	})
+}
+
+// readCSV calls fn with the cells of columns, the first one tokenized, and the weight
+// of every row of r whose first column isn't empty
+func readCSV(r io.Reader, columns []string, opts []CSVOption, fn func(cells, tokens []string, weight int) error) error {
+	o := csvOptions{tokenize: strings.Fields, comma: ','}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	cr := csv.NewReader(r)
+	cr.Comma = o.comma
+	cr.FieldsPerRecord = -1
+	cr.ReuseRecord = true
+	if o.weight != "" {
+		columns = append(columns, o.weight)
+	}
+	indices := make([]int, len(columns))
+	if o.noHeader {
+		for i, column := range columns {
+			index, err := strconv.Atoi(column)
+			if err != nil || index < 0 {
+				return fmt.Errorf("Column %q is not an index, as the CSV has no header", column)
+			}
+			indices[i] = index
+		}
+	} else {
+		header, err := cr.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		positions := make(map[string]int, len(header))
+		for i, name := range header {
+			if i == 0 {
+				// Spreadsheets often start their exports with a byte order mark
+				name = strings.TrimPrefix(name, "\ufeff")
+			}
+			if _, ok := positions[name]; !ok {
+				positions[name] = i
+			}
+		}
+		for i, column := range columns {
+			index, ok := positions[column]
+			if !ok {
+				return fmt.Errorf("CSV has no column %q", column)
+			}
+			indices[i] = index
+		}
+	}
+	cells := make([]string, len(columns))
+	for {
+		record, err := cr.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		line, _ := cr.FieldPos(0)
+		for i, index := range indices {
+			if index >= len(record) {
+				return fmt.Errorf("Line %d: expected at least %d columns, got %d", line, index+1, len(record))
+			}
+			cells[i] = record[index]
+		}
+		if cells[0] == "" {
+			continue
+		}
+		weight := 1
+		if o.weight != "" {
+			if cell := strings.TrimSpace(cells[len(cells)-1]); cell != "" {
+				if weight, err = strconv.Atoi(cell); err != nil || weight < 0 {
+					return fmt.Errorf("Line %d: weight %q is not a non-negative integer", line, cell)
+				}
+			}
+		}
+		if err := fn(cells, o.tokenize(cells[0]), weight); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,169 @@
+package gomarkov
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestChain_ExportCSV(t *testing.T) {
+	tests := []struct {
+		name  string
+		order int
+		data  [][]string
+		want  string
+	}{
+		{"Empty chain", 1, [][]string{}, "current_1,next,count,probability\n"},
+		{
+			"Order 1",
+			1,
+			[][]string{{"test", "data"}, {"test", "data"}, {"test", "node"}},
+			"current_1,next,count,probability\n" +
+				"^,test,3,1\n" +
+				"data,$,2,1\n" +
+				"node,$,1,1\n" +
+				"test,data,2,0.6666666666666666\n" +
+				"test,node,1,0.3333333333333333\n",
+		},
+		{
+			"Order 2 with quoting",
+			2,
+			[][]string{{"hello,", "world"}},
+			"current_1,current_2,next,count,probability\n" +
+				"^,^,\"hello,\",1,1\n" +
+				"^,\"hello,\",world,1,1\n" +
+				"\"hello,\",world,$,1,1\n" +
+				"world,$,$,1,1\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chain := NewChain(tt.order)
+			for _, data := range tt.data {
+				chain.Add(data)
+			}
+			var buf strings.Builder
+			if err := chain.ExportCSV(&buf); err != nil {
+				t.Fatalf("Chain.ExportCSV() error = %v", err)
+			}
+			if buf.String() != tt.want {
+				t.Errorf("Chain.ExportCSV() = %v, want %v", buf.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestImportCSV(t *testing.T) {
+	trained := NewChain(1)
+	trained.Add([]string{"test", "data"})
+	trained.Add([]string{"test", "data"})
+	trained.Add([]string{"test", "node"})
+
+	tests := []struct {
+		name    string
+		input   string
+		order   int
+		want    *Chain
+		wantErr bool
+	}{
+		{"Empty", "", 1, NewChain(1), false},
+		{
+			"With header and probability",
+			"current_1,next,count,probability\n^,test,3,1\ndata,$,2,1\nnode,$,1,1\ntest,data,2,0.6\ntest,node,1,0.3\n",
+			1,
+			trained,
+			false,
+		},
+		{"Without header", "^,test,3\ndata,$,2\nnode,$,1\ntest,data,1\ntest,data,1\ntest,node,1\n", 1, trained, false},
+		{"Too few columns", "^,test\n", 1, nil, true},
+		{"Invalid count", "^,test,3\ntest,data,many\n", 1, nil, true},
+		{"Negative count", "^,test,-3\n", 1, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chain, err := ImportCSV(strings.NewReader(tt.input), tt.order)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ImportCSV() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if got, want := transitionCounts(t, chain), transitionCounts(t, tt.want); !reflect.DeepEqual(got, want) {
+				t.Errorf("ImportCSV() = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestImportCSV_RoundTrip(t *testing.T) {
+	chain := NewChain(2)
+	chain.Add([]string{"hello,", "world"})
+	chain.Add([]string{"hello,", "there"})
+	var buf strings.Builder
+	chain.ExportCSV(&buf)
+
+	imported, err := ImportCSV(strings.NewReader(buf.String()), 2)
+	if err != nil {
+		t.Fatalf("ImportCSV() error = %v", err)
+	}
+	if got, want := transitionCounts(t, imported), transitionCounts(t, chain); !reflect.DeepEqual(got, want) {
+		t.Errorf("ImportCSV(ExportCSV()) = %v, want %v", got, want)
+	}
+}
+
+func TestChain_TrainCSV(t *testing.T) {
+	input := "\ufeffid,text,likes,author\n" +
+		"1,\"hello, there\",2,ann\n" +
+		"2,\"hello\nyou\",,bob\n" +
+		"3,,5,ann\n"
+	chain := NewChain(1)
+	if err := chain.TrainCSV(strings.NewReader(input), "text", CSVWeight("likes")); err != nil {
+		t.Fatalf("Chain.TrainCSV() error = %v", err)
+	}
+	want := NewChain(1)
+	want.AddWeighted([]string{"hello,", "there"}, 2)
+	want.Add([]string{"hello", "you"})
+	if got := transitionCounts(t, chain); !reflect.DeepEqual(got, transitionCounts(t, want)) {
+		t.Errorf("Chain.TrainCSV() counts = %v, want %v", got, transitionCounts(t, want))
+	}
+
+	set := NewChainSet(1)
+	if err := set.TrainCSV(strings.NewReader(input), "text", "author"); err != nil {
+		t.Fatalf("ChainSet.TrainCSV() error = %v", err)
+	}
+	if got := set.Labels(); !reflect.DeepEqual(got, []string{"ann", "bob"}) {
+		t.Errorf("ChainSet.Labels() = %v, want [ann bob]", got)
+	}
+
+	headless := NewChain(1)
+	err := headless.TrainCSV(strings.NewReader("a;hello there\nb;hello you\n"), "1", CSVNoHeader(), CSVComma(';'))
+	if err != nil {
+		t.Fatalf("Chain.TrainCSV() without a header error = %v", err)
+	}
+	if p, _ := headless.TransitionProbability("there", NGram{"hello"}); p != 0.5 {
+		t.Errorf("P(there | hello) = %v, want 0.5", p)
+	}
+}
+
+func TestChain_TrainCSV_Errors(t *testing.T) {
+	tests := []struct {
+		name   string
+		input  string
+		column string
+		opts   []CSVOption
+		want   string
+	}{
+		{"Unknown column", "id,text\n", "body", nil, `CSV has no column "body"`},
+		{"Short row", "id,text\n1,a\n2\n", "text", nil, "Line 3: expected at least 2 columns, got 1"},
+		{"Bad weight", "text,n\na,x\n", "text", []CSVOption{CSVWeight("n")}, `Line 2: weight "x" is not a non-negative integer`},
+		{"Not an index", "a\n", "text", []CSVOption{CSVNoHeader()}, `Column "text" is not an index, as the CSV has no header`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := NewChain(1).TrainCSV(strings.NewReader(tt.input), tt.column, tt.opts...)
+			if err == nil || err.Error() != tt.want {
+				t.Errorf("Chain.TrainCSV() error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,124 @@
+package gomarkov
+
+import (
+	"errors"
+	"sort"
+	"strings"
+)
+
+// Cycle is a loop in the transition graph that generation can fall into, such as
+// "and the ... and the ..."
+type Cycle struct {
+	// States lists the states around the cycle, starting from the first in sorted order
+	States []NGram
+	// Tokens are the tokens generated going once around the cycle from the first state
+	Tokens []string
+	// Probability is the probability of going once around the cycle from its first
+	// state. Once in the cycle, a walk goes around it Probability/(1-Probability)
+	// more times on average.
+	Probability float64
+}
+
+// CycleOption configures FindCycles
+type CycleOption func(*cycleOptions)
+
+type cycleOptions struct {
+	minProbability float64
+}
+
+// CycleMinProbability only reports cycles at least p likely to be gone around once,
+// 0.01 by default. Lowering it finds more cycles but makes the search much longer.
+func CycleMinProbability(p float64) CycleOption {
+	return func(o *cycleOptions) {
+		o.minProbability = p
+	}
+}
+
+// cycleEdge is a transition out of a state of FindCycles
+type cycleEdge struct {
+	to    int
+	token string
+	p     float64
+}
+
+// FindCycles reports the cycles of the transition graph of up to maxLen transitions
+// that are likely enough to be a risk, most likely first. These are the loops that
+// make generated text repeat itself, which repetition penalties only hide. Every
+// cycle is reported once, however many of its states it could start from.
+func (chain *Chain) FindCycles(maxLen int, opts ...CycleOption) ([]Cycle, error) {
+	o := cycleOptions{minProbability: 0.01}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if maxLen < 1 {
+		return nil, errors.New("Cycle length must be positive")
+	}
+	byKey, err := chain.stateRows()
+	if err != nil {
+		return nil, err
+	}
+	keys := make([]string, 0, len(byKey))
+	for key := range byKey {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	index := make(map[string]int, len(keys))
+	for i, key := range keys {
+		index[key] = i
+	}
+	states := make([]NGram, len(keys))
+	edges := make([][]cycleEdge, len(keys))
+	for i, key := range keys {
+		s := byKey[key]
+		states[i] = s.ngram
+		for _, t := range s.transitions {
+			if t.Next == EndToken || t.Probability < o.minProbability {
+				continue
+			}
+			if to, ok := index[s.ngram.Shift(t.Next).key()]; ok {
+				edges[i] = append(edges[i], cycleEdge{to, t.Next, t.Probability})
+			}
+		}
+	}
+
+	// Search from each state for cycles returning to it through later states only,
+	// so each cycle is found from its first state
+	var cycles []Cycle
+	var path []int
+	var tokens []string
+	onPath := make([]bool, len(states))
+	var extend func(root, from int, p float64)
+	extend = func(root, from int, p float64) {
+		for _, e := range edges[from] {
+			q := p * e.p
+			if e.to < root || q < o.minProbability {
+				continue
+			}
+			if e.to == root {
+				c := Cycle{Tokens: append(append([]string{}, tokens...), e.token), Probability: q}
+				for _, i := range path {
+					c.States = append(c.States, states[i])
+				}
+				cycles = append(cycles, c)
+				continue
+			}
+			if onPath[e.to] || len(path) == maxLen {
+				continue
+			}
+			path, tokens, onPath[e.to] = append(path, e.to), append(tokens, e.token), true
+			extend(root, e.to, q)
+			path, tokens, onPath[e.to] = path[:len(path)-1], tokens[:len(tokens)-1], false
+		}
+	}
+	for root := range states {
+		path, tokens = append(path[:0], root), tokens[:0]
+		extend(root, root, 1)
+	}
+	sort.SliceStable(cycles, func(a, b int) bool {
+		if cycles[a].Probability != cycles[b].Probability {
+			return cycles[a].Probability > cycles[b].Probability
+		}
+		return strings.Join(cycles[a].Tokens, " ") < strings.Join(cycles[b].Tokens, " ")
+	})
+	return cycles, nil
+}
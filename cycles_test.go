@@ -0,0 +1,58 @@
+package gomarkov
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestChain_FindCycles(t *testing.T) {
+	tests := []struct {
+		name   string
+		order  int
+		corpus [][]string
+		maxLen int
+		opts   []CycleOption
+		want   []Cycle
+	}{
+		{
+			"Loop", 1, [][]string{{"and", "the", "cat", "and", "the", "dog"}}, 3, nil,
+			[]Cycle{{States: []NGram{{"and"}, {"the"}, {"cat"}}, Tokens: []string{"the", "cat", "and"}, Probability: 0.5}},
+		},
+		{"Longer than maxLen", 1, [][]string{{"and", "the", "cat", "and", "the", "dog"}}, 2, nil, nil},
+		{
+			"Below the minimum probability", 1, [][]string{{"and", "the", "cat", "and", "the", "dog"}}, 3,
+			[]CycleOption{CycleMinProbability(0.6)}, nil,
+		},
+		{
+			"Self loop", 1, [][]string{{"x", "x"}}, 1, nil,
+			[]Cycle{{States: []NGram{{"x"}}, Tokens: []string{"x"}, Probability: 0.5}},
+		},
+		{
+			"Unigram", 0, [][]string{{"a", "a", "b"}}, 1, nil,
+			[]Cycle{
+				{States: []NGram{{}}, Tokens: []string{"a"}, Probability: 0.5},
+				{States: []NGram{{}}, Tokens: []string{"b"}, Probability: 0.25},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chain := NewChain(tt.order)
+			chain.AddBatch(tt.corpus)
+			got, err := chain.FindCycles(tt.maxLen, tt.opts...)
+			if err != nil {
+				t.Fatalf("Chain.FindCycles() error = %v", err)
+			}
+			for i := range got {
+				got[i].Probability = math.Round(got[i].Probability*1e9) / 1e9
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Chain.FindCycles() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+	if _, err := NewChain(1).FindCycles(0); err == nil {
+		t.Errorf("Chain.FindCycles(0) succeeded")
+	}
+}
@@ -0,0 +1,116 @@
+package gomarkov
+
+import "sort"
+
+// denseRowThreshold is the number of successors above which a memoryStore row is
+// moved from a map to a denseRow
+const denseRowThreshold = 256
+
+// denseRow holds the counts of a high out-degree row in parallel slices sorted by
+// next state index, which iterate faster and take less memory than a map
+type denseRow struct {
+	keys   []int
+	counts []int
+}
+
+func newDenseRow(row sparseArray) *denseRow {
+	d := &denseRow{
+		keys:   make([]int, 0, len(row)),
+		counts: make([]int, len(row)),
+	}
+	for next := range row {
+		d.keys = append(d.keys, next)
+	}
+	sort.Ints(d.keys)
+	for i, next := range d.keys {
+		d.counts[i] = row[next]
+	}
+	return d
+}
+
+func (d *denseRow) get(next int) int {
+	i := sort.SearchInts(d.keys, next)
+	if i < len(d.keys) && d.keys[i] == next {
+		return d.counts[i]
+	}
+	return 0
+}
+
+func (d *denseRow) add(next, delta int) {
+	i := sort.SearchInts(d.keys, next)
+	if i < len(d.keys) && d.keys[i] == next {
+		d.counts[i] += delta
+		return
+	}
+	d.keys = append(d.keys, 0)
+	d.counts = append(d.counts, 0)
+	copy(d.keys[i+1:], d.keys[i:])
+	copy(d.counts[i+1:], d.counts[i:])
+	d.keys[i], d.counts[i] = next, delta
+}
+
+func (d *denseRow) clone() *denseRow {
+	return &denseRow{
+		keys:   append(make([]int, 0, len(d.keys)+1), d.keys...),
+		counts: append(make([]int, 0, len(d.counts)+1), d.counts...),
+	}
+}
+
+// rowReader reads the counts of a row in place, whether the memory store holds it in
+// a map or in a denseRow, see Chain.iterateRows. It is a struct rather than an
+// interface so that each is inlined and its callback isn't allocated.
+type rowReader struct {
+	counts sparseArray
+	dense  *denseRow
+}
+
+func (r rowReader) size() int {
+	if r.dense != nil {
+		return len(r.dense.keys)
+	}
+	return len(r.counts)
+}
+
+// each calls fn with every count of the row
+func (r rowReader) each(fn func(next, count int)) {
+	if r.dense != nil {
+		for i, next := range r.dense.keys {
+			fn(next, r.dense.counts[i])
+		}
+		return
+	}
+	for next, count := range r.counts {
+		fn(next, count)
+	}
+}
+
+// sparse converts the row back to the map form returned by Store
+func (d *denseRow) sparse() sparseArray {
+	row := make(sparseArray, len(d.keys))
+	for i, next := range d.keys {
+		row[next] = d.counts[i]
+	}
+	return row
+}
+
+// cumulative orders the row for sampling exactly as sparseArray.cumulative does
+func (d *denseRow) cumulative() *cumulativeDist {
+	order := make([]int, len(d.keys))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return d.counts[order[a]] > d.counts[order[b]]
+	})
+	c := &cumulativeDist{
+		keys:   make([]int, len(order)),
+		totals: make([]int, len(order)),
+	}
+	total := 0
+	for i, j := range order {
+		total += d.counts[j]
+		c.keys[i] = d.keys[j]
+		c.totals[i] = total
+	}
+	return c
+}
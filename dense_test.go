@@ -0,0 +1,131 @@
+package gomarkov
+
+import (
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+func denseTestChain() *Chain {
+	chain := NewChain(1)
+	for i := 0; i <= denseRowThreshold; i++ {
+		for n := 0; n <= i%3; n++ {
+			chain.Add([]string{"the", "w" + strconv.Itoa(i)})
+		}
+	}
+	return chain
+}
+
+func Test_denseRow_cumulative(t *testing.T) {
+	row := sparseArray{}
+	for i := 0; i < 50; i++ {
+		row[i*7%50] = i%4 + 1
+	}
+	got, want := newDenseRow(row).cumulative(), row.cumulative()
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("denseRow.cumulative() = %v, want %v", got, want)
+	}
+}
+
+func Test_denseRow_add(t *testing.T) {
+	d := newDenseRow(sparseArray{1: 1, 5: 2})
+	d.add(3, 4)
+	d.add(5, 1)
+	d.add(0, 1)
+	if want := (sparseArray{0: 1, 1: 1, 3: 4, 5: 3}); !reflect.DeepEqual(d.sparse(), want) {
+		t.Errorf("denseRow.sparse() = %v, want %v", d.sparse(), want)
+	}
+	if got := d.get(2); got != 0 {
+		t.Errorf("denseRow.get() = %v, want 0", got)
+	}
+}
+
+func TestChain_DenseRows(t *testing.T) {
+	chain := denseTestChain()
+	m := chain.store.(*memoryStore)
+	index, _, _ := m.LookupState("the")
+	if m.stripe(index).row(index).dense == nil {
+		t.Fatalf("Row with %d successors is not dense", denseRowThreshold+1)
+	}
+
+	got, err := chain.TransitionProbability("w2", NGram{"the"})
+	if err != nil {
+		t.Fatalf("Chain.TransitionProbability() error = %v", err)
+	}
+	_, sum := m.transitionCount(index, 0)
+	if want := 3.0 / float64(sum); got != want {
+		t.Errorf("Chain.TransitionProbability() = %v, want %v", got, want)
+	}
+
+	data, err := chain.MarshalJSON()
+	if err != nil {
+		t.Fatalf("Chain.MarshalJSON() error = %v", err)
+	}
+	var loaded Chain
+	if err := loaded.UnmarshalJSON(data); err != nil {
+		t.Fatalf("Chain.UnmarshalJSON() error = %v", err)
+	}
+	if !reflect.DeepEqual(transitionCounts(t, &loaded), transitionCounts(t, chain)) {
+		t.Errorf("Chain.UnmarshalJSON() transitions differ from the marshaled chain")
+	}
+	for r := 0; r < sum; r += 17 {
+		a, _ := chain.GenerateDeterministic(NGram{"the"}, &sequenceRand{[]int{r}})
+		b, _ := loaded.GenerateDeterministic(NGram{"the"}, &sequenceRand{[]int{r}})
+		if a != b {
+			t.Errorf("Chain.GenerateDeterministic() = %v after loading, want %v", b, a)
+		}
+	}
+
+	before, _ := chain.TransitionProbability("w0", NGram{"the"})
+	snap, err := chain.Snapshot()
+	if err != nil {
+		t.Fatalf("Chain.Snapshot() error = %v", err)
+	}
+	chain.Add([]string{"the", "w0"})
+	if got, _ := snap.TransitionProbability("w0", NGram{"the"}); got != before {
+		t.Errorf("Snapshot.TransitionProbability() = %v after training, want %v", got, before)
+	}
+	if got, _ := chain.TransitionProbability("w0", NGram{"the"}); got == before {
+		t.Errorf("Chain.TransitionProbability() = %v, want it changed by training", got)
+	}
+}
+
+// benchmarkDenseChain builds 50 rows of the given number of successors, held as maps if
+// sparse is set
+func benchmarkDenseChain(successors int, sparse bool) *Chain {
+	chain := NewChain(1)
+	for row := 0; row < 50; row++ {
+		for i := 0; i < successors; i++ {
+			chain.Add([]string{"s" + strconv.Itoa(row), "w" + strconv.Itoa(i)})
+		}
+	}
+	if sparse {
+		m := chain.store.(*memoryStore)
+		for _, s := range m.stripes {
+			for j := range s.rows {
+				if r := &s.rows[j]; r.dense != nil {
+					r.counts, r.dense = r.dense.sparse(), nil
+				}
+			}
+		}
+	}
+	return chain
+}
+
+func BenchmarkChain_Stats_DenseRows(b *testing.B) {
+	for _, tt := range []struct {
+		name   string
+		sparse bool
+	}{{"Dense", false}, {"Map", true}} {
+		b.Run(tt.name, func(b *testing.B) {
+			chain := benchmarkDenseChain(4*denseRowThreshold, tt.sparse)
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err := chain.Stats(); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,180 @@
+// Package detect flags messages that are unlikely under a chain trained on normal
+// text, such as spam or bot output in a chat. Messages are scored by their mean log
+// probability per transition, and a message is anomalous when its score falls more
+// than a few standard deviations below the scores of recent normal messages:
+//
+//	detector := detect.New(gomarkov.NewChain(2))
+//	for _, message := range ham {
+//		detector.Train(message)
+//	}
+//	if spam, score := detector.IsAnomalous(incoming); spam {
+//		log.Printf("dropping %q, score %.2f", incoming, score)
+//	}
+package detect
+
+import (
+	"math"
+	"strings"
+	"sync"
+
+	"github.com/mb-14/gomarkov"
+)
+
+// Option configures a Detector
+type Option func(*Detector)
+
+// WithTokenizer splits messages into tokens, strings.Fields by default
+func WithTokenizer(tokenize func(string) []string) Option {
+	return func(d *Detector) {
+		d.tokenize = tokenize
+	}
+}
+
+// WithWindow sets how many recent normal scores the threshold is computed from,
+// 1000 by default, and how many are needed before anything is flagged, 30 by default
+func WithWindow(size, warmup int) Option {
+	return func(d *Detector) {
+		d.scores = make([]float64, 0, size)
+		d.warmup = warmup
+	}
+}
+
+// WithSensitivity sets how many standard deviations below the mean of recent normal
+// scores a message must fall to be anomalous, 3 by default
+func WithSensitivity(k float64) Option {
+	return func(d *Detector) {
+		d.sensitivity = k
+	}
+}
+
+// WithFloor sets the probability given to transitions the chain has never seen, so
+// a single unseen word lowers a score instead of making it negative infinity. The
+// default is 1e-4.
+func WithFloor(p float64) Option {
+	return func(d *Detector) {
+		d.floor = p
+	}
+}
+
+// Detector scores messages against a chain and keeps a rolling threshold. It is safe
+// for concurrent use.
+type Detector struct {
+	chain       *gomarkov.Chain
+	tokenize    func(string) []string
+	sensitivity float64
+	floor       float64
+	warmup      int
+
+	lock sync.Mutex
+	// scores is a ring of recent normal scores, next is where the next one goes
+	// once it is full
+	scores     []float64
+	next       int
+	sum, sumSq float64
+}
+
+// New creates a Detector over chain, which may already be trained on normal text
+func New(chain *gomarkov.Chain, opts ...Option) *Detector {
+	d := &Detector{
+		chain:       chain,
+		tokenize:    strings.Fields,
+		sensitivity: 3,
+		floor:       1e-4,
+		warmup:      30,
+		scores:      make([]float64, 0, 1000),
+	}
+	for _, opt := range opts {
+		opt(d)
+	}
+	return d
+}
+
+// Train adds a normal message to the chain. Its score against the chain before
+// training is also added to the window, so the threshold is ready once enough
+// messages have been trained on.
+func (d *Detector) Train(text string) error {
+	tokens := d.tokenize(text)
+	score, err := d.score(tokens)
+	if err != nil {
+		return err
+	}
+	if err := d.chain.Add(tokens); err != nil {
+		return err
+	}
+	d.observe(score)
+	return nil
+}
+
+// Score returns the mean natural log probability of the transitions of a message,
+// from the start of a sequence to its end. Higher is more likely.
+func (d *Detector) Score(text string) (float64, error) {
+	return d.score(d.tokenize(text))
+}
+
+func (d *Detector) score(tokens []string) (float64, error) {
+	total, _, err := d.chain.FlooredLogProbability(tokens, d.floor)
+	if err != nil {
+		return 0, err
+	}
+	return total / float64(len(tokens)+1), nil
+}
+
+// Check scores a message and reports whether it is anomalous. Messages that aren't
+// are added to the window, so the threshold follows gradual changes in normal
+// text while bursts of spam don't shift it. Nothing is anomalous during warmup.
+func (d *Detector) Check(text string) (anomalous bool, score float64, err error) {
+	score, err = d.Score(text)
+	if err != nil {
+		return false, 0, err
+	}
+	threshold, ok := d.Threshold()
+	if ok && score < threshold {
+		return true, score, nil
+	}
+	d.observe(score)
+	return false, score, nil
+}
+
+// IsAnomalous is Check for callers that can't handle errors. Messages that can't be
+// scored are reported as anomalous with a score of negative infinity.
+func (d *Detector) IsAnomalous(text string) (bool, float64) {
+	anomalous, score, err := d.Check(text)
+	if err != nil {
+		return true, math.Inf(-1)
+	}
+	return anomalous, score
+}
+
+// Threshold returns the score below which messages are anomalous, with ok false
+// during warmup
+func (d *Detector) Threshold() (threshold float64, ok bool) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	n := float64(len(d.scores))
+	if len(d.scores) == 0 || len(d.scores) < d.warmup {
+		return 0, false
+	}
+	mean := d.sum / n
+	variance := math.Max(d.sumSq/n-mean*mean, 0)
+	return mean - d.sensitivity*math.Sqrt(variance), true
+}
+
+// observe adds a normal score to the window, replacing the oldest once it is full
+func (d *Detector) observe(score float64) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	if cap(d.scores) == 0 {
+		return
+	}
+	if len(d.scores) < cap(d.scores) {
+		d.scores = append(d.scores, score)
+	} else {
+		old := d.scores[d.next]
+		d.sum -= old
+		d.sumSq -= old * old
+		d.scores[d.next] = score
+		d.next = (d.next + 1) % len(d.scores)
+	}
+	d.sum += score
+	d.sumSq += score * score
+}
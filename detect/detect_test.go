@@ -0,0 +1,84 @@
+package detect
+
+import (
+	"math"
+	"testing"
+
+	"github.com/mb-14/gomarkov"
+)
+
+var ham = []string{
+	"good morning everyone",
+	"good morning how are you",
+	"how are you doing today",
+	"i am doing well thanks",
+	"thanks for asking",
+	"good night everyone",
+}
+
+func trained(t *testing.T, opts ...Option) *Detector {
+	d := New(gomarkov.NewChain(1), opts...)
+	for i := 0; i < 10; i++ {
+		for _, message := range ham {
+			if err := d.Train(message); err != nil {
+				t.Fatalf("Detector.Train() error = %v", err)
+			}
+		}
+	}
+	return d
+}
+
+func TestDetector_IsAnomalous(t *testing.T) {
+	d := trained(t)
+	if _, ok := d.Threshold(); !ok {
+		t.Fatal("Detector.Threshold() is not ready after training")
+	}
+	for _, tt := range []struct {
+		text string
+		want bool
+	}{
+		{"good morning everyone", false},
+		{"how are you doing today", false},
+		{"BUY CHEAP PILLS NOW", true},
+		{"click here for free money", true},
+	} {
+		if got, score := d.IsAnomalous(tt.text); got != tt.want {
+			threshold, _ := d.Threshold()
+			t.Errorf("Detector.IsAnomalous(%q) = %v with score %.2f and threshold %.2f, want %v", tt.text, got, score, threshold, tt.want)
+		}
+	}
+}
+
+func TestDetector_Warmup(t *testing.T) {
+	d := New(gomarkov.NewChain(1), WithWindow(10, 5))
+	for i := 0; i < 4; i++ {
+		d.Train("hello world")
+	}
+	if _, ok := d.Threshold(); ok {
+		t.Error("Detector.Threshold() is ready before warmup")
+	}
+	if anomalous, _ := d.IsAnomalous("spam spam spam"); anomalous {
+		t.Error("Detector.IsAnomalous() flagged a message during warmup")
+	}
+}
+
+func TestDetector_Window(t *testing.T) {
+	d := New(gomarkov.NewChain(1), WithWindow(3, 1))
+	for i := 0; i < 5; i++ {
+		d.observe(float64(i))
+	}
+	// Only the last 3 scores remain, their mean is 3 and standard deviation sqrt(2/3)
+	threshold, _ := d.Threshold()
+	if want := 3 - 3*math.Sqrt(2.0/3); math.Abs(threshold-want) > 1e-9 {
+		t.Errorf("Detector.Threshold() = %v, want %v", threshold, want)
+	}
+}
+
+func TestDetector_Score(t *testing.T) {
+	d := trained(t)
+	known, _ := d.Score("good morning everyone")
+	unknown, _ := d.Score("good evening everyone")
+	if !(known > unknown) || math.IsInf(unknown, 0) {
+		t.Errorf("Detector.Score() = %v for a known message and %v with an unseen word", known, unknown)
+	}
+}
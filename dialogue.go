@@ -0,0 +1,220 @@
+package gomarkov
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// turnToken separates a prompt from its response in a DialogueChain's joint chain
+const turnToken = "\x1e"
+
+// defaultStopWords are common English words that say little about a prompt
+var defaultStopWords = strings.Fields(`a an and are as at be but by can do for from
+	has have how i in is it its me my not of on or so that the their them there they
+	this to was we were what when where which who why will with you your`)
+
+// DialogueOption configures a DialogueChain
+type DialogueOption func(*DialogueChain)
+
+// DialogueTokenizer splits prompts and responses into tokens, strings.Fields by
+// default. Responses are joined with spaces.
+func DialogueTokenizer(tokenize func(string) []string) DialogueOption {
+	return func(d *DialogueChain) {
+		d.tokenize = tokenize
+	}
+}
+
+// DialogueStopWords replaces the words that are never keywords, a short list of
+// common English words by default
+func DialogueStopWords(words ...string) DialogueOption {
+	return func(d *DialogueChain) {
+		d.stopWords = make(map[string]bool, len(words))
+		for _, w := range words {
+			d.stopWords[strings.ToLower(w)] = true
+		}
+	}
+}
+
+// DialogueRand makes responses draw from prng instead of the package-wide PRNG
+func DialogueRand(prng PRNG) DialogueOption {
+	return func(d *DialogueChain) {
+		d.prng = prng
+	}
+}
+
+// DialogueMaxLength limits how many tokens a response grows on either side of its
+// keyword, and after the prompt when there is none, 50 by default
+func DialogueMaxLength(n int) DialogueOption {
+	return func(d *DialogueChain) {
+		d.maxLength = n
+	}
+}
+
+// DialogueChain generates responses to prompts from (prompt, response) pairs. A
+// response is grown around a word that tended to appear in responses to prompts
+// sharing keywords with this one. Prompts without known keywords fall back to a
+// chain of prompts followed by their responses, seeded with the end of the prompt.
+// It is safe for concurrent use.
+type DialogueChain struct {
+	// Responses is trained on the responses only, in both directions
+	Responses *Bidirectional
+	// joint is trained on each prompt followed by turnToken and its response
+	joint *Chain
+
+	tokenize  func(string) []string
+	stopWords map[string]bool
+	prng      PRNG
+	maxLength int
+
+	lock sync.RWMutex
+	// pairs counts the responses containing a keyword, given one in the prompt
+	pairs map[string]map[string]int
+	// prompts and responses count the pairs each keyword appears in on either side
+	prompts, responses map[string]int
+	total              int
+	// surface holds a token of the responses for each of their keywords
+	surface map[string]string
+}
+
+// NewDialogueChain creates an empty DialogueChain of the given order
+func NewDialogueChain(order int, opts ...DialogueOption) *DialogueChain {
+	d := &DialogueChain{
+		Responses: NewBidirectional(order),
+		joint:     NewChain(order),
+		tokenize:  strings.Fields,
+		maxLength: 50,
+		pairs:     make(map[string]map[string]int),
+		prompts:   make(map[string]int),
+		responses: make(map[string]int),
+		surface:   make(map[string]string),
+	}
+	DialogueStopWords(defaultStopWords...)(d)
+	for _, opt := range opts {
+		opt(d)
+	}
+	return d
+}
+
+// keywords returns the distinct keywords of tokens in lower case, with the token
+// each first appeared as
+func (d *DialogueChain) keywords(tokens []string) (words, surface []string) {
+	seen := make(map[string]bool)
+	for _, token := range tokens {
+		w := strings.ToLower(strings.TrimFunc(token, func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+		}))
+		if len([]rune(w)) < 3 || d.stopWords[w] || seen[w] {
+			continue
+		}
+		seen[w] = true
+		words = append(words, w)
+		surface = append(surface, token)
+	}
+	return words, surface
+}
+
+// Train adds a prompt and a response to it
+func (d *DialogueChain) Train(prompt, response string) error {
+	promptTokens, responseTokens := d.tokenize(prompt), d.tokenize(response)
+	if err := d.Responses.Add(responseTokens); err != nil {
+		return err
+	}
+	joint := make([]string, 0, len(promptTokens)+1+len(responseTokens))
+	joint = append(append(append(joint, promptTokens...), turnToken), responseTokens...)
+	if err := d.joint.Add(joint); err != nil {
+		return err
+	}
+	promptWords, _ := d.keywords(promptTokens)
+	responseWords, surface := d.keywords(responseTokens)
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	d.total++
+	for _, p := range promptWords {
+		d.prompts[p]++
+		if d.pairs[p] == nil {
+			d.pairs[p] = make(map[string]int)
+		}
+		for _, r := range responseWords {
+			d.pairs[p][r]++
+		}
+	}
+	for i, r := range responseWords {
+		d.responses[r]++
+		if _, ok := d.surface[r]; !ok {
+			d.surface[r] = surface[i]
+		}
+	}
+	return nil
+}
+
+// keyword draws a response keyword for prompt keywords, weighting each candidate by
+// how much more often it appears in responses to them than in responses overall
+func (d *DialogueChain) keyword(words []string, prng PRNG) (string, bool) {
+	d.lock.RLock()
+	scores := make(map[string]float64)
+	for _, p := range words {
+		for r, count := range d.pairs[p] {
+			conditional := float64(count) / float64(d.prompts[p])
+			overall := float64(d.responses[r]) / float64(d.total)
+			scores[r] += conditional * math.Log1p(conditional/overall)
+		}
+	}
+	d.lock.RUnlock()
+	if len(scores) == 0 {
+		return "", false
+	}
+	candidates := make([]string, 0, len(scores))
+	for r := range scores {
+		candidates = append(candidates, r)
+	}
+	sort.Strings(candidates)
+	weights := make([]float64, len(candidates))
+	for i, r := range candidates {
+		weights[i] = scores[r]
+	}
+	return candidates[drawFloat(prng, weights)], true
+}
+
+// Respond generates a response to prompt
+func (d *DialogueChain) Respond(prompt string) (string, error) {
+	tokens, err := d.RespondTokens(prompt)
+	return strings.Join(tokens, " "), err
+}
+
+// RespondTokens is Respond returning the tokens of the response. Growing a response
+// around a keyword with chains of order 2 or more looks through every state of the
+// response chain.
+func (d *DialogueChain) RespondTokens(prompt string) ([]string, error) {
+	prng := d.prng
+	if prng == nil {
+		prng = defaultPrng
+	}
+	promptTokens := d.tokenize(prompt)
+	promptWords, _ := d.keywords(promptTokens)
+	if keyword, ok := d.keyword(promptWords, prng); ok {
+		d.lock.RLock()
+		seed, ok := d.surface[keyword]
+		d.lock.RUnlock()
+		if ok {
+			tokens, err := d.Responses.GenerateAroundDeterministic(NGram{seed}, prng, AroundMaxLength(d.maxLength))
+			if err == nil {
+				return tokens, nil
+			}
+		}
+	}
+
+	// Seed the joint chain with the end of the prompt and the turn
+	order := d.joint.Order
+	seed := make(NGram, 0, order)
+	seed = append(seed, boundary(StartToken, max(order-1-len(promptTokens), 0))...)
+	seed = append(seed, promptTokens[max(len(promptTokens)-order+1, 0):]...)
+	seed = append(seed, turnToken)
+	tokens, err := d.joint.GenerateSequenceDeterministic(seed, d.maxLength, prng)
+	if err == nil {
+		return tokens, nil
+	}
+	return d.Responses.Forward.GenerateSequenceDeterministic(NGram(boundary(StartToken, order)), d.maxLength, prng)
+}
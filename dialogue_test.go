@@ -0,0 +1,65 @@
+package gomarkov
+
+import (
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+var dialogues = [][2]string{
+	{"how is the weather today", "it is sunny and warm outside"},
+	{"will the weather be nice tomorrow", "expect sunny skies and warm air"},
+	{"what should we eat tonight", "let us order pizza with cheese"},
+	{"any ideas for dinner", "pizza sounds great tonight"},
+}
+
+func TestDialogueChain_Respond(t *testing.T) {
+	d := NewDialogueChain(1, DialogueRand(rand.New(rand.NewSource(1))))
+	for i := 0; i < 5; i++ {
+		for _, pair := range dialogues {
+			if err := d.Train(pair[0], pair[1]); err != nil {
+				t.Fatalf("DialogueChain.Train() error = %v", err)
+			}
+		}
+	}
+	for prompt, want := range map[string][]string{
+		"what is the weather like": {"sunny", "warm"},
+		"I want dinner":            {"pizza", "tonight", "cheese", "great"},
+		"Weather report, please!":  {"sunny", "warm"},
+		"shall we eat pizza":       {"pizza", "tonight", "cheese", "great"},
+	} {
+		for i := 0; i < 10; i++ {
+			got, err := d.Respond(prompt)
+			if err != nil {
+				t.Fatalf("DialogueChain.Respond(%q) error = %v", prompt, err)
+			}
+			found := false
+			for _, w := range want {
+				found = found || strings.Contains(got, w)
+			}
+			if !found {
+				t.Errorf("DialogueChain.Respond(%q) = %q, want it to mention one of %v", prompt, got, want)
+			}
+		}
+	}
+}
+
+func TestDialogueChain_Respond_fallback(t *testing.T) {
+	d := NewDialogueChain(2, DialogueStopWords("and", "today"), DialogueRand(rand.New(rand.NewSource(1))))
+	for _, pair := range dialogues {
+		d.Train(pair[0], pair[1])
+	}
+	// No keywords, but the end of the prompt was seen before the weather responses
+	got, err := d.RespondTokens("and today")
+	if err != nil {
+		t.Fatalf("DialogueChain.RespondTokens() error = %v", err)
+	}
+	if len(got) == 0 || got[0] != "it" {
+		t.Errorf("DialogueChain.RespondTokens(and today) = %v, want the response after today", got)
+	}
+	// Nothing is known at all, the response chain starts from scratch
+	got, err = d.RespondTokens("zzz")
+	if err != nil || len(got) == 0 {
+		t.Errorf("DialogueChain.RespondTokens(zzz) = %v, %v, want a response", got, err)
+	}
+}
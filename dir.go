@@ -0,0 +1,181 @@
+package gomarkov
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+)
+
+// DirOption configures TrainDir
+type DirOption func(*dirOptions)
+
+type dirOptions struct {
+	split    func(string) []string
+	tokenize func(string) []string
+}
+
+// DirSplitter splits the text of each file into sequences, SplitSentences by default.
+// Corpora with one sequence per line can be split on newlines instead.
+func DirSplitter(split func(string) []string) DirOption {
+	return func(o *dirOptions) {
+		o.split = split
+	}
+}
+
+// DirTokenizer splits each sequence into tokens, strings.Fields by default
+func DirTokenizer(tokenize func(string) []string) DirOption {
+	return func(o *dirOptions) {
+		o.tokenize = tokenize
+	}
+}
+
+// TrainDir trains the chain on every file under root whose name matches glob, such
+// as "*.txt", or on every file if glob is empty. Up to workers files are read, split
+// into sentences and tokenized at once, each file's sequences being added with
+// AddBatch. Training stops at the first error, which names the file it came from.
+func (chain *Chain) TrainDir(root, glob string, workers int, opts ...DirOption) error {
+	o := dirOptions{split: SplitSentences, tokenize: strings.Fields}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if _, err := filepath.Match(glob, ""); err != nil {
+		return err
+	}
+	if workers < 1 {
+		workers = 1
+	}
+
+	paths := make(chan string)
+	done := make(chan struct{})
+	var once sync.Once
+	var firstErr error
+	fail := func(err error) {
+		once.Do(func() {
+			firstErr = err
+			close(done)
+		})
+	}
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for path := range paths {
+				if err := chain.trainFile(path, o); err != nil {
+					fail(err)
+				}
+			}
+		}()
+	}
+	walkErr := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		if glob != "" {
+			if ok, _ := filepath.Match(glob, d.Name()); !ok {
+				return nil
+			}
+		}
+		select {
+		case paths <- path:
+			return nil
+		case <-done:
+			return filepath.SkipAll
+		}
+	})
+	close(paths)
+	wg.Wait()
+	if walkErr != nil {
+		return walkErr
+	}
+	return firstErr
+}
+
+// trainFile adds the sequences of a single file
+func (chain *Chain) trainFile(path string, o dirOptions) error {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	sentences := o.split(string(b))
+	inputs := make([][]string, 0, len(sentences))
+	for _, sentence := range sentences {
+		inputs = append(inputs, o.tokenize(sentence))
+	}
+	if err := chain.AddBatch(inputs); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	return nil
+}
+
+// SplitSentences splits text into sentences, each ending with a run of '.', '!' or
+// '?' followed by whitespace, or with a blank line. Closing quotes and brackets after
+// the punctuation stay with the sentence. Line breaks within a sentence are kept, and
+// sentences are trimmed of surrounding whitespace.
+func SplitSentences(text string) []string {
+	var sentences []string
+	start := 0
+	emit := func(end int) {
+		if s := strings.TrimSpace(text[start:end]); s != "" {
+			sentences = append(sentences, s)
+		}
+		start = end
+	}
+	for i := 0; i < len(text); {
+		r, size := utf8.DecodeRuneInString(text[i:])
+		switch {
+		case r == '.' || r == '!' || r == '?':
+			end := i + size
+			for end < len(text) {
+				next, n := utf8.DecodeRuneInString(text[end:])
+				if next != '.' && next != '!' && next != '?' && !closesSentence(next) {
+					break
+				}
+				end += n
+			}
+			if end == len(text) {
+				emit(end)
+			} else if next, _ := utf8.DecodeRuneInString(text[end:]); unicode.IsSpace(next) {
+				emit(end)
+			}
+			i = end
+		case r == '\n' && blankLineAfter(text[i+size:]):
+			emit(i)
+			i += size
+		default:
+			i += size
+		}
+	}
+	emit(len(text))
+	return sentences
+}
+
+// closesSentence reports whether r can follow the punctuation ending a sentence
+func closesSentence(r rune) bool {
+	switch r {
+	case '"', '\'', ')', ']', '}', '»', '’', '”':
+		return true
+	}
+	return false
+}
+
+// blankLineAfter reports whether text starts with a line holding only whitespace
+func blankLineAfter(text string) bool {
+	for _, r := range text {
+		if r == '\n' {
+			return true
+		}
+		if !unicode.IsSpace(r) {
+			return false
+		}
+	}
+	return false
+}
@@ -0,0 +1,78 @@
+package gomarkov
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSplitSentences(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want []string
+	}{
+		{"Empty", " \n ", nil},
+		{"Punctuation", "The cat sat. Did it?! Yes.", []string{"The cat sat.", "Did it?!", "Yes."}},
+		{"No end", "one. two", []string{"one.", "two"}},
+		{"Inner dots", "Pi is 3.14 or so... Really", []string{"Pi is 3.14 or so...", "Really"}},
+		{"Quotes", `He said "stop." Then (he left.) Done`, []string{`He said "stop."`, "Then (he left.)", "Done"}},
+		{"Line breaks", "a line\nwrapped here.\n\nA heading\n \nnext", []string{"a line\nwrapped here.", "A heading", "next"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := SplitSentences(tt.text); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("SplitSentences() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestChain_TrainDir(t *testing.T) {
+	root := t.TempDir()
+	files := map[string]string{
+		"a.txt":       "the cat sat. the dog ran.",
+		"sub/b.txt":   "the cat ran!",
+		"sub/c.md":    "ignored text.",
+		"sub/d/e.txt": "",
+	}
+	for name, text := range files {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(text), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want := NewChain(1)
+	for _, sentence := range []string{"the cat sat.", "the dog ran.", "the cat ran!"} {
+		want.Add(strings.Fields(sentence))
+	}
+	for _, workers := range []int{0, 1, 4} {
+		chain := NewChain(1)
+		if err := chain.TrainDir(root, "*.txt", workers); err != nil {
+			t.Fatalf("Chain.TrainDir() with %d workers error = %v", workers, err)
+		}
+		if got := transitionCounts(t, chain); !reflect.DeepEqual(got, transitionCounts(t, want)) {
+			t.Errorf("Chain.TrainDir() with %d workers counts = %v, want %v", workers, got, transitionCounts(t, want))
+		}
+	}
+
+	all := NewChain(1)
+	if err := all.TrainDir(root, "", 2, DirSplitter(func(text string) []string { return []string{text} })); err != nil {
+		t.Fatal(err)
+	}
+	if p, _ := all.TransitionProbability("text.", NGram{"ignored"}); p != 1 {
+		t.Errorf("Chain.TrainDir() without a glob skipped files, P(text. | ignored) = %v", p)
+	}
+
+	if err := NewChain(1).TrainDir(filepath.Join(root, "missing"), "*.txt", 2); !os.IsNotExist(err) {
+		t.Errorf("Chain.TrainDir() of a missing directory error = %v, want not exist", err)
+	}
+	if err := NewChain(1).TrainDir(root, "[", 2); err == nil {
+		t.Errorf("Chain.TrainDir() with a malformed glob succeeded")
+	}
+}
@@ -0,0 +1,210 @@
+package gomarkov
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// DOTOption configures ExportDOT
+type DOTOption func(*dotOptions)
+
+type dotOptions struct {
+	minProbability float64
+	maxNodes       int
+	around         NGram
+	depth          int
+}
+
+// DOTMinProbability drops edges whose transition probability is below p
+func DOTMinProbability(p float64) DOTOption {
+	return func(o *dotOptions) {
+		o.minProbability = p
+	}
+}
+
+// DOTMaxNodes keeps only the n most frequently visited states
+func DOTMaxNodes(n int) DOTOption {
+	return func(o *dotOptions) {
+		o.maxNodes = n
+	}
+}
+
+// DOTAround keeps only the states reachable from state in at most depth transitions
+func DOTAround(state NGram, depth int) DOTOption {
+	return func(o *dotOptions) {
+		o.around = state
+		o.depth = depth
+	}
+}
+
+// graphEdge is a transition between two states of the chain
+type graphEdge struct {
+	from, to    string
+	count       int
+	probability float64
+}
+
+// transitionGraph is the chain viewed as a graph over n-gram states
+type transitionGraph struct {
+	order  int
+	weight map[string]int
+	edges  []graphEdge
+}
+
+// transitionGraph builds the state graph of the chain. Edges lead from a state to
+// the state obtained by shifting the next token into it.
+func (chain *Chain) transitionGraph() (*transitionGraph, error) {
+	spoolMap, freqMat, err := chain.export()
+	if err != nil {
+		return nil, err
+	}
+	states := make(map[int]string, len(spoolMap))
+	for state, index := range spoolMap {
+		states[index] = state
+	}
+	g := &transitionGraph{order: chain.Order, weight: make(map[string]int)}
+	for current, row := range freqMat {
+		from := states[current]
+		ngram, ok := splitKey(from, chain.Order)
+		if !ok {
+			return nil, fmt.Errorf("Cannot split state %q into %d tokens", from, chain.Order)
+		}
+		sum := row.sum()
+		g.weight[from] += sum
+		for next, count := range row {
+			to := ngram.Shift(states[next]).key()
+			if _, ok := g.weight[to]; !ok {
+				g.weight[to] = 0
+			}
+			g.edges = append(g.edges, graphEdge{from, to, count, float64(count) / float64(sum)})
+		}
+	}
+	sort.Slice(g.edges, func(a, b int) bool {
+		if g.edges[a].from == g.edges[b].from {
+			return g.edges[a].to < g.edges[b].to
+		}
+		return g.edges[a].from < g.edges[b].from
+	})
+	return g, nil
+}
+
+// filter keeps only the given states and the edges between them
+func (g *transitionGraph) filter(keep map[string]bool) {
+	for state := range g.weight {
+		if !keep[state] {
+			delete(g.weight, state)
+		}
+	}
+	edges := g.edges[:0]
+	for _, e := range g.edges {
+		if keep[e.from] && keep[e.to] {
+			edges = append(edges, e)
+		}
+	}
+	g.edges = edges
+}
+
+func (g *transitionGraph) minProbability(p float64) {
+	edges := g.edges[:0]
+	for _, e := range g.edges {
+		if e.probability >= p {
+			edges = append(edges, e)
+		}
+	}
+	g.edges = edges
+}
+
+func (g *transitionGraph) around(start string, depth int) {
+	successors := make(map[string][]string)
+	for _, e := range g.edges {
+		successors[e.from] = append(successors[e.from], e.to)
+	}
+	keep := map[string]bool{start: true}
+	frontier := []string{start}
+	for i := 0; i < depth && len(frontier) > 0; i++ {
+		var next []string
+		for _, state := range frontier {
+			for _, to := range successors[state] {
+				if !keep[to] {
+					keep[to] = true
+					next = append(next, to)
+				}
+			}
+		}
+		frontier = next
+	}
+	g.filter(keep)
+}
+
+func (g *transitionGraph) topNodes(n int) {
+	nodes := g.nodes()
+	sort.SliceStable(nodes, func(a, b int) bool {
+		return g.weight[nodes[a]] > g.weight[nodes[b]]
+	})
+	keep := make(map[string]bool, n)
+	for i := 0; i < n && i < len(nodes); i++ {
+		keep[nodes[i]] = true
+	}
+	g.filter(keep)
+}
+
+// apply filters the graph as configured by the options
+func (g *transitionGraph) apply(o dotOptions) {
+	g.minProbability(o.minProbability)
+	if o.around != nil {
+		g.around(o.around.key(), o.depth)
+	}
+	if o.maxNodes > 0 {
+		g.topNodes(o.maxNodes)
+	}
+}
+
+// nodes returns the states of the graph in sorted order
+func (g *transitionGraph) nodes() []string {
+	nodes := make([]string, 0, len(g.weight))
+	for state := range g.weight {
+		nodes = append(nodes, state)
+	}
+	sort.Strings(nodes)
+	return nodes
+}
+
+// label renders a state key as its space separated tokens
+func (g *transitionGraph) label(state string) string {
+	ngram, _ := splitKey(state, g.order)
+	return strings.Join(ngram, " ")
+}
+
+// ExportDOT writes the transition graph of the chain in Graphviz DOT format, with
+// edges labelled by their transition probability
+func (chain *Chain) ExportDOT(w io.Writer, opts ...DOTOption) error {
+	var o dotOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	g, err := chain.transitionGraph()
+	if err != nil {
+		return err
+	}
+	g.apply(o)
+
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, "digraph chain {")
+	for _, state := range g.nodes() {
+		fmt.Fprintf(bw, "\t%s [label=%s];\n", dotQuote(state), dotQuote(g.label(state)))
+	}
+	for _, e := range g.edges {
+		fmt.Fprintf(bw, "\t%s -> %s [label=\"%.3g\"];\n", dotQuote(e.from), dotQuote(e.to), e.probability)
+	}
+	fmt.Fprintln(bw, "}")
+	return bw.Flush()
+}
+
+func dotQuote(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, `"`, `\"`)
+	return `"` + s + `"`
+}
@@ -0,0 +1,99 @@
+package gomarkov
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestChain_ExportDOT(t *testing.T) {
+	chain := NewChain(1)
+	chain.Add([]string{"test", "data"})
+	chain.Add([]string{"test", "data"})
+	chain.Add([]string{"test", "node"})
+
+	tests := []struct {
+		name string
+		opts []DOTOption
+		want string
+	}{
+		{
+			"Whole chain",
+			nil,
+			`digraph chain {
+	"$" [label="$"];
+	"^" [label="^"];
+	"data" [label="data"];
+	"node" [label="node"];
+	"test" [label="test"];
+	"^" -> "test" [label="1"];
+	"data" -> "$" [label="1"];
+	"node" -> "$" [label="1"];
+	"test" -> "data" [label="0.667"];
+	"test" -> "node" [label="0.333"];
+}
+`,
+		},
+		{
+			"Min probability",
+			[]DOTOption{DOTMinProbability(0.5)},
+			`digraph chain {
+	"$" [label="$"];
+	"^" [label="^"];
+	"data" [label="data"];
+	"node" [label="node"];
+	"test" [label="test"];
+	"^" -> "test" [label="1"];
+	"data" -> "$" [label="1"];
+	"node" -> "$" [label="1"];
+	"test" -> "data" [label="0.667"];
+}
+`,
+		},
+		{
+			"Max nodes",
+			[]DOTOption{DOTMaxNodes(2)},
+			`digraph chain {
+	"^" [label="^"];
+	"test" [label="test"];
+	"^" -> "test" [label="1"];
+}
+`,
+		},
+		{
+			"Around a state",
+			[]DOTOption{DOTAround(NGram{"node"}, 1)},
+			`digraph chain {
+	"$" [label="$"];
+	"node" [label="node"];
+	"node" -> "$" [label="1"];
+}
+`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf strings.Builder
+			if err := chain.ExportDOT(&buf, tt.opts...); err != nil {
+				t.Fatalf("Chain.ExportDOT() error = %v", err)
+			}
+			if buf.String() != tt.want {
+				t.Errorf("Chain.ExportDOT() = %v, want %v", buf.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestChain_ExportDOT_Order2(t *testing.T) {
+	chain := NewChain(2)
+	chain.Add([]string{"a", "b"})
+	var buf strings.Builder
+	if err := chain.ExportDOT(&buf); err != nil {
+		t.Fatalf("Chain.ExportDOT() error = %v", err)
+	}
+	if !strings.Contains(buf.String(), `"^_a" -> "a_b" [label="1"];`) {
+		t.Errorf("Chain.ExportDOT() missing shifted state edge: %v", buf.String())
+	}
+	if !strings.Contains(buf.String(), `"a_b" [label="a b"];`) {
+		t.Errorf("Chain.ExportDOT() missing n-gram label: %v", buf.String())
+	}
+}
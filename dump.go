@@ -0,0 +1,105 @@
+package gomarkov
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// ChainStats counts the contents of a chain
+type ChainStats struct {
+	Order int `json:"order"`
+	// States is the number of n-grams with outgoing transitions
+	States int `json:"states"`
+	// Transitions is the number of distinct transitions between states
+	Transitions int `json:"transitions"`
+	// Vocabulary is the number of distinct tokens, as listed by Vocabulary
+	Vocabulary int `json:"vocabulary"`
+}
+
+// Stats counts the contents of the chain without copying its rows
+func (chain *Chain) Stats() (ChainStats, error) {
+	s := ChainStats{Order: chain.Order}
+	err := chain.iterateRows(func(current int, row rowReader) bool {
+		s.States++
+		s.Transitions += row.size()
+		return true
+	})
+	if err != nil {
+		return s, err
+	}
+	tokens, err := chain.tokenIndices()
+	s.Vocabulary = len(tokens)
+	return s, err
+}
+
+// String summarizes the chain, such as "gomarkov.Chain{order: 2, states: 10,
+// transitions: 14, vocab: 9}", see Stats
+func (chain *Chain) String() string {
+	s, err := chain.Stats()
+	if err != nil {
+		return fmt.Sprintf("gomarkov.Chain{order: %d, error: %v}", chain.Order, err)
+	}
+	return fmt.Sprintf("gomarkov.Chain{order: %d, states: %d, transitions: %d, vocab: %d}",
+		s.Order, s.States, s.Transitions, s.Vocabulary)
+}
+
+// Dump writes a human-readable listing of the chain to w: the limit most frequent
+// states, each followed by its limit most likely transitions with their counts and
+// probabilities. A limit of 0 or less lists everything.
+func (chain *Chain) Dump(w io.Writer, limit int) error {
+	spoolMap, freqMat, err := chain.exportCounts("dumps")
+	if err != nil {
+		return err
+	}
+	states := make(map[int]string, len(spoolMap))
+	for state, index := range spoolMap {
+		states[index] = state
+	}
+	sums := make(map[int]int, len(freqMat))
+	currents := make([]int, 0, len(freqMat))
+	transitions := 0
+	for current, row := range freqMat {
+		sums[current] = row.sum()
+		currents = append(currents, current)
+		transitions += len(row)
+	}
+	sort.Slice(currents, func(a, b int) bool {
+		if sums[currents[a]] == sums[currents[b]] {
+			return states[currents[a]] < states[currents[b]]
+		}
+		return sums[currents[a]] > sums[currents[b]]
+	})
+
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "Chain of order %d: %d states, %d transitions\n", chain.Order, len(currents), transitions)
+	if limit > 0 && len(currents) > limit {
+		currents = currents[:limit]
+	}
+	for _, current := range currents {
+		fmt.Fprintf(bw, "%s (%d)\n", dumpState(states[current], chain.Order), sums[current])
+		pairs := freqMat[current].orderedPairs()
+		shown := pairs
+		if limit > 0 && len(shown) > limit {
+			shown = shown[:limit]
+		}
+		for _, p := range shown {
+			fmt.Fprintf(bw, "  -> %-20s %6d %7.2f%%\n", states[p[0]], p[1], 100*float64(p[1])/float64(sums[current]))
+		}
+		if len(shown) < len(pairs) {
+			fmt.Fprintf(bw, "  ... %d more\n", len(pairs)-len(shown))
+		}
+	}
+	return bw.Flush()
+}
+
+// dumpState formats a state key as its tokens, or as-is when it can't be split
+func dumpState(key string, order int) string {
+	ngram, ok := splitKey(key, order)
+	if !ok {
+		return fmt.Sprintf("%q", key)
+	}
+	return "[" + strings.Join(ngram, " ") + "]"
+}
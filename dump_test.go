@@ -0,0 +1,41 @@
+package gomarkov
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestChain_String(t *testing.T) {
+	chain := NewChain(1)
+	chain.Add([]string{"I", "want", "a", "cheese", "burger"})
+	chain.Add([]string{"I", "want", "a", "chilled", "sprite"})
+	want := "gomarkov.Chain{order: 1, states: 8, transitions: 9, vocab: 7}"
+	if got := chain.String(); got != want {
+		t.Errorf("Chain.String() = %q, want %q", got, want)
+	}
+	stats, err := chain.Stats()
+	if wantStats := (ChainStats{Order: 1, States: 8, Transitions: 9, Vocabulary: 7}); err != nil || stats != wantStats {
+		t.Errorf("Chain.Stats() = %+v, %v, want %+v", stats, err, wantStats)
+	}
+}
+
+func TestChain_Dump(t *testing.T) {
+	chain := NewChain(2)
+	chain.Add([]string{"I", "want", "a", "cheese", "burger"})
+	chain.Add([]string{"I", "want", "a", "chilled", "sprite"})
+	chain.Add([]string{"I", "want", "to", "go"})
+	var buf bytes.Buffer
+	if err := chain.Dump(&buf, 2); err != nil {
+		t.Fatalf("Chain.Dump() error = %v", err)
+	}
+	want := `Chain of order 2: 13 states, 15 transitions
+[I want] (3)
+  -> a                         2   66.67%
+  -> to                        1   33.33%
+[^ I] (3)
+  -> want                      3  100.00%
+`
+	if got := buf.String(); got != want {
+		t.Errorf("Chain.Dump() =\n%s\nwant\n%s", got, want)
+	}
+}
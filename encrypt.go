@@ -0,0 +1,83 @@
+package gomarkov
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"io"
+)
+
+// Encrypted models are the JSON serialization sealed with AES-GCM:
+//
+//	magic "GMKE" | version byte | nonce | ciphertext and tag
+//
+// The magic, version and nonce are authenticated along with the ciphertext.
+const (
+	encryptedMagic   = "GMKE"
+	encryptedVersion = 1
+)
+
+// SaveEncrypted writes the chain encrypted with AES-GCM under key, which must be
+// 16, 24 or 32 bytes long to select AES-128, AES-192 or AES-256
+func (chain *Chain) SaveEncrypted(w io.Writer, key []byte) error {
+	aead, err := newGCM(key)
+	if err != nil {
+		return err
+	}
+	plaintext, err := chain.MarshalJSON()
+	if err != nil {
+		return err
+	}
+	header := make([]byte, len(encryptedMagic)+1+aead.NonceSize())
+	copy(header, encryptedMagic)
+	header[len(encryptedMagic)] = encryptedVersion
+	nonce := header[len(encryptedMagic)+1:]
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return err
+	}
+	// The header is authenticated as well as prefixed, so it can't double as dst
+	dst := append(make([]byte, 0, len(header)+len(plaintext)+aead.Overhead()), header...)
+	sealed := aead.Seal(dst, nonce, plaintext, header)
+	_, err = w.Write(sealed)
+	return err
+}
+
+// LoadEncrypted reads a chain written by SaveEncrypted. An error is returned if the
+// key is wrong or the data has been tampered with.
+func LoadEncrypted(r io.Reader, key []byte) (*Chain, error) {
+	aead, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	headerSize := len(encryptedMagic) + 1 + aead.NonceSize()
+	if len(data) < headerSize+aead.Overhead() || string(data[:len(encryptedMagic)]) != encryptedMagic {
+		return nil, errors.New("Not an encrypted model")
+	}
+	if data[len(encryptedMagic)] != encryptedVersion {
+		return nil, errors.New("Unsupported encrypted model version")
+	}
+	header := data[:headerSize]
+	nonce := header[len(encryptedMagic)+1:]
+	plaintext, err := aead.Open(nil, nonce, data[headerSize:], header)
+	if err != nil {
+		return nil, errors.New("Encrypted model could not be authenticated, wrong key or tampered data")
+	}
+	var chain Chain
+	if err := chain.UnmarshalJSON(plaintext); err != nil {
+		return nil, err
+	}
+	return &chain, nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,56 @@
+package gomarkov
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestChain_SaveEncrypted(t *testing.T) {
+	key := bytes.Repeat([]byte{7}, 32)
+	chain := NewChain(1)
+	chain.Add([]string{"private", "message"})
+
+	var buf bytes.Buffer
+	if err := chain.SaveEncrypted(&buf, key); err != nil {
+		t.Fatalf("Chain.SaveEncrypted() error = %v", err)
+	}
+	if bytes.Contains(buf.Bytes(), []byte("private")) {
+		t.Errorf("Chain.SaveEncrypted() leaked plaintext")
+	}
+	sealed := buf.Bytes()
+
+	tamper := func(i int) []byte {
+		b := append([]byte{}, sealed...)
+		b[i] ^= 1
+		return b
+	}
+	tests := []struct {
+		name    string
+		data    []byte
+		key     []byte
+		wantErr bool
+	}{
+		{"Round trip", sealed, key, false},
+		{"Wrong key", sealed, bytes.Repeat([]byte{8}, 32), true},
+		{"Invalid key size", sealed, []byte("short"), true},
+		{"Tampered header", tamper(5), key, true},
+		{"Tampered ciphertext", tamper(len(sealed) - 1), key, true},
+		{"Truncated", sealed[:10], key, true},
+		{"Not encrypted", []byte(`{"version":3,"order":1,"spool_map":{},"freq_mat":{}}`), key, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			loaded, err := LoadEncrypted(bytes.NewReader(tt.data), tt.key)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("LoadEncrypted() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if got, want := transitionCounts(t, loaded), transitionCounts(t, chain); !reflect.DeepEqual(got, want) {
+				t.Errorf("LoadEncrypted() = %v, want %v", got, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,65 @@
+package gomarkov
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrOrderMismatch is returned when an n-gram's length doesn't match the chain order
+	ErrOrderMismatch = errors.New("N-gram length does not match chain order")
+	// ErrUnknownNGram is matched by every UnknownNGramError
+	ErrUnknownNGram = errors.New("Unknown ngram")
+	// ErrEmptyChain is returned when generating from a chain without any transitions
+	ErrEmptyChain = errors.New("Chain has no transitions")
+	// ErrDeadEnd is returned when generating from a state whose transitions have all
+	// been removed, so that nothing can follow it
+	ErrDeadEnd = errors.New("State has no transitions")
+	// ErrInvalidOrder is returned when a chain order is negative
+	ErrInvalidOrder = errors.New("Chain order must not be negative")
+	// ErrUnsatisfiable is returned when a constrained search finds no sequence
+	// meeting its constraint within its limit
+	ErrUnsatisfiable = errors.New("No sequence satisfies the constraint")
+	// ErrCountOverflow is returned when adding to a transition count would take the
+	// total of its row past the largest int, which would silently corrupt every
+	// probability of the row
+	ErrCountOverflow = errors.New("Transition count overflows")
+)
+
+// UnknownNGramError is returned when generating from an n-gram the chain has never
+// seen. It matches ErrUnknownNGram with errors.Is.
+type UnknownNGramError struct {
+	NGram NGram
+}
+
+func (e *UnknownNGramError) Error() string {
+	return fmt.Sprintf("Unknown ngram %v", e.NGram)
+}
+
+// Is reports whether target is ErrUnknownNGram
+func (e *UnknownNGramError) Is(target error) bool {
+	return target == ErrUnknownNGram
+}
+
+// SequenceError is returned when generating or scoring a sequence fails part way.
+// It records where, and unwraps to the underlying error.
+type SequenceError struct {
+	// Op is the failed operation, "generate", "walk" or "score"
+	Op string
+	// Position is the index, within the generated or scored tokens, of the token
+	// that couldn't be generated or scored
+	Position int
+	// NGram is the state the token follows
+	NGram NGram
+	// Partial holds the tokens generated or scored before the failure
+	Partial []string
+	Err     error
+}
+
+func (e *SequenceError) Error() string {
+	return fmt.Sprintf("Cannot %s token %d after %v: %v", e.Op, e.Position, e.NGram, e.Err)
+}
+
+func (e *SequenceError) Unwrap() error {
+	return e.Err
+}
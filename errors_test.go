@@ -0,0 +1,96 @@
+package gomarkov
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestChain_Errors(t *testing.T) {
+	trained := NewChain(2)
+	trained.Add([]string{"I", "want", "a", "cheese", "burger"})
+
+	tests := []struct {
+		name    string
+		chain   *Chain
+		current NGram
+		want    error
+	}{
+		{"Order mismatch", trained, NGram{"I"}, ErrOrderMismatch},
+		{"Unknown ngram", trained, NGram{"I", "need"}, ErrUnknownNGram},
+		{"Empty chain", NewChain(2), NGram{StartToken, StartToken}, ErrEmptyChain},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := tt.chain.Generate(tt.current); !errors.Is(err, tt.want) {
+				t.Errorf("Chain.Generate() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+
+	if _, err := trained.TransitionProbability("a", NGram{"want"}); !errors.Is(err, ErrOrderMismatch) {
+		t.Errorf("Chain.TransitionProbability() error = %v, want %v", err, ErrOrderMismatch)
+	}
+
+	current := NGram{"I", "need"}
+	_, err := trained.Generate(current)
+	var unknown *UnknownNGramError
+	if !errors.As(err, &unknown) || !reflect.DeepEqual(unknown.NGram, current) {
+		t.Fatalf("Chain.Generate() error = %v, want UnknownNGramError for %v", err, current)
+	}
+	if err.Error() != "Unknown ngram [I need]" {
+		t.Errorf("UnknownNGramError.Error() = %q", err.Error())
+	}
+	current[1] = "changed"
+	if unknown.NGram[1] != "need" {
+		t.Errorf("UnknownNGramError.NGram shares memory with the caller's n-gram")
+	}
+}
+
+func TestChain_DeadEnd(t *testing.T) {
+	chain := NewChain(1)
+	chain.Add([]string{"I", "want", "a", "burger"})
+	chain.Add([]string{"I", "need", "a", "burger"})
+	// Prune every transition out of "want"
+	want, _, _ := chain.store.LookupState("want")
+	a, _, _ := chain.store.LookupState("a")
+	chain.store.IncrementTransition(want, a, -1)
+
+	frozen, err := chain.Freeze()
+	if err != nil {
+		t.Fatalf("Chain.Freeze() error = %v", err)
+	}
+	var buf bytes.Buffer
+	if err := chain.Compile(&buf); err != nil {
+		t.Fatalf("Chain.Compile() error = %v", err)
+	}
+	compiled, err := LoadCompiled(buf.Bytes())
+	if err != nil {
+		t.Fatalf("LoadCompiled() error = %v", err)
+	}
+	for _, chain := range []*Chain{chain, frozen, compiled} {
+		if _, err := chain.Generate(NGram{"want"}); !errors.Is(err, ErrDeadEnd) {
+			t.Errorf("Chain.Generate() error = %v, want %v", err, ErrDeadEnd)
+		}
+		if got, err := chain.Generate(NGram{"need"}); err != nil || got != "a" {
+			t.Errorf("Chain.Generate() = %v, %v, want a", got, err)
+		}
+		if p, err := chain.TransitionProbability("a", NGram{"want"}); err != nil || p != 0 {
+			t.Errorf("Chain.TransitionProbability() = %v, %v, want 0", p, err)
+		}
+	}
+
+	empty := NewChain(1)
+	empty.store.AddState(StartToken)
+	if _, err := empty.Generate(NGram{StartToken}); !errors.Is(err, ErrEmptyChain) {
+		t.Errorf("Chain.Generate() error = %v, want %v", err, ErrEmptyChain)
+	}
+
+	typed := NewChainOf[int](1)
+	typed.Add([]int{1, 2})
+	typed.rows[typed.states[[2]int{0, firstTokenIndex}]] = sparseArray{}
+	if _, _, err := typed.Generate([]int{1}); !errors.Is(err, ErrDeadEnd) {
+		t.Errorf("ChainOf.Generate() error = %v, want %v", err, ErrDeadEnd)
+	}
+}
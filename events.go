@@ -0,0 +1,165 @@
+package gomarkov
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// Prediction is a possible next token and its probability
+type Prediction[T comparable] struct {
+	Token       T
+	Probability float64
+}
+
+// recent returns the last tokens of history that determine the next one
+func (chain *ChainOf[T]) recent(history []T) []T {
+	if len(history) > chain.Order {
+		return history[len(history)-chain.Order:]
+	}
+	return history
+}
+
+// Predict returns the n most likely tokens to follow history, most likely first, or
+// all of them if n is 0 or less. Only the last Order tokens of history matter, and a
+// shorter history is the start of a sequence, so a session's events so far can be
+// passed as they are. The probability of the sequence ending instead is given by
+// EndProbability.
+func (chain *ChainOf[T]) Predict(history []T, n int) ([]Prediction[T], error) {
+	chain.lock.RLock()
+	defer chain.lock.RUnlock()
+	state, ok, err := chain.state(chain.recent(history))
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		if len(chain.rows) == 0 {
+			return nil, ErrEmptyChain
+		}
+		return nil, ErrUnknownNGram
+	}
+	row := chain.rows[state]
+	sum := float64(row.sum())
+	predictions := make([]Prediction[T], 0, len(row))
+	for _, pair := range row.orderedPairs() {
+		if n > 0 && len(predictions) == n {
+			break
+		}
+		if pair[0] == endIndex {
+			continue
+		}
+		predictions = append(predictions, Prediction[T]{chain.tokens[pair[0]], float64(pair[1]) / sum})
+	}
+	return predictions, nil
+}
+
+// Event is a token observed for a key at a point in time, such as a page viewed by
+// a user or an API call made by a client
+type Event[K, T comparable] struct {
+	Key   K
+	Token T
+	Time  time.Time
+}
+
+// SplitSessions groups events by key and splits each key's events into sessions,
+// in time order, wherever more than gap passes between two events. Sessions are
+// returned in the order they started.
+func SplitSessions[K, T comparable](events []Event[K, T], gap time.Duration) [][]T {
+	sorted := append([]Event[K, T](nil), events...)
+	sort.SliceStable(sorted, func(a, b int) bool {
+		return sorted[a].Time.Before(sorted[b].Time)
+	})
+	var sessions [][]T
+	open := make(map[K]int)
+	last := make(map[K]time.Time)
+	for _, e := range sorted {
+		i, ok := open[e.Key]
+		if !ok || e.Time.Sub(last[e.Key]) > gap {
+			i = len(sessions)
+			open[e.Key] = i
+			sessions = append(sessions, nil)
+		}
+		sessions[i] = append(sessions[i], e.Token)
+		last[e.Key] = e.Time
+	}
+	return sessions
+}
+
+// Sessionizer trains a chain from a live stream of events, adding each key's events
+// as a sequence once its session ends. It is safe for concurrent use.
+type Sessionizer[K, T comparable] struct {
+	chain *ChainOf[T]
+	gap   time.Duration
+	lock  sync.Mutex
+	open  map[K]*session[T]
+}
+
+type session[T comparable] struct {
+	tokens []T
+	last   time.Time
+}
+
+// NewSessionizer creates a Sessionizer training chain, ending a key's session once
+// more than gap passes without an event for it
+func NewSessionizer[K, T comparable](chain *ChainOf[T], gap time.Duration) *Sessionizer[K, T] {
+	return &Sessionizer[K, T]{chain: chain, gap: gap, open: make(map[K]*session[T])}
+}
+
+// Observe adds an event to its key's session, first training on the key's previous
+// session if the event comes too long after it. Events of a key must be observed in
+// time order.
+func (s *Sessionizer[K, T]) Observe(e Event[K, T]) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	open, ok := s.open[e.Key]
+	if ok && e.Time.Sub(open.last) > s.gap {
+		s.chain.Add(open.tokens)
+		ok = false
+	}
+	if !ok {
+		open = &session[T]{}
+		s.open[e.Key] = open
+	}
+	open.tokens = append(open.tokens, e.Token)
+	open.last = e.Time
+}
+
+// Session returns the events of a key's open session, for example to pass to
+// Predict, or nil if it has none
+func (s *Sessionizer[K, T]) Session(key K) []T {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if open, ok := s.open[key]; ok {
+		return append([]T(nil), open.tokens...)
+	}
+	return nil
+}
+
+// Expire trains on and closes every session without an event for more than gap
+// before now, returning how many there were. Call it periodically so sessions of
+// keys that stop sending events are trained on.
+func (s *Sessionizer[K, T]) Expire(now time.Time) int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	expired := 0
+	for key, open := range s.open {
+		if now.Sub(open.last) > s.gap {
+			s.chain.Add(open.tokens)
+			delete(s.open, key)
+			expired++
+		}
+	}
+	return expired
+}
+
+// Flush trains on and closes every open session, returning how many there were
+func (s *Sessionizer[K, T]) Flush() int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	flushed := len(s.open)
+	for key, open := range s.open {
+		s.chain.Add(open.tokens)
+		delete(s.open, key)
+	}
+	return flushed
+}
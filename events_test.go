@@ -0,0 +1,78 @@
+package gomarkov
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestChainOf_Predict(t *testing.T) {
+	chain := NewChainOf[string](1)
+	chain.Add([]string{"home", "search", "product", "cart"})
+	chain.Add([]string{"home", "search", "product"})
+	chain.Add([]string{"home", "product", "cart"})
+	chain.Add([]string{"home", "search", "search"})
+
+	got, err := chain.Predict([]string{"home", "search"}, 0)
+	if err != nil {
+		t.Fatalf("ChainOf.Predict() error = %v", err)
+	}
+	want := []Prediction[string]{{"product", 0.5}, {"search", 0.25}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ChainOf.Predict() = %v, want %v", got, want)
+	}
+	if got, _ := chain.Predict(nil, 1); len(got) != 1 || got[0] != (Prediction[string]{"home", 1}) {
+		t.Errorf("ChainOf.Predict() at the start = %v, want home", got)
+	}
+	if _, err := chain.Predict([]string{"checkout"}, 1); !errors.Is(err, ErrUnknownNGram) {
+		t.Errorf("ChainOf.Predict() error = %v, want %v", err, ErrUnknownNGram)
+	}
+}
+
+func TestSplitSessions(t *testing.T) {
+	at := func(minutes int) time.Time {
+		return time.Date(2024, 1, 1, 0, minutes, 0, 0, time.UTC)
+	}
+	events := []Event[string, int]{
+		{"bob", 1, at(0)},
+		{"alice", 1, at(1)},
+		{"bob", 2, at(5)},
+		{"alice", 3, at(50)},
+		{"bob", 3, at(2)},
+	}
+	got := SplitSessions(events, 30*time.Minute)
+	want := [][]int{{1, 3, 2}, {1}, {3}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("SplitSessions() = %v, want %v", got, want)
+	}
+}
+
+func TestSessionizer(t *testing.T) {
+	chain := NewChainOf[string](1)
+	s := NewSessionizer[int](chain, time.Minute)
+	start := time.Now()
+	s.Observe(Event[int, string]{1, "login", start})
+	s.Observe(Event[int, string]{2, "login", start})
+	s.Observe(Event[int, string]{1, "view", start.Add(time.Second)})
+	if got := s.Session(1); !reflect.DeepEqual(got, []string{"login", "view"}) {
+		t.Errorf("Sessionizer.Session() = %v", got)
+	}
+	// A late event starts a new session, training on the previous one
+	s.Observe(Event[int, string]{1, "view", start.Add(time.Hour)})
+	if p, _ := chain.EndProbability([]string{"view"}); p != 1 {
+		t.Errorf("ChainOf.EndProbability() = %v, want 1 after the first session", p)
+	}
+	if got := s.Expire(start.Add(time.Hour)); got != 1 {
+		t.Errorf("Sessionizer.Expire() = %d, want 1", got)
+	}
+	if got := s.Flush(); got != 1 {
+		t.Errorf("Sessionizer.Flush() = %d, want 1", got)
+	}
+	if s.Session(1) != nil {
+		t.Errorf("Sessionizer.Session() is not empty after Flush")
+	}
+	if p, _ := chain.TransitionProbability("login", nil); p != 2.0/3 {
+		t.Errorf("ChainOf.TransitionProbability() of starting with login = %v, want 2/3", p)
+	}
+}
@@ -0,0 +1,168 @@
+package gomarkov
+
+import (
+	"errors"
+	"unsafe"
+)
+
+// Footprint is an estimate of the memory held by a chain, in bytes
+type Footprint struct {
+	// States covers the state pool: state strings and the maps indexing them
+	States int64
+	// Transitions covers the frequency matrix
+	Transitions int64
+	// Caches covers derived data kept to speed up lookups and sampling, such as
+	// row sums, sampling distributions and the n-gram index
+	Caches int64
+}
+
+// Total returns the sum of all components
+func (f Footprint) Total() int64 {
+	return f.States + f.Transitions + f.Caches
+}
+
+// MemoryFootprint estimates the memory used by the chain, for sizing the process
+// serving it. Estimates model Go's map and slice layouts and are typically within
+// a few tens of percent of what a heap profile reports.
+func (chain *Chain) MemoryFootprint() (Footprint, error) {
+	s, ok := chain.store.(interface{ footprint() Footprint })
+	if !ok {
+		return Footprint{}, errors.New("Chain backend does not report its memory footprint")
+	}
+	return s.footprint(), nil
+}
+
+const (
+	stringHeaderBytes = int64(unsafe.Sizeof(""))
+	intBytes          = int64(unsafe.Sizeof(0))
+	sliceHeaderBytes  = int64(unsafe.Sizeof([]int{}))
+	mapHeaderBytes    = 48
+)
+
+// mapBytes estimates a map of n entries of entryBytes each. Buckets hold 8
+// entries with a byte of hash each and are about 80% full on average.
+func mapBytes(n int, entryBytes int64) int64 {
+	return mapHeaderBytes + int64(n)*(entryBytes+1)*10/8
+}
+
+// intSliceBytes estimates a slice of n ints
+func intSliceBytes(n int) int64 {
+	return sliceHeaderBytes + int64(n)*intBytes
+}
+
+func (s *spool) footprint() int64 {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	bytes := mapBytes(len(s.stringMap), stringHeaderBytes+intBytes) +
+		mapBytes(len(s.intMap), intBytes+stringHeaderBytes)
+	for state := range s.stringMap {
+		bytes += int64(len(state))
+	}
+	return bytes
+}
+
+func (x *ngramIndex) footprint() int64 {
+	x.lock.RLock()
+	defer x.lock.RUnlock()
+	bytes := mapBytes(len(x.steps), int64(unsafe.Sizeof(ngramStep{}))+intBytes)
+	for step := range x.steps {
+		bytes += int64(len(step.token))
+	}
+	return bytes
+}
+
+func (m *memoryStore) footprint() Footprint {
+	f := Footprint{
+		States: m.statePool.footprint(),
+		Caches: m.ngrams.footprint(),
+	}
+	for _, s := range m.stripes {
+		s.lock.RLock()
+		f.Transitions += sliceHeaderBytes + int64(cap(s.rows))*int64(unsafe.Sizeof(memoryRow{}))
+		for _, r := range s.rows {
+			if r.dense != nil {
+				f.Transitions += intSliceBytes(cap(r.dense.keys)) + intSliceBytes(cap(r.dense.counts))
+			} else if r.counts != nil {
+				f.Transitions += mapBytes(len(r.counts), 2*intBytes)
+			}
+			if r.cdf != nil {
+				f.Caches += intSliceBytes(len(r.cdf.keys)) + intSliceBytes(len(r.cdf.totals))
+			}
+		}
+		s.lock.RUnlock()
+	}
+	return f
+}
+
+func (c *compactStore) footprint() Footprint {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	f := Footprint{
+		States: mapBytes(len(c.stringMap), stringHeaderBytes+4) +
+			sliceHeaderBytes + int64(cap(c.states))*stringHeaderBytes,
+		Transitions: mapBytes(len(c.frequencyMat), 4+intBytes) + mapBytes(len(c.totals), 8),
+	}
+	for _, state := range c.states {
+		f.States += int64(len(state))
+	}
+	for _, row := range c.frequencyMat {
+		f.Transitions += mapBytes(len(row), 8)
+	}
+	return f
+}
+
+func (f *frozenStore) footprint() Footprint {
+	fp := Footprint{
+		States: mapBytes(len(f.stringMap), stringHeaderBytes+4) +
+			sliceHeaderBytes + int64(len(f.states))*stringHeaderBytes,
+		Transitions: intSliceBytes(len(f.rowOffsets)) + sliceHeaderBytes +
+			int64(len(f.cols))*4 + intSliceBytes(len(f.totals)),
+	}
+	for _, state := range f.states {
+		fp.States += int64(len(state))
+	}
+	return fp
+}
+
+// footprint of a compiled model is the size of its sections, whether they are
+// memory-mapped or held in memory
+func (c *compiledStore) footprint() Footprint {
+	return Footprint{
+		States:      int64(compiledHeaderSize + len(c.strOffsets) + len(c.blob) + padding(len(c.blob)) + len(c.rowOffsets)),
+		Transitions: int64(len(c.cols) + len(c.counts)),
+		Caches:      c.aliases.footprint() + intSliceBytes(len(c.names)),
+	}
+}
+
+func (l *lazyStore) footprint() Footprint {
+	f := l.compiledStore.footprint()
+	f.Transitions = 0
+	l.lock.Lock()
+	f.Caches += mapBytes(len(l.cache), 2*intBytes)
+	for _, row := range l.cache {
+		f.Caches += mapBytes(len(row.counts), 2*intBytes) + distributionBytes(row.dist)
+	}
+	l.lock.Unlock()
+	return f
+}
+
+func (c *aliasCache) footprint() int64 {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	bytes := mapBytes(len(c.tables), 2*intBytes)
+	for _, table := range c.tables {
+		bytes += distributionBytes(table)
+	}
+	return bytes
+}
+
+// distributionBytes is the size of a cached sampling distribution, 0 if it is nil
+func distributionBytes(d distribution) int64 {
+	switch t := d.(type) {
+	case *aliasTable:
+		return 3*intSliceBytes(len(t.keys)) + intBytes
+	case *cumulativeDist:
+		return intSliceBytes(len(t.keys)) + intSliceBytes(len(t.totals))
+	}
+	return 0
+}
@@ -0,0 +1,45 @@
+package gomarkov
+
+import (
+	"bytes"
+	"strconv"
+	"testing"
+)
+
+func TestChain_MemoryFootprint(t *testing.T) {
+	chain := NewChain(2)
+	empty, err := chain.MemoryFootprint()
+	if err != nil {
+		t.Fatalf("Chain.MemoryFootprint() error = %v", err)
+	}
+	for n := 0; n < 1000; n++ {
+		chain.Add([]string{"I", "want", strconv.Itoa(n), "burgers"})
+	}
+	trained, _ := chain.MemoryFootprint()
+	if trained.States <= empty.States || trained.Transitions <= empty.Transitions {
+		t.Errorf("Chain.MemoryFootprint() = %+v after training, want more than %+v", trained, empty)
+	}
+	if trained.Total() != trained.States+trained.Transitions+trained.Caches {
+		t.Errorf("Footprint.Total() = %v, want the sum of its components", trained.Total())
+	}
+	// 1000 distinct tokens and a few thousand states can't fit in a few kilobytes
+	if trained.Total() < 100000 {
+		t.Errorf("Chain.MemoryFootprint() = %v bytes, implausibly small", trained.Total())
+	}
+
+	frozen, _ := chain.Freeze()
+	if f, err := frozen.MemoryFootprint(); err != nil || f.Transitions == 0 {
+		t.Errorf("Frozen Chain.MemoryFootprint() = %+v, %v", f, err)
+	}
+
+	var buf bytes.Buffer
+	chain.Compile(&buf)
+	compiled, _ := LoadCompiled(buf.Bytes())
+	if f, _ := compiled.MemoryFootprint(); f.States+f.Transitions != int64(buf.Len()) {
+		t.Errorf("Compiled Chain.MemoryFootprint() = %+v, want %d bytes of sections", f, buf.Len())
+	}
+
+	if _, err := NewRedisChain(1, newFakeRedis(), "bot").MemoryFootprint(); err == nil {
+		t.Errorf("Chain.MemoryFootprint() on Redis should return an error")
+	}
+}
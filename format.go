@@ -0,0 +1,154 @@
+package gomarkov
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+)
+
+// formatVersion is the version of the serialized format written by MarshalJSON.
+// Bump it whenever chainJSON changes and append the matching migration.
+const formatVersion = 4
+
+// migrations upgrade a decoded model in place from version i to version i+1
+var migrations = []func(obj map[string]json.RawMessage) error{
+	// 0 -> 1: models saved before the format was versioned only lack the version field
+	func(obj map[string]json.RawMessage) error {
+		return nil
+	},
+	// 1 -> 2: the order was tagged "int"
+	func(obj map[string]json.RawMessage) error {
+		if order, ok := obj["int"]; ok {
+			obj["order"] = order
+			delete(obj, "int")
+		}
+		return nil
+	},
+	// 2 -> 3: a checksum was added and is required from now on, older models load
+	// without verification
+	func(obj map[string]json.RawMessage) error {
+		return nil
+	},
+	// 3 -> 4: float weights were added, older models only hold counts
+	func(obj map[string]json.RawMessage) error {
+		return nil
+	},
+}
+
+var crc32c = crc32.MakeTable(crc32.Castagnoli)
+
+// checksum computes the CRC-32C of a model's order and compacted contents
+func checksum(obj chainJSON) string {
+	h := crc32.New(crc32c)
+	fmt.Fprintf(h, "%d:", obj.Order)
+	h.Write(obj.SpoolMap)
+	h.Write([]byte{':'})
+	h.Write(obj.FreqMat)
+	if len(obj.Weights) > 0 {
+		h.Write([]byte{':'})
+		h.Write(obj.Weights)
+	}
+	return fmt.Sprintf("crc32c:%08x", h.Sum32())
+}
+
+// validateModel checks that a decoded model is consistent: state indices are unique
+// and non-negative, rows only reference known states and counts are not negative.
+// It returns the index to state mapping.
+func validateModel(spoolMap map[string]int, freqMat map[int]sparseArray) (map[int]string, error) {
+	intMap := make(map[int]string, len(spoolMap))
+	for state, index := range spoolMap {
+		if index < 0 {
+			return nil, fmt.Errorf("State %q has negative index %d", state, index)
+		}
+		if other, ok := intMap[index]; ok {
+			return nil, fmt.Errorf("States %q and %q share index %d", state, other, index)
+		}
+		intMap[index] = state
+	}
+	for current, row := range freqMat {
+		if _, ok := intMap[current]; !ok {
+			return nil, fmt.Errorf("Row for unknown state index %d", current)
+		}
+		sum := 0
+		for next, count := range row {
+			if _, ok := intMap[next]; !ok {
+				return nil, fmt.Errorf("Transition to unknown state index %d", next)
+			}
+			if count < 0 {
+				return nil, fmt.Errorf("Negative count %d for transition %d -> %d", count, current, next)
+			}
+			var ok bool
+			if sum, ok = addCount(sum, count); !ok {
+				return nil, fmt.Errorf("%w: row of state index %d", ErrCountOverflow, current)
+			}
+		}
+	}
+	return intMap, nil
+}
+
+// checksumVersion is the first format version whose models always hold a checksum
+const checksumVersion = 3
+
+// migrate upgrades a decoded model of any known version to formatVersion, returning
+// the version it was saved with
+func migrate(obj map[string]json.RawMessage) (int, error) {
+	if obj == nil {
+		return 0, errors.New("Serialized chain must be a JSON object")
+	}
+	saved := 0
+	if raw, ok := obj["version"]; ok {
+		if err := json.Unmarshal(raw, &saved); err != nil {
+			return 0, fmt.Errorf("Invalid format version: %v", err)
+		}
+	}
+	if saved < 0 || saved > formatVersion {
+		return 0, fmt.Errorf("Unsupported format version %d, this package supports up to %d", saved, formatVersion)
+	}
+	for version := saved; version < formatVersion; version++ {
+		if err := migrations[version](obj); err != nil {
+			return 0, fmt.Errorf("Migrating format version %d: %v", version, err)
+		}
+	}
+	obj["version"] = json.RawMessage(fmt.Sprint(formatVersion))
+	return saved, nil
+}
+
+// decodeModel migrates a serialized model to the current format, verifies its
+// checksum, which only models older than checksumVersion may lack, and decodes its
+// contents
+func decodeModel(b []byte) (chainJSON, map[string]int, map[int]sparseArray, error) {
+	var obj chainJSON
+	var raw map[string]json.RawMessage
+	err := json.Unmarshal(b, &raw)
+	if err != nil {
+		return obj, nil, nil, err
+	}
+	saved, err := migrate(raw)
+	if err != nil {
+		return obj, nil, nil, err
+	}
+	b, err = json.Marshal(raw)
+	if err != nil {
+		return obj, nil, nil, err
+	}
+	err = json.Unmarshal(b, &obj)
+	if err != nil {
+		return obj, nil, nil, err
+	}
+	if obj.Checksum == "" && saved >= checksumVersion {
+		return obj, nil, nil, fmt.Errorf("Chain checksum missing, required from format version %d", checksumVersion)
+	}
+	if obj.Checksum != "" && obj.Checksum != checksum(obj) {
+		return obj, nil, nil, errors.New("Chain checksum mismatch, the model is corrupt")
+	}
+	var spoolMap map[string]int
+	var freqMat map[int]sparseArray
+	if err := json.Unmarshal(obj.SpoolMap, &spoolMap); err != nil {
+		return obj, nil, nil, err
+	}
+	if err := json.Unmarshal(obj.FreqMat, &freqMat); err != nil {
+		return obj, nil, nil, err
+	}
+	return obj, spoolMap, freqMat, nil
+}
@@ -0,0 +1,90 @@
+package gomarkov
+
+import (
+	"encoding/json"
+	"os"
+	"testing"
+)
+
+func TestChain_UnmarshalJSON_Versions(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		wantErr bool
+	}{
+		{"Unversioned", `{"int":1,"spool_map":{"^":0,"Test":1,"$":2},"freq_mat":{"0":{"1":1},"1":{"2":1}}}`, false},
+		{"Version 1", `{"version":1,"int":1,"spool_map":{"^":0,"Test":1,"$":2},"freq_mat":{"0":{"1":1},"1":{"2":1}}}`, false},
+		{"Version 2", `{"version":2,"order":1,"spool_map":{"^":0,"Test":1,"$":2},"freq_mat":{"0":{"1":1},"1":{"2":1}}}`, false},
+		{"Version 3", `{"version":3,"order":1,"spool_map":{"$":2,"Test":1,"^":0},"freq_mat":{"0":{"1":1},"1":{"2":1}},"checksum":"crc32c:e167b5db"}`, false},
+		{"Future version", `{"version":99,"int":1,"spool_map":{},"freq_mat":{}}`, true},
+		{"Negative version", `{"version":-1,"int":1,"spool_map":{},"freq_mat":{}}`, true},
+		{"Invalid version", `{"version":"one","int":1,"spool_map":{},"freq_mat":{}}`, true},
+		{"Not an object", `null`, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chain := NewChain(3)
+			err := chain.UnmarshalJSON([]byte(tt.data))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Chain.UnmarshalJSON() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if chain.Order != 1 {
+				t.Errorf("Chain.Order = %d, want 1", chain.Order)
+			}
+			got, err := chain.TransitionProbability("Test", NGram{StartToken})
+			if err != nil || got != 1 {
+				t.Errorf("Chain.TransitionProbability() = %v, %v, want 1", got, err)
+			}
+		})
+	}
+}
+
+func TestChain_UnmarshalJSON_SavedModel(t *testing.T) {
+	// The gibberish example ships a model saved before the format was versioned
+	data, err := os.ReadFile("examples/gibberish/model.json")
+	if err != nil {
+		t.Skip(err)
+	}
+	var saved struct {
+		Chain *Chain `json:"chain"`
+	}
+	if err := json.Unmarshal(data, &saved); err != nil {
+		t.Fatalf("json.Unmarshal() error = %v", err)
+	}
+	if saved.Chain.Order != 2 {
+		t.Errorf("Chain.Order = %d, want 2", saved.Chain.Order)
+	}
+}
+
+func TestChain_UnmarshalJSON_Integrity(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		wantErr bool
+	}{
+		{"Valid checksum", `{"version":3,"order":1,"spool_map":{"$":2,"Test":1,"^":0},"freq_mat":{"0":{"1":1},"1":{"2":1}},"checksum":"crc32c:e167b5db"}`, false},
+		{"Pretty printed", "{\n  \"version\": 3,\n  \"order\": 1,\n  \"spool_map\": {\"$\": 2, \"Test\": 1, \"^\": 0},\n  \"freq_mat\": {\"0\": {\"1\": 1}, \"1\": {\"2\": 1}},\n  \"checksum\": \"crc32c:e167b5db\"\n}", false},
+		{"Reordered keys", `{"version":3,"order":1,"spool_map":{"^":0,"Test":1,"$":2},"freq_mat":{"1":{"2":1},"0":{"1":1}},"checksum":"crc32c:e167b5db"}`, true},
+		{"Tampered count", `{"version":3,"order":1,"spool_map":{"$":2,"Test":1,"^":0},"freq_mat":{"0":{"1":5},"1":{"2":1}},"checksum":"crc32c:e167b5db"}`, true},
+		{"Tampered order", `{"version":3,"order":2,"spool_map":{"$":2,"Test":1,"^":0},"freq_mat":{"0":{"1":1},"1":{"2":1}},"checksum":"crc32c:e167b5db"}`, true},
+		{"Stripped checksum", `{"version":3,"order":1,"spool_map":{"$":2,"Test":1,"^":0},"freq_mat":{"0":{"1":1},"1":{"2":1}}}`, true},
+		{"Stripped checksum, version 4", `{"version":4,"order":1,"spool_map":{"$":2,"Test":1,"^":0},"freq_mat":{"0":{"1":1},"1":{"2":1}}}`, true},
+		{"No checksum, version 2", `{"version":2,"order":1,"spool_map":{"$":2,"Test":1,"^":0},"freq_mat":{"0":{"1":1},"1":{"2":1}}}`, false},
+		{"Unknown row state", `{"version":2,"order":1,"spool_map":{"$":2,"Test":1,"^":0},"freq_mat":{"7":{"1":1}}}`, true},
+		{"Unknown next state", `{"version":2,"order":1,"spool_map":{"$":2,"Test":1,"^":0},"freq_mat":{"0":{"7":1}}}`, true},
+		{"Shared index", `{"version":2,"order":1,"spool_map":{"$":1,"Test":1,"^":0},"freq_mat":{}}`, true},
+		{"Negative index", `{"version":2,"order":1,"spool_map":{"$":-1},"freq_mat":{}}`, true},
+		{"Negative count", `{"version":2,"order":1,"spool_map":{"$":2,"Test":1,"^":0},"freq_mat":{"0":{"1":-1}}}`, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chain := NewChain(1)
+			if err := chain.UnmarshalJSON([]byte(tt.data)); (err != nil) != tt.wantErr {
+				t.Errorf("Chain.UnmarshalJSON() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
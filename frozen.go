@@ -0,0 +1,221 @@
+package gomarkov
+
+import (
+	"errors"
+	"math"
+	"sort"
+	"sync/atomic"
+)
+
+var errFrozen = errors.New("Frozen chains are read-only")
+
+// Freeze returns a read-only copy of the chain whose rows and sampling
+// distributions are computed up front. It never takes a lock, so any number of
+// goroutines can generate from it without contention. Frozen rows hold counts, so
+// chains holding float weights, see WithFloatWeights, can't be frozen.
+func (chain *Chain) Freeze() (*Chain, error) {
+	spoolMap, freqMat, err := chain.exportCounts("frozen chains")
+	if err != nil {
+		return nil, err
+	}
+	if uint64(len(spoolMap)) >= math.MaxUint32 {
+		return nil, errors.New("Chain is too large to freeze")
+	}
+	// Renumber states densely in their original order
+	indices := make([]int, 0, len(spoolMap))
+	for _, index := range spoolMap {
+		indices = append(indices, index)
+	}
+	sort.Ints(indices)
+	remap := make(map[int]int, len(indices))
+	for i, index := range indices {
+		remap[index] = i
+	}
+
+	f := &frozenStore{
+		stringMap:  make(map[string]uint32, len(spoolMap)),
+		states:     make([]string, len(spoolMap)),
+		rowOffsets: make([]int, len(spoolMap)+1),
+	}
+	for state, index := range spoolMap {
+		f.stringMap[state] = uint32(remap[index])
+		f.states[remap[index]] = state
+	}
+	edges := 0
+	for _, row := range freqMat {
+		edges += len(row)
+	}
+	f.cols = make([]uint32, 0, edges)
+	f.totals = make([]int, 0, edges)
+	for i, index := range indices {
+		d := freqMat[index].cumulative()
+		for j, next := range d.keys {
+			f.cols = append(f.cols, uint32(remap[next]))
+			f.totals = append(f.totals, d.totals[j])
+		}
+		f.rowOffsets[i+1] = len(f.cols)
+	}
+	return chain.withStore(f), nil
+}
+
+// frozenStore is an immutable Store, safe for concurrent reads without locking.
+// Transitions are kept in compressed sparse row form: the successors of state i
+// are cols[rowOffsets[i]:rowOffsets[i+1]], in sampling order, see orderedPairs,
+// with totals holding the running total of their counts within the row.
+type frozenStore struct {
+	stringMap  map[string]uint32
+	states     []string
+	rowOffsets []int
+	cols       []uint32
+	totals     []int
+}
+
+func (f *frozenStore) AddState(state string) (int, error) {
+	if index, ok := f.stringMap[state]; ok {
+		return int(index), nil
+	}
+	return 0, errFrozen
+}
+
+func (f *frozenStore) LookupState(state string) (int, bool, error) {
+	index, ok := f.stringMap[state]
+	return int(index), ok, nil
+}
+
+// lookupNGram is LookupState for an n-gram, building its key on the stack
+func (f *frozenStore) lookupNGram(ngram NGram) (int, bool, error) {
+	var buf [128]byte
+	index, ok := f.stringMap[string(ngram.appendKey(buf[:0]))]
+	return int(index), ok, nil
+}
+
+func (f *frozenStore) LookupIndex(index int) (string, bool, error) {
+	if index < 0 || index >= len(f.states) {
+		return "", false, nil
+	}
+	return f.states[index], true, nil
+}
+
+func (f *frozenStore) IncrementTransition(current, next, delta int) error {
+	return errFrozen
+}
+
+// span returns the bounds of a row in cols and totals
+func (f *frozenStore) span(current int) (start, end int) {
+	if current < 0 || current >= len(f.states) {
+		return 0, 0
+	}
+	return f.rowOffsets[current], f.rowOffsets[current+1]
+}
+
+func (f *frozenStore) GetRow(current int) (map[int]int, error) {
+	start, end := f.span(current)
+	if start == end {
+		return nil, nil
+	}
+	row := make(map[int]int, end-start)
+	previous := 0
+	for i := start; i < end; i++ {
+		row[int(f.cols[i])] = f.totals[i] - previous
+		previous = f.totals[i]
+	}
+	return row, nil
+}
+
+func (f *frozenStore) IterateRows(fn func(current int, row map[int]int) bool) error {
+	for current := range f.states {
+		row, _ := f.GetRow(current)
+		if row == nil {
+			continue
+		}
+		if !fn(current, row) {
+			break
+		}
+	}
+	return nil
+}
+
+// draw samples the next state of a row directly from the flat arrays
+func (f *frozenStore) draw(current int, prng PRNG) (int, bool) {
+	start, end := f.span(current)
+	if end == start || f.totals[end-1] == 0 {
+		return 0, false
+	}
+	randN := prng.Intn(f.totals[end-1])
+	return int(f.cols[start+sort.SearchInts(f.totals[start:end], randN+1)]), true
+}
+
+func (f *frozenStore) transitionCount(current, next int) (count, sum int) {
+	start, end := f.span(current)
+	if start == end {
+		return 0, 0
+	}
+	for i := start; i < end; i++ {
+		if int(f.cols[i]) == next {
+			count = f.totals[i]
+			if i > start {
+				count -= f.totals[i-1]
+			}
+			break
+		}
+	}
+	return count, f.totals[end-1]
+}
+
+func (f *frozenStore) snapshot() Store {
+	return f
+}
+
+// LiveChain trains a chain while serving reads from the last frozen view of it.
+// Add only touches the training chain, and Publish atomically swaps in a new view,
+// so Generate and TransitionProbability never wait on training. Publishing copies
+// the whole chain, so publish after batches of Adds rather than after each one.
+type LiveChain struct {
+	chain *Chain
+	view  atomic.Pointer[Chain]
+}
+
+// NewLiveChain wraps a chain and publishes its current state as the first view
+func NewLiveChain(chain *Chain) (*LiveChain, error) {
+	live := &LiveChain{chain: chain}
+	if err := live.Publish(); err != nil {
+		return nil, err
+	}
+	return live, nil
+}
+
+// Add trains the chain without affecting the published view
+func (live *LiveChain) Add(input []string) error {
+	return live.chain.Add(input)
+}
+
+// Publish freezes the chain as it is now and makes it the view served to readers
+func (live *LiveChain) Publish() error {
+	view, err := live.chain.Freeze()
+	if err != nil {
+		return err
+	}
+	live.view.Store(view)
+	return nil
+}
+
+// View returns the currently published read-only chain
+func (live *LiveChain) View() *Chain {
+	return live.view.Load()
+}
+
+// Generate generates new text from the published view
+func (live *LiveChain) Generate(current NGram) (string, error) {
+	return live.View().Generate(current)
+}
+
+// TransitionProbability returns a transition probability from the published view
+func (live *LiveChain) TransitionProbability(next string, current NGram) (float64, error) {
+	return live.View().TransitionProbability(next, current)
+}
+
+// LookupTransition returns a transition probability from the published view and
+// whether its state is known, see Chain.LookupTransition
+func (live *LiveChain) LookupTransition(next string, current NGram) (float64, bool, error) {
+	return live.View().LookupTransition(next, current)
+}
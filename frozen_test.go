@@ -0,0 +1,126 @@
+package gomarkov
+
+import (
+	"bytes"
+	"math/rand"
+	"reflect"
+	"strconv"
+	"sync"
+	"testing"
+)
+
+func TestChain_Freeze(t *testing.T) {
+	chain := NewChain(1)
+	chain.Add([]string{"I", "want", "a", "cheese", "burger"})
+	chain.Add([]string{"I", "want", "a", "chilled", "sprite"})
+	frozen, err := chain.Freeze()
+	if err != nil {
+		t.Fatalf("Chain.Freeze() error = %v", err)
+	}
+	if !reflect.DeepEqual(transitionCounts(t, frozen), transitionCounts(t, chain)) {
+		t.Errorf("Chain.Freeze() transitions differ from the chain")
+	}
+	for r := 0; r < 2; r++ {
+		a, _ := chain.GenerateDeterministic(NGram{"a"}, &sequenceRand{[]int{r}})
+		b, _ := frozen.GenerateDeterministic(NGram{"a"}, &sequenceRand{[]int{r}})
+		if a != b {
+			t.Errorf("Frozen Chain.GenerateDeterministic() = %v, want %v", b, a)
+		}
+	}
+	if got, _ := frozen.TransitionProbability("cheese", NGram{"a"}); got != 0.5 {
+		t.Errorf("Frozen Chain.TransitionProbability() = %v, want 0.5", got)
+	}
+	if err := frozen.Add([]string{"I", "want", "fries"}); err != errFrozen {
+		t.Errorf("Frozen Chain.Add() error = %v, want %v", err, errFrozen)
+	}
+}
+
+func TestLiveChain(t *testing.T) {
+	chain := NewChain(1)
+	chain.Add([]string{"I", "want", "a", "cheese", "burger"})
+	live, err := NewLiveChain(chain)
+	if err != nil {
+		t.Fatalf("NewLiveChain() error = %v", err)
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for n := 0; n < 100; n++ {
+			live.Add([]string{"I", "want", "a", strconv.Itoa(n)})
+			if n%10 == 0 {
+				if err := live.Publish(); err != nil {
+					t.Error(err)
+				}
+			}
+		}
+	}()
+	for n := 0; n < 100; n++ {
+		if _, err := live.Generate(NGram{"a"}); err != nil {
+			t.Fatalf("LiveChain.Generate() error = %v", err)
+		}
+	}
+	wg.Wait()
+
+	if got, _ := live.TransitionProbability("99", NGram{"a"}); got != 0 {
+		t.Errorf("LiveChain.TransitionProbability() = %v before publishing, want 0", got)
+	}
+	live.Publish()
+	if got, _ := live.TransitionProbability("99", NGram{"a"}); got != 1.0/101 {
+		t.Errorf("LiveChain.TransitionProbability() = %v after publishing, want %v", got, 1.0/101)
+	}
+}
+
+func TestChain_GenerateDeterministic_Allocs(t *testing.T) {
+	for _, order := range []int{1, 2} {
+		chain := NewChain(order)
+		chain.Add([]string{"I", "want", "a", "cheese", "burger"})
+		chain.Add([]string{"I", "want", "a", "chilled", "sprite"})
+		frozen, _ := chain.Freeze()
+		var buf bytes.Buffer
+		chain.Compile(&buf)
+		compiled, _ := LoadCompiled(buf.Bytes())
+		current := NGram{"want", "a"}[2-order:]
+		prng := rand.New(rand.NewSource(1))
+		for _, c := range []*Chain{chain, frozen, compiled} {
+			c.GenerateDeterministic(current, prng)
+			allocs := testing.AllocsPerRun(100, func() {
+				c.GenerateDeterministic(current, prng)
+			})
+			if allocs != 0 {
+				t.Errorf("Order %d Chain.GenerateDeterministic() allocates %v times, want 0", order, allocs)
+			}
+		}
+	}
+}
+
+func TestChain_Freeze_Layout(t *testing.T) {
+	chain := denseTestChain()
+	chain.Add([]string{"a", "b"})
+	frozen, err := chain.Freeze()
+	if err != nil {
+		t.Fatalf("Chain.Freeze() error = %v", err)
+	}
+	f := frozen.store.(*frozenStore)
+	if len(f.rowOffsets) != len(f.states)+1 || len(f.cols) != len(f.totals) || f.rowOffsets[len(f.states)] != len(f.cols) {
+		t.Fatalf("frozenStore arrays are inconsistent: %d states, %d offsets, %d cols, %d totals",
+			len(f.states), len(f.rowOffsets), len(f.cols), len(f.totals))
+	}
+	if !reflect.DeepEqual(transitionCounts(t, frozen), transitionCounts(t, chain)) {
+		t.Errorf("Chain.Freeze() transitions differ from the chain")
+	}
+	for _, next := range []string{"w0", "w1", "w2", "w256", "missing"} {
+		want, _ := chain.TransitionProbability(next, NGram{"the"})
+		if got, _ := frozen.TransitionProbability(next, NGram{"the"}); got != want {
+			t.Errorf("Frozen Chain.TransitionProbability(%v) = %v, want %v", next, got, want)
+		}
+	}
+	for r := 0; r < 500; r += 7 {
+		a, _ := chain.GenerateDeterministic(NGram{"the"}, &sequenceRand{[]int{r}})
+		b, _ := frozen.GenerateDeterministic(NGram{"the"}, &sequenceRand{[]int{r}})
+		if a != b {
+			t.Errorf("Frozen Chain.GenerateDeterministic() = %v, want %v", b, a)
+		}
+	}
+}
@@ -4,7 +4,10 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"math/rand"
+	"sort"
+	"strings"
 	"sync"
 	"time"
 )
@@ -16,12 +19,29 @@ const (
 	EndToken   = "$"
 )
 
-// Chain is a markov chain instance
+// Chain is a markov chain instance. All of its methods are safe for concurrent use,
+// so a chain can be trained while other goroutines generate from, score or serialize
+// it, with the exception of UnmarshalJSON, which replaces the whole chain, and of
+// chains created with WithoutLocking. Readers
+// running during training see the counts of every Add that has returned, and some
+// of those still running.
 type Chain struct {
-	Order        int
-	statePool    *spool
-	frequencyMat map[int]sparseArray
-	lock         *sync.RWMutex
+	Order int
+	store Store
+	// prng is used by Generate, defaultPrng when nil
+	prng PRNG
+	// metrics is reported to when it isn't nil, see WithInstrumentation
+	metrics Instrumentation
+	// lengths records training sequence lengths when it isn't nil, see WithLengthMatching
+	lengths *LengthDistribution
+	// novelty records training sequences when it isn't nil, see WithNoveltyFilter
+	novelty *NoveltyFilter
+	// minLength is the length of the shortest sequence trained on, see WithMinLength
+	minLength int
+	// stop ends generated sequences early when it isn't nil, see WithStopWhen
+	stop func(tokens []string) bool
+	// checkpoints saves the chain as it trains when it isn't nil, see WithCheckpoints
+	checkpoints *Checkpointer
 }
 
 // PRNG is a pseudo-random number generator compatible with math/rand interfaces.
@@ -31,123 +51,776 @@ type PRNG interface {
 }
 
 type chainJSON struct {
-	Order    int                 `json:"int"`
-	SpoolMap map[string]int      `json:"spool_map"`
-	FreqMat  map[int]sparseArray `json:"freq_mat"`
+	Version  int             `json:"version"`
+	Order    int             `json:"order"`
+	SpoolMap json.RawMessage `json:"spool_map"`
+	FreqMat  json.RawMessage `json:"freq_mat"`
+	// Weights holds the exact transition weights of chains with float weights, whose
+	// frequency matrix holds them rounded
+	Weights  json.RawMessage `json:"weights,omitempty"`
+	Checksum string          `json:"checksum,omitempty"`
+	Shard    int             `json:"shard,omitempty"`
+	Shards   int             `json:"shards,omitempty"`
 }
 
-var defaultPrng = rand.New(rand.NewSource(time.Now().UnixNano()))
+var defaultPrng PRNG = &lockedRand{r: rand.New(rand.NewSource(time.Now().UnixNano()))}
 
-// MarshalJSON ...
+// lockedRand makes a rand.Rand safe for concurrent use
+type lockedRand struct {
+	lock sync.Mutex
+	r    *rand.Rand
+}
+
+func (l *lockedRand) Intn(n int) int {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	return l.r.Intn(n)
+}
+
+// MarshalJSON encodes the chain with its state indices, so they survive a round
+// trip. Keys are written in sorted order, so serializing the same chain always
+// produces the same bytes, see MarshalCanonicalJSON to compare different chains.
 func (chain Chain) MarshalJSON() ([]byte, error) {
-	obj := chainJSON{
-		chain.Order,
-		chain.statePool.stringMap,
-		chain.frequencyMat,
+	spoolMap, freqMat, weights, err := chain.exportModel()
+	if err != nil {
+		return nil, err
+	}
+	return marshalModel(chain.Order, spoolMap, freqMat, weights)
+}
+
+// MarshalCanonicalJSON encodes the chain like MarshalJSON, but with states renumbered
+// in sorted order first. Chains holding the same transitions encode to identical
+// bytes however their states were indexed, for example when trained with
+// AddParallel, making the output suitable for content-addressed storage and diffs.
+func (chain *Chain) MarshalCanonicalJSON() ([]byte, error) {
+	spoolMap, freqMat, exported, err := chain.exportModel()
+	if err != nil {
+		return nil, err
+	}
+	states := make([]string, 0, len(spoolMap))
+	for state := range spoolMap {
+		states = append(states, state)
+	}
+	sort.Strings(states)
+	remap := make(map[int]int, len(states))
+	canonical := make(map[string]int, len(states))
+	for i, state := range states {
+		remap[spoolMap[state]] = i
+		canonical[state] = i
+	}
+	// exportModel fails on indices without a state, so remap covers every row
+	rows := make(map[int]sparseArray, len(freqMat))
+	for current, row := range freqMat {
+		remapped := make(sparseArray, len(row))
+		for next, count := range row {
+			remapped[remap[next]] = count
+		}
+		rows[remap[current]] = remapped
+	}
+	var weights map[int]map[int]float64
+	if exported != nil {
+		weights = make(map[int]map[int]float64, len(exported))
+		for current, row := range exported {
+			remapped := make(map[int]float64, len(row))
+			for next, weight := range row {
+				remapped[remap[next]] = weight
+			}
+			weights[remap[current]] = remapped
+		}
+	}
+	return marshalModel(chain.Order, canonical, rows, weights)
+}
+
+// marshalModel encodes a model in the current serialization format, with its
+// weights if it holds float weights
+func marshalModel(order int, spoolMap map[string]int, freqMat map[int]sparseArray, weights map[int]map[int]float64) ([]byte, error) {
+	var err error
+	obj := chainJSON{Version: formatVersion, Order: order}
+	if obj.SpoolMap, err = json.Marshal(spoolMap); err != nil {
+		return nil, err
 	}
+	if obj.FreqMat, err = json.Marshal(freqMat); err != nil {
+		return nil, err
+	}
+	if weights != nil {
+		if obj.Weights, err = json.Marshal(weights); err != nil {
+			return nil, err
+		}
+	}
+	obj.Checksum = checksum(obj)
 	return json.Marshal(obj)
 }
 
-// UnmarshalJSON ...
+// exportCounts is export for formats that only hold integer counts. Rounding float
+// weights to counts would change the model, so chains holding them are refused.
+func (chain Chain) exportCounts(format string) (map[string]int, map[int]sparseArray, error) {
+	if _, ok := chain.store.(WeightedStore); ok {
+		return nil, nil, fmt.Errorf("Chain store holds float weights, which %s can't hold", format)
+	}
+	return chain.export()
+}
+
+// exportModel is export along with the float weights of a chain holding them. Their
+// counts are rounded from the same snapshot of the weights, as reading both
+// separately would let an Add land in between and save rows that don't match.
+func (chain Chain) exportModel() (map[string]int, map[int]sparseArray, map[int]map[int]float64, error) {
+	ws, ok := chain.store.(WeightedStore)
+	if !ok {
+		spoolMap, freqMat, err := chain.export()
+		return spoolMap, freqMat, nil, err
+	}
+	weights, err := exportWeights(ws)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	freqMat := make(map[int]sparseArray, len(weights))
+	referenced := make(map[int]bool)
+	for current, row := range weights {
+		counts := make(sparseArray, len(row))
+		for next, weight := range row {
+			counts[next] = roundWeight(weight)
+			referenced[next] = true
+		}
+		freqMat[current] = counts
+		referenced[current] = true
+	}
+	spoolMap, err := chain.exportStates(referenced)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return spoolMap, freqMat, weights, nil
+}
+
+// export collects the states of the chain and its transition rows, see exportStates
+func (chain Chain) export() (map[string]int, map[int]sparseArray, error) {
+	freqMat := make(map[int]sparseArray)
+	referenced := make(map[int]bool)
+	err := chain.iterateRows(func(current int, row rowReader) bool {
+		// Rows may change once iterateRows returns, keep a copy
+		copied := make(sparseArray, row.size())
+		row.each(func(next, count int) {
+			copied[next] = count
+			referenced[next] = true
+		})
+		freqMat[current] = copied
+		referenced[current] = true
+		return true
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	spoolMap, err := chain.exportStates(referenced)
+	if err != nil {
+		return nil, nil, err
+	}
+	return spoolMap, freqMat, nil
+}
+
+// exportStates maps state indices to their states, failing if the store doesn't know
+// one of the referenced indices. Stores that can list their states export all of
+// them, even those only interned by a failed Add, so that saved indices stay as
+// dense as the store's. Others only export the referenced states.
+func (chain Chain) exportStates(referenced map[int]bool) (map[string]int, error) {
+	spoolMap := make(map[string]int, len(referenced))
+	if s, ok := chain.store.(interface {
+		rangeStates(fn func(state string, index int))
+	}); ok {
+		// Listing states by name avoids building the store's reverse index
+		found := 0
+		s.rangeStates(func(state string, index int) {
+			spoolMap[state] = index
+			if referenced[index] {
+				found++
+			}
+		})
+		if found < len(referenced) {
+			found := make(map[int]bool, len(spoolMap))
+			for _, index := range spoolMap {
+				found[index] = true
+			}
+			for index := range referenced {
+				if !found[index] {
+					return nil, fmt.Errorf("Chain has no state with index %d", index)
+				}
+			}
+		}
+		return spoolMap, nil
+	}
+	for index := range referenced {
+		state, ok, err := chain.store.LookupIndex(index)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			return nil, fmt.Errorf("Chain has no state with index %d", index)
+		}
+		spoolMap[state] = index
+	}
+	return spoolMap, nil
+}
+
+// UnmarshalJSON replaces the chain's store with an in-memory one holding the decoded model,
+// holding float weights if the model was saved with them. The model is rejected if its
+// checksum doesn't match or it is structurally inconsistent.
 func (chain *Chain) UnmarshalJSON(b []byte) error {
-	var obj chainJSON
-	err := json.Unmarshal(b, &obj)
+	obj, spoolMap, freqMat, err := decodeModel(b)
+	if err != nil {
+		return err
+	}
+	var weights map[int]map[int]float64
+	if len(obj.Weights) > 0 {
+		if err := json.Unmarshal(obj.Weights, &weights); err != nil {
+			return err
+		}
+		if err := validateWeights(freqMat, weights); err != nil {
+			return err
+		}
+	}
+	return chain.load(obj.Order, spoolMap, freqMat, weights)
+}
+
+// load validates a decoded model and replaces the chain's store with it, holding
+// float weights if weights isn't nil
+func (chain *Chain) load(order int, spoolMap map[string]int, freqMat map[int]sparseArray, weights map[int]map[int]float64) error {
+	if err := validateOrder(order); err != nil {
+		return err
+	}
+	intMap, err := validateModel(spoolMap, freqMat)
 	if err != nil {
 		return err
 	}
-	chain.Order = obj.Order
-	intMap := make(map[int]string)
-	for k, v := range obj.SpoolMap {
-		intMap[v] = k
+	// Tokens may contain the separator, so a key can split into more tokens than the
+	// order but never fewer
+	for current := range freqMat {
+		if state := intMap[current]; strings.Count(state, "_")+1 < order {
+			return fmt.Errorf("%w: state %q has fewer than %d tokens", ErrOrderMismatch, state, order)
+		}
+	}
+	chain.Order = order
+	if weights != nil {
+		chain.store = loadWeightedMemoryStore(spoolMap, intMap, weights)
+		return nil
+	}
+	chain.store = loadMemoryStore(spoolMap, intMap, freqMat)
+	return nil
+}
+
+// validateOrder checks that a chain of the given order can be built
+func validateOrder(order int) error {
+	if order < 0 {
+		return fmt.Errorf("%w, got %d", ErrInvalidOrder, order)
+	}
+	return nil
+}
+
+// NewChain creates an instance of Chain. It panics if order is negative. A chain of
+// order 0 is a unigram model, drawing every token from the overall token frequencies
+// regardless of what came before, with the empty NGram as its only state.
+func NewChain(order int, opts ...ChainOption) *Chain {
+	o := newChainOptions(opts)
+	if o.floatWeights {
+		store := newWeightedMemoryStore(o.expectedVocab + o.expectedStates)
+		if o.withoutLocking {
+			store.disableLocking()
+		}
+		return newChain(order, store, o)
+	}
+	store := newMemoryStoreSized(o.expectedVocab+o.expectedStates, o.expectedStates)
+	if o.withoutLocking {
+		store.disableLocking()
+	}
+	return newChain(order, store, o)
+}
+
+// NewChainWithStore creates an instance of Chain backed by the given store.
+// Capacity hints don't apply to stores created by the caller and are ignored.
+// It panics if order is negative.
+func NewChainWithStore(order int, store Store, opts ...ChainOption) *Chain {
+	return newChain(order, store, newChainOptions(opts))
+}
+
+func newChain(order int, store Store, o chainOptions) *Chain {
+	if err := validateOrder(order); err != nil {
+		panic("gomarkov: " + err.Error())
+	}
+	return &Chain{Order: order, store: store, prng: o.prng, metrics: o.metrics, lengths: o.lengths, novelty: o.novelty, minLength: o.minLength, stop: o.stop, checkpoints: o.checkpoints}
+}
+
+// Close releases any resources held by the chain's store, such as a memory-mapped model
+func (chain *Chain) Close() error {
+	if closer, ok := chain.store.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+// Snapshot returns a consistent copy of the chain that can be read, for example
+// serialized, while training continues on the original. Rows are shared and only
+// copied once either chain modifies them, so taking a snapshot is cheap.
+func (chain *Chain) Snapshot() (*Chain, error) {
+	s, ok := chain.store.(interface{ snapshot() Store })
+	if !ok {
+		return nil, errors.New("Chain backend does not support snapshots")
+	}
+	return chain.withStore(s.snapshot()), nil
+}
+
+// withStore returns a chain configured like this one over another store
+func (chain *Chain) withStore(store Store) *Chain {
+	derived := *chain
+	derived.store = store
+	// Only the original chain is checkpointed
+	derived.checkpoints = nil
+	return &derived
+}
+
+// skips reports whether a sequence is too short to train on. Empty sequences would
+// only add a transition from the start straight to the end, which no one wants
+// generated, so they are always skipped.
+func (chain *Chain) skips(input []string) bool {
+	return len(input) < max(chain.minLength, 1)
+}
+
+// transitionCount returns the number of transitions training on input adds
+func (chain *Chain) transitionCount(input []string) int {
+	return len(input) + max(chain.Order, 1)
+}
+
+// trainable returns the inputs that aren't skipped, inputs itself if none are
+func (chain *Chain) trainable(inputs [][]string) [][]string {
+	for i, input := range inputs {
+		if !chain.skips(input) {
+			continue
+		}
+		kept := append(make([][]string, 0, len(inputs)-1), inputs[:i]...)
+		for _, input := range inputs[i+1:] {
+			if !chain.skips(input) {
+				kept = append(kept, input)
+			}
+		}
+		return kept
+	}
+	return inputs
+}
+
+// Add adds the transition counts to the chain for a given sequence of words.
+// Sequences shorter than the order are added with the boundary tokens filling their
+// states, so they can be generated too. Empty sequences, and those shorter than
+// WithMinLength, are skipped.
+func (chain *Chain) Add(input []string) error {
+	return chain.AddWeighted(input, 1)
+}
+
+// AddWeighted adds the transition counts of a sequence seen weight times, the same
+// as calling Add weight times but in a single pass. A weight of 0 adds nothing.
+// TrainMixture adds sequences with weights above 1, outweighing those of Add.
+func (chain *Chain) AddWeighted(input []string, weight int) error {
+	if weight < 0 {
+		return fmt.Errorf("Sequence weight must not be negative, got %d", weight)
+	}
+	return chain.addWeighted(input, weight, weight)
+}
+
+// addWeighted adds the transition counts of a sequence weight times, recording it
+// as seen sequences for instrumentation, length matching and checkpoints
+func (chain *Chain) addWeighted(input []string, weight, seen int) (err error) {
+	if weight == 0 || chain.skips(input) {
+		return nil
+	}
+	if chain.metrics != nil {
+		defer chain.observeTrain(time.Now(), seen, seen*chain.transitionCount(input), &err)
+	}
+	if chain.observing() {
+		defer chain.observeSequences(&err, seen, input)
+	}
+	buf := chain.padded(input)
+	defer releaseTokens(buf)
+	tokens := *buf
+	for i := 0; i+chain.Order < len(tokens); i++ {
+		if err := chain.addTransition(tokens[i:i+chain.Order], tokens[i+chain.Order], weight); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// AddBatch adds the transition counts of several sequences of words. Counts are
+// aggregated first and applied together, which is much faster than calling Add for
+// each sequence when bulk training. If a state can't be added, an error is returned
+// before any count is applied. Sequences are skipped like with Add.
+func (chain *Chain) AddBatch(inputs [][]string) (err error) {
+	inputs = chain.trainable(inputs)
+	if chain.metrics != nil {
+		transitions := 0
+		for _, input := range inputs {
+			transitions += chain.transitionCount(input)
+		}
+		defer chain.observeTrain(time.Now(), len(inputs), transitions, &err)
+	}
+	if chain.observing() {
+		defer chain.observeSequences(&err, 1, inputs...)
+	}
+	rows := make(map[int]sparseArray)
+	for _, input := range inputs {
+		buf := chain.padded(input)
+		err := chain.countTransitions(*buf, 0, len(*buf)-chain.Order, rows)
+		releaseTokens(buf)
+		if err != nil {
+			return err
+		}
+	}
+	return chain.applyCounts(rows)
+}
+
+// AddParallel adds the transition counts of a single long sequence of words, such
+// as a whole book for a character level model, splitting it into chunks counted by
+// up to workers goroutines. Counts are the same as with Add, though states may be
+// assigned indices in a different order. The sequence is skipped like with Add.
+func (chain *Chain) AddParallel(input []string, workers int) (err error) {
+	if chain.skips(input) {
+		return nil
+	}
+	if chain.metrics != nil {
+		defer chain.observeTrain(time.Now(), 1, chain.transitionCount(input), &err)
+	}
+	if chain.observing() {
+		defer chain.observeSequences(&err, 1, input)
+	}
+	buf := chain.padded(input)
+	defer releaseTokens(buf)
+	tokens := *buf
+	transitions := len(tokens) - chain.Order
+	if workers > transitions {
+		workers = transitions
+	}
+	if workers <= 1 {
+		rows := make(map[int]sparseArray)
+		if err := chain.countTransitions(tokens, 0, transitions, rows); err != nil {
+			return err
+		}
+		return chain.applyCounts(rows)
+	}
+
+	// Chunks overlap by the order so transitions spanning a boundary are counted once
+	chunks := make([]map[int]sparseArray, workers)
+	errs := make([]error, workers)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			chunks[w] = make(map[int]sparseArray)
+			from, to := transitions*w/workers, transitions*(w+1)/workers
+			errs[w] = chain.countTransitions(tokens, from, to, chunks[w])
+		}(w)
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
 	}
-	chain.statePool = &spool{
-		stringMap: obj.SpoolMap,
-		intMap:    intMap,
+	rows := chunks[0]
+	for _, chunk := range chunks[1:] {
+		for current, row := range chunk {
+			if rows[current] == nil {
+				rows[current] = row
+				continue
+			}
+			for next, count := range row {
+				rows[current][next] += count
+			}
+		}
+	}
+	return chain.applyCounts(rows)
+}
+
+// countTransitions adds the transitions starting at positions from to to of a
+// padded sequence to rows, adding states to the store but leaving its counts untouched
+func (chain *Chain) countTransitions(tokens []string, from, to int, rows map[int]sparseArray) error {
+	for i := from; i < to; i++ {
+		currentIndex, err := chain.addState(tokens[i : i+chain.Order])
+		if err != nil {
+			return err
+		}
+		nextIndex, err := chain.store.AddState(tokens[i+chain.Order])
+		if err != nil {
+			return err
+		}
+		if rows[currentIndex] == nil {
+			rows[currentIndex] = make(sparseArray)
+		}
+		rows[currentIndex][nextIndex]++
 	}
-	chain.frequencyMat = obj.FreqMat
-	chain.lock = new(sync.RWMutex)
 	return nil
 }
 
-// NewChain creates an instance of Chain
-func NewChain(order int) *Chain {
-	chain := Chain{Order: order}
-	chain.statePool = &spool{
-		stringMap: make(map[string]int),
-		intMap:    make(map[int]string),
+// applyCounts adds aggregated counts to the store, in one batch if it supports it
+func (chain *Chain) applyCounts(rows map[int]sparseArray) error {
+	if batch, ok := chain.store.(interface {
+		incrementRows(rows map[int]sparseArray) error
+	}); ok {
+		return batch.incrementRows(rows)
+	}
+	for current, row := range rows {
+		for next, count := range row {
+			if err := chain.store.IncrementTransition(current, next, count); err != nil {
+				return err
+			}
+		}
 	}
-	chain.frequencyMat = make(map[int]sparseArray, 0)
-	chain.lock = new(sync.RWMutex)
-	return &chain
+	return nil
+}
+
+// tokenPool recycles the padded token buffers built while adding sequences
+var tokenPool = sync.Pool{
+	New: func() interface{} {
+		tokens := make([]string, 0, 64)
+		return &tokens
+	},
 }
 
-// Add adds the transition counts to the chain for a given sequence of words
-func (chain *Chain) Add(input []string) {
-	startTokens := array(StartToken, chain.Order)
-	endTokens := array(EndToken, chain.Order)
-	tokens := make([]string, 0)
-	tokens = append(tokens, startTokens...)
+// padded returns the sequence of words wrapped in start and end tokens, in a
+// pooled buffer that must be returned with releaseTokens
+func (chain *Chain) padded(input []string) *[]string {
+	buf := tokenPool.Get().(*[]string)
+	tokens := (*buf)[:0]
+	if need := chain.Order + chain.transitionCount(input); cap(tokens) < need {
+		tokens = make([]string, 0, need)
+	}
+	tokens = append(tokens, boundary(StartToken, chain.Order)...)
 	tokens = append(tokens, input...)
-	tokens = append(tokens, endTokens...)
-	pairs := MakePairs(tokens, chain.Order)
-	for i := 0; i < len(pairs); i++ {
-		pair := pairs[i]
-		currentIndex := chain.statePool.add(pair.CurrentState.key())
-		nextIndex := chain.statePool.add(pair.NextState)
-		chain.lock.Lock()
-		if chain.frequencyMat[currentIndex] == nil {
-			chain.frequencyMat[currentIndex] = make(sparseArray, 0)
+	// Order 0 chains still need an end token to learn where sequences end
+	tokens = append(tokens, boundary(EndToken, max(chain.Order, 1))...)
+	*buf = tokens
+	return buf
+}
+
+func releaseTokens(buf *[]string) {
+	// Drop references to the words so pooled buffers don't keep them alive
+	clear(*buf)
+	*buf = (*buf)[:0]
+	tokenPool.Put(buf)
+}
+
+// addTransition adds count occurrences of the transition between two states
+func (chain *Chain) addTransition(current NGram, next string, count int) error {
+	currentIndex, err := chain.addState(current)
+	if err != nil {
+		return err
+	}
+	nextIndex, err := chain.store.AddState(next)
+	if err != nil {
+		return err
+	}
+	return chain.store.IncrementTransition(currentIndex, nextIndex, count)
+}
+
+// addEndTransition adds a transition and, when it leads to the end token, the trailing
+// end token states Add would have produced but other model formats don't record
+func (chain *Chain) addEndTransition(current NGram, next string, count int) error {
+	if err := chain.addTransition(current, next, count); err != nil {
+		return err
+	}
+	if next != EndToken {
+		return nil
+	}
+	for k := 1; k < chain.Order; k++ {
+		tail := make(NGram, 0, len(current))
+		tail = append(append(tail, current[k:]...), boundary(EndToken, k)...)
+		if err := chain.addTransition(tail, EndToken, count); err != nil {
+			return err
 		}
-		chain.frequencyMat[currentIndex][nextIndex]++
-		chain.lock.Unlock()
 	}
+	return nil
 }
 
-// TransitionProbability returns the transition probability between two states
+// TransitionProbability returns the transition probability between two states.
+// It is 0 both for states the chain has never seen and for transitions it has never
+// seen out of a known state, use LookupTransition to tell the two apart.
 func (chain *Chain) TransitionProbability(next string, current NGram) (float64, error) {
+	prob, _, err := chain.LookupTransition(next, current)
+	return prob, err
+}
+
+// LookupTransition returns the transition probability between two states and whether
+// the chain has seen current followed by any token. A known state never followed by
+// next has probability 0 with known true, so callers can smooth or back off only
+// when the context itself is unseen.
+func (chain *Chain) LookupTransition(next string, current NGram) (prob float64, known bool, err error) {
 	if len(current) != chain.Order {
-		return 0, errors.New("N-gram length does not match chain order")
+		return 0, false, ErrOrderMismatch
+	}
+	currentIndex, currentExists, err := chain.lookupState(current)
+	if err != nil || !currentExists {
+		return 0, false, err
+	}
+	nextIndex, nextExists, err := chain.store.LookupState(next)
+	if err != nil {
+		return 0, false, err
+	}
+	var freq, sum float64
+	if weights, ok := chain.store.(interface {
+		transitionWeight(current, next int) (weight, sum float64)
+	}); ok {
+		freq, sum = weights.transitionWeight(currentIndex, nextIndex)
+	} else if counts, ok := chain.store.(interface {
+		transitionCount(current, next int) (count, sum int)
+	}); ok {
+		count, total := counts.transitionCount(currentIndex, nextIndex)
+		freq, sum = float64(count), float64(total)
+	} else {
+		arr, err := chain.getRow(currentIndex)
+		if err != nil {
+			return 0, false, err
+		}
+		freq, sum = float64(arr[nextIndex]), float64(arr.sum())
 	}
-	currentIndex, currentExists := chain.statePool.get(current.key())
-	nextIndex, nextExists := chain.statePool.get(next)
-	if !currentExists || !nextExists {
-		return 0, nil
+	if sum == 0 {
+		// The state only appears as the end of a sequence
+		return 0, false, nil
 	}
-	arr := chain.frequencyMat[currentIndex]
-	sum := float64(arr.sum())
-	freq := float64(arr[nextIndex])
-	return freq / sum, nil
+	if !nextExists {
+		return 0, true, nil
+	}
+	return freq / sum, true, nil
 }
 
-// Generate generates new text based on an initial seed of words
+// Generate generates new text based on an initial seed of words, using the PRNG
+// set with WithRand or a package-wide one
 func (chain *Chain) Generate(current NGram) (string, error) {
-	return chain.GenerateDeterministic(current, defaultPrng)
+	prng := chain.prng
+	if prng == nil {
+		prng = defaultPrng
+	}
+	return chain.GenerateDeterministic(current, prng)
 }
 
 // GenerateDeterministic generates new text deterministically, based on an initial seed of words and using a specified PRNG.
 // Use it for reproducibly pseudo-random results (i.e. pass the same PRNG and same state every time).
-func (chain *Chain) GenerateDeterministic(current NGram, prng PRNG) (string, error) {
+func (chain *Chain) GenerateDeterministic(current NGram, prng PRNG) (next string, err error) {
+	if chain.metrics != nil {
+		defer func(start time.Time) {
+			tokens := 1
+			if err != nil {
+				tokens = 0
+			}
+			chain.observeGenerate(start, tokens, err)
+		}(time.Now())
+	}
+	return chain.next(current, prng)
+}
+
+// next is GenerateDeterministic without instrumentation
+func (chain *Chain) next(current NGram, prng PRNG) (string, error) {
 	if len(current) != chain.Order {
-		return "", errors.New("N-gram length does not match chain order")
+		return "", ErrOrderMismatch
 	}
-	if current[len(current)-1] == EndToken {
+	if current.ended() {
 		// Dont generate anything after the end token
 		return "", nil
 	}
-	currentIndex, currentExists := chain.statePool.get(current.key())
+	currentIndex, currentExists, err := chain.lookupState(current)
+	if err != nil {
+		return "", err
+	}
 	if !currentExists {
-		return "", fmt.Errorf("Unknown ngram %v", current)
+		if chain.empty() {
+			return "", ErrEmptyChain
+		}
+		return "", &UnknownNGramError{NGram: append(NGram{}, current...)}
+	}
+	nextIndex, ok, err := chain.draw(currentIndex, prng)
+	if err != nil {
+		return "", err
 	}
-	arr := chain.frequencyMat[currentIndex]
-	sum := arr.sum()
-	randN := prng.Intn(sum)
-	pairs := arr.orderedPairs()
-	for _, p := range pairs {
-		key, freq := p[0], p[1]
-		randN -= freq
-		if randN <= 0 {
-			return chain.statePool.intMap[key], nil
+	if !ok {
+		if chain.empty() {
+			return "", ErrEmptyChain
 		}
+		return "", fmt.Errorf("%w: %v", ErrDeadEnd, current)
+	}
+	next, _, err := chain.store.LookupIndex(nextIndex)
+	return next, err
+}
+
+// iterateRows is IterateRows for the package's own use, reading the rows of the
+// memory store in place rather than converting dense rows to maps. fn must not keep
+// or modify a row once it returns.
+func (chain *Chain) iterateRows(fn func(current int, row rowReader) bool) error {
+	if s, ok := chain.store.(interface {
+		rangeRows(fn func(current int, row rowReader) bool)
+	}); ok {
+		s.rangeRows(fn)
+		return nil
+	}
+	return chain.store.IterateRows(func(current int, row map[int]int) bool {
+		return fn(current, rowReader{counts: row})
+	})
+}
+
+func (chain *Chain) getRow(current int) (sparseArray, error) {
+	row, err := chain.store.GetRow(current)
+	return sparseArray(row), err
+}
+
+// addState returns the index of an n-gram's state, adding it to the store if needed
+func (chain *Chain) addState(ngram NGram) (int, error) {
+	if s, ok := chain.store.(interface {
+		addNGram(ngram NGram) (int, error)
+	}); ok {
+		return s.addNGram(ngram)
+	}
+	return chain.store.AddState(ngram.key())
+}
+
+// lookupState returns the index of an n-gram's state and whether it exists
+func (chain *Chain) lookupState(ngram NGram) (int, bool, error) {
+	if s, ok := chain.store.(interface {
+		lookupNGram(ngram NGram) (int, bool, error)
+	}); ok {
+		return s.lookupNGram(ngram)
+	}
+	return chain.store.LookupState(ngram.key())
+}
+
+// empty reports whether the chain has no transitions at all
+func (chain *Chain) empty() bool {
+	empty := true
+	chain.iterateRows(func(current int, row rowReader) bool {
+		empty = false
+		return false
+	})
+	return empty
+}
+
+// draw samples the index of the state following current, with ok false if current
+// has no transitions
+func (chain *Chain) draw(current int, prng PRNG) (next int, ok bool, err error) {
+	if s, ok := chain.store.(interface {
+		draw(current int, prng PRNG) (int, bool)
+	}); ok {
+		next, ok := s.draw(current, prng)
+		return next, ok, nil
+	}
+	dist, err := chain.distribution(current)
+	if err != nil {
+		return 0, false, err
+	}
+	next, ok = dist.draw(prng)
+	return next, ok, nil
+}
+
+// distribution returns the sampling distribution of a row, cached by stores that can
+func (chain *Chain) distribution(current int) (distribution, error) {
+	if cache, ok := chain.store.(interface {
+		distribution(current int) (distribution, error)
+	}); ok {
+		return cache.distribution(current)
+	}
+	arr, err := chain.getRow(current)
+	if err != nil {
+		return nil, err
 	}
-	return "", nil
+	return arr.cumulative(), nil
 }
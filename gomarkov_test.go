@@ -1,8 +1,12 @@
 package gomarkov
 
 import (
+	"encoding/json"
+	"errors"
+	"math"
 	"math/rand"
 	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -14,10 +18,10 @@ func TestChain_MarshalJSON(t *testing.T) {
 		want    string
 		wantErr bool
 	}{
-		{"Empty chain", 2, [][]string{}, `{"int":2,"spool_map":{},"freq_mat":{}}`, false},
-		{"Empty chain, order 1", 1, [][]string{}, `{"int":1,"spool_map":{},"freq_mat":{}}`, false},
-		{"Trained once", 1, [][]string{{"Test"}}, `{"int":1,"spool_map":{"$":2,"Test":1,"^":0},"freq_mat":{"0":{"1":1},"1":{"2":1}}}`, false},
-		{"Trained on more data", 1, [][]string{{"test", "data"}, {"test", "data"}, {"test", "node"}}, `{"int":1,"spool_map":{"$":3,"^":0,"data":2,"node":4,"test":1},"freq_mat":{"0":{"1":3},"1":{"2":2,"4":1},"2":{"3":2},"4":{"3":1}}}`, false},
+		{"Empty chain", 2, [][]string{}, `{"version":4,"order":2,"spool_map":{},"freq_mat":{},"checksum":"crc32c:e1c39e14"}`, false},
+		{"Empty chain, order 1", 1, [][]string{}, `{"version":4,"order":1,"spool_map":{},"freq_mat":{},"checksum":"crc32c:59c6eb4c"}`, false},
+		{"Trained once", 1, [][]string{{"Test"}}, `{"version":4,"order":1,"spool_map":{"$":2,"Test":1,"^":0},"freq_mat":{"0":{"1":1},"1":{"2":1}},"checksum":"crc32c:e167b5db"}`, false},
+		{"Trained on more data", 1, [][]string{{"test", "data"}, {"test", "data"}, {"test", "node"}}, `{"version":4,"order":1,"spool_map":{"$":3,"^":0,"data":2,"node":4,"test":1},"freq_mat":{"0":{"1":3},"1":{"2":2,"4":1},"2":{"3":2},"4":{"3":1}},"checksum":"crc32c:701c0224"}`, false},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -38,6 +42,118 @@ func TestChain_MarshalJSON(t *testing.T) {
 	}
 }
 
+func TestChain_MarshalJSON_Deterministic(t *testing.T) {
+	chain := NewChain(2)
+	chain.Add([]string{"I", "want", "a", "cheese", "burger"})
+	chain.Add([]string{"I", "want", "a", "chilled", "sprite"})
+	first, _ := chain.MarshalJSON()
+	for i := 0; i < 10; i++ {
+		if got, _ := chain.MarshalJSON(); string(got) != string(first) {
+			t.Fatalf("Chain.MarshalJSON() differs between calls")
+		}
+	}
+	var loaded Chain
+	if err := loaded.UnmarshalJSON(first); err != nil {
+		t.Fatalf("Chain.UnmarshalJSON() error = %v", err)
+	}
+	if got, _ := loaded.MarshalJSON(); string(got) != string(first) {
+		t.Errorf("Chain.MarshalJSON() differs after a round trip")
+	}
+}
+
+func TestChain_MarshalCanonicalJSON(t *testing.T) {
+	text := strings.Split("the quick brown fox jumps over the lazy dog", "")
+	sequential, parallel := NewChain(2), NewChain(2)
+	sequential.Add(text)
+	parallel.AddParallel(text, 4)
+	// Index a state first so the plain encodings can't match
+	reversed := NewChain(2)
+	reversed.store.AddState("o_g")
+	reversed.Add(text)
+	plain, _ := sequential.MarshalJSON()
+	if got, _ := reversed.MarshalJSON(); string(got) == string(plain) {
+		t.Fatalf("Chain.MarshalJSON() of differently indexed chains should differ")
+	}
+	want, err := sequential.MarshalCanonicalJSON()
+	if err != nil {
+		t.Fatalf("Chain.MarshalCanonicalJSON() error = %v", err)
+	}
+	for _, chain := range []*Chain{parallel, reversed} {
+		if got, _ := chain.MarshalCanonicalJSON(); string(got) != string(want) {
+			t.Errorf("Chain.MarshalCanonicalJSON() = %s, want %s", got, want)
+		}
+	}
+	var loaded Chain
+	if err := loaded.UnmarshalJSON(want); err != nil {
+		t.Fatalf("Chain.UnmarshalJSON() error = %v", err)
+	}
+	if !reflect.DeepEqual(transitionCounts(t, &loaded), transitionCounts(t, sequential)) {
+		t.Errorf("Chain.MarshalCanonicalJSON() changed the transitions")
+	}
+}
+
+func TestChain_UnmarshalJSON_SparseIndices(t *testing.T) {
+	chain := NewChain(1)
+	if err := chain.UnmarshalJSON([]byte(`{"int":1,"spool_map":{"^":0,"a":5,"$":2},"freq_mat":{"0":{"5":1},"5":{"2":1}}}`)); err != nil {
+		t.Fatalf("Chain.UnmarshalJSON() error = %v", err)
+	}
+	chain.Add([]string{"b"})
+	chain.Add([]string{"d"})
+	chain.Add([]string{"d"})
+	for next, want := range map[string]float64{"a": 0.25, "b": 0.25, "d": 0.5} {
+		if got, _ := chain.TransitionProbability(next, NGram{StartToken}); got != want {
+			t.Errorf("Chain.TransitionProbability(%q) = %v, want %v", next, got, want)
+		}
+	}
+}
+
+func TestChain_MarshalJSON_UnreferencedStates(t *testing.T) {
+	for name, store := range map[string]Store{"Memory": NewMemoryStore(), "Compact": NewCompactMemoryStore()} {
+		t.Run(name, func(t *testing.T) {
+			chain := NewChainWithStore(1, store)
+			// A failed Add can intern states without adding transitions from them
+			store.AddState("orphan")
+			chain.Add([]string{"a"})
+			data, err := chain.MarshalJSON()
+			if err != nil {
+				t.Fatalf("Chain.MarshalJSON() error = %v", err)
+			}
+			var obj chainJSON
+			json.Unmarshal(data, &obj)
+			var spoolMap map[string]int
+			json.Unmarshal(obj.SpoolMap, &spoolMap)
+			if index, ok := spoolMap["orphan"]; !ok || index != 0 {
+				t.Errorf("Chain.MarshalJSON() spool map = %v, want orphan at index 0", spoolMap)
+			}
+		})
+	}
+}
+
+// forgetfulStore can't resolve the index of one state
+type forgetfulStore struct {
+	Store
+	forget int
+}
+
+func (f forgetfulStore) LookupIndex(index int) (string, bool, error) {
+	if index == f.forget {
+		return "", false, nil
+	}
+	return f.Store.LookupIndex(index)
+}
+
+func TestChain_MarshalJSON_UnknownState(t *testing.T) {
+	store := forgetfulStore{Store: NewMemoryStore(), forget: 1}
+	chain := NewChainWithStore(1, store)
+	chain.Add([]string{"a", "b"})
+	if _, err := chain.MarshalJSON(); err == nil {
+		t.Errorf("Chain.MarshalJSON() of a chain missing a state succeeded")
+	}
+	if _, err := chain.MarshalCanonicalJSON(); err == nil {
+		t.Errorf("Chain.MarshalCanonicalJSON() of a chain missing a state succeeded")
+	}
+}
+
 func TestChain_UnmarshalJSON(t *testing.T) {
 	tests := []struct {
 		name    string
@@ -47,6 +163,9 @@ func TestChain_UnmarshalJSON(t *testing.T) {
 		{"Empty chain", []byte(`{"int":2,"spool_map":{},"freq_mat":{}}`), false},
 		{"More complex chain", []byte(`{"int":1,"spool_map":{"^":0,"$":3,"data":2,"node":4,"test":1},"freq_mat":{"0":{"1":3},"1":{"2":2,"4":1},"2":{"3":2},"4":{"3":1}}}`), false},
 		{"Invalid json", []byte(`{{"int":2,"spool_map":{},"freq_mat":{}}`), true},
+		{"Negative order", []byte(`{"int":-1,"spool_map":{},"freq_mat":{}}`), true},
+		{"States shorter than order", []byte(`{"int":2,"spool_map":{"^":0,"test":1},"freq_mat":{"0":{"1":1}}}`), true},
+		{"Overflowing row", []byte(`{"int":1,"spool_map":{"^":0,"a":1,"$":2},"freq_mat":{"0":{"1":9223372036854775807,"2":1}}}`), true},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -81,22 +200,267 @@ func TestNewChain(t *testing.T) {
 	}
 }
 
-func TestChain_Add(t *testing.T) {
-	type args struct {
-		input []string
+func TestNewChain_InvalidOrder(t *testing.T) {
+	for _, order := range []int{-1, -2} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("NewChain(%d) did not panic", order)
+				}
+			}()
+			NewChain(order)
+		}()
+	}
+	if _, err := ImportCSV(strings.NewReader(""), -1); !errors.Is(err, ErrInvalidOrder) {
+		t.Errorf("ImportCSV() order -1 error = %v, want ErrInvalidOrder", err)
+	}
+}
+
+func TestNewChain_Unigram(t *testing.T) {
+	chain := NewChain(0)
+	if _, err := chain.Generate(NGram{}); !errors.Is(err, ErrEmptyChain) {
+		t.Errorf("Chain.Generate() on an empty chain error = %v, want %v", err, ErrEmptyChain)
+	}
+	chain.Add([]string{"a", "b", "a"})
+	chain.AddBatch([][]string{{"c"}})
+	chain.AddParallel([]string{"a", "a"}, 2)
+	want := map[string]map[string]int{"": {"a": 4, "b": 1, "c": 1, "$": 3}}
+	if got := transitionCounts(t, chain); !reflect.DeepEqual(got, want) {
+		t.Fatalf("unigram counts = %v, want %v", got, want)
+	}
+
+	if got, err := chain.TransitionProbability("a", NGram{}); err != nil || got != 4.0/9 {
+		t.Errorf("Chain.TransitionProbability(a) = %v, %v, want %v", got, err, 4.0/9)
+	}
+	if got, known, err := chain.LookupTransition("z", NGram{}); err != nil || got != 0 || !known {
+		t.Errorf("Chain.LookupTransition(z) = %v, %v, %v, want 0, true", got, known, err)
+	}
+	wantLog := math.Log(4.0/9) + math.Log(1.0/9) + math.Log(3.0/9)
+	if got, err := chain.LogProbability([]string{"a", "b"}); err != nil || math.Abs(got-wantLog) > 1e-12 {
+		t.Errorf("Chain.LogProbability() = %v, %v, want %v", got, err, wantLog)
+	}
+
+	prng := rand.New(rand.NewSource(1))
+	seen := make(map[string]int)
+	for i := 0; i < 200; i++ {
+		tokens, err := chain.GenerateSequenceDeterministic(NGram{}, 50, prng)
+		if err != nil {
+			t.Fatalf("Chain.GenerateSequenceDeterministic() error = %v", err)
+		}
+		for _, token := range tokens {
+			seen[token]++
+		}
+	}
+	if seen["a"] <= seen["b"] || seen["a"] <= seen["c"] || seen[EndToken] != 0 {
+		t.Errorf("generated token counts = %v, want mostly a and no end tokens", seen)
+	}
+
+	b, err := chain.MarshalJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded Chain
+	if err := decoded.UnmarshalJSON(b); err != nil || decoded.Order != 0 {
+		t.Fatalf("Chain.UnmarshalJSON() order = %d, error = %v", decoded.Order, err)
+	}
+	if got := transitionCounts(t, &decoded); !reflect.DeepEqual(got, want) {
+		t.Errorf("decoded unigram counts = %v, want %v", got, want)
+	}
+	reverse, err := chain.Reverse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := transitionCounts(t, reverse); !reflect.DeepEqual(got, want) {
+		t.Errorf("Chain.Reverse() counts = %v, want %v", got, want)
+	}
+
+	typed := NewChainOf[int](0)
+	if _, _, err := typed.Generate(nil); !errors.Is(err, ErrEmptyChain) {
+		t.Errorf("ChainOf.Generate() on an empty chain error = %v, want %v", err, ErrEmptyChain)
+	}
+	typed.Add([]int{1, 2})
+	if got, _ := typed.EndProbability(nil); got != 1.0/3 {
+		t.Errorf("ChainOf.EndProbability() = %v, want %v", got, 1.0/3)
+	}
+}
+
+func TestNewChain_Options(t *testing.T) {
+	chain := NewChain(2, WithExpectedVocab(1000), WithExpectedStates(5000))
+	chain.Add([]string{"I", "want", "a", "cheese", "burger"})
+	want := NewChain(2)
+	want.Add([]string{"I", "want", "a", "cheese", "burger"})
+	if !reflect.DeepEqual(transitionCounts(t, chain), transitionCounts(t, want)) {
+		t.Errorf("NewChain() with capacity hints trains differently")
 	}
+}
+
+func TestNewChain_WithoutLocking(t *testing.T) {
+	inputs := [][]string{
+		{"I", "want", "a", "cheese", "burger"},
+		{"I", "want", "a", "chilled", "sprite"},
+	}
+	chain, want := NewChain(2, WithoutLocking()), NewChain(2)
+	for _, input := range inputs {
+		chain.Add(input)
+		want.Add(input)
+	}
+	if !reflect.DeepEqual(transitionCounts(t, chain), transitionCounts(t, want)) {
+		t.Errorf("NewChain() without locking trains differently")
+	}
+	if got, err := chain.Generate(NGram{"I", "want"}); err != nil || got != "a" {
+		t.Errorf("Chain.Generate() = %v, %v, want a", got, err)
+	}
+	// Snapshots are locked again, so they can be read while the chain trains
+	snap, err := chain.Snapshot()
+	if err != nil {
+		t.Fatalf("Chain.Snapshot() error = %v", err)
+	}
+	if snap.store.(*memoryStore).stripes[0].lock.disabled {
+		t.Errorf("Chain.Snapshot() of a chain without locking is unlocked")
+	}
+}
+
+func TestNewChain_WithRand(t *testing.T) {
+	generate := func(chain *Chain) []string {
+		chain.Add([]string{"I", "want", "a", "cheese", "burger"})
+		chain.Add([]string{"I", "want", "a", "chilled", "sprite"})
+		chain.Add([]string{"I", "want", "to", "go", "to", "the", "movies"})
+		var got []string
+		for i := 0; i < 20; i++ {
+			next, err := chain.Generate(NGram{"want"})
+			if err != nil {
+				t.Fatalf("Chain.Generate() error = %v", err)
+			}
+			got = append(got, next)
+		}
+		return got
+	}
+	a := generate(NewChain(1, WithRand(rand.New(rand.NewSource(7)))))
+	b := generate(NewChain(1, WithRand(rand.New(rand.NewSource(7)))))
+	if !reflect.DeepEqual(a, b) {
+		t.Errorf("Chain.Generate() with the same WithRand seed = %v and %v", a, b)
+	}
+	snap, _ := NewChain(1, WithRand(rand.New(rand.NewSource(7)))).Snapshot()
+	if snap.prng == nil {
+		t.Errorf("Chain.Snapshot() dropped the chain's PRNG")
+	}
+}
+
+func TestChain_Add(t *testing.T) {
 	tests := []struct {
 		name  string
 		chain *Chain
-		args  args
+		input []string
+		want  map[string]map[string]int
 	}{
-		// TODO: Add test cases.
+		{"nil", NewChain(1), nil, map[string]map[string]int{}},
+		{"empty order 3", NewChain(3), []string{}, map[string]map[string]int{}},
+		{"one token order 1", NewChain(1), []string{"hi"}, map[string]map[string]int{
+			"^":  {"hi": 1},
+			"hi": {"$": 1},
+		}},
+		{"one token order 2", NewChain(2), []string{"hi"}, map[string]map[string]int{
+			"^_^":  {"hi": 1},
+			"^_hi": {"$": 1},
+			"hi_$": {"$": 1},
+		}},
+		{"shorter than order 3", NewChain(3), []string{"hi", "there"}, map[string]map[string]int{
+			"^_^_^":      {"hi": 1},
+			"^_^_hi":     {"there": 1},
+			"^_hi_there": {"$": 1},
+			"hi_there_$": {"$": 1},
+			"there_$_$":  {"$": 1},
+		}},
+		{"below min length", NewChain(2, WithMinLength(2)), []string{"hi"}, map[string]map[string]int{}},
+		{"at min length", NewChain(1, WithMinLength(2)), []string{"hi", "you"}, map[string]map[string]int{
+			"^":   {"hi": 1},
+			"hi":  {"you": 1},
+			"you": {"$": 1},
+		}},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			tt.chain.Add(tt.args.input)
+			if err := tt.chain.Add(tt.input); err != nil {
+				t.Fatalf("Chain.Add() error = %v", err)
+			}
+			if got := transitionCounts(t, tt.chain); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Chain.Add() counts = %v, want %v", got, tt.want)
+			}
+			batch := NewChain(tt.chain.Order, WithMinLength(tt.chain.minLength))
+			if err := batch.AddBatch([][]string{tt.input, nil}); err != nil {
+				t.Fatalf("Chain.AddBatch() error = %v", err)
+			}
+			if got := transitionCounts(t, batch); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Chain.AddBatch() counts = %v, want %v", got, tt.want)
+			}
 		})
 	}
+
+	// A one token sequence can be generated back from the start of any order
+	for _, order := range []int{1, 2, 3} {
+		chain := NewChain(order)
+		chain.Add(nil)
+		if _, err := chain.GenerateSequence(make(NGram, order), 0); err == nil {
+			t.Errorf("order %d: Chain.GenerateSequence() after Add(nil) succeeded, want an error", order)
+		}
+		chain.Add([]string{"hi"})
+		seed := NGram(array(StartToken, order))
+		if got, err := chain.GenerateSequence(seed, 0); err != nil || !reflect.DeepEqual(got, []string{"hi"}) {
+			t.Errorf("order %d: Chain.GenerateSequence() = %v, %v, want [hi]", order, got, err)
+		}
+	}
+}
+
+func TestChain_Add_Allocs(t *testing.T) {
+	chain := NewChain(2)
+	input := []string{"I", "want", "a", "cheese", "burger"}
+	chain.Add(input)
+	allocs := testing.AllocsPerRun(100, func() {
+		chain.Add(input)
+	})
+	// Pools may be emptied by a GC during the run
+	if allocs >= 1 {
+		t.Errorf("Chain.Add() of known transitions allocates %v times, want 0", allocs)
+	}
+}
+
+func TestChain_AddBatch(t *testing.T) {
+	inputs := [][]string{
+		{"I", "want", "a", "cheese", "burger"},
+		{"I", "want", "a", "chilled", "sprite"},
+		{"I", "want", "to", "go", "to", "the", "movies"},
+	}
+	for _, order := range []int{1, 2, 3} {
+		want := NewChain(order)
+		for _, input := range inputs {
+			want.Add(input)
+		}
+		for _, chain := range []*Chain{NewChain(order), NewRedisChain(order, newFakeRedis(), "bot")} {
+			if err := chain.AddBatch(inputs); err != nil {
+				t.Fatalf("Chain.AddBatch() error = %v", err)
+			}
+			if !reflect.DeepEqual(transitionCounts(t, chain), transitionCounts(t, want)) {
+				t.Errorf("Chain.AddBatch() order %d counts differ from Chain.Add()", order)
+			}
+		}
+	}
+}
+
+func TestChain_AddParallel(t *testing.T) {
+	text := strings.Split("the quick brown fox jumps over the lazy dog and the quick cat", "")
+	for _, order := range []int{1, 2, 3} {
+		want := NewChain(order)
+		want.Add(text)
+		for _, workers := range []int{0, 1, 3, 8, 1000} {
+			chain := NewChain(order)
+			if err := chain.AddParallel(text, workers); err != nil {
+				t.Fatalf("Chain.AddParallel() error = %v", err)
+			}
+			if !reflect.DeepEqual(transitionCounts(t, chain), transitionCounts(t, want)) {
+				t.Errorf("Chain.AddParallel() order %d with %d workers counts differ from Chain.Add()", order, workers)
+			}
+		}
+	}
 }
 
 func TestChain_TransitionProbability(t *testing.T) {
@@ -105,17 +469,19 @@ func TestChain_TransitionProbability(t *testing.T) {
 		current NGram
 	}
 	tests := []struct {
-		name    string
-		chain   []byte
-		args    args
-		want    float64
-		wantErr bool
+		name      string
+		chain     []byte
+		args      args
+		want      float64
+		wantKnown bool
+		wantErr   bool
 	}{
 		{
 			"Simple transition positive",
 			[]byte(`{"int":1,"spool_map":{"^":0,"Test":1,"$":2},"freq_mat":{"0":{"1":1},"1":{"2":1}}}`),
 			args{next: "Test", current: NGram{"^"}},
 			1,
+			true,
 			false,
 		},
 		{
@@ -123,6 +489,7 @@ func TestChain_TransitionProbability(t *testing.T) {
 			[]byte(`{"int":1,"spool_map":{"^":0,"Test":1,"$":2},"freq_mat":{"0":{"1":1},"1":{"2":1}}}`),
 			args{next: "Test", current: NGram{"Test"}},
 			0,
+			true,
 			false,
 		},
 		{
@@ -130,6 +497,15 @@ func TestChain_TransitionProbability(t *testing.T) {
 			[]byte(`{"int":1,"spool_map":{"^":0,"Test":1,"$":2},"freq_mat":{"0":{"1":1},"1":{"2":1}}}`),
 			args{next: "Unknown", current: NGram{"Test"}},
 			0,
+			true,
+			false,
+		},
+		{
+			"End state",
+			[]byte(`{"int":1,"spool_map":{"^":0,"Test":1,"$":2},"freq_mat":{"0":{"1":1},"1":{"2":1}}}`),
+			args{next: "Test", current: NGram{"$"}},
+			0,
+			false,
 			false,
 		},
 		{
@@ -138,12 +514,14 @@ func TestChain_TransitionProbability(t *testing.T) {
 			args{next: "Test", current: NGram{"Unknown"}},
 			0,
 			false,
+			false,
 		},
 		{
 			"Invalid Ngram",
 			[]byte(`{"int":1,"spool_map":{"^":0,"Test":1,"$":2},"freq_mat":{"0":{"1":1},"1":{"2":1}}}`),
 			args{next: "Unknown", current: NGram{"Test", "data"}},
 			0,
+			false,
 			true,
 		},
 		{
@@ -151,6 +529,7 @@ func TestChain_TransitionProbability(t *testing.T) {
 			[]byte(`{"int":1,"spool_map":{"^":0,"$":3,"data":2,"node":4,"test":1},"freq_mat":{"0":{"1":3},"1":{"2":2,"4":2},"2":{"3":2},"4":{"3":1}}}`),
 			args{next: "node", current: NGram{"test"}},
 			0.5,
+			true,
 			false,
 		},
 	}
@@ -167,6 +546,11 @@ func TestChain_TransitionProbability(t *testing.T) {
 			if got != tt.want {
 				t.Errorf("Chain.TransitionProbability() = %v, want %v", got, tt.want)
 			}
+
+			got, known, err := chain.LookupTransition(tt.args.next, tt.args.current)
+			if (err != nil) != tt.wantErr || got != tt.want || known != tt.wantKnown {
+				t.Errorf("Chain.LookupTransition() = %v, %v, %v, want %v, %v", got, known, err, tt.want, tt.wantKnown)
+			}
 		})
 	}
 }
@@ -250,11 +634,11 @@ func TestChain_GenerateDeterministic(t *testing.T) {
 	chain.Add(NGram{"i", "like", "tacos"})
 
 	pairs := map[int64]string{
-		0:    "cake",
-		1:    "bees",
-		10:   "cake",
-		100:  "pizza",
-		1000: "bees",
+		0:    "pizza",
+		1:    "cake",
+		10:   "pizza",
+		100:  "tacos",
+		1000: "cake",
 	}
 	for seed, expected := range pairs {
 		for i := 0; i < 16; i++ {
@@ -0,0 +1,152 @@
+// Package gonumgraph converts chains to gonum weighted directed graphs, so gonum's
+// path, community detection and flow algorithms can run on trained chains:
+//
+//	g, err := gonumgraph.New(chain, gonumgraph.WithWeight(gonumgraph.Surprisal))
+//	from, _ := g.NodeFor(gomarkov.NGram{"I"})
+//	paths := path.DijkstraFrom(from, g)
+//
+// It is a separate package so that gomarkov itself doesn't depend on gonum.
+package gonumgraph
+
+import (
+	"math"
+	"strings"
+
+	"gonum.org/v1/gonum/graph"
+	"gonum.org/v1/gonum/graph/simple"
+
+	"github.com/mb-14/gomarkov"
+)
+
+// Node is a state of the chain
+type Node struct {
+	id    int64
+	State gomarkov.NGram
+	// Loop is the weight of the transition from the state to itself, such as a
+	// repeated token in an order 1 chain, as simple graphs can't hold self edges.
+	// It is 0 if there is none.
+	Loop float64
+}
+
+// ID implements graph.Node
+func (n *Node) ID() int64 {
+	return n.id
+}
+
+// DOTID names the node after its tokens when the graph is encoded with gonum's dot package
+func (n *Node) DOTID() string {
+	return strings.Join(n.State, " ")
+}
+
+// Weight computes the weight of an edge from a transition
+type Weight func(gomarkov.Transition) float64
+
+// Probability weighs edges by transition probability, the default
+func Probability(t gomarkov.Transition) float64 {
+	return t.Probability
+}
+
+// Count weighs edges by the number of times the transition was seen
+func Count(t gomarkov.Transition) float64 {
+	return float64(t.Count)
+}
+
+// Surprisal weighs edges by the negative log of their probability, so the shortest
+// path between two states is the most likely one
+func Surprisal(t gomarkov.Transition) float64 {
+	return -math.Log(t.Probability)
+}
+
+// Option configures New
+type Option func(*options)
+
+type options struct {
+	weight         Weight
+	minProbability float64
+}
+
+// WithWeight sets how edges are weighed, Probability by default
+func WithWeight(w Weight) Option {
+	return func(o *options) {
+		o.weight = w
+	}
+}
+
+// WithMinProbability drops transitions whose probability is below p
+func WithMinProbability(p float64) Option {
+	return func(o *options) {
+		o.minProbability = p
+	}
+}
+
+// Graph is the transition graph of a chain, where edges lead from a state to the
+// state obtained by shifting the next token into it, as a gonum weighted directed
+// graph. Missing edges have infinite weight.
+type Graph struct {
+	*simple.WeightedDirectedGraph
+	ids map[string]int64
+}
+
+// New builds the transition graph of chain. States whose tokens contain the n-gram
+// separator are left out, see gomarkov.Chain.ForEachTransition.
+func New(chain *gomarkov.Chain, opts ...Option) (*Graph, error) {
+	o := options{weight: Probability}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	g := &Graph{
+		WeightedDirectedGraph: simple.NewWeightedDirectedGraph(0, math.Inf(1)),
+		ids:                   make(map[string]int64),
+	}
+	err := chain.ForEachTransition(func(current gomarkov.NGram, t gomarkov.Transition) bool {
+		if t.Probability < o.minProbability {
+			return true
+		}
+		from := g.node(current)
+		to := g.node(current.Shift(t.Next))
+		if from == to {
+			from.Loop = o.weight(t)
+			return true
+		}
+		g.SetWeightedEdge(simple.WeightedEdge{F: from, T: to, W: o.weight(t)})
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	return g, nil
+}
+
+// key identifies a state, tokens can't contain a NUL separator in practice
+func key(state gomarkov.NGram) string {
+	return strings.Join(state, "\x00")
+}
+
+// node returns the node of a state, adding it if needed
+func (g *Graph) node(state gomarkov.NGram) *Node {
+	if id, ok := g.ids[key(state)]; ok {
+		return g.Node(id).(*Node)
+	}
+	n := &Node{id: int64(len(g.ids)), State: state}
+	g.ids[key(state)] = n.id
+	g.AddNode(n)
+	return n
+}
+
+// NodeFor returns the node of a state, with ok false if the graph doesn't have it
+func (g *Graph) NodeFor(state gomarkov.NGram) (n *Node, ok bool) {
+	id, ok := g.ids[key(state)]
+	if !ok {
+		return nil, false
+	}
+	return g.Node(id).(*Node), true
+}
+
+// States returns the states along a path of nodes, such as one found by gonum's path package
+func States(nodes []graph.Node) []gomarkov.NGram {
+	states := make([]gomarkov.NGram, len(nodes))
+	for i, n := range nodes {
+		states[i] = n.(*Node).State
+	}
+	return states
+}
@@ -0,0 +1,55 @@
+package gonumgraph
+
+import (
+	"math"
+	"reflect"
+	"testing"
+
+	"gonum.org/v1/gonum/graph/path"
+
+	"github.com/mb-14/gomarkov"
+)
+
+func TestNew(t *testing.T) {
+	chain := gomarkov.NewChain(1)
+	chain.Add([]string{"a", "b", "d"})
+	chain.Add([]string{"a", "b", "d"})
+	chain.Add([]string{"a", "c", "d"})
+	chain.Add([]string{"d", "d"})
+
+	g, err := New(chain, WithWeight(Surprisal))
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	if got := g.Nodes().Len(); got != 6 {
+		t.Errorf("graph has %d nodes, want 6", got)
+	}
+	a, ok := g.NodeFor(gomarkov.NGram{"a"})
+	if !ok {
+		t.Fatal("Graph.NodeFor() didn't find a")
+	}
+	d, _ := g.NodeFor(gomarkov.NGram{"d"})
+	if d.Loop != -math.Log(1.0/5) {
+		t.Errorf("d has a loop of weight %v, want %v", d.Loop, -math.Log(1.0/5))
+	}
+	if w, ok := g.Weight(a.ID(), d.ID()); ok || !math.IsInf(w, 1) {
+		t.Errorf("missing edge has weight %v, %v, want +Inf", w, ok)
+	}
+
+	// The most likely way from a to d goes through b
+	nodes, _ := path.DijkstraFrom(a, g).To(d.ID())
+	want := []gomarkov.NGram{{"a"}, {"b"}, {"d"}}
+	if got := States(nodes); !reflect.DeepEqual(got, want) {
+		t.Errorf("shortest path = %v, want %v", got, want)
+	}
+
+	g, _ = New(chain, WithMinProbability(0.5), WithWeight(Count))
+	a, _ = g.NodeFor(gomarkov.NGram{"a"})
+	b, _ := g.NodeFor(gomarkov.NGram{"b"})
+	if c, _ := g.NodeFor(gomarkov.NGram{"c"}); g.HasEdgeFromTo(a.ID(), c.ID()) {
+		t.Errorf("graph kept an edge below the minimum probability")
+	}
+	if w, _ := g.Weight(a.ID(), b.ID()); w != 2 {
+		t.Errorf("a -> b has weight %v, want a count of 2", w)
+	}
+}
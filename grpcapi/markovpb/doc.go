@@ -0,0 +1,6 @@
+// Package markovpb holds the protobuf messages and gRPC stubs of the Markov
+// service defined in markov.proto, for clients in any language. Regenerate them with
+// go generate and protoc, with protoc-gen-go and protoc-gen-go-grpc installed.
+package markovpb
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative markov.proto
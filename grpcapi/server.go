@@ -0,0 +1,228 @@
+// Package grpcapi serves a chain over gRPC with the Markov service defined in
+// markovpb/markov.proto:
+//
+//	s := grpc.NewServer()
+//	markovpb.RegisterMarkovServer(s, grpcapi.NewServer(chain, grpcapi.WithTraining()))
+//
+// NewSetServer serves every chain of a ChainSet instead, each request naming the
+// label of its chain. Training only creates chains for new labels with
+// WithLabelCreation.
+//
+// Errors carry status codes: InvalidArgument for malformed requests,
+// FailedPrecondition for requests the chain can't serve, such as unknown n-grams,
+// NotFound for labels without a chain, PermissionDenied for training without
+// WithTraining and Internal otherwise.
+package grpcapi
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"math"
+
+	"github.com/mb-14/gomarkov"
+	"github.com/mb-14/gomarkov/grpcapi/markovpb"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Option configures a Server
+type Option func(*Server)
+
+// WithTraining enables the Train method, letting clients modify the chain
+func WithTraining() Option {
+	return func(s *Server) {
+		s.training = true
+	}
+}
+
+// WithLabelCreation lets Train create the chain of a label the ChainSet of
+// NewSetServer doesn't hold yet. Without it, training an unknown label is NotFound,
+// so clients can't create any number of chains.
+func WithLabelCreation() Option {
+	return func(s *Server) {
+		s.createLabels = true
+	}
+}
+
+// WithLimits caps the number of sequences per request and the tokens generated per
+// sequence. The defaults are 100 of each.
+func WithLimits(maxCount, maxLength int) Option {
+	return func(s *Server) {
+		s.maxCount, s.maxLength = maxCount, maxLength
+	}
+}
+
+// Server implements markovpb.MarkovServer over a chain or a set of chains
+type Server struct {
+	markovpb.UnimplementedMarkovServer
+	chain        *gomarkov.Chain
+	set          *gomarkov.ChainSet
+	training     bool
+	createLabels bool
+	maxCount     int
+	maxLength    int
+}
+
+// NewServer returns a Server serving chain
+func NewServer(chain *gomarkov.Chain, opts ...Option) *Server {
+	s := &Server{chain: chain, maxCount: 100, maxLength: 100}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// NewSetServer returns a Server serving the chains of set by the label of each
+// request. Training a new label creates its chain with WithLabelCreation.
+func NewSetServer(set *gomarkov.ChainSet, opts ...Option) *Server {
+	s := NewServer(nil, opts...)
+	s.set = set
+	return s
+}
+
+// lookup returns the chain a request is for, creating it if create is set and the
+// server allows it. Servers of a single chain only serve the empty label.
+func (s *Server) lookup(label string, create bool) (*gomarkov.Chain, error) {
+	if s.set == nil {
+		if label != "" {
+			return nil, status.Error(codes.InvalidArgument, "Labels are only served by a set of chains")
+		}
+		return s.chain, nil
+	}
+	if create && s.createLabels {
+		return s.set.Chain(label), nil
+	}
+	chain, ok := s.set.Lookup(label)
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "No chain with label %q", label)
+	}
+	return chain, nil
+}
+
+func (s *Server) Train(ctx context.Context, req *markovpb.TrainRequest) (*markovpb.TrainResponse, error) {
+	if !s.training {
+		return nil, status.Error(codes.PermissionDenied, "Training is disabled")
+	}
+	if len(req.GetSequences()) > s.maxCount {
+		return nil, status.Error(codes.InvalidArgument, "Too many sequences to train on")
+	}
+	chain, err := s.lookup(req.GetLabel(), true)
+	if err != nil {
+		return nil, err
+	}
+	inputs := make([][]string, len(req.GetSequences()))
+	for i, seq := range req.GetSequences() {
+		inputs[i] = seq.GetTokens()
+	}
+	if err := chain.AddBatch(inputs); err != nil {
+		return nil, chainError(err)
+	}
+	return &markovpb.TrainResponse{Added: int32(len(inputs))}, nil
+}
+
+func (s *Server) Generate(ctx context.Context, req *markovpb.GenerateRequest) (*markovpb.GenerateResponse, error) {
+	count, maxLength := int(req.GetCount()), int(req.GetMaxLength())
+	if count <= 0 {
+		count = 1
+	}
+	if count > s.maxCount {
+		return nil, status.Error(codes.InvalidArgument, "Too many sequences requested")
+	}
+	if maxLength <= 0 || maxLength > s.maxLength {
+		maxLength = s.maxLength
+	}
+	chain, err := s.lookup(req.GetLabel(), false)
+	if err != nil {
+		return nil, err
+	}
+	seed := gomarkov.FromTokens(req.GetSeed(), chain.Order)
+
+	resp := &markovpb.GenerateResponse{Sequences: make([]*markovpb.Sequence, 0, count)}
+	for i := 0; i < count; i++ {
+		if err := ctx.Err(); err != nil {
+			return nil, status.FromContextError(err).Err()
+		}
+		seq, err := chain.GenerateSequence(seed, maxLength)
+		if err != nil {
+			return nil, chainError(err)
+		}
+		resp.Sequences = append(resp.Sequences, &markovpb.Sequence{Tokens: seq})
+	}
+	return resp, nil
+}
+
+func (s *Server) Score(ctx context.Context, req *markovpb.ScoreRequest) (*markovpb.ScoreResponse, error) {
+	if len(req.GetSequences()) > s.maxCount {
+		return nil, status.Error(codes.InvalidArgument, "Too many sequences to score")
+	}
+	chain, err := s.lookup(req.GetLabel(), false)
+	if err != nil {
+		return nil, err
+	}
+	resp := &markovpb.ScoreResponse{Scores: make([]*markovpb.Score, len(req.GetSequences()))}
+	for i, seq := range req.GetSequences() {
+		logProb, err := chain.LogProbability(seq.GetTokens())
+		if err != nil {
+			return nil, chainError(err)
+		}
+		score := &markovpb.Score{}
+		if !math.IsInf(logProb, -1) {
+			score.Possible, score.LogProbability = true, logProb
+		}
+		resp.Scores[i] = score
+	}
+	return resp, nil
+}
+
+func (s *Server) GetStats(ctx context.Context, req *markovpb.GetStatsRequest) (*markovpb.Stats, error) {
+	chain, err := s.lookup(req.GetLabel(), false)
+	if err != nil {
+		return nil, err
+	}
+	stats, err := chain.Stats()
+	if err != nil {
+		return nil, chainError(err)
+	}
+	return &markovpb.Stats{
+		Order:       int32(stats.Order),
+		States:      int64(stats.States),
+		Transitions: int64(stats.Transitions),
+		Vocabulary:  int64(stats.Vocabulary),
+	}, nil
+}
+
+func (s *Server) Export(ctx context.Context, req *markovpb.ExportRequest) (*markovpb.ExportResponse, error) {
+	chain, err := s.lookup(req.GetLabel(), false)
+	if err != nil {
+		return nil, err
+	}
+	var model []byte
+	switch req.GetFormat() {
+	case markovpb.Format_FORMAT_JSON:
+		model, err = chain.MarshalJSON()
+	case markovpb.Format_FORMAT_CANONICAL_JSON:
+		model, err = chain.MarshalCanonicalJSON()
+	case markovpb.Format_FORMAT_COMPILED:
+		var buf bytes.Buffer
+		err = chain.Compile(&buf)
+		model = buf.Bytes()
+	default:
+		return nil, status.Errorf(codes.InvalidArgument, "Unknown format %v", req.GetFormat())
+	}
+	if err != nil {
+		return nil, chainError(err)
+	}
+	return &markovpb.ExportResponse{Model: model}, nil
+}
+
+// chainError converts an error from the chain to a status, distinguishing requests
+// the chain can't serve from failures of the chain's store
+func chainError(err error) error {
+	for _, target := range []error{gomarkov.ErrOrderMismatch, gomarkov.ErrUnknownNGram, gomarkov.ErrDeadEnd, gomarkov.ErrEmptyChain} {
+		if errors.Is(err, target) {
+			return status.Error(codes.FailedPrecondition, err.Error())
+		}
+	}
+	return status.Error(codes.Internal, err.Error())
+}
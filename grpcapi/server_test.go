@@ -0,0 +1,157 @@
+package grpcapi
+
+import (
+	"context"
+	"net"
+	"reflect"
+	"testing"
+
+	"github.com/mb-14/gomarkov"
+	"github.com/mb-14/gomarkov/grpcapi/markovpb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// dial serves a Server over an in-memory connection and returns a client for it
+func dial(t *testing.T, s *Server) markovpb.MarkovClient {
+	t.Helper()
+	lis := bufconn.Listen(1 << 20)
+	srv := grpc.NewServer()
+	markovpb.RegisterMarkovServer(srv, s)
+	go srv.Serve(lis)
+	t.Cleanup(srv.Stop)
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("grpc.NewClient() error = %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return markovpb.NewMarkovClient(conn)
+}
+
+func testChain() *gomarkov.Chain {
+	chain := gomarkov.NewChain(2)
+	chain.Add([]string{"I", "want", "a", "cheese", "burger"})
+	return chain
+}
+
+func TestServer(t *testing.T) {
+	ctx := context.Background()
+	chain := testChain()
+	client := dial(t, NewServer(chain, WithTraining()))
+
+	gen, err := client.Generate(ctx, &markovpb.GenerateRequest{Seed: []string{"a", "cheese"}, Count: 2})
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	if len(gen.Sequences) != 2 || !reflect.DeepEqual(gen.Sequences[0].Tokens, []string{"burger"}) {
+		t.Errorf("Generate() = %v, want two sequences of [burger]", gen.Sequences)
+	}
+	if _, err := client.Generate(ctx, &markovpb.GenerateRequest{Seed: []string{"cheese"}}); status.Code(err) != codes.FailedPrecondition {
+		t.Errorf("Generate() with an unknown seed error = %v, want FailedPrecondition", err)
+	}
+
+	score, err := client.Score(ctx, &markovpb.ScoreRequest{Sequences: []*markovpb.Sequence{
+		{Tokens: []string{"I", "want", "a", "cheese", "burger"}},
+		{Tokens: []string{"I", "need"}},
+	}})
+	if err != nil {
+		t.Fatalf("Score() error = %v", err)
+	}
+	if s := score.Scores; len(s) != 2 || !s[0].Possible || s[0].LogProbability != 0 || s[1].Possible {
+		t.Errorf("Score() = %v", s)
+	}
+
+	if _, err := client.Train(ctx, &markovpb.TrainRequest{Sequences: []*markovpb.Sequence{
+		{Tokens: []string{"I", "want", "a", "chilled", "sprite"}},
+	}}); err != nil {
+		t.Fatalf("Train() error = %v", err)
+	}
+	stats, err := client.GetStats(ctx, &markovpb.GetStatsRequest{})
+	if err != nil {
+		t.Fatalf("GetStats() error = %v", err)
+	}
+	if stats.Order != 2 || stats.Vocabulary != 7 {
+		t.Errorf("GetStats() = %v, want order 2 and 7 tokens", stats)
+	}
+
+	export, err := client.Export(ctx, &markovpb.ExportRequest{Format: markovpb.Format_FORMAT_COMPILED})
+	if err != nil {
+		t.Fatalf("Export() error = %v", err)
+	}
+	loaded, err := gomarkov.LoadCompiled(export.Model)
+	if err != nil {
+		t.Fatalf("LoadCompiled() error = %v", err)
+	}
+	if p, _ := loaded.TransitionProbability("chilled", gomarkov.NGram{"want", "a"}); p != 0.5 {
+		t.Errorf("exported TransitionProbability() = %v, want 0.5", p)
+	}
+}
+
+func TestServer_TrainingDisabled(t *testing.T) {
+	client := dial(t, NewServer(testChain()))
+	_, err := client.Train(context.Background(), &markovpb.TrainRequest{})
+	if status.Code(err) != codes.PermissionDenied {
+		t.Errorf("Train() error = %v, want PermissionDenied", err)
+	}
+}
+
+func TestServer_Limits(t *testing.T) {
+	client := dial(t, NewServer(testChain(), WithTraining(), WithLimits(1, 10)))
+	_, err := client.Train(context.Background(), &markovpb.TrainRequest{Sequences: []*markovpb.Sequence{
+		{Tokens: []string{"a"}}, {Tokens: []string{"b"}},
+	}})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("Train() of too many sequences error = %v, want InvalidArgument", err)
+	}
+}
+
+func TestSetServer(t *testing.T) {
+	ctx := context.Background()
+	set := gomarkov.NewChainSet(1)
+	set.Add("en", []string{"hello", "world"})
+	client := dial(t, NewSetServer(set, WithTraining(), WithLabelCreation()))
+
+	gen, err := client.Generate(ctx, &markovpb.GenerateRequest{Label: "en"})
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	if want := []string{"hello", "world"}; !reflect.DeepEqual(gen.Sequences[0].Tokens, want) {
+		t.Errorf("Generate() = %v, want %v", gen.Sequences[0].Tokens, want)
+	}
+	if _, err := client.Generate(ctx, &markovpb.GenerateRequest{Label: "fr"}); status.Code(err) != codes.NotFound {
+		t.Errorf("Generate() of an unknown label error = %v, want NotFound", err)
+	}
+
+	if _, err := client.Train(ctx, &markovpb.TrainRequest{Label: "fr", Sequences: []*markovpb.Sequence{
+		{Tokens: []string{"bonjour"}},
+	}}); err != nil {
+		t.Fatalf("Train() error = %v", err)
+	}
+	stats, err := client.GetStats(ctx, &markovpb.GetStatsRequest{Label: "fr"})
+	if err != nil {
+		t.Fatalf("GetStats() error = %v", err)
+	}
+	if stats.Vocabulary != 1 {
+		t.Errorf("GetStats() = %v, want the 1 token trained on", stats)
+	}
+
+	closed := dial(t, NewSetServer(set, WithTraining()))
+	if _, err := closed.Train(ctx, &markovpb.TrainRequest{Label: "de", Sequences: []*markovpb.Sequence{
+		{Tokens: []string{"hallo"}},
+	}}); status.Code(err) != codes.NotFound {
+		t.Errorf("Train() of a new label without WithLabelCreation error = %v, want NotFound", err)
+	}
+	if _, ok := set.Lookup("de"); ok {
+		t.Errorf("Train() without WithLabelCreation created a chain")
+	}
+
+	single := dial(t, NewServer(testChain()))
+	if _, err := single.GetStats(ctx, &markovpb.GetStatsRequest{Label: "en"}); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("GetStats() of a label from a single chain error = %v, want InvalidArgument", err)
+	}
+}
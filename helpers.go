@@ -20,6 +20,31 @@ func (ngram NGram) key() string {
 	return strings.Join(ngram, "_")
 }
 
+// appendKey appends the key of the n-gram to buf. Indexing a map with
+// string(ngram.appendKey(stackBuf[:0])) looks a state up without allocating.
+func (ngram NGram) appendKey(buf []byte) []byte {
+	for i, token := range ngram {
+		if i > 0 {
+			buf = append(buf, '_')
+		}
+		buf = append(buf, token...)
+	}
+	return buf
+}
+
+// splitKey reverses key for a chain of the given order. It fails when tokens
+// containing the separator make the split ambiguous.
+func splitKey(key string, order int) (NGram, bool) {
+	switch order {
+	case 0:
+		return NGram{}, key == ""
+	case 1:
+		return NGram{key}, true
+	}
+	ngram := NGram(strings.Split(key, "_"))
+	return ngram, len(ngram) == order
+}
+
 func (s sparseArray) orderedKeys() []int {
 	keys := make([]int, 0, len(s))
 	for k := range s {
@@ -46,6 +71,87 @@ func (s sparseArray) orderedPairs() [][2]int {
 	return pairs
 }
 
+// distribution draws the index of the next state from a row, with ok false if the
+// row has no transitions to draw from
+type distribution interface {
+	draw(prng PRNG) (next int, ok bool)
+}
+
+// cumulativeDist holds the keys of a row in sampling order, see orderedPairs,
+// along with the running total of their counts
+type cumulativeDist struct {
+	keys   []int
+	totals []int
+}
+
+func (s sparseArray) cumulative() *cumulativeDist {
+	pairs := s.orderedPairs()
+	d := &cumulativeDist{
+		keys:   make([]int, len(pairs)),
+		totals: make([]int, len(pairs)),
+	}
+	total := 0
+	for i, p := range pairs {
+		total += p[1]
+		d.keys[i] = p[0]
+		d.totals[i] = total
+	}
+	return d
+}
+
+func (d *cumulativeDist) sum() int {
+	if d == nil || len(d.totals) == 0 {
+		return 0
+	}
+	return d.totals[len(d.totals)-1]
+}
+
+// sample returns the first key whose running total is past randN, so that each key
+// is sampled by as many values of randN in [0, sum) as its count
+func (d *cumulativeDist) sample(randN int) int {
+	return d.keys[sort.SearchInts(d.totals, randN+1)]
+}
+
+func (d *cumulativeDist) draw(prng PRNG) (int, bool) {
+	sum := d.sum()
+	if sum == 0 {
+		return 0, false
+	}
+	return d.sample(prng.Intn(sum)), true
+}
+
+// uniform returns a float drawn uniformly from [0, 1), to the resolution of 30 bits
+// so that it only asks the PRNG for ints that fit in 32 bits
+func uniform(prng PRNG) float64 {
+	const resolution = 1 << 30
+	return float64(prng.Intn(resolution)) / resolution
+}
+
+// drawFloat returns an index into weights drawn by weight, the last one if the sum
+// rounds below the draw
+func drawFloat(prng PRNG, weights []float64) int {
+	total := 0.0
+	for _, w := range weights {
+		total += w
+	}
+	x := uniform(prng) * total
+	for i, w := range weights {
+		if x -= w; x < 0 {
+			return i
+		}
+	}
+	return len(weights) - 1
+}
+
+// addCount returns a+b, with ok false if it overflows an int
+func addCount(a, b int) (sum int, ok bool) {
+	sum = a + b
+	if (b > 0 && sum < a) || (b < 0 && sum > a) {
+		return 0, false
+	}
+	return sum, true
+}
+
 func (s sparseArray) sum() int {
 	sum := 0
 	for _, count := range s {
@@ -61,6 +167,24 @@ func max(a, b int) int {
 	return b
 }
 
+// Runs of boundary tokens shared by every chain of a moderate order
+var (
+	startRun = array(StartToken, 16)
+	endRun   = array(EndToken, 16)
+)
+
+// boundary returns count start or end tokens. The result may be shared and must
+// not be modified.
+func boundary(token string, count int) []string {
+	switch {
+	case token == StartToken && count <= len(startRun):
+		return startRun[:count:count]
+	case token == EndToken && count <= len(endRun):
+		return endRun[:count:count]
+	}
+	return array(token, count)
+}
+
 func array(value string, count int) []string {
 	arr := make([]string, count)
 	for i := range arr {
@@ -71,13 +195,26 @@ func array(value string, count int) []string {
 
 // MakePairs generates n-gram pairs of consecutive states in a sequence
 func MakePairs(tokens []string, order int) []Pair {
-	var pairs []Pair
+	pairs := make([]Pair, 0, max(len(tokens)-order, 0))
+	ForEachPair(tokens, order, func(pair Pair) bool {
+		pairs = append(pairs, pair)
+		return true
+	})
+	return pairs
+}
+
+// ForEachPair calls fn with each pair MakePairs would return, in order, stopping
+// early if fn returns false. It doesn't allocate, so it suits very long sequences
+// such as whole documents at the character level. The pair's CurrentState shares
+// memory with tokens.
+func ForEachPair(tokens []string, order int, fn func(Pair) bool) {
 	for i := 0; i < len(tokens)-order; i++ {
 		pair := Pair{
 			CurrentState: tokens[i : i+order],
 			NextState:    tokens[i+order],
 		}
-		pairs = append(pairs, pair)
+		if !fn(pair) {
+			return
+		}
 	}
-	return pairs
 }
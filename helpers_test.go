@@ -86,6 +86,31 @@ func Test_array(t *testing.T) {
 	}
 }
 
+func Test_boundary(t *testing.T) {
+	tests := []struct {
+		name  string
+		token string
+		count int
+	}{
+		{"No tokens", StartToken, 0},
+		{"Shared start", StartToken, 3},
+		{"Shared end", EndToken, 16},
+		{"Longer than shared", EndToken, 20},
+		{"Other token", "x", 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := boundary(tt.token, tt.count)
+			if want := array(tt.token, tt.count); !reflect.DeepEqual(got, want) {
+				t.Errorf("boundary() = %v, want %v", got, want)
+			}
+			if cap(got) != tt.count {
+				t.Errorf("boundary() has capacity %d, appending would overwrite shared tokens", cap(got))
+			}
+		})
+	}
+}
+
 func TestMakePairs(t *testing.T) {
 	type args struct {
 		tokens []string
@@ -106,3 +131,101 @@ func TestMakePairs(t *testing.T) {
 		})
 	}
 }
+
+func TestForEachPair(t *testing.T) {
+	tokens := []string{"^", "I", "want", "a", "$"}
+	want := []Pair{
+		{NGram{"^", "I"}, "want"},
+		{NGram{"I", "want"}, "a"},
+		{NGram{"want", "a"}, "$"},
+	}
+	var got []Pair
+	ForEachPair(tokens, 2, func(pair Pair) bool {
+		got = append(got, pair)
+		return len(got) < 2
+	})
+	if !reflect.DeepEqual(got, want[:2]) {
+		t.Errorf("ForEachPair() = %v, want %v", got, want[:2])
+	}
+	if got := MakePairs(tokens, 2); !reflect.DeepEqual(got, want) {
+		t.Errorf("MakePairs() = %v, want %v", got, want)
+	}
+	count := 0
+	allocs := testing.AllocsPerRun(10, func() {
+		ForEachPair(tokens, 1, func(pair Pair) bool {
+			count++
+			return true
+		})
+	})
+	if allocs != 0 {
+		t.Errorf("ForEachPair() allocated %v times, want 0", allocs)
+	}
+}
+
+func Test_splitKey(t *testing.T) {
+	tests := []struct {
+		name   string
+		key    string
+		order  int
+		want   NGram
+		wantOk bool
+	}{
+		{"Order 1", "One", 1, NGram{"One"}, true},
+		{"Order 1 with separator", "snake_case", 1, NGram{"snake_case"}, true},
+		{"Order 2", "Two_words", 2, NGram{"Two", "words"}, true},
+		{"Ambiguous", "snake_case_word", 2, NGram{"snake", "case", "word"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := splitKey(tt.key, tt.order)
+			if !reflect.DeepEqual(got, tt.want) || ok != tt.wantOk {
+				t.Errorf("splitKey() = %v, %v, want %v, %v", got, ok, tt.want, tt.wantOk)
+			}
+		})
+	}
+}
+
+func Test_cumulativeDist_sample(t *testing.T) {
+	tests := []struct {
+		name  string
+		s     sparseArray
+		randN int
+		want  int
+	}{
+		{"Single key", sparseArray{7: 3}, 0, 7},
+		{"Most frequent first", sparseArray{1: 1, 2: 3}, 0, 2},
+		{"Boundary stays on key", sparseArray{1: 1, 2: 3}, 2, 2},
+		{"Past boundary", sparseArray{1: 1, 2: 3}, 3, 1},
+		{"Ties broken by key", sparseArray{5: 2, 3: 2}, 1, 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.s.cumulative().sample(tt.randN); got != tt.want {
+				t.Errorf("cumulativeDist.sample() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_drawFloat(t *testing.T) {
+	tests := []struct {
+		name    string
+		weights []float64
+		randN   int
+		want    int
+	}{
+		{"Single weight", []float64{0.5}, 0, 0},
+		{"First", []float64{1, 3}, 0, 0},
+		{"Boundary moves on", []float64{1, 3}, 1 << 28, 1},
+		{"Last", []float64{1, 3}, 1<<30 - 1, 1},
+		{"Zero weight skipped", []float64{1, 0, 1}, 1 << 29, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			prng := &sequenceRand{values: []int{tt.randN}}
+			if got := drawFloat(prng, tt.weights); got != tt.want {
+				t.Errorf("drawFloat() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,129 @@
+package hmm
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"math"
+)
+
+// formatVersion is the version of the serialized format written by MarshalJSON
+const formatVersion = 1
+
+// modelJSON is the serialized form of a model. Like chains, models carry a CRC-32C
+// checksum of their contents so corrupted files are rejected.
+type modelJSON struct {
+	Version    int         `json:"version"`
+	States     []string    `json:"states"`
+	Symbols    []string    `json:"symbols"`
+	Initial    []float64   `json:"initial"`
+	Transition [][]float64 `json:"transition"`
+	Emission   [][]float64 `json:"emission"`
+	Checksum   string      `json:"checksum"`
+}
+
+var crc32c = crc32.MakeTable(crc32.Castagnoli)
+
+func checksum(obj modelJSON) (string, error) {
+	obj.Checksum = ""
+	data, err := json.Marshal(obj)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("crc32c:%08x", crc32.Checksum(data, crc32c)), nil
+}
+
+// MarshalJSON encodes the model
+func (m *HMM) MarshalJSON() ([]byte, error) {
+	obj := modelJSON{
+		Version:    formatVersion,
+		States:     m.States,
+		Symbols:    m.Symbols,
+		Initial:    m.Initial,
+		Transition: m.Transition,
+		Emission:   m.Emission,
+	}
+	var err error
+	if obj.Checksum, err = checksum(obj); err != nil {
+		return nil, err
+	}
+	return json.Marshal(obj)
+}
+
+// UnmarshalJSON replaces the model with a decoded one. The model is rejected if its
+// checksum doesn't match or its parameters aren't probability distributions of the
+// right sizes.
+func (m *HMM) UnmarshalJSON(b []byte) error {
+	var obj modelJSON
+	if err := json.Unmarshal(b, &obj); err != nil {
+		return err
+	}
+	if obj.Version != formatVersion {
+		return fmt.Errorf("Unsupported model version %d", obj.Version)
+	}
+	want, err := checksum(obj)
+	if err != nil {
+		return err
+	}
+	if obj.Checksum != want {
+		return errors.New("Model checksum does not match its contents")
+	}
+	if err := validate(obj); err != nil {
+		return err
+	}
+	*m = HMM{
+		States:     obj.States,
+		Symbols:    obj.Symbols,
+		Initial:    obj.Initial,
+		Transition: obj.Transition,
+		Emission:   obj.Emission,
+	}
+	m.index()
+	return nil
+}
+
+// validate checks the sizes of a decoded model and that its rows are distributions
+func validate(obj modelJSON) error {
+	n, v := len(obj.States), len(obj.Symbols)
+	if n == 0 {
+		return errors.New("Model has no states")
+	}
+	seen := make(map[string]bool, v)
+	for _, symbol := range obj.Symbols {
+		if seen[symbol] {
+			return fmt.Errorf("Symbol %q appears twice", symbol)
+		}
+		seen[symbol] = true
+	}
+	if err := validateRow("initial", obj.Initial, n); err != nil {
+		return err
+	}
+	if len(obj.Transition) != n || len(obj.Emission) != n {
+		return fmt.Errorf("Model must have a transition and emission row for each of its %d states", n)
+	}
+	for i := 0; i < n; i++ {
+		if err := validateRow(fmt.Sprintf("transition row %d", i), obj.Transition[i], n); err != nil {
+			return err
+		}
+		if err := validateRow(fmt.Sprintf("emission row %d", i), obj.Emission[i], v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func validateRow(name string, row []float64, size int) error {
+	if len(row) != size {
+		return fmt.Errorf("Model %s has %d entries, want %d", name, len(row), size)
+	}
+	for _, p := range row {
+		if p < 0 || math.IsNaN(p) {
+			return fmt.Errorf("Model %s has invalid probability %v", name, p)
+		}
+	}
+	if math.Abs(sum(row)-1) > 1e-6 {
+		return fmt.Errorf("Model %s sums to %v, want 1", name, sum(row))
+	}
+	return nil
+}
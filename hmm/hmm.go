@@ -0,0 +1,359 @@
+// Package hmm implements discrete hidden Markov models, where the chain's states
+// aren't observed directly but each emits an observed symbol. A model is fitted to
+// unlabeled sequences with Baum-Welch, decodes the most likely states behind a
+// sequence with Viterbi and scores sequences with the forward-backward algorithm:
+//
+//	m := hmm.New([]string{"rainy", "sunny"}, []string{"walk", "shop", "clean"})
+//	m.BaumWelch(observations, 100, 1e-6)
+//	states, _, err := m.Viterbi([]string{"walk", "shop", "clean"})
+//
+// Computations are scaled or in log space, so long sequences don't underflow.
+package hmm
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+
+	"github.com/mb-14/gomarkov"
+)
+
+// ErrUnknownSymbol is returned for observations outside the model's symbols
+var ErrUnknownSymbol = errors.New("Observation is not a symbol of the model")
+
+// HMM is a discrete hidden Markov model. It is safe for concurrent reads, but must
+// not be read while BaumWelch runs or its fields are modified.
+type HMM struct {
+	// States names the hidden states
+	States []string
+	// Symbols are the observable symbols
+	Symbols []string
+	// Initial[i] is the probability of starting in state i
+	Initial []float64
+	// Transition[i][j] is the probability of moving from state i to state j
+	Transition [][]float64
+	// Emission[i][k] is the probability of state i emitting symbol k
+	Emission [][]float64
+
+	symbolIndex map[string]int
+}
+
+// Option configures New
+type Option func(*options)
+
+type options struct {
+	prng gomarkov.PRNG
+}
+
+// WithRand draws the initial parameters from prng. By default they are drawn from
+// a fixed seed, so fitting the same data gives the same model.
+func WithRand(prng gomarkov.PRNG) Option {
+	return func(o *options) {
+		o.prng = prng
+	}
+}
+
+// New creates a model with random parameters, which BaumWelch needs to break the
+// symmetry between states
+func New(states, symbols []string, opts ...Option) *HMM {
+	o := options{prng: rand.New(rand.NewSource(1))}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	random := func(n int) []float64 {
+		row := make([]float64, n)
+		for i := range row {
+			// Keep every probability well away from zero, so nothing is ruled out
+			row[i] = 1 + float64(o.prng.Intn(1000))/1000
+		}
+		return normalize(row)
+	}
+	m := &HMM{
+		States:     append([]string(nil), states...),
+		Symbols:    append([]string(nil), symbols...),
+		Initial:    random(len(states)),
+		Transition: make([][]float64, len(states)),
+		Emission:   make([][]float64, len(states)),
+	}
+	for i := range states {
+		m.Transition[i] = random(len(states))
+		m.Emission[i] = random(len(symbols))
+	}
+	m.index()
+	return m
+}
+
+// index maps symbols to their positions
+func (m *HMM) index() {
+	m.symbolIndex = make(map[string]int, len(m.Symbols))
+	for k, symbol := range m.Symbols {
+		m.symbolIndex[symbol] = k
+	}
+}
+
+// observations maps symbols to their indices
+func (m *HMM) observations(symbols []string) ([]int, error) {
+	index := m.symbolIndex
+	if len(index) != len(m.Symbols) {
+		// The model was built without New, index it without caching so concurrent
+		// reads stay safe
+		index = make(map[string]int, len(m.Symbols))
+		for k, symbol := range m.Symbols {
+			index[symbol] = k
+		}
+	}
+	obs := make([]int, len(symbols))
+	for t, symbol := range symbols {
+		k, ok := index[symbol]
+		if !ok {
+			return nil, fmt.Errorf("%w: %q", ErrUnknownSymbol, symbol)
+		}
+		obs[t] = k
+	}
+	return obs, nil
+}
+
+// forward computes the scaled forward probabilities of a sequence and the scaling
+// factor of each position, with ok false if the model can't produce it
+func (m *HMM) forward(obs []int) (alpha [][]float64, scale []float64, ok bool) {
+	n := len(m.States)
+	alpha = make([][]float64, len(obs))
+	scale = make([]float64, len(obs))
+	for t, k := range obs {
+		alpha[t] = make([]float64, n)
+		for j := 0; j < n; j++ {
+			p := m.Initial[j]
+			if t > 0 {
+				p = 0
+				for i := 0; i < n; i++ {
+					p += alpha[t-1][i] * m.Transition[i][j]
+				}
+			}
+			alpha[t][j] = p * m.Emission[j][k]
+			scale[t] += alpha[t][j]
+		}
+		if scale[t] == 0 {
+			return nil, nil, false
+		}
+		for j := range alpha[t] {
+			alpha[t][j] /= scale[t]
+		}
+	}
+	return alpha, scale, true
+}
+
+// backward computes the backward probabilities with the scaling of forward
+func (m *HMM) backward(obs []int, scale []float64) [][]float64 {
+	n := len(m.States)
+	beta := make([][]float64, len(obs))
+	for t := len(obs) - 1; t >= 0; t-- {
+		beta[t] = make([]float64, n)
+		for i := 0; i < n; i++ {
+			if t == len(obs)-1 {
+				beta[t][i] = 1
+				continue
+			}
+			for j := 0; j < n; j++ {
+				beta[t][i] += m.Transition[i][j] * m.Emission[j][obs[t+1]] * beta[t+1][j]
+			}
+			beta[t][i] /= scale[t+1]
+		}
+	}
+	return beta
+}
+
+// LogLikelihood returns the natural log of the probability of the model emitting
+// symbols, summed over every sequence of hidden states. Sequences the model can't
+// emit have a log likelihood of negative infinity.
+func (m *HMM) LogLikelihood(symbols []string) (float64, error) {
+	obs, err := m.observations(symbols)
+	if err != nil {
+		return 0, err
+	}
+	_, scale, ok := m.forward(obs)
+	if !ok {
+		return math.Inf(-1), nil
+	}
+	logLik := 0.0
+	for _, c := range scale {
+		logLik += math.Log(c)
+	}
+	return logLik, nil
+}
+
+// Posterior returns, for each position of symbols, the probability of each hidden
+// state given the whole sequence. It returns nil if the model can't emit symbols.
+func (m *HMM) Posterior(symbols []string) ([][]float64, error) {
+	obs, err := m.observations(symbols)
+	if err != nil {
+		return nil, err
+	}
+	alpha, scale, ok := m.forward(obs)
+	if !ok {
+		return nil, nil
+	}
+	beta := m.backward(obs, scale)
+	gamma := make([][]float64, len(obs))
+	for t := range obs {
+		gamma[t] = make([]float64, len(m.States))
+		for i := range gamma[t] {
+			gamma[t][i] = alpha[t][i] * beta[t][i]
+		}
+		normalize(gamma[t])
+	}
+	return gamma, nil
+}
+
+// Viterbi returns the most likely sequence of hidden states behind symbols and the
+// natural log of its joint probability with them. It returns a nil path and negative
+// infinity if the model can't emit symbols.
+func (m *HMM) Viterbi(symbols []string) (path []string, logProb float64, err error) {
+	obs, err := m.observations(symbols)
+	if err != nil || len(obs) == 0 {
+		return nil, 0, err
+	}
+	n := len(m.States)
+	delta := make([]float64, n)
+	for i := range delta {
+		delta[i] = math.Log(m.Initial[i]) + math.Log(m.Emission[i][obs[0]])
+	}
+	back := make([][]int, len(obs))
+	for t := 1; t < len(obs); t++ {
+		back[t] = make([]int, n)
+		next := make([]float64, n)
+		for j := 0; j < n; j++ {
+			best, from := math.Inf(-1), 0
+			for i := 0; i < n; i++ {
+				if p := delta[i] + math.Log(m.Transition[i][j]); p > best {
+					best, from = p, i
+				}
+			}
+			next[j] = best + math.Log(m.Emission[j][obs[t]])
+			back[t][j] = from
+		}
+		delta = next
+	}
+	last := 0
+	for i := range delta {
+		if delta[i] > delta[last] {
+			last = i
+		}
+	}
+	if math.IsInf(delta[last], -1) {
+		return nil, math.Inf(-1), nil
+	}
+	states := make([]int, len(obs))
+	states[len(obs)-1] = last
+	for t := len(obs) - 1; t > 0; t-- {
+		states[t-1] = back[t][states[t]]
+	}
+	path = make([]string, len(obs))
+	for t, i := range states {
+		path[t] = m.States[i]
+	}
+	return path, delta[last], nil
+}
+
+// BaumWelch fits the model to unlabeled sequences of symbols, iterating until the
+// total log likelihood improves by less than tol or after maxIter iterations. It
+// returns the total log likelihood of the sequences under the fitted model. Empty
+// sequences and sequences the model can't emit are ignored.
+func (m *HMM) BaumWelch(sequences [][]string, maxIter int, tol float64) (float64, error) {
+	encoded := make([][]int, 0, len(sequences))
+	for _, symbols := range sequences {
+		obs, err := m.observations(symbols)
+		if err != nil {
+			return 0, err
+		}
+		if len(obs) > 0 {
+			encoded = append(encoded, obs)
+		}
+	}
+	n, v := len(m.States), len(m.Symbols)
+	prev := math.Inf(-1)
+	for iter := 0; iter < maxIter; iter++ {
+		initial := make([]float64, n)
+		transitions := newMatrix(n, n)
+		emissions := newMatrix(n, v)
+		logLik := 0.0
+		for _, obs := range encoded {
+			alpha, scale, ok := m.forward(obs)
+			if !ok {
+				continue
+			}
+			for _, c := range scale {
+				logLik += math.Log(c)
+			}
+			beta := m.backward(obs, scale)
+			for t, k := range obs {
+				for i := 0; i < n; i++ {
+					gamma := alpha[t][i] * beta[t][i]
+					if t == 0 {
+						initial[i] += gamma
+					}
+					emissions[i][k] += gamma
+					if t == len(obs)-1 {
+						continue
+					}
+					for j := 0; j < n; j++ {
+						transitions[i][j] += alpha[t][i] * m.Transition[i][j] * m.Emission[j][obs[t+1]] * beta[t+1][j] / scale[t+1]
+					}
+				}
+			}
+		}
+		if logLik-prev < tol {
+			break
+		}
+		prev = logLik
+		if sum(initial) > 0 {
+			m.Initial = normalize(initial)
+		}
+		for i := 0; i < n; i++ {
+			// States never visited keep their parameters
+			if sum(transitions[i]) > 0 {
+				m.Transition[i] = normalize(transitions[i])
+			}
+			if sum(emissions[i]) > 0 {
+				m.Emission[i] = normalize(emissions[i])
+			}
+		}
+	}
+	total := 0.0
+	for _, obs := range encoded {
+		if _, scale, ok := m.forward(obs); ok {
+			for _, c := range scale {
+				total += math.Log(c)
+			}
+		}
+	}
+	return total, nil
+}
+
+func newMatrix(rows, cols int) [][]float64 {
+	m := make([][]float64, rows)
+	for i := range m {
+		m[i] = make([]float64, cols)
+	}
+	return m
+}
+
+func sum(row []float64) float64 {
+	total := 0.0
+	for _, p := range row {
+		total += p
+	}
+	return total
+}
+
+// normalize scales row in place to sum to 1 and returns it
+func normalize(row []float64) []float64 {
+	total := sum(row)
+	if total == 0 {
+		return row
+	}
+	for i := range row {
+		row[i] /= total
+	}
+	return row
+}
@@ -0,0 +1,155 @@
+package hmm
+
+import (
+	"errors"
+	"math"
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// weather is the classic example of a friend's activities depending on the weather
+func weather() *HMM {
+	m := New([]string{"rainy", "sunny"}, []string{"walk", "shop", "clean"})
+	m.Initial = []float64{0.6, 0.4}
+	m.Transition = [][]float64{{0.7, 0.3}, {0.4, 0.6}}
+	m.Emission = [][]float64{{0.1, 0.4, 0.5}, {0.6, 0.3, 0.1}}
+	return m
+}
+
+func TestHMM_Viterbi(t *testing.T) {
+	path, logProb, err := weather().Viterbi([]string{"walk", "shop", "clean"})
+	if err != nil {
+		t.Fatalf("HMM.Viterbi() error = %v", err)
+	}
+	if want := []string{"sunny", "rainy", "rainy"}; !reflect.DeepEqual(path, want) {
+		t.Errorf("HMM.Viterbi() = %v, want %v", path, want)
+	}
+	if want := math.Log(0.01344); math.Abs(logProb-want) > 1e-9 {
+		t.Errorf("HMM.Viterbi() log probability = %v, want %v", logProb, want)
+	}
+	if _, _, err := weather().Viterbi([]string{"swim"}); !errors.Is(err, ErrUnknownSymbol) {
+		t.Errorf("HMM.Viterbi() error = %v, want %v", err, ErrUnknownSymbol)
+	}
+}
+
+func TestHMM_LogLikelihood(t *testing.T) {
+	m := weather()
+	got, err := m.LogLikelihood([]string{"walk", "shop", "clean"})
+	if err != nil {
+		t.Fatalf("HMM.LogLikelihood() error = %v", err)
+	}
+	if want := math.Log(0.033612); math.Abs(got-want) > 1e-9 {
+		t.Errorf("HMM.LogLikelihood() = %v, want %v", got, want)
+	}
+	// Long sequences must not underflow
+	long := strings.Fields(strings.Repeat("walk shop clean ", 1000))
+	if got, _ := m.LogLikelihood(long); math.IsInf(got, 0) || math.IsNaN(got) {
+		t.Errorf("HMM.LogLikelihood() of a long sequence = %v", got)
+	}
+	m.Emission[0][0], m.Emission[0][2] = 0, 0.6
+	m.Emission[1][0], m.Emission[1][1] = 0, 0.9
+	if got, _ := m.LogLikelihood([]string{"walk"}); !math.IsInf(got, -1) {
+		t.Errorf("HMM.LogLikelihood() of an impossible sequence = %v, want -Inf", got)
+	}
+}
+
+func TestHMM_Posterior(t *testing.T) {
+	gamma, err := weather().Posterior([]string{"walk", "shop", "clean"})
+	if err != nil {
+		t.Fatalf("HMM.Posterior() error = %v", err)
+	}
+	for pos, row := range gamma {
+		if math.Abs(row[0]+row[1]-1) > 1e-9 {
+			t.Errorf("posterior at %d sums to %v", pos, row[0]+row[1])
+		}
+	}
+	if gamma[0][1] < gamma[0][0] || gamma[2][0] < gamma[2][1] {
+		t.Errorf("HMM.Posterior() = %v, want sunny first and rainy last", gamma)
+	}
+}
+
+// sample draws a sequence of symbols from a model
+func sample(m *HMM, length int, prng *rand.Rand) []string {
+	draw := func(row []float64) int {
+		r := prng.Float64()
+		for i, p := range row {
+			if r -= p; r < 0 {
+				return i
+			}
+		}
+		return len(row) - 1
+	}
+	symbols := make([]string, length)
+	state := draw(m.Initial)
+	for t := range symbols {
+		symbols[t] = m.Symbols[draw(m.Emission[state])]
+		state = draw(m.Transition[state])
+	}
+	return symbols
+}
+
+func TestHMM_BaumWelch(t *testing.T) {
+	truth := weather()
+	prng := rand.New(rand.NewSource(1))
+	sequences := make([][]string, 50)
+	for i := range sequences {
+		sequences[i] = sample(truth, 20, prng)
+	}
+	m := New(truth.States, truth.Symbols)
+	before := 0.0
+	for _, symbols := range sequences {
+		logLik, _ := m.LogLikelihood(symbols)
+		before += logLik
+	}
+	after, err := m.BaumWelch(sequences, 200, 1e-9)
+	if err != nil {
+		t.Fatalf("HMM.BaumWelch() error = %v", err)
+	}
+	if after <= before {
+		t.Errorf("HMM.BaumWelch() log likelihood = %v, not better than %v before fitting", after, before)
+	}
+	for i := range m.States {
+		if math.Abs(sum(m.Transition[i])-1) > 1e-9 || math.Abs(sum(m.Emission[i])-1) > 1e-9 {
+			t.Errorf("state %d parameters are not distributions", i)
+		}
+	}
+	// The fitted model explains the data at least as well as the one that generated it
+	truthLik := 0.0
+	for _, symbols := range sequences {
+		logLik, _ := truth.LogLikelihood(symbols)
+		truthLik += logLik
+	}
+	if after < truthLik-1 {
+		t.Errorf("HMM.BaumWelch() log likelihood = %v, want about %v or more", after, truthLik)
+	}
+}
+
+func TestHMM_JSON(t *testing.T) {
+	m := weather()
+	data, err := m.MarshalJSON()
+	if err != nil {
+		t.Fatalf("HMM.MarshalJSON() error = %v", err)
+	}
+	var loaded HMM
+	if err := loaded.UnmarshalJSON(data); err != nil {
+		t.Fatalf("HMM.UnmarshalJSON() error = %v", err)
+	}
+	if got, _ := loaded.LogLikelihood([]string{"walk", "shop"}); got == 0 || math.IsInf(got, 0) {
+		t.Errorf("loaded model log likelihood = %v", got)
+	}
+	if !reflect.DeepEqual(loaded.Transition, m.Transition) {
+		t.Errorf("loaded transitions = %v, want %v", loaded.Transition, m.Transition)
+	}
+
+	corrupt := strings.Replace(string(data), "0.7", "0.8", 1)
+	if err := loaded.UnmarshalJSON([]byte(corrupt)); err == nil {
+		t.Error("HMM.UnmarshalJSON() accepted a model with a wrong checksum")
+	}
+	m.Transition[0] = []float64{0.5, 0.6}
+	data, _ = m.MarshalJSON()
+	if err := loaded.UnmarshalJSON(data); err == nil {
+		t.Error("HMM.UnmarshalJSON() accepted a row that isn't a distribution")
+	}
+}
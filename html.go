@@ -0,0 +1,211 @@
+package gomarkov
+
+import (
+	"html/template"
+	"io"
+)
+
+// HTMLOption configures ExportHTML
+type HTMLOption func(*htmlOptions)
+
+type htmlOptions struct {
+	graph dotOptions
+	title string
+}
+
+// HTMLMaxNodes keeps only the n most frequently visited states, 50 by default
+func HTMLMaxNodes(n int) HTMLOption {
+	return func(o *htmlOptions) {
+		o.graph.maxNodes = n
+	}
+}
+
+// HTMLMinProbability drops edges whose transition probability is below p
+func HTMLMinProbability(p float64) HTMLOption {
+	return func(o *htmlOptions) {
+		o.graph.minProbability = p
+	}
+}
+
+// HTMLAround keeps only the states reachable from state in at most depth transitions
+func HTMLAround(state NGram, depth int) HTMLOption {
+	return func(o *htmlOptions) {
+		o.graph.around = state
+		o.graph.depth = depth
+	}
+}
+
+// HTMLTitle sets the title of the page
+func HTMLTitle(title string) HTMLOption {
+	return func(o *htmlOptions) {
+		o.title = title
+	}
+}
+
+type htmlNode struct {
+	ID     string `json:"id"`
+	Label  string `json:"label"`
+	Weight int    `json:"weight"`
+}
+
+type htmlEdge struct {
+	From        string  `json:"from"`
+	To          string  `json:"to"`
+	Count       int     `json:"count"`
+	Probability float64 `json:"probability"`
+}
+
+type htmlPage struct {
+	Title string
+	Nodes []htmlNode
+	Edges []htmlEdge
+}
+
+// ExportHTML writes a standalone HTML page drawing the transition graph of the chain
+// as a force-directed layout, with node sizes by how often states are visited and
+// edge widths by transition probability. The page needs no network access or
+// external tools to view.
+func (chain *Chain) ExportHTML(w io.Writer, opts ...HTMLOption) error {
+	o := htmlOptions{graph: dotOptions{maxNodes: 50}, title: "Markov chain"}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	g, err := chain.transitionGraph()
+	if err != nil {
+		return err
+	}
+	g.apply(o.graph)
+
+	page := htmlPage{Title: o.title, Nodes: []htmlNode{}, Edges: []htmlEdge{}}
+	for _, state := range g.nodes() {
+		page.Nodes = append(page.Nodes, htmlNode{state, g.label(state), g.weight[state]})
+	}
+	for _, e := range g.edges {
+		page.Edges = append(page.Edges, htmlEdge{e.from, e.to, e.count, e.probability})
+	}
+	return htmlTemplate.Execute(w, page)
+}
+
+var htmlTemplate = template.Must(template.New("chain").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+body { margin: 0; font-family: sans-serif; }
+h1 { position: absolute; margin: 12px; font-size: 18px; }
+svg { width: 100vw; height: 100vh; display: block; }
+line { stroke: #8a9bb0; stroke-opacity: 0.7; }
+circle { fill: #3f7fbf; stroke: #fff; stroke-width: 1.5px; cursor: move; }
+text { font-size: 12px; pointer-events: none; }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+<svg id="graph">
+<defs><marker id="arrow" viewBox="0 0 10 10" refX="10" refY="5" markerWidth="6" markerHeight="6" orient="auto-start-reverse"><path d="M 0 0 L 10 5 L 0 10 z" fill="#8a9bb0"/></marker></defs>
+</svg>
+<script>
+const nodes = {{.Nodes}};
+const edges = {{.Edges}};
+const svg = document.getElementById("graph");
+const ns = "http://www.w3.org/2000/svg";
+const width = () => svg.clientWidth, height = () => svg.clientHeight;
+const byID = {};
+const maxWeight = Math.max(1, ...nodes.map(n => n.weight));
+nodes.forEach((n, i) => {
+  const angle = 2 * Math.PI * i / nodes.length;
+  n.x = width() / 2 + 200 * Math.cos(angle);
+  n.y = height() / 2 + 200 * Math.sin(angle);
+  n.vx = n.vy = 0;
+  n.r = 5 + 15 * Math.sqrt(n.weight / maxWeight);
+  byID[n.id] = n;
+});
+edges.forEach(e => {
+  e.line = document.createElementNS(ns, "line");
+  e.line.setAttribute("stroke-width", 0.5 + 5 * e.probability);
+  e.line.setAttribute("marker-end", "url(#arrow)");
+  const title = document.createElementNS(ns, "title");
+  title.textContent = byID[e.from].label + " → " + byID[e.to].label + ": " + e.probability.toFixed(3) + " (" + e.count + ")";
+  e.line.appendChild(title);
+  svg.appendChild(e.line);
+});
+nodes.forEach(n => {
+  n.circle = document.createElementNS(ns, "circle");
+  n.circle.setAttribute("r", n.r);
+  const title = document.createElementNS(ns, "title");
+  title.textContent = n.label + " (" + n.weight + ")";
+  n.circle.appendChild(title);
+  n.text = document.createElementNS(ns, "text");
+  n.text.textContent = n.label;
+  svg.appendChild(n.circle);
+  svg.appendChild(n.text);
+  n.circle.addEventListener("pointerdown", ev => {
+    dragged = n;
+    n.circle.setPointerCapture(ev.pointerId);
+  });
+});
+let dragged = null;
+svg.addEventListener("pointermove", ev => {
+  if (dragged) {
+    dragged.x = ev.offsetX;
+    dragged.y = ev.offsetY;
+    alpha = Math.max(alpha, 0.3);
+  }
+});
+svg.addEventListener("pointerup", () => { dragged = null; });
+let alpha = 1;
+function tick() {
+  for (let i = 0; i < nodes.length; i++) {
+    for (let j = i + 1; j < nodes.length; j++) {
+      const a = nodes[i], b = nodes[j];
+      let dx = b.x - a.x, dy = b.y - a.y;
+      const d2 = Math.max(dx * dx + dy * dy, 1);
+      const f = 3000 / d2;
+      const d = Math.sqrt(d2);
+      dx /= d; dy /= d;
+      a.vx -= f * dx; a.vy -= f * dy;
+      b.vx += f * dx; b.vy += f * dy;
+    }
+  }
+  edges.forEach(e => {
+    const a = byID[e.from], b = byID[e.to];
+    if (a === b) return;
+    const dx = b.x - a.x, dy = b.y - a.y;
+    const d = Math.max(Math.sqrt(dx * dx + dy * dy), 1);
+    const f = 0.02 * (d - 120) * (0.5 + e.probability);
+    a.vx += f * dx / d; a.vy += f * dy / d;
+    b.vx -= f * dx / d; b.vy -= f * dy / d;
+  });
+  nodes.forEach(n => {
+    n.vx += 0.005 * (width() / 2 - n.x);
+    n.vy += 0.005 * (height() / 2 - n.y);
+    if (n !== dragged) {
+      n.x += alpha * Math.max(-20, Math.min(20, n.vx));
+      n.y += alpha * Math.max(-20, Math.min(20, n.vy));
+    }
+    n.vx *= 0.6; n.vy *= 0.6;
+  });
+  edges.forEach(e => {
+    const a = byID[e.from], b = byID[e.to];
+    const dx = b.x - a.x, dy = b.y - a.y;
+    const d = Math.max(Math.sqrt(dx * dx + dy * dy), 1);
+    e.line.setAttribute("x1", a.x + a.r * dx / d);
+    e.line.setAttribute("y1", a.y + a.r * dy / d);
+    e.line.setAttribute("x2", b.x - b.r * dx / d);
+    e.line.setAttribute("y2", b.y - b.r * dy / d);
+  });
+  nodes.forEach(n => {
+    n.circle.setAttribute("cx", n.x);
+    n.circle.setAttribute("cy", n.y);
+    n.text.setAttribute("x", n.x + n.r + 3);
+    n.text.setAttribute("y", n.y + 4);
+  });
+  alpha = Math.max(alpha * 0.99, 0.02);
+  requestAnimationFrame(tick);
+}
+tick();
+</script>
+</body>
+</html>
+`))
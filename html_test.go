@@ -0,0 +1,37 @@
+package gomarkov
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestChain_ExportHTML(t *testing.T) {
+	chain := NewChain(1)
+	chain.Add([]string{"test", "data"})
+	chain.Add([]string{"test", "data"})
+	chain.Add([]string{"test", "</script>"})
+
+	var buf bytes.Buffer
+	if err := chain.ExportHTML(&buf, HTMLTitle("Test <chain>"), HTMLMinProbability(0.5)); err != nil {
+		t.Fatalf("Chain.ExportHTML() error = %v", err)
+	}
+	page := buf.String()
+	for _, want := range []string{
+		"<title>Test &lt;chain&gt;</title>",
+		`{"from":"test","to":"data","count":2,"probability":0.6666666666666666}`,
+	} {
+		if !strings.Contains(page, want) {
+			t.Errorf("Chain.ExportHTML() is missing %q", want)
+		}
+	}
+	if strings.Contains(page, `"to":"</script>"`) {
+		t.Errorf("Chain.ExportHTML() kept an edge below the minimum probability")
+	}
+	if strings.Count(page, "</script>") != 1 {
+		t.Errorf("Chain.ExportHTML() did not escape a token closing the script")
+	}
+	if strings.Contains(page, "http://") && !strings.Contains(page, `"http://www.w3.org/2000/svg"`) {
+		t.Errorf("Chain.ExportHTML() references external resources")
+	}
+}
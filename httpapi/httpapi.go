@@ -0,0 +1,251 @@
+// Package httpapi serves a chain over HTTP with JSON requests and responses:
+//
+//	POST /generate  GenerateRequest -> GenerateResponse
+//	POST /score     ScoreRequest    -> ScoreResponse
+//	GET  /stats                     -> gomarkov.ChainStats
+//	POST /train     TrainRequest    -> TrainResponse, only WithTraining
+//
+// Errors are returned as an ErrorResponse, with status 400 for malformed requests,
+// 422 for requests the chain can't serve, such as unknown n-grams, and 500 otherwise.
+package httpapi
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"net/http"
+
+	"github.com/mb-14/gomarkov"
+)
+
+// GenerateRequest asks for Count sequences continuing from Seed, which is padded
+// with start tokens when shorter than the chain order. Zero values generate one
+// sequence from the start.
+type GenerateRequest struct {
+	Seed      []string `json:"seed,omitempty"`
+	Count     int      `json:"count,omitempty"`
+	MaxLength int      `json:"max_length,omitempty"`
+}
+
+// GenerateResponse holds the generated sequences, without the seed
+type GenerateResponse struct {
+	Sequences [][]string `json:"sequences"`
+}
+
+// ScoreRequest holds the sequences to score
+type ScoreRequest struct {
+	Sequences [][]string `json:"sequences"`
+}
+
+// ScoreResponse holds a score for each requested sequence, in order
+type ScoreResponse struct {
+	Scores []Score `json:"scores"`
+}
+
+// Score is the log probability of a sequence, nil if the chain can't produce it
+type Score struct {
+	LogProbability *float64 `json:"log_probability"`
+}
+
+// TrainRequest holds the sequences to train the chain on
+type TrainRequest struct {
+	Sequences [][]string `json:"sequences"`
+}
+
+// TrainResponse reports how many sequences were added
+type TrainResponse struct {
+	Added int `json:"added"`
+}
+
+// ErrorResponse is the body of every failed request
+type ErrorResponse struct {
+	Error string `json:"error"`
+}
+
+// Option configures a Handler
+type Option func(*Handler)
+
+// WithTraining enables the /train endpoint, letting clients modify the chain
+func WithTraining() Option {
+	return func(h *Handler) {
+		h.training = true
+	}
+}
+
+// WithLimits caps the number of sequences per request and the tokens generated per
+// sequence. The defaults are 100 of each.
+func WithLimits(maxCount, maxLength int) Option {
+	return func(h *Handler) {
+		h.maxCount, h.maxLength = maxCount, maxLength
+	}
+}
+
+// WithMaxBodyBytes caps the size of request bodies, 1 MiB by default
+func WithMaxBodyBytes(n int64) Option {
+	return func(h *Handler) {
+		h.maxBody = n
+	}
+}
+
+// Handler serves a chain. Its endpoints can be mounted separately, or the Handler
+// itself mounted to serve them all.
+type Handler struct {
+	chain     *gomarkov.Chain
+	training  bool
+	maxCount  int
+	maxLength int
+	maxBody   int64
+	mux       *http.ServeMux
+}
+
+// NewHandler returns a Handler serving chain
+func NewHandler(chain *gomarkov.Chain, opts ...Option) *Handler {
+	h := &Handler{chain: chain, maxCount: 100, maxLength: 100, maxBody: 1 << 20}
+	for _, opt := range opts {
+		opt(h)
+	}
+	h.mux = http.NewServeMux()
+	h.mux.HandleFunc("/generate", h.Generate)
+	h.mux.HandleFunc("/score", h.Score)
+	h.mux.HandleFunc("/stats", h.Stats)
+	if h.training {
+		h.mux.HandleFunc("/train", h.Train)
+	}
+	return h
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.mux.ServeHTTP(w, r)
+}
+
+// Generate serves /generate, accepting GET for a single sequence from the start
+func (h *Handler) Generate(w http.ResponseWriter, r *http.Request) {
+	var req GenerateRequest
+	if r.Method != http.MethodGet && !h.decode(w, r, &req) {
+		return
+	}
+	if req.Count <= 0 {
+		req.Count = 1
+	}
+	if req.MaxLength <= 0 || req.MaxLength > h.maxLength {
+		req.MaxLength = h.maxLength
+	}
+	if req.Count > h.maxCount {
+		writeError(w, http.StatusBadRequest, errors.New("Too many sequences requested"))
+		return
+	}
+	seed := gomarkov.FromTokens(req.Seed, h.chain.Order)
+
+	resp := GenerateResponse{Sequences: make([][]string, 0, req.Count)}
+	for i := 0; i < req.Count; i++ {
+		seq, err := h.chain.GenerateSequence(seed, req.MaxLength)
+		if err != nil {
+			writeChainError(w, err)
+			return
+		}
+		if seq == nil {
+			seq = []string{}
+		}
+		resp.Sequences = append(resp.Sequences, seq)
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// Score serves /score
+func (h *Handler) Score(w http.ResponseWriter, r *http.Request) {
+	var req ScoreRequest
+	if !h.post(w, r) || !h.decode(w, r, &req) {
+		return
+	}
+	if len(req.Sequences) > h.maxCount {
+		writeError(w, http.StatusBadRequest, errors.New("Too many sequences to score"))
+		return
+	}
+	resp := ScoreResponse{Scores: make([]Score, len(req.Sequences))}
+	for i, seq := range req.Sequences {
+		logProb, err := h.chain.LogProbability(seq)
+		if err != nil {
+			writeChainError(w, err)
+			return
+		}
+		if !math.IsInf(logProb, -1) {
+			resp.Scores[i].LogProbability = &logProb
+		}
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// Stats serves /stats
+func (h *Handler) Stats(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", http.MethodGet)
+		writeError(w, http.StatusMethodNotAllowed, errors.New("Method not allowed"))
+		return
+	}
+	stats, err := h.chain.Stats()
+	if err != nil {
+		writeChainError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, stats)
+}
+
+// Train serves /train. It is only mounted by the Handler WithTraining, but can be
+// mounted directly.
+func (h *Handler) Train(w http.ResponseWriter, r *http.Request) {
+	var req TrainRequest
+	if !h.post(w, r) || !h.decode(w, r, &req) {
+		return
+	}
+	if len(req.Sequences) > h.maxCount {
+		writeError(w, http.StatusBadRequest, errors.New("Too many sequences to train on"))
+		return
+	}
+	if err := h.chain.AddBatch(req.Sequences); err != nil {
+		writeChainError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, TrainResponse{Added: len(req.Sequences)})
+}
+
+func (h *Handler) post(w http.ResponseWriter, r *http.Request) bool {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		writeError(w, http.StatusMethodNotAllowed, errors.New("Method not allowed"))
+		return false
+	}
+	return true
+}
+
+// decode reads a JSON request body into v, writing an error response if it can't
+func (h *Handler) decode(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, h.maxBody))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return false
+	}
+	return true
+}
+
+// writeChainError responds to an error from the chain, distinguishing requests the
+// chain can't serve from failures of the chain's store
+func writeChainError(w http.ResponseWriter, err error) {
+	status := http.StatusInternalServerError
+	for _, target := range []error{gomarkov.ErrOrderMismatch, gomarkov.ErrUnknownNGram, gomarkov.ErrDeadEnd, gomarkov.ErrEmptyChain} {
+		if errors.Is(err, target) {
+			status = http.StatusUnprocessableEntity
+		}
+	}
+	writeError(w, status, err)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, ErrorResponse{Error: err.Error()})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
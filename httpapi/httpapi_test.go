@@ -0,0 +1,112 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/mb-14/gomarkov"
+)
+
+func testChain() *gomarkov.Chain {
+	chain := gomarkov.NewChain(2)
+	chain.Add([]string{"I", "want", "a", "cheese", "burger"})
+	return chain
+}
+
+func do(t *testing.T, h http.Handler, method, path, body string, resp interface{}) int {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(method, path, strings.NewReader(body)))
+	// Unknown paths get the ServeMux's plain text 404
+	if got := rec.Header().Get("Content-Type"); rec.Code != http.StatusNotFound && got != "application/json" {
+		t.Errorf("%s %s Content-Type = %q", method, path, got)
+	}
+	if resp != nil {
+		if err := json.Unmarshal(rec.Body.Bytes(), resp); err != nil {
+			t.Fatalf("%s %s response %q: %v", method, path, rec.Body.String(), err)
+		}
+	}
+	return rec.Code
+}
+
+func TestHandler_Generate(t *testing.T) {
+	h := NewHandler(testChain())
+	var resp GenerateResponse
+	if code := do(t, h, "POST", "/generate", `{"count":2}`, &resp); code != http.StatusOK {
+		t.Fatalf("POST /generate status = %d", code)
+	}
+	want := [][]string{{"I", "want", "a", "cheese", "burger"}, {"I", "want", "a", "cheese", "burger"}}
+	if !reflect.DeepEqual(resp.Sequences, want) {
+		t.Errorf("POST /generate = %v, want %v", resp.Sequences, want)
+	}
+
+	resp = GenerateResponse{}
+	if code := do(t, h, "POST", "/generate", `{"seed":["cheese"],"max_length":1}`, &resp); code != http.StatusUnprocessableEntity {
+		t.Errorf("POST /generate with an unknown seed status = %d, want 422", code)
+	}
+	if code := do(t, h, "POST", "/generate", `{"seed":["a","cheese"],"max_length":1}`, &resp); code != http.StatusOK ||
+		!reflect.DeepEqual(resp.Sequences, [][]string{{"burger"}}) {
+		t.Errorf("POST /generate with a seed = %d, %v", code, resp.Sequences)
+	}
+	if code := do(t, h, "GET", "/generate", "", &resp); code != http.StatusOK || len(resp.Sequences) != 1 {
+		t.Errorf("GET /generate = %d, %v", code, resp.Sequences)
+	}
+	var errResp ErrorResponse
+	if code := do(t, h, "POST", "/generate", `{"count":1000}`, &errResp); code != http.StatusBadRequest || errResp.Error == "" {
+		t.Errorf("POST /generate over the limit = %d, %+v", code, errResp)
+	}
+	if code := do(t, h, "POST", "/generate", `{"bogus":1}`, &errResp); code != http.StatusBadRequest {
+		t.Errorf("POST /generate with an unknown field status = %d, want 400", code)
+	}
+}
+
+func TestHandler_Score(t *testing.T) {
+	h := NewHandler(testChain())
+	var resp ScoreResponse
+	body := `{"sequences":[["I","want","a","cheese","burger"],["I","need"]]}`
+	if code := do(t, h, "POST", "/score", body, &resp); code != http.StatusOK {
+		t.Fatalf("POST /score status = %d", code)
+	}
+	if len(resp.Scores) != 2 || resp.Scores[0].LogProbability == nil || *resp.Scores[0].LogProbability != 0 ||
+		resp.Scores[1].LogProbability != nil {
+		t.Errorf("POST /score = %+v, want a log probability of 0 then null", resp.Scores)
+	}
+	if code := do(t, h, "GET", "/score", "", nil); code != http.StatusMethodNotAllowed {
+		t.Errorf("GET /score status = %d, want 405", code)
+	}
+}
+
+func TestHandler_Stats(t *testing.T) {
+	h := NewHandler(testChain())
+	var resp gomarkov.ChainStats
+	if code := do(t, h, "GET", "/stats", "", &resp); code != http.StatusOK {
+		t.Fatalf("GET /stats status = %d", code)
+	}
+	if want := (gomarkov.ChainStats{Order: 2, States: 7, Transitions: 7, Vocabulary: 5}); resp != want {
+		t.Errorf("GET /stats = %+v, want %+v", resp, want)
+	}
+}
+
+func TestHandler_Train(t *testing.T) {
+	chain := testChain()
+	body := `{"sequences":[["I","want","a","chilled","sprite"]]}`
+	if code := do(t, NewHandler(chain), "POST", "/train", body, nil); code != http.StatusNotFound {
+		t.Errorf("POST /train without training status = %d, want 404", code)
+	}
+	var resp TrainResponse
+	if code := do(t, NewHandler(chain, WithTraining()), "POST", "/train", body, &resp); code != http.StatusOK || resp.Added != 1 {
+		t.Fatalf("POST /train = %d, %+v", code, resp)
+	}
+	if p, _ := chain.TransitionProbability("chilled", gomarkov.NGram{"want", "a"}); p != 0.5 {
+		t.Errorf("Chain.TransitionProbability() after training = %v, want 0.5", p)
+	}
+	var errResp ErrorResponse
+	limited := NewHandler(chain, WithTraining(), WithLimits(1, 10))
+	if code := do(t, limited, "POST", "/train", `{"sequences":[["a"],["b"]]}`, &errResp); code != http.StatusBadRequest || errResp.Error == "" {
+		t.Errorf("POST /train over the limit = %d, %+v", code, errResp)
+	}
+}
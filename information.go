@@ -0,0 +1,119 @@
+package gomarkov
+
+import "math"
+
+// Information measures how predictable a chain is, in bits, see Chain.Information
+type Information struct {
+	// ConditionalEntropy is the entropy of the next token given the state, averaged
+	// over states weighted by how often they were left in training
+	ConditionalEntropy float64
+	// Perplexity is 2 to the power of ConditionalEntropy, the number of equally
+	// likely tokens the chain chooses between on average
+	Perplexity float64
+	// TokenEntropy is the entropy of the next token regardless of the state
+	TokenEntropy float64
+	// MutualInformation is how much knowing the state reduces the entropy of the next
+	// token, TokenEntropy minus ConditionalEntropy
+	MutualInformation float64
+	// Deterministic is the fraction of transitions made from states with a single
+	// successor, which reproduce the corpus verbatim
+	Deterministic float64
+}
+
+// Information measures the predictability of the chain over all its transitions,
+// ending sequences included but not the padding after the end. Chains of increasing
+// order trained on the same corpus always look more predictable, as each state is
+// seen fewer times; a high Deterministic fraction shows the order has outgrown the
+// corpus. Comparing the LogProbability of held out sequences tells how well they
+// generalize.
+func (chain *Chain) Information() (Information, error) {
+	var total, conditional, deterministic float64
+	marginal := make(map[int]float64)
+	measure := func(each func(visit func(next int, weight float64))) {
+		sum, sumLog, successors := 0.0, 0.0, 0
+		each(func(next int, weight float64) {
+			if weight <= 0 {
+				return
+			}
+			sum += weight
+			sumLog += weight * math.Log2(weight)
+			successors++
+			marginal[next] += weight
+		})
+		if sum == 0 {
+			return
+		}
+		total += sum
+		// sum times the entropy of the row, which sums without normalizing it first
+		conditional += sum*math.Log2(sum) - sumLog
+		if successors == 1 {
+			deterministic += sum
+		}
+	}
+	// States that have already ended are only padding, see ExportMarkovify, and would
+	// make chains of higher order look more predictable than they are
+	var lookupErr error
+	padding := func(current int) bool {
+		key, ok, err := chain.store.LookupIndex(current)
+		if err != nil {
+			lookupErr = err
+			return true
+		}
+		ngram, ok := splitKey(key, chain.Order)
+		return ok && ngram.ended()
+	}
+	var err error
+	if ws, ok := chain.store.(WeightedStore); ok {
+		err = ws.IterateWeights(func(current int, row map[int]float64) bool {
+			if padding(current) {
+				return lookupErr == nil
+			}
+			measure(func(visit func(int, float64)) {
+				for next, weight := range row {
+					visit(next, weight)
+				}
+			})
+			return true
+		})
+	} else {
+		err = chain.iterateRows(func(current int, row rowReader) bool {
+			if padding(current) {
+				return lookupErr == nil
+			}
+			measure(func(visit func(int, float64)) {
+				row.each(func(next, count int) {
+					visit(next, float64(count))
+				})
+			})
+			return true
+		})
+	}
+	if err == nil {
+		err = lookupErr
+	}
+	if err != nil {
+		return Information{}, err
+	}
+	if total == 0 {
+		return Information{}, ErrEmptyChain
+	}
+
+	info := Information{
+		ConditionalEntropy: math.Max(conditional/total, 0),
+		Deterministic:      deterministic / total,
+	}
+	for _, weight := range marginal {
+		p := weight / total
+		info.TokenEntropy -= p * math.Log2(p)
+	}
+	info.Perplexity = math.Exp2(info.ConditionalEntropy)
+	info.MutualInformation = math.Max(info.TokenEntropy-info.ConditionalEntropy, 0)
+	return info, nil
+}
+
+// ConditionalEntropy returns the entropy in bits of the next token given the state,
+// weighted by state frequency, see Information
+func (chain *Chain) ConditionalEntropy() (float64, error) {
+	info, err := chain.Information()
+	return info.ConditionalEntropy, err
+}
@@ -0,0 +1,73 @@
+package gomarkov
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestChain_Information(t *testing.T) {
+	corpus := [][]string{{"a", "b"}, {"a", "c"}}
+	tokenEntropy := 2.0/3*math.Log2(3) + 1.0/3*math.Log2(6)
+	want := Information{
+		ConditionalEntropy: 1.0 / 3,
+		Perplexity:         math.Cbrt(2),
+		TokenEntropy:       tokenEntropy,
+		MutualInformation:  tokenEntropy - 1.0/3,
+		Deterministic:      2.0 / 3,
+	}
+	weighted := NewChain(1, WithFloatWeights())
+	for _, input := range corpus {
+		weighted.AddWeightedFloat(input, 0.5)
+	}
+	unigram := NewChain(0)
+	unigram.AddBatch(corpus)
+	tests := []struct {
+		name  string
+		chain *Chain
+		want  Information
+	}{
+		{"Counts", func() *Chain { c := NewChain(1); c.AddBatch(corpus); return c }(), want},
+		{"Float weights", weighted, want},
+		// The padding after the end adds nothing to measure
+		{"Order 2", func() *Chain { c := NewChain(2); c.AddBatch(corpus); return c }(), want},
+		// A unigram's only state tells nothing about the next token
+		{"Unigram", unigram, Information{
+			ConditionalEntropy: tokenEntropy,
+			Perplexity:         math.Exp2(tokenEntropy),
+			TokenEntropy:       tokenEntropy,
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.chain.Information()
+			if err != nil {
+				t.Fatalf("Chain.Information() error = %v", err)
+			}
+			for _, f := range []struct {
+				name      string
+				got, want float64
+			}{
+				{"ConditionalEntropy", got.ConditionalEntropy, tt.want.ConditionalEntropy},
+				{"Perplexity", got.Perplexity, tt.want.Perplexity},
+				{"TokenEntropy", got.TokenEntropy, tt.want.TokenEntropy},
+				{"MutualInformation", got.MutualInformation, tt.want.MutualInformation},
+				{"Deterministic", got.Deterministic, tt.want.Deterministic},
+			} {
+				if math.Abs(f.got-f.want) > 1e-9 {
+					t.Errorf("Chain.Information().%s = %v, want %v", f.name, f.got, f.want)
+				}
+			}
+		})
+	}
+	for order := 1; order <= 3; order++ {
+		chain := NewChain(order)
+		chain.AddBatch([][]string{{"a"}, {"b"}})
+		if got, err := chain.ConditionalEntropy(); err != nil || math.Abs(got-0.5) > 1e-9 {
+			t.Errorf("Chain.ConditionalEntropy() of order %d = %v, %v, want 0.5, <nil>", order, got, err)
+		}
+	}
+	if _, err := NewChain(1).ConditionalEntropy(); !errors.Is(err, ErrEmptyChain) {
+		t.Errorf("Chain.ConditionalEntropy() of an empty chain error = %v, want ErrEmptyChain", err)
+	}
+}
@@ -0,0 +1,55 @@
+//go:build go1.23
+
+package gomarkov
+
+import "iter"
+
+// Transitions iterates over every transition of the chain, like ForEachTransition.
+// Iteration stops at the first error from the chain's store.
+func (chain *Chain) Transitions() iter.Seq2[NGram, Transition] {
+	return func(yield func(NGram, Transition) bool) {
+		chain.ForEachTransition(yield)
+	}
+}
+
+// States iterates over every state with outgoing transitions, see Transitions
+func (chain *Chain) States() iter.Seq[NGram] {
+	return func(yield func(NGram) bool) {
+		indices, err := chain.rowIndices()
+		if err != nil {
+			return
+		}
+		for _, current := range indices {
+			key, ok, err := chain.store.LookupIndex(current)
+			if err != nil {
+				return
+			}
+			if !ok {
+				continue
+			}
+			if ngram, ok := splitKey(key, chain.Order); ok && !yield(ngram) {
+				return
+			}
+		}
+	}
+}
+
+// GenerateSeq iterates over the tokens generated after seed until the end of the
+// sequence, like GenerateSequence without a length limit. If a token can't be
+// generated, the last pair yielded holds the *SequenceError.
+func (chain *Chain) GenerateSeq(seed NGram) iter.Seq2[string, error] {
+	return func(yield func(string, error) bool) {
+		prng := chain.prng
+		if prng == nil {
+			prng = defaultPrng
+		}
+		stopped := false
+		_, err := chain.generate(seed, 0, prng, func(token string) bool {
+			stopped = !yield(token, nil)
+			return !stopped
+		})
+		if err != nil && !stopped {
+			yield("", err)
+		}
+	}
+}
@@ -0,0 +1,82 @@
+//go:build go1.23
+
+package gomarkov
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestChain_Transitions(t *testing.T) {
+	chain := NewChain(1)
+	chain.Add([]string{"test", "data"})
+	chain.Add([]string{"test", "data"})
+	chain.Add([]string{"test", "node"})
+	got := map[string][]Transition{}
+	for state, transition := range chain.Transitions() {
+		got[state.key()] = append(got[state.key()], transition)
+	}
+	want := map[string][]Transition{
+		StartToken: {{"test", 3, 1}},
+		"test":     {{"data", 2, 2.0 / 3.0}, {"node", 1, 1.0 / 3.0}},
+		"data":     {{EndToken, 2, 1}},
+		"node":     {{EndToken, 1, 1}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Chain.Transitions() = %v, want %v", got, want)
+	}
+
+	states := map[string]bool{}
+	for state := range chain.States() {
+		states[state.key()] = true
+	}
+	if len(states) != len(want) {
+		t.Errorf("Chain.States() = %v, want the states of %v", states, want)
+	}
+	for range chain.States() {
+		break
+	}
+}
+
+func TestChain_GenerateSeq(t *testing.T) {
+	chain := NewChain(2)
+	chain.Add([]string{"I", "want", "a", "cheese", "burger"})
+	var got []string
+	for token, err := range chain.GenerateSeq(NGram{StartToken, StartToken}) {
+		if err != nil {
+			t.Fatalf("Chain.GenerateSeq() error = %v", err)
+		}
+		got = append(got, token)
+	}
+	if want := []string{"I", "want", "a", "cheese", "burger"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Chain.GenerateSeq() = %v, want %v", got, want)
+	}
+
+	got = nil
+	for token := range chain.GenerateSeq(NGram{StartToken, StartToken}) {
+		if got = append(got, token); len(got) == 2 {
+			break
+		}
+	}
+	if len(got) != 2 {
+		t.Errorf("Chain.GenerateSeq() did not stop early, got %v", got)
+	}
+
+	stopped := NewChain(2, WithStopTokens("a"))
+	stopped.Add([]string{"I", "want", "a", "cheese", "burger"})
+	got = nil
+	for token := range stopped.GenerateSeq(NGram{StartToken, StartToken}) {
+		got = append(got, token)
+	}
+	if want := []string{"I", "want", "a"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Chain.GenerateSeq() with a stop token = %v, want %v", got, want)
+	}
+
+	for _, err := range chain.GenerateSeq(NGram{"I", "need"}) {
+		var seqErr *SequenceError
+		if !errors.As(err, &seqErr) || !errors.Is(err, ErrUnknownNGram) {
+			t.Errorf("Chain.GenerateSeq() error = %v, want a SequenceError", err)
+		}
+	}
+}
@@ -0,0 +1,167 @@
+package gomarkov
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// JSONLOption configures TrainJSONL
+type JSONLOption func(*jsonlOptions)
+
+type jsonlOptions struct {
+	tokenize func(string) []string
+	weight   string
+}
+
+// JSONLTokenizer splits the text of each record into tokens, strings.Fields by default
+func JSONLTokenizer(tokenize func(string) []string) JSONLOption {
+	return func(o *jsonlOptions) {
+		o.tokenize = tokenize
+	}
+}
+
+// JSONLWeight trains each record as many times as the non-negative integer in field,
+// see Chain.AddWeighted. Records without the field have a weight of 1.
+func JSONLWeight(field string) JSONLOption {
+	return func(o *jsonlOptions) {
+		o.weight = field
+	}
+}
+
+// jsonlRecord is a decoded record along with the line it was read from
+type jsonlRecord struct {
+	line   int
+	fields map[string]json.RawMessage
+}
+
+// TrainJSONL trains the chain on newline-delimited JSON records, one object per
+// line, streaming them from r. Each record's text is taken from field, which may be
+// a dotted path into nested objects such as "message.text", and tokenized. Blank
+// lines and records without the field, or whose field is null, are skipped.
+func (chain *Chain) TrainJSONL(r io.Reader, field string, opts ...JSONLOption) error {
+	o := newJSONLOptions(opts)
+	return readJSONL(r, func(rec jsonlRecord) error {
+		tokens, weight, ok, err := o.sequence(rec, field)
+		if err != nil || !ok {
+			return err
+		}
+		return chain.AddWeighted(tokens, weight)
+	})
+}
+
+// TrainJSONL trains the set on newline-delimited JSON records like Chain.TrainJSONL,
+// adding each record to the chain of the label in labelField. Labels may be strings
+// or numbers. Records without a label are skipped.
+func (s *ChainSet) TrainJSONL(r io.Reader, field, labelField string, opts ...JSONLOption) error {
+	o := newJSONLOptions(opts)
+	return readJSONL(r, func(rec jsonlRecord) error {
+		raw, ok, err := rec.lookup(labelField)
+		if err != nil || !ok {
+			return err
+		}
+		label, err := rec.label(labelField, raw)
+		if err != nil {
+			return err
+		}
+		tokens, weight, ok, err := o.sequence(rec, field)
+		if err != nil || !ok {
+			return err
+		}
+		return s.Chain(label).AddWeighted(tokens, weight)
+	})
+}
+
+func newJSONLOptions(opts []JSONLOption) jsonlOptions {
+	o := jsonlOptions{tokenize: strings.Fields}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// readJSONL calls fn with every record of r in order, stopping at the first error
+func readJSONL(r io.Reader, fn func(jsonlRecord) error) error {
+	br := bufio.NewReader(r)
+	for line := 1; ; line++ {
+		b, err := br.ReadBytes('\n')
+		if err != nil && err != io.EOF {
+			return err
+		}
+		if b = bytes.TrimSpace(b); len(b) > 0 {
+			rec := jsonlRecord{line: line}
+			if decodeErr := json.Unmarshal(b, &rec.fields); decodeErr != nil {
+				return fmt.Errorf("Line %d: %v", line, decodeErr)
+			}
+			if fnErr := fn(rec); fnErr != nil {
+				return fnErr
+			}
+		}
+		if err == io.EOF {
+			return nil
+		}
+	}
+}
+
+// sequence returns the tokens and weight of a record, with ok false if it has no text
+func (o jsonlOptions) sequence(rec jsonlRecord, field string) (tokens []string, weight int, ok bool, err error) {
+	raw, ok, err := rec.lookup(field)
+	if err != nil || !ok {
+		return nil, 0, false, err
+	}
+	var text string
+	if err := json.Unmarshal(raw, &text); err != nil {
+		return nil, 0, false, fmt.Errorf("Line %d: field %q is not a string", rec.line, field)
+	}
+	weight = 1
+	if o.weight != "" {
+		raw, found, err := rec.lookup(o.weight)
+		if err != nil {
+			return nil, 0, false, err
+		}
+		if found {
+			if weight, err = strconv.Atoi(string(raw)); err != nil || weight < 0 {
+				return nil, 0, false, fmt.Errorf("Line %d: weight %s is not a non-negative integer", rec.line, raw)
+			}
+		}
+	}
+	return o.tokenize(text), weight, true, nil
+}
+
+// lookup returns the value at a dotted path of the record, with ok false if it is
+// missing or null
+func (rec jsonlRecord) lookup(path string) (json.RawMessage, bool, error) {
+	fields := rec.fields
+	parts := strings.Split(path, ".")
+	for i, part := range parts {
+		raw, ok := fields[part]
+		if !ok || string(raw) == "null" {
+			return nil, false, nil
+		}
+		if i == len(parts)-1 {
+			return raw, true, nil
+		}
+		fields = nil
+		if err := json.Unmarshal(raw, &fields); err != nil {
+			return nil, false, fmt.Errorf("Line %d: %q is not an object", rec.line, strings.Join(parts[:i+1], "."))
+		}
+	}
+	return nil, false, nil
+}
+
+// label decodes a string or number label
+func (rec jsonlRecord) label(field string, raw json.RawMessage) (string, error) {
+	var label string
+	if err := json.Unmarshal(raw, &label); err == nil {
+		return label, nil
+	}
+	var number json.Number
+	if err := json.Unmarshal(raw, &number); err == nil {
+		return number.String(), nil
+	}
+	return "", fmt.Errorf("Line %d: label %q is neither a string nor a number", rec.line, field)
+}
@@ -0,0 +1,91 @@
+package gomarkov
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestChain_TrainJSONL(t *testing.T) {
+	input := `{"user": "ann", "msg": {"text": "hello there"}, "n": 2}
+
+{"user": "bob", "msg": {"text": "hello you"}}
+{"user": 7, "msg": {"text": null}}
+{"msg": {"text": "hello there"}, "n": 0}
+`
+	chain := NewChain(1)
+	if err := chain.TrainJSONL(strings.NewReader(input), "msg.text", JSONLWeight("n")); err != nil {
+		t.Fatalf("Chain.TrainJSONL() error = %v", err)
+	}
+	want := NewChain(1)
+	want.AddWeighted([]string{"hello", "there"}, 2)
+	want.Add([]string{"hello", "you"})
+	if got := transitionCounts(t, chain); !reflect.DeepEqual(got, transitionCounts(t, want)) {
+		t.Errorf("Chain.TrainJSONL() counts = %v, want %v", got, transitionCounts(t, want))
+	}
+
+	set := NewChainSet(1)
+	if err := set.TrainJSONL(strings.NewReader(input), "msg.text", "user", JSONLTokenizer(func(s string) []string {
+		return strings.Split(s, "")
+	})); err != nil {
+		t.Fatalf("ChainSet.TrainJSONL() error = %v", err)
+	}
+	if got := set.Labels(); !reflect.DeepEqual(got, []string{"ann", "bob"}) {
+		t.Errorf("ChainSet.Labels() = %v, want [ann bob]", got)
+	}
+	ann, _ := set.Lookup("ann")
+	if p, _ := ann.TransitionProbability("h", NGram{StartToken}); p != 1 {
+		t.Errorf("ann's chain P(h | ^) = %v, want 1", p)
+	}
+	if _, ok := set.Lookup("7"); ok {
+		t.Errorf("ChainSet.TrainJSONL() created a chain for a record without text")
+	}
+}
+
+func TestChain_TrainJSONL_Errors(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{"Malformed", "{\"text\": \"a\"}\n{\"text\": ", "Line 2: "},
+		{"Not a string", `{"text": 3}`, `Line 1: field "text" is not a string`},
+		{"Not an object", `{"text": "a", "meta": [1]}`, `Line 1: "meta" is not an object`},
+		{"Negative weight", `{"text": "a", "meta": {"weight": -1}}`, "Line 1: weight -1 is not a non-negative integer"},
+		{"Fractional weight", `{"text": "a", "meta": {"weight": 1.5}}`, "Line 1: weight 1.5 is not a non-negative integer"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := NewChain(1).TrainJSONL(strings.NewReader(tt.input), "text", JSONLWeight("meta.weight"))
+			if err == nil || !strings.HasPrefix(err.Error(), tt.want) {
+				t.Errorf("Chain.TrainJSONL() error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+	err := NewChainSet(1).TrainJSONL(strings.NewReader(`{"text": "a", "user": true}`), "text", "user")
+	if want := `Line 1: label "user" is neither a string nor a number`; err == nil || err.Error() != want {
+		t.Errorf("ChainSet.TrainJSONL() error = %v, want %q", err, want)
+	}
+}
+
+func TestChain_AddWeighted(t *testing.T) {
+	lengths := NewLengthDistribution()
+	chain := NewChain(2, WithLengthMatching(lengths))
+	if err := chain.AddWeighted([]string{"a", "b"}, 3); err != nil {
+		t.Fatal(err)
+	}
+	chain.AddWeighted([]string{"c"}, 0)
+	want := NewChain(2)
+	for i := 0; i < 3; i++ {
+		want.Add([]string{"a", "b"})
+	}
+	if got := transitionCounts(t, chain); !reflect.DeepEqual(got, transitionCounts(t, want)) {
+		t.Errorf("Chain.AddWeighted() counts = %v, want %v", got, transitionCounts(t, want))
+	}
+	if got := lengths.Counts(); !reflect.DeepEqual(got, map[int]int{2: 3}) {
+		t.Errorf("Chain.AddWeighted() lengths = %v, want map[2:3]", got)
+	}
+	if err := chain.AddWeighted([]string{"a"}, -1); err == nil {
+		t.Errorf("Chain.AddWeighted() with a negative weight succeeded")
+	}
+}
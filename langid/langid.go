@@ -0,0 +1,166 @@
+// Package langid identifies the language of text with a character level chain per
+// language, picking the language whose chain is most likely to produce the text:
+//
+//	c := langid.New(3)
+//	c.Train("en", englishSample)
+//	c.Train("fr", frenchSample)
+//	lang, _, err := c.Classify("où est la bibliothèque")
+//
+// Short texts are harder to tell apart, a sentence or more is usually enough. The
+// langid command trains and saves classifiers from sample files.
+package langid
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/mb-14/gomarkov"
+)
+
+// ErrNoLanguages is returned when classifying before any language was trained
+var ErrNoLanguages = errors.New("Classifier has no languages")
+
+// Score is the mean log probability per character of a text under a language's chain
+type Score struct {
+	Language       string
+	LogProbability float64
+}
+
+// Option configures a Classifier
+type Option func(*Classifier)
+
+// WithFloor sets the probability given to character transitions a language has
+// never seen, 1e-6 by default. It stops a single foreign character from ruling a
+// language out.
+func WithFloor(p float64) Option {
+	return func(c *Classifier) {
+		c.floor = p
+	}
+}
+
+// Classifier holds a character level chain per language. It is safe for concurrent use.
+type Classifier struct {
+	order int
+	floor float64
+	lock  sync.RWMutex
+	langs map[string]*gomarkov.Chain
+}
+
+// New creates a Classifier with chains of the given order, 3 being a good default
+func New(order int, opts ...Option) *Classifier {
+	c := &Classifier{order: order, floor: 1e-6, langs: make(map[string]*gomarkov.Chain)}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// normalize lower cases text and collapses whitespace, so lines and sentences of
+// samples are treated alike
+func normalize(text string) []string {
+	return strings.Split(strings.Join(strings.Fields(strings.ToLower(text)), " "), "")
+}
+
+// Train adds a sample of a language, training each line separately
+func (c *Classifier) Train(lang, sample string) error {
+	var sequences [][]string
+	for _, line := range strings.Split(sample, "\n") {
+		if chars := normalize(line); len(chars) > 0 {
+			sequences = append(sequences, chars)
+		}
+	}
+	c.lock.Lock()
+	chain, ok := c.langs[lang]
+	if !ok {
+		chain = gomarkov.NewChain(c.order)
+		c.langs[lang] = chain
+	}
+	c.lock.Unlock()
+	return chain.AddBatch(sequences)
+}
+
+// Languages returns the trained languages in sorted order
+func (c *Classifier) Languages() []string {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	langs := make([]string, 0, len(c.langs))
+	for lang := range c.langs {
+		langs = append(langs, lang)
+	}
+	sort.Strings(langs)
+	return langs
+}
+
+// Classify returns the most likely language of text, along with the score of every
+// language from most to least likely
+func (c *Classifier) Classify(text string) (string, []Score, error) {
+	chars := normalize(text)
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	if len(c.langs) == 0 {
+		return "", nil, ErrNoLanguages
+	}
+	scores := make([]Score, 0, len(c.langs))
+	for lang, chain := range c.langs {
+		logProb, err := c.score(chain, chars)
+		if err != nil {
+			return "", nil, err
+		}
+		scores = append(scores, Score{lang, logProb})
+	}
+	sort.Slice(scores, func(a, b int) bool {
+		if scores[a].LogProbability == scores[b].LogProbability {
+			return scores[a].Language < scores[b].Language
+		}
+		return scores[a].LogProbability > scores[b].LogProbability
+	})
+	return scores[0].Language, scores, nil
+}
+
+// score returns the mean log probability of the transitions of chars, from the
+// start of a sequence to its end
+func (c *Classifier) score(chain *gomarkov.Chain, chars []string) (float64, error) {
+	total, _, err := chain.FlooredLogProbability(chars, c.floor)
+	if err != nil {
+		return 0, err
+	}
+	return total / float64(len(chars)+1), nil
+}
+
+// classifierJSON is the serialized form of a Classifier
+type classifierJSON struct {
+	Order     int                        `json:"order"`
+	Floor     float64                    `json:"floor"`
+	Languages map[string]*gomarkov.Chain `json:"languages"`
+}
+
+// MarshalJSON encodes the classifier with the chain of each language
+func (c *Classifier) MarshalJSON() ([]byte, error) {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return json.Marshal(classifierJSON{Order: c.order, Floor: c.floor, Languages: c.langs})
+}
+
+// UnmarshalJSON replaces the classifier with a decoded one
+func (c *Classifier) UnmarshalJSON(b []byte) error {
+	var obj classifierJSON
+	if err := json.Unmarshal(b, &obj); err != nil {
+		return err
+	}
+	for lang, chain := range obj.Languages {
+		if chain == nil || chain.Order != obj.Order {
+			return fmt.Errorf("Chain of language %q does not have order %d", lang, obj.Order)
+		}
+	}
+	if obj.Languages == nil {
+		obj.Languages = make(map[string]*gomarkov.Chain)
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.order, c.floor, c.langs = obj.Order, obj.Floor, obj.Languages
+	return nil
+}
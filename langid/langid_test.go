@@ -0,0 +1,84 @@
+package langid
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/mb-14/gomarkov"
+)
+
+var samples = map[string]string{
+	"en": `the quick brown fox jumps over the lazy dog
+where is the library and when does it open
+i would like a cup of coffee with milk please
+the weather is nice today so we are going for a walk
+she has been reading that book for three weeks`,
+	"fr": `le renard brun rapide saute par dessus le chien paresseux
+où est la bibliothèque et quand est-ce qu'elle ouvre
+je voudrais une tasse de café au lait s'il vous plaît
+il fait beau aujourd'hui alors nous allons nous promener
+elle lit ce livre depuis trois semaines`,
+	"de": `der schnelle braune fuchs springt über den faulen hund
+wo ist die bibliothek und wann öffnet sie
+ich hätte gerne eine tasse kaffee mit milch bitte
+das wetter ist heute schön also gehen wir spazieren
+sie liest dieses buch seit drei wochen`,
+}
+
+func trained() *Classifier {
+	c := New(2)
+	for lang, sample := range samples {
+		c.Train(lang, sample)
+	}
+	return c
+}
+
+func TestClassifier_Classify(t *testing.T) {
+	c := trained()
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"the dog is reading a book", "en"},
+		{"je voudrais un livre", "fr"},
+		{"wo ist der hund", "de"},
+		{"THE   Library is OPEN", "en"},
+	}
+	for _, tt := range tests {
+		got, scores, err := c.Classify(tt.text)
+		if err != nil {
+			t.Fatalf("Classifier.Classify() error = %v", err)
+		}
+		if got != tt.want {
+			t.Errorf("Classifier.Classify(%q) = %s with scores %v, want %s", tt.text, got, scores, tt.want)
+		}
+		if len(scores) != 3 || scores[0].Language != got || scores[0].LogProbability < scores[1].LogProbability {
+			t.Errorf("Classifier.Classify(%q) scores = %v", tt.text, scores)
+		}
+	}
+	if _, _, err := New(2).Classify("hello"); !errors.Is(err, ErrNoLanguages) {
+		t.Errorf("Classifier.Classify() error = %v, want %v", err, ErrNoLanguages)
+	}
+}
+
+func TestClassifier_JSON(t *testing.T) {
+	data, err := json.Marshal(trained())
+	if err != nil {
+		t.Fatalf("Classifier.MarshalJSON() error = %v", err)
+	}
+	loaded := New(1)
+	if err := json.Unmarshal(data, loaded); err != nil {
+		t.Fatalf("Classifier.UnmarshalJSON() error = %v", err)
+	}
+	if got := loaded.Languages(); len(got) != 3 || got[0] != "de" {
+		t.Errorf("Classifier.Languages() = %v after loading", got)
+	}
+	if got, _, _ := loaded.Classify("je voudrais un livre"); got != "fr" {
+		t.Errorf("loaded Classifier.Classify() = %s, want fr", got)
+	}
+	chain, _ := gomarkov.NewChain(2).MarshalJSON()
+	if err := loaded.UnmarshalJSON([]byte(`{"order":3,"languages":{"en":` + string(chain) + `}}`)); err == nil {
+		t.Errorf("Classifier.UnmarshalJSON() accepted chains of the wrong order")
+	}
+}
@@ -0,0 +1,163 @@
+package gomarkov
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"sync"
+)
+
+// LoadCompiledLazy returns a read-only chain over a compiled model that reads only the
+// vocabulary and state index up front, fetching rows from r on demand. Up to cacheRows
+// recently used rows are kept in memory, along with the tables sampling them. r may be
+// a file or any other seekable source, such as a ranged reader over object storage.
+func LoadCompiledLazy(r io.ReaderAt, size int64, cacheRows int) (*Chain, error) {
+	header := make([]byte, compiledHeaderSize)
+	if _, err := r.ReadAt(header, 0); err != nil {
+		return nil, err
+	}
+	h, err := parseCompiledHeader(header)
+	if err != nil {
+		return nil, err
+	}
+	if size != int64(h.size()) {
+		return nil, errors.New("Compiled model is truncated")
+	}
+	if err := validateOrder(h.order); err != nil {
+		return nil, err
+	}
+	index := make([]byte, h.colsOffset())
+	if _, err := r.ReadAt(index, 0); err != nil {
+		return nil, err
+	}
+	s, err := newCompiledStore(h, index)
+	if err != nil {
+		return nil, err
+	}
+	lazy := &lazyStore{
+		compiledStore: s,
+		r:             r,
+		colsAt:        int64(h.colsOffset()),
+		countsAt:      int64(h.colsOffset() + 4*h.edges),
+		cacheRows:     cacheRows,
+		cache:         make(map[int]*lazyRow, cacheRows),
+	}
+	return NewChainWithStore(h.order, lazy), nil
+}
+
+// lazyStore serves the state index of a compiled model from memory and reads rows on demand
+type lazyStore struct {
+	*compiledStore
+	r         io.ReaderAt
+	colsAt    int64
+	countsAt  int64
+	cacheRows int
+	cache     map[int]*lazyRow
+	lock      sync.Mutex
+}
+
+// lazyRow is a cached row along with its alias table once it is sampled, so that
+// both are evicted together
+type lazyRow struct {
+	counts map[int]int
+	dist   distribution
+}
+
+func (l *lazyStore) GetRow(current int) (map[int]int, error) {
+	row, err := l.row(current)
+	if err != nil || row == nil {
+		return nil, err
+	}
+	return row.counts, nil
+}
+
+// row returns a row from the cache, reading it and caching it if it isn't there
+func (l *lazyStore) row(current int) (*lazyRow, error) {
+	if current < 0 || current >= l.states {
+		return nil, nil
+	}
+	l.lock.Lock()
+	row, ok := l.cache[current]
+	l.lock.Unlock()
+	if ok {
+		return row, nil
+	}
+
+	start, end := l.rowOffset(current), l.rowOffset(current+1)
+	buf := make([]byte, 8*(end-start))
+	cols, counts := buf[:4*(end-start)], buf[4*(end-start):]
+	if _, err := l.r.ReadAt(cols, l.colsAt+int64(4*start)); err != nil {
+		return nil, err
+	}
+	if _, err := l.r.ReadAt(counts, l.countsAt+int64(4*start)); err != nil {
+		return nil, err
+	}
+	row = &lazyRow{counts: make(map[int]int, end-start)}
+	for i := 0; i < end-start; i++ {
+		row.counts[int(binary.LittleEndian.Uint32(cols[4*i:]))] = int(binary.LittleEndian.Uint32(counts[4*i:]))
+	}
+
+	if l.cacheRows > 0 {
+		l.lock.Lock()
+		if len(l.cache) >= l.cacheRows {
+			// Evict an arbitrary row, hot rows are quickly brought back
+			for evict := range l.cache {
+				delete(l.cache, evict)
+				break
+			}
+		}
+		l.cache[current] = row
+		l.lock.Unlock()
+	}
+	return row, nil
+}
+
+func (l *lazyStore) IterateRows(fn func(current int, row map[int]int) bool) error {
+	for current := 0; current < l.states; current++ {
+		if l.rowOffset(current) == l.rowOffset(current+1) {
+			continue
+		}
+		row, err := l.GetRow(current)
+		if err != nil {
+			return err
+		}
+		if !fn(current, row) {
+			break
+		}
+	}
+	return nil
+}
+
+// distribution keeps alias tables with their cached rows rather than in the
+// compiledStore's aliasCache, which never evicts them
+func (l *lazyStore) distribution(current int) (distribution, error) {
+	row, err := l.row(current)
+	if err != nil {
+		return nil, err
+	}
+	if row == nil {
+		return newAliasTable(nil), nil
+	}
+	l.lock.Lock()
+	dist := row.dist
+	l.lock.Unlock()
+	if dist == nil {
+		dist = newAliasTable(row.counts)
+		l.lock.Lock()
+		row.dist = dist
+		l.lock.Unlock()
+	}
+	return dist, nil
+}
+
+func (l *lazyStore) snapshot() Store {
+	return l
+}
+
+// Close closes the underlying reader if it is an io.Closer
+func (l *lazyStore) Close() error {
+	if closer, ok := l.r.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
@@ -0,0 +1,107 @@
+package gomarkov
+
+import (
+	"bytes"
+	"io"
+	"strconv"
+	"testing"
+)
+
+// countingReaderAt records how many reads hit the underlying data
+type countingReaderAt struct {
+	r     io.ReaderAt
+	reads int
+}
+
+func (c *countingReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	c.reads++
+	return c.r.ReadAt(p, off)
+}
+
+func TestLoadCompiledLazy(t *testing.T) {
+	var buf bytes.Buffer
+	if err := compiledTestChain().Compile(&buf); err != nil {
+		t.Fatalf("Chain.Compile() error = %v", err)
+	}
+	r := &countingReaderAt{r: bytes.NewReader(buf.Bytes())}
+	chain, err := LoadCompiledLazy(r, int64(buf.Len()), 1)
+	if err != nil {
+		t.Fatalf("LoadCompiledLazy() error = %v", err)
+	}
+	loadReads := r.reads
+
+	tests := []struct {
+		name      string
+		next      string
+		current   NGram
+		want      float64
+		wantReads int
+	}{
+		{"Row read on demand", "data", NGram{"test"}, 2.0 / 3.0, 2},
+		{"Cached row", "node", NGram{"test"}, 1.0 / 3.0, 0},
+		{"Evicting row", "test", NGram{StartToken}, 1, 2},
+		{"Unknown state", "test", NGram{"unknown"}, 0, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before := r.reads
+			got, err := chain.TransitionProbability(tt.next, tt.current)
+			if err != nil {
+				t.Fatalf("Chain.TransitionProbability() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Chain.TransitionProbability() = %v, want %v", got, tt.want)
+			}
+			if reads := r.reads - before; reads != tt.wantReads {
+				t.Errorf("Chain.TransitionProbability() made %d reads, want %d", reads, tt.wantReads)
+			}
+		})
+	}
+	if loadReads != 2 {
+		t.Errorf("LoadCompiledLazy() made %d reads, want 2", loadReads)
+	}
+
+	data, err := chain.MarshalJSON()
+	if err != nil {
+		t.Fatalf("Chain.MarshalJSON() error = %v", err)
+	}
+	loaded := NewChain(1)
+	if err := loaded.UnmarshalJSON(data); err != nil {
+		t.Fatalf("Chain.UnmarshalJSON() error = %v", err)
+	}
+}
+
+func TestLoadCompiledLazy_CacheBound(t *testing.T) {
+	trained := NewChain(1)
+	for i := 0; i < 100; i++ {
+		trained.Add([]string{strconv.Itoa(i), strconv.Itoa(i + 1)})
+	}
+	var buf bytes.Buffer
+	if err := trained.Compile(&buf); err != nil {
+		t.Fatalf("Chain.Compile() error = %v", err)
+	}
+	chain, err := LoadCompiledLazy(bytes.NewReader(buf.Bytes()), int64(buf.Len()), 4)
+	if err != nil {
+		t.Fatalf("LoadCompiledLazy() error = %v", err)
+	}
+	for i := 0; i < 100; i++ {
+		if _, err := chain.Generate(NGram{strconv.Itoa(i)}); err != nil {
+			t.Fatalf("Chain.Generate() error = %v", err)
+		}
+	}
+	l := chain.store.(*lazyStore)
+	if len(l.cache) > 4 {
+		t.Errorf("lazy store cached %d rows, want at most 4", len(l.cache))
+	}
+	if len(l.aliases.tables) != 0 {
+		t.Errorf("lazy store kept %d alias tables outside its row cache", len(l.aliases.tables))
+	}
+}
+
+func TestLoadCompiledLazy_Truncated(t *testing.T) {
+	var buf bytes.Buffer
+	compiledTestChain().Compile(&buf)
+	if _, err := LoadCompiledLazy(bytes.NewReader(buf.Bytes()), int64(buf.Len()-4), 0); err == nil {
+		t.Errorf("LoadCompiledLazy() expected an error for a wrong size")
+	}
+}
@@ -0,0 +1,181 @@
+package gomarkov
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+)
+
+// LengthDistribution is a histogram of sequence lengths in tokens. It is safe for
+// concurrent use.
+type LengthDistribution struct {
+	lock   sync.Mutex
+	counts []int
+	// tails[n] is the number of sequences at least n tokens long, rebuilt when nil
+	tails []int
+}
+
+// NewLengthDistribution creates an empty LengthDistribution
+func NewLengthDistribution() *LengthDistribution {
+	return &LengthDistribution{}
+}
+
+// Observe records a sequence of n tokens
+func (d *LengthDistribution) Observe(n int) {
+	d.observe(n, 1)
+}
+
+// observe records count sequences of n tokens
+func (d *LengthDistribution) observe(n, count int) {
+	if n < 0 {
+		return
+	}
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	if n >= len(d.counts) {
+		d.counts = append(d.counts, make([]int, n+1-len(d.counts))...)
+	}
+	d.counts[n] += count
+	d.tails = nil
+}
+
+// Counts returns how many sequences of each length have been recorded
+func (d *LengthDistribution) Counts() map[int]int {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	counts := make(map[int]int)
+	for n, count := range d.counts {
+		if count > 0 {
+			counts[n] = count
+		}
+	}
+	return counts
+}
+
+// Total returns the number of sequences recorded
+func (d *LengthDistribution) Total() int {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	total := 0
+	for _, count := range d.counts {
+		total += count
+	}
+	return total
+}
+
+// hazard returns the probability of a sequence ending after n tokens given that it
+// has n, 1 beyond the longest sequence recorded, with ok false if nothing has been
+// recorded
+func (d *LengthDistribution) hazard(n int) (p float64, ok bool) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	if d.tails == nil {
+		d.tails = make([]int, len(d.counts)+1)
+		for i := len(d.counts) - 1; i >= 0; i-- {
+			d.tails[i] = d.tails[i+1] + d.counts[i]
+		}
+	}
+	if d.tails[0] == 0 {
+		return 0, false
+	}
+	if n >= len(d.counts) || d.tails[n] == 0 {
+		return 1, true
+	}
+	return float64(d.counts[n]) / float64(d.tails[n]), true
+}
+
+// MarshalJSON encodes the distribution as its counts keyed by length
+func (d *LengthDistribution) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.Counts())
+}
+
+// UnmarshalJSON replaces the distribution with decoded counts
+func (d *LengthDistribution) UnmarshalJSON(b []byte) error {
+	var counts map[int]int
+	if err := json.Unmarshal(b, &counts); err != nil {
+		return err
+	}
+	max := -1
+	for n, count := range counts {
+		if n < 0 || count < 0 {
+			return fmt.Errorf("Invalid count %d of sequences of length %d", count, n)
+		}
+		if n > max {
+			max = n
+		}
+	}
+	dense := make([]int, max+1)
+	for n, count := range counts {
+		dense[n] = count
+	}
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	d.counts, d.tails = dense, nil
+	return nil
+}
+
+// observing reports whether trained sequences must be passed to observeSequences
+func (chain *Chain) observing() bool {
+	return chain.lengths != nil || chain.novelty != nil || chain.checkpoints != nil
+}
+
+// observeSequences records sequences, each seen weight times, for length matching,
+// novelty filtering and checkpointing once they have been trained on
+func (chain *Chain) observeSequences(err *error, weight int, inputs ...[]string) {
+	if *err != nil {
+		return
+	}
+	if chain.checkpoints != nil {
+		defer chain.checkpoints.trained(chain, weight*len(inputs))
+	}
+	for _, input := range inputs {
+		if chain.lengths != nil {
+			chain.lengths.observe(len(input), weight)
+		}
+		if chain.novelty != nil {
+			chain.novelty.Add(input)
+		}
+	}
+}
+
+// endsAt decides whether a sequence of n tokens from current ends, with the
+// probability that sequences of the training corpus that long ended. It only ends
+// where the chain can, and always ends where the chain must.
+func (chain *Chain) endsAt(current NGram, n int, prng PRNG) (end, decided bool, err error) {
+	p, ok := chain.lengths.hazard(n)
+	if !ok {
+		return false, false, nil
+	}
+	pEnd, err := chain.TransitionProbability(EndToken, current)
+	if err != nil || pEnd == 0 {
+		return false, false, err
+	}
+	if pEnd == 1 {
+		return true, true, nil
+	}
+	return uniform(prng) < p, true, nil
+}
+
+// nextMatchingLength draws the next token like next, ending sequences to match the
+// chain's length distribution. n is the number of tokens so far.
+func (chain *Chain) nextMatchingLength(current NGram, n int, prng PRNG) (string, error) {
+	end, decided, err := chain.endsAt(current, n, prng)
+	if err != nil {
+		return "", err
+	}
+	if !decided {
+		return chain.next(current, prng)
+	}
+	if end {
+		return EndToken, nil
+	}
+	// Draw until something other than the end comes up, ending if the chain ends
+	// states like this one too reliably to get anything else
+	for i := 0; i < 32; i++ {
+		next, err := chain.next(current, prng)
+		if err != nil || next != EndToken {
+			return next, err
+		}
+	}
+	return EndToken, nil
+}
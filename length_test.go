@@ -0,0 +1,69 @@
+package gomarkov
+
+import (
+	"encoding/json"
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestWithLengthMatching(t *testing.T) {
+	lengths := NewLengthDistribution()
+	chain := NewChain(1, WithLengthMatching(lengths))
+	plain := NewChain(1)
+	for i := 0; i < 10; i++ {
+		chain.Add(strings.Fields("a a a a a"))
+		plain.Add(strings.Fields("a a a a a"))
+	}
+	chain.AddBatch([][]string{strings.Fields("a a a a a")})
+	if got, want := lengths.Counts(), map[int]int{5: 11}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("LengthDistribution.Counts() = %v, want %v", got, want)
+	}
+
+	// Ending after any a, the plain chain produces lengths from 1 up, while every
+	// training sequence had 5
+	prng := rand.New(rand.NewSource(1))
+	seen := make(map[int]bool)
+	for i := 0; i < 200; i++ {
+		got, err := chain.GenerateSequenceDeterministic(NGram{StartToken}, 0, prng)
+		if err != nil {
+			t.Fatalf("Chain.GenerateSequence() error = %v", err)
+		}
+		if len(got) != 5 {
+			t.Fatalf("Chain.GenerateSequence() = %d tokens, want 5", len(got))
+		}
+		got, _ = plain.GenerateSequenceDeterministic(NGram{StartToken}, 0, prng)
+		seen[len(got)] = true
+	}
+	if len(seen) < 3 {
+		t.Errorf("plain chain generated lengths %v, want them to vary", seen)
+	}
+
+	// Seeds part way through count towards the length
+	got, err := chain.GenerateSequenceDeterministic(NGram{"a"}, 0, prng)
+	if err != nil || len(got) != 4 {
+		t.Errorf("Chain.GenerateSequence(a) = %v, %v, want 4 tokens", got, err)
+	}
+}
+
+func TestLengthDistribution_JSON(t *testing.T) {
+	d := NewLengthDistribution()
+	for _, n := range []int{3, 3, 7, 0} {
+		d.Observe(n)
+	}
+	b, err := json.Marshal(d)
+	if err != nil {
+		t.Fatalf("LengthDistribution.MarshalJSON() error = %v", err)
+	}
+	decoded := NewLengthDistribution()
+	if err := json.Unmarshal(b, decoded); err != nil {
+		t.Fatalf("LengthDistribution.UnmarshalJSON() error = %v", err)
+	}
+	if !reflect.DeepEqual(decoded.Counts(), d.Counts()) || decoded.Total() != 4 {
+		t.Errorf("decoded counts %v, want %v", decoded.Counts(), d.Counts())
+	}
+	if err := json.Unmarshal([]byte(`{"-1": 2}`), decoded); err == nil {
+		t.Error("LengthDistribution.UnmarshalJSON() accepted a negative length")
+	}
+}
@@ -0,0 +1,142 @@
+package gomarkov
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+)
+
+// Boundary tokens used by markovify in place of StartToken and EndToken
+const (
+	markovifyBegin = "___BEGIN__"
+	markovifyEnd   = "___END__"
+)
+
+type markovifyText struct {
+	StateSize int             `json:"state_size"`
+	Chain     json.RawMessage `json:"chain"`
+}
+
+// ImportMarkovify builds a chain from a model exported by Python's markovify, either
+// the output of Chain.to_json() or of Text.to_json(), which embeds the chain.
+func ImportMarkovify(r io.Reader, order int) (*Chain, error) {
+	if err := validateOrder(order); err != nil {
+		return nil, err
+	}
+	var raw json.RawMessage
+	if err := json.NewDecoder(r).Decode(&raw); err != nil {
+		return nil, err
+	}
+	raw = bytes.TrimSpace(raw)
+	if len(raw) > 0 && raw[0] == '{' {
+		var text markovifyText
+		if err := json.Unmarshal(raw, &text); err != nil {
+			return nil, err
+		}
+		if text.StateSize != 0 && text.StateSize != order {
+			return nil, fmt.Errorf("Markovify state size %d does not match chain order %d", text.StateSize, order)
+		}
+		raw = bytes.TrimSpace(text.Chain)
+		// Text.to_json() stores the chain as a JSON encoded string
+		if len(raw) > 0 && raw[0] == '"' {
+			var encoded string
+			if err := json.Unmarshal(raw, &encoded); err != nil {
+				return nil, err
+			}
+			raw = json.RawMessage(encoded)
+		}
+	}
+
+	var items [][2]json.RawMessage
+	if err := json.Unmarshal(raw, &items); err != nil {
+		return nil, err
+	}
+	chain := NewChain(order)
+	for _, item := range items {
+		var state []string
+		var row map[string]int
+		if err := json.Unmarshal(item[0], &state); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(item[1], &row); err != nil {
+			return nil, err
+		}
+		if len(state) != order {
+			return nil, ErrOrderMismatch
+		}
+		current := make(NGram, order)
+		for i, token := range state {
+			current[i] = fromMarkovify(token)
+		}
+		for next, count := range row {
+			if err := chain.addEndTransition(current, fromMarkovify(next), count); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return chain, nil
+}
+
+// ExportMarkovify writes the chain in the format of markovify's Chain.to_json(), which
+// can be loaded in Python with markovify.Chain.from_json(). The trailing end token
+// states markovify doesn't use are dropped.
+func (chain *Chain) ExportMarkovify(w io.Writer) error {
+	spoolMap, freqMat, err := chain.exportCounts("markovify models")
+	if err != nil {
+		return err
+	}
+	states := make(map[int]string, len(spoolMap))
+	for state, index := range spoolMap {
+		states[index] = state
+	}
+	keys := make([]string, 0, len(freqMat))
+	rows := make(map[string]sparseArray, len(freqMat))
+	for current, row := range freqMat {
+		keys = append(keys, states[current])
+		rows[states[current]] = row
+	}
+	sort.Strings(keys)
+
+	items := make([][2]interface{}, 0, len(keys))
+	for _, key := range keys {
+		current, ok := splitKey(key, chain.Order)
+		if !ok {
+			return fmt.Errorf("Cannot split state %q into %d tokens", key, chain.Order)
+		}
+		if current.ended() {
+			continue
+		}
+		state := make([]string, len(current))
+		for i, token := range current {
+			state[i] = toMarkovify(token)
+		}
+		row := make(map[string]int, len(rows[key]))
+		for next, count := range rows[key] {
+			row[toMarkovify(states[next])] = count
+		}
+		items = append(items, [2]interface{}{state, row})
+	}
+	return json.NewEncoder(w).Encode(items)
+}
+
+func toMarkovify(token string) string {
+	switch token {
+	case StartToken:
+		return markovifyBegin
+	case EndToken:
+		return markovifyEnd
+	}
+	return token
+}
+
+func fromMarkovify(token string) string {
+	switch token {
+	case markovifyBegin:
+		return StartToken
+	case markovifyEnd:
+		return EndToken
+	}
+	return token
+}
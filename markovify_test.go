@@ -0,0 +1,108 @@
+package gomarkov
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// transitionCounts resolves a chain's rows into state -> next token -> count
+func transitionCounts(t *testing.T, chain *Chain) map[string]map[string]int {
+	spoolMap, freqMat, err := chain.export()
+	if err != nil {
+		t.Fatalf("Chain.export() error = %v", err)
+	}
+	states := make(map[int]string, len(spoolMap))
+	for state, index := range spoolMap {
+		states[index] = state
+	}
+	counts := make(map[string]map[string]int)
+	for current, row := range freqMat {
+		counts[states[current]] = make(map[string]int)
+		for next, count := range row {
+			counts[states[current]][states[next]] = count
+		}
+	}
+	return counts
+}
+
+func TestImportMarkovify(t *testing.T) {
+	trained := NewChain(2)
+	trained.Add([]string{"the", "cat"})
+	trained.Add([]string{"the", "dog"})
+
+	chainJSON := `[[["___BEGIN__", "___BEGIN__"], {"the": 2}], [["___BEGIN__", "the"], {"cat": 1, "dog": 1}], [["the", "cat"], {"___END__": 1}], [["the", "dog"], {"___END__": 1}]]`
+	textJSON := `{"state_size": 2, "chain": "` + strings.ReplaceAll(chainJSON, `"`, `\"`) + `", "parsed_sentences": null}`
+
+	tests := []struct {
+		name    string
+		input   string
+		order   int
+		wantErr bool
+	}{
+		{"Chain JSON", chainJSON, 2, false},
+		{"Text JSON", textJSON, 2, false},
+		{"Order mismatch", chainJSON, 1, true},
+		{"Text order mismatch", textJSON, 3, true},
+		{"Invalid json", `[[`, 2, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chain, err := ImportMarkovify(strings.NewReader(tt.input), tt.order)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ImportMarkovify() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			got, want := transitionCounts(t, chain), transitionCounts(t, trained)
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("ImportMarkovify() = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestChain_ExportMarkovify(t *testing.T) {
+	tests := []struct {
+		name  string
+		order int
+		data  [][]string
+		want  string
+	}{
+		{"Empty chain", 1, [][]string{}, "[]\n"},
+		{"Order 1", 1, [][]string{{"the", "cat"}}, `[[["___BEGIN__"],{"the":1}],[["cat"],{"___END__":1}],[["the"],{"cat":1}]]` + "\n"},
+		{"Order 2", 2, [][]string{{"the", "cat"}, {"the", "dog"}}, `[[["___BEGIN__","___BEGIN__"],{"the":2}],[["___BEGIN__","the"],{"cat":1,"dog":1}],[["the","cat"],{"___END__":1}],[["the","dog"],{"___END__":1}]]` + "\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chain := NewChain(tt.order)
+			for _, data := range tt.data {
+				chain.Add(data)
+			}
+			var buf strings.Builder
+			if err := chain.ExportMarkovify(&buf); err != nil {
+				t.Fatalf("Chain.ExportMarkovify() error = %v", err)
+			}
+			if buf.String() != tt.want {
+				t.Errorf("Chain.ExportMarkovify() = %v, want %v", buf.String(), tt.want)
+			}
+
+			imported, err := ImportMarkovify(strings.NewReader(buf.String()), tt.order)
+			if err != nil {
+				t.Fatalf("ImportMarkovify() error = %v", err)
+			}
+			if got, want := transitionCounts(t, imported), transitionCounts(t, chain); !reflect.DeepEqual(got, want) {
+				t.Errorf("ImportMarkovify(ExportMarkovify()) = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestChain_ExportMarkovify_AmbiguousState(t *testing.T) {
+	chain := NewChain(2)
+	chain.Add([]string{"snake_case", "word"})
+	if err := chain.ExportMarkovify(&strings.Builder{}); err == nil {
+		t.Errorf("Chain.ExportMarkovify() expected an error for ambiguous states")
+	}
+}
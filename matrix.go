@@ -0,0 +1,89 @@
+package gomarkov
+
+import "sort"
+
+// TransitionMatrix is the row-normalized transition matrix of a chain, with entry
+// (i, j) the probability of moving from state i to state j, the state obtained by
+// shifting the next token into state i. It is held in coordinate (COO) sparse form,
+// and Dense converts it for numerical libraries such as gonum/mat:
+//
+//	m, _ := chain.TransitionMatrix()
+//	n := len(m.States)
+//	p := mat.NewDense(n, n, m.Dense())
+//
+// Rows of states without transitions, such as the end of a sequence, are zero
+// unless MakeAbsorbing is called.
+type TransitionMatrix struct {
+	// States orders the rows and columns, sorted by their tokens
+	States []NGram
+	// Rows, Cols and Values hold the non-zero entries, sorted by row then column
+	Rows   []int
+	Cols   []int
+	Values []float64
+}
+
+// TransitionMatrix returns the transition matrix of the chain
+func (chain *Chain) TransitionMatrix() (*TransitionMatrix, error) {
+	g, err := chain.transitionGraph()
+	if err != nil {
+		return nil, err
+	}
+	nodes := g.nodes()
+	index := make(map[string]int, len(nodes))
+	m := &TransitionMatrix{
+		States: make([]NGram, len(nodes)),
+		Rows:   make([]int, len(g.edges)),
+		Cols:   make([]int, len(g.edges)),
+		Values: make([]float64, len(g.edges)),
+	}
+	for i, state := range nodes {
+		index[state] = i
+		m.States[i], _ = splitKey(state, chain.Order)
+	}
+	// Edges are sorted by state key, so entries come out sorted by row and column
+	for i, e := range g.edges {
+		m.Rows[i], m.Cols[i], m.Values[i] = index[e.from], index[e.to], e.probability
+	}
+	return m, nil
+}
+
+// Index returns the row and column of a state, with ok false if the matrix doesn't have it
+func (m *TransitionMatrix) Index(state NGram) (i int, ok bool) {
+	key := state.key()
+	i = sort.Search(len(m.States), func(i int) bool {
+		return m.States[i].key() >= key
+	})
+	return i, i < len(m.States) && m.States[i].key() == key
+}
+
+// Dense returns the matrix as a row-major slice of len(States)² entries
+func (m *TransitionMatrix) Dense() []float64 {
+	n := len(m.States)
+	dense := make([]float64, n*n)
+	for k, v := range m.Values {
+		dense[m.Rows[k]*n+m.Cols[k]] = v
+	}
+	return dense
+}
+
+// MakeAbsorbing gives every state without transitions a transition to itself with
+// probability 1, making the matrix stochastic
+func (m *TransitionMatrix) MakeAbsorbing() {
+	hasRow := make([]bool, len(m.States))
+	for _, row := range m.Rows {
+		hasRow[row] = true
+	}
+	rows := make([]int, 0, len(m.Rows))
+	cols := make([]int, 0, len(m.Cols))
+	values := make([]float64, 0, len(m.Values))
+	k := 0
+	for i := range m.States {
+		for ; k < len(m.Rows) && m.Rows[k] == i; k++ {
+			rows, cols, values = append(rows, i), append(cols, m.Cols[k]), append(values, m.Values[k])
+		}
+		if !hasRow[i] {
+			rows, cols, values = append(rows, i), append(cols, i), append(values, 1)
+		}
+	}
+	m.Rows, m.Cols, m.Values = rows, cols, values
+}
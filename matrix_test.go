@@ -0,0 +1,49 @@
+package gomarkov
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestChain_TransitionMatrix(t *testing.T) {
+	chain := NewChain(1)
+	chain.Add([]string{"a", "b"})
+	chain.Add([]string{"a", "a"})
+
+	m, err := chain.TransitionMatrix()
+	if err != nil {
+		t.Fatalf("Chain.TransitionMatrix() error = %v", err)
+	}
+	// States sort as $, ^, a, b
+	wantStates := []NGram{{EndToken}, {StartToken}, {"a"}, {"b"}}
+	if !reflect.DeepEqual(m.States, wantStates) {
+		t.Fatalf("TransitionMatrix.States = %v, want %v", m.States, wantStates)
+	}
+	third := 1.0 / 3
+	wantDense := []float64{
+		0, 0, 0, 0,
+		0, 0, 1, 0,
+		third, 0, third, third,
+		1, 0, 0, 0,
+	}
+	if got := m.Dense(); !reflect.DeepEqual(got, wantDense) {
+		t.Errorf("TransitionMatrix.Dense() = %v, want %v", got, wantDense)
+	}
+	if !reflect.DeepEqual(m.Rows, []int{1, 2, 2, 2, 3}) || !reflect.DeepEqual(m.Cols, []int{2, 0, 2, 3, 0}) {
+		t.Errorf("TransitionMatrix entries are at rows %v and columns %v", m.Rows, m.Cols)
+	}
+	if i, ok := m.Index(NGram{"a"}); i != 2 || !ok {
+		t.Errorf("TransitionMatrix.Index() = %d, %v, want 2", i, ok)
+	}
+	if _, ok := m.Index(NGram{"c"}); ok {
+		t.Errorf("TransitionMatrix.Index() found a missing state")
+	}
+
+	m.MakeAbsorbing()
+	if got := m.Dense(); got[0] != 1 || !reflect.DeepEqual(got[1:], wantDense[1:]) {
+		t.Errorf("TransitionMatrix.MakeAbsorbing() gives %v", got)
+	}
+	if !reflect.DeepEqual(m.Rows, []int{0, 1, 2, 2, 2, 3}) {
+		t.Errorf("TransitionMatrix.MakeAbsorbing() rows = %v, want them sorted", m.Rows)
+	}
+}
@@ -0,0 +1,73 @@
+package gomarkov
+
+import "time"
+
+// Instrumentation receives measurements of a chain's activity, see WithInstrumentation.
+// Its methods are called inline from whichever goroutine used the chain, so they must
+// be safe for concurrent use and return quickly.
+type Instrumentation interface {
+	// ObserveTrain is called after every training call returns: Add, AddWeighted,
+	// AddWeightedFloat, AddBatch and AddParallel, and once per sequence of TrainMixture
+	ObserveTrain(TrainEvent)
+	// ObserveGenerate is called after Generate, GenerateDeterministic, GenerateSequence,
+	// GenerateSequenceDeterministic, GenerateSeq, GenerateConstrained or BestSequence
+	// returns
+	ObserveGenerate(GenerateEvent)
+	// ObserveScore is called after LogProbability or FlooredLogProbability returns
+	ObserveScore(ScoreEvent)
+}
+
+// TrainEvent describes a call adding sequences to a chain
+type TrainEvent struct {
+	// Sequences and Transitions are the number of sequences and transitions in the
+	// call, whether or not they were all added
+	Sequences   int
+	Transitions int
+	// States is the number of states in the store once the call returned, or -1 if
+	// the store can't count them cheaply
+	States   int
+	Duration time.Duration
+	Err      error
+}
+
+// GenerateEvent describes a call generating tokens from a chain
+type GenerateEvent struct {
+	// Tokens is the number of tokens generated, not counting the end token of a sequence
+	Tokens int
+	// Duration of GenerateSeq includes the time spent in the loop consuming its tokens
+	Duration time.Duration
+	Err      error
+}
+
+// ScoreEvent describes a call scoring a sequence against a chain
+type ScoreEvent struct {
+	Tokens   int
+	Duration time.Duration
+	Err      error
+}
+
+// observeTrain reports a training call that started at start, reading err once it
+// returns. It is meant to be deferred.
+func (chain *Chain) observeTrain(start time.Time, sequences, transitions int, err *error) {
+	states := -1
+	if s, ok := chain.store.(interface{ stateCount() int }); ok {
+		states = s.stateCount()
+	}
+	chain.metrics.ObserveTrain(TrainEvent{
+		Sequences:   sequences,
+		Transitions: transitions,
+		States:      states,
+		Duration:    time.Since(start),
+		Err:         *err,
+	})
+}
+
+// observeGenerate reports a generating call that started at start
+func (chain *Chain) observeGenerate(start time.Time, tokens int, err error) {
+	chain.metrics.ObserveGenerate(GenerateEvent{Tokens: tokens, Duration: time.Since(start), Err: err})
+}
+
+// observeScore reports a scoring call, see observeTrain
+func (chain *Chain) observeScore(start time.Time, tokens int, err *error) {
+	chain.metrics.ObserveScore(ScoreEvent{Tokens: tokens, Duration: time.Since(start), Err: *err})
+}
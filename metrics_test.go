@@ -0,0 +1,81 @@
+package gomarkov
+
+import (
+	"errors"
+	"sync"
+	"testing"
+)
+
+// recorder keeps every event it observes
+type recorder struct {
+	lock     sync.Mutex
+	train    []TrainEvent
+	generate []GenerateEvent
+	score    []ScoreEvent
+}
+
+func (r *recorder) ObserveTrain(e TrainEvent) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.train = append(r.train, e)
+}
+
+func (r *recorder) ObserveGenerate(e GenerateEvent) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.generate = append(r.generate, e)
+}
+
+func (r *recorder) ObserveScore(e ScoreEvent) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.score = append(r.score, e)
+}
+
+func TestWithInstrumentation(t *testing.T) {
+	r := &recorder{}
+	chain := NewChain(1, WithInstrumentation(r))
+	chain.Add([]string{"a", "b"})
+	chain.AddBatch([][]string{{"a"}, {"b", "c"}})
+	chain.AddParallel([]string{"c", "a", "b"}, 2)
+	if len(r.train) != 3 {
+		t.Fatalf("observed %d training events, want 3", len(r.train))
+	}
+	for i, want := range []TrainEvent{
+		{Sequences: 1, Transitions: 3, States: 4},
+		{Sequences: 2, Transitions: 5, States: 5},
+		{Sequences: 1, Transitions: 4, States: 5},
+	} {
+		got := r.train[i]
+		if got.Sequences != want.Sequences || got.Transitions != want.Transitions || got.States != want.States || got.Err != nil {
+			t.Errorf("training event %d = %+v, want %+v", i, got, want)
+		}
+	}
+
+	chain.Generate(NGram{"a"})
+	chain.GenerateSequence(NGram{StartToken}, 0)
+	chain.Generate(NGram{"unknown"})
+	if len(r.generate) != 3 {
+		t.Fatalf("observed %d generation events, want 3", len(r.generate))
+	}
+	if r.generate[0].Tokens != 1 || r.generate[0].Err != nil {
+		t.Errorf("Generate event = %+v", r.generate[0])
+	}
+	if r.generate[1].Tokens == 0 || r.generate[1].Err != nil {
+		t.Errorf("GenerateSequence event = %+v", r.generate[1])
+	}
+	if r.generate[2].Tokens != 0 || !errors.Is(r.generate[2].Err, ErrUnknownNGram) {
+		t.Errorf("failed Generate event = %+v", r.generate[2])
+	}
+
+	chain.LogProbability([]string{"a", "b"})
+	if len(r.score) != 1 || r.score[0].Tokens != 2 || r.score[0].Err != nil {
+		t.Errorf("observed score events %+v", r.score)
+	}
+
+	frozen, _ := chain.Freeze()
+	frozen.Generate(NGram{"a"})
+	if len(r.generate) != 4 {
+		t.Errorf("frozen chain didn't report to the chain's instrumentation")
+	}
+}
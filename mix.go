@@ -0,0 +1,130 @@
+package gomarkov
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+// MixSource is a corpus trained on by TrainMixture along with its share of the mixture
+type MixSource struct {
+	Sequences [][]string
+	// Weight is the source's share of the mixture's transition counts, relative to the
+	// weights of the other sources. Sources with a weight of 0 are left out.
+	Weight float64
+}
+
+// TrainMixture trains the chain on several corpora so each contributes transition
+// counts in proportion to its Weight whatever its size, such as 70% domain chat and
+// 30% encyclopedia text, without duplicating files to fake the ratios. Every sequence
+// of a source is added with the same weight, see AddWeighted. The lightest source is
+// scaled up by the smallest whole factor, up to mixResolution, that rounds every
+// weight to within 0.05%, such as 3 for sources of the same size mixed 70/30 and 1
+// for an even mix. Mixture sequences then outweigh those added with Add, before or
+// after, by that factor; train on a chain of its own or see WithFloatWeights if that
+// matters. Chains holding float weights are given the exact shares with
+// AddWeightedFloat, the lightest at a weight of 1. Either way every sequence counts
+// once towards the length distribution and checkpoints. Sources are interleaved so
+// that training interrupted part way, or checkpointed, holds about the same mix.
+func (chain *Chain) TrainMixture(sources ...MixSource) error {
+	weights, err := chain.mixWeights(sources)
+	if err != nil {
+		return err
+	}
+	add := chain.AddWeightedFloat
+	if _, ok := chain.store.(WeightedStore); !ok {
+		// Scale the integer weights up only as far as rounding them needs
+		scale := mixScale(weights)
+		for i, weight := range weights {
+			if weight*scale > math.MaxInt32 {
+				return fmt.Errorf("Source %d outweighs the others too much for integer counts, see WithFloatWeights", i)
+			}
+			weights[i] = math.Round(weight * scale)
+		}
+		add = func(input []string, weight float64) error {
+			return chain.addWeighted(input, int(weight), 1)
+		}
+	}
+	next := make([]int, len(sources))
+	for {
+		// Train on the source furthest behind, so all of them finish together
+		pick := -1
+		for i, source := range sources {
+			if weights[i] == 0 || next[i] == len(source.Sequences) {
+				continue
+			}
+			progress := float64(next[i]) / float64(len(source.Sequences))
+			if pick < 0 || progress < float64(next[pick])/float64(len(sources[pick].Sequences)) {
+				pick = i
+			}
+		}
+		if pick < 0 {
+			return nil
+		}
+		if err := add(sources[pick].Sequences[next[pick]], weights[pick]); err != nil {
+			return err
+		}
+		next[pick]++
+	}
+}
+
+// mixResolution is the most the sequences of the lightest source are scaled up by
+// for chains holding integer counts, which rounds every weight to within
+// mixTolerance as the others are at least as heavy
+const (
+	mixResolution = 1000
+	mixTolerance  = 0.0005
+)
+
+// mixScale returns the smallest whole factor that rounds weights, the smallest of
+// which is 1, to within mixTolerance of their ratios
+func mixScale(weights []float64) float64 {
+	for scale := 1.0; scale < mixResolution; scale++ {
+		rounds := true
+		for _, weight := range weights {
+			if scaled := weight * scale; math.Abs(math.Round(scaled)-scaled) > mixTolerance*scaled {
+				rounds = false
+				break
+			}
+		}
+		if rounds {
+			return scale
+		}
+	}
+	return mixResolution
+}
+
+// mixWeights returns the weight each sequence of a source is added with, relative
+// to the smallest
+func (chain *Chain) mixWeights(sources []MixSource) ([]float64, error) {
+	// scales[i] is the source's weight per transition it holds
+	scales := make([]float64, len(sources))
+	smallest := math.Inf(1)
+	for i, source := range sources {
+		if source.Weight < 0 || math.IsNaN(source.Weight) || math.IsInf(source.Weight, 0) {
+			return nil, fmt.Errorf("Source %d has invalid weight %v", i, source.Weight)
+		}
+		if source.Weight == 0 {
+			continue
+		}
+		transitions := 0
+		for _, input := range source.Sequences {
+			if !chain.skips(input) {
+				transitions += chain.transitionCount(input)
+			}
+		}
+		if transitions == 0 {
+			return nil, fmt.Errorf("Source %d has no sequences to train on", i)
+		}
+		scales[i] = source.Weight / float64(transitions)
+		smallest = math.Min(smallest, scales[i])
+	}
+	if math.IsInf(smallest, 1) {
+		return nil, errors.New("No source has a positive weight")
+	}
+	weights := make([]float64, len(sources))
+	for i, scale := range scales {
+		weights[i] = scale / smallest
+	}
+	return weights, nil
+}
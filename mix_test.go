@@ -0,0 +1,142 @@
+package gomarkov
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestChain_TrainMixture(t *testing.T) {
+	chat := [][]string{{"hi"}}
+	wiki := [][]string{{"the"}, {"the"}, {"the"}, {"the"}, {"the"}, {"the"}}
+	tests := []struct {
+		name       string
+		chatWeight float64
+		wikiWeight float64
+		want       float64
+	}{
+		{"Even", 1, 1, 0.5},
+		{"70/30", 0.7, 0.3, 0.7},
+		{"55/45", 0.55, 0.45, 0.55},
+		{"Chat only", 1, 0, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chain := NewChain(1)
+			err := chain.TrainMixture(MixSource{chat, tt.chatWeight}, MixSource{wiki, tt.wikiWeight})
+			if err != nil {
+				t.Fatalf("Chain.TrainMixture() error = %v", err)
+			}
+			got, _ := chain.TransitionProbability("hi", NGram{StartToken})
+			if math.Abs(got-tt.want) > 0.01 {
+				t.Errorf("P(hi | ^) = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	// Sources of the same size need weights in the exact ratio, not rounded to whole
+	// multiples of each other
+	for _, share := range []float64{0.7, 0.55} {
+		chain := NewChain(1)
+		err := chain.TrainMixture(MixSource{[][]string{{"hi"}, {"hi"}}, share}, MixSource{[][]string{{"the"}, {"the"}}, 1 - share})
+		if err != nil {
+			t.Fatalf("Chain.TrainMixture() error = %v", err)
+		}
+		if got, _ := chain.TransitionProbability("hi", NGram{StartToken}); math.Abs(got-share) > 0.001 {
+			t.Errorf("P(hi | ^) of equal sources = %v, want %v", got, share)
+		}
+	}
+
+	// Sequences added with Add keep their weight against the mixture, scaled up only
+	// as far as rounding the shares needs
+	mixed := []struct {
+		name       string
+		chatWeight float64
+		want       float64
+	}{
+		{"Even", 0.5, 1.0 / 3},
+		{"70/30", 0.7, 1.0 / 11},
+	}
+	for _, tt := range mixed {
+		chain := NewChain(1)
+		chain.Add([]string{"hello"})
+		if err := chain.TrainMixture(MixSource{[][]string{{"hi"}}, tt.chatWeight}, MixSource{[][]string{{"the"}}, 1 - tt.chatWeight}); err != nil {
+			t.Fatalf("Chain.TrainMixture() error = %v", err)
+		}
+		if got, _ := chain.TransitionProbability("hello", NGram{StartToken}); math.Abs(got-tt.want) > 1e-12 {
+			t.Errorf("%s P(hello | ^) after Add and TrainMixture = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+
+	// The even mix needs chat sequences to count 1.5 times as much as wiki ones, which
+	// float weights hold exactly
+	chain := NewChain(1, WithFloatWeights())
+	err := chain.TrainMixture(MixSource{[][]string{{"hi"}, {"hi"}}, 1}, MixSource{[][]string{{"the"}, {"the"}, {"the"}}, 1})
+	if err != nil {
+		t.Fatalf("Chain.TrainMixture() error = %v", err)
+	}
+	if got, _ := chain.TransitionProbability("hi", NGram{StartToken}); math.Abs(got-0.5) > 1e-12 {
+		t.Errorf("P(hi | ^) with float weights = %v, want 0.5", got)
+	}
+
+	// Sequences count once however much they are scaled up
+	r := &recorder{}
+	if err := NewChain(1, WithInstrumentation(r)).TrainMixture(MixSource{chat, 0.7}, MixSource{wiki, 0.3}); err != nil {
+		t.Fatalf("Chain.TrainMixture() error = %v", err)
+	}
+	for _, e := range r.train {
+		if e.Sequences != 1 || e.Transitions != 2 {
+			t.Errorf("training event %+v, want 1 sequence of 2 transitions", e)
+		}
+	}
+
+	errs := []struct {
+		name    string
+		sources []MixSource
+		want    string
+	}{
+		{"Negative", []MixSource{{chat, -1}}, "Source 0 has invalid weight -1"},
+		{"NaN", []MixSource{{chat, math.NaN()}}, "Source 0 has invalid weight NaN"},
+		{"Empty source", []MixSource{{chat, 1}, {[][]string{{}}, 1}}, "Source 1 has no sequences to train on"},
+		{"No weight", []MixSource{{chat, 0}}, "No source has a positive weight"},
+		{"Uneven", []MixSource{{chat, 1e12}, {chat, 1}}, "Source 0 outweighs the others too much for integer counts, see WithFloatWeights"},
+	}
+	for _, tt := range errs {
+		t.Run(tt.name, func(t *testing.T) {
+			err := NewChain(1).TrainMixture(tt.sources...)
+			if err == nil || err.Error() != tt.want {
+				t.Errorf("Chain.TrainMixture() error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+// recordingStore records the order states are first added in
+type recordingStore struct {
+	Store
+	added *[]string
+}
+
+func (r recordingStore) AddState(state string) (int, error) {
+	if _, ok, _ := r.Store.LookupState(state); !ok && state != StartToken && state != EndToken {
+		*r.added = append(*r.added, state)
+	}
+	return r.Store.AddState(state)
+}
+
+func TestChain_TrainMixture_Interleaved(t *testing.T) {
+	var added []string
+	chain := NewChainWithStore(1, recordingStore{NewMemoryStore(), &added})
+	a := MixSource{[][]string{{"a1"}, {"a2"}, {"a3"}, {"a4"}}, 1}
+	b := MixSource{[][]string{{"b1"}, {"b2"}}, 1}
+	if err := chain.TrainMixture(a, b); err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"a1", "b1", "a2", "a3", "b2", "a4"}; !reflect.DeepEqual(added, want) {
+		t.Errorf("Chain.TrainMixture() trained in order %v, want %v", added, want)
+	}
+	// b is half the size of a, so each of its sequences counts twice
+	if p, _ := chain.TransitionProbability("b1", NGram{StartToken}); p != 0.25 {
+		t.Errorf("P(b1 | ^) = %v, want 0.25", p)
+	}
+}
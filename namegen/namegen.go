@@ -0,0 +1,242 @@
+// Package namegen generates names, such as fantasy characters or products, from a
+// character level chain trained on example names:
+//
+//	g := namegen.New(gomarkov.NewChain(3), namegen.WithLength(4, 10))
+//	g.Train(pokemon...)
+//	names, err := g.GenerateN(10)
+//
+// Generated names are rejected until they satisfy the length limits and, by default,
+// differ from every training name.
+package namegen
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/mb-14/gomarkov"
+)
+
+// ErrNoName is returned when no generated name satisfies the constraints within
+// the allowed attempts
+var ErrNoName = errors.New("No generated name satisfies the constraints")
+
+// Capitalization is how names are cased
+type Capitalization int
+
+const (
+	// Title capitalizes the first letter of every word, the default
+	Title Capitalization = iota
+	// Sentence capitalizes the first letter of the name only
+	Sentence
+	// Lower keeps names in lower case
+	Lower
+	// Upper puts names in upper case
+	Upper
+	// AsTrained trains on names as given and generates them without changing their case
+	AsTrained
+)
+
+// apply cases a name generated in lower case
+func (c Capitalization) apply(name string) string {
+	switch c {
+	case Title:
+		var b strings.Builder
+		start := true
+		for _, r := range name {
+			if start {
+				r = unicode.ToTitle(r)
+			}
+			b.WriteRune(r)
+			start = unicode.IsSpace(r) || r == '-'
+		}
+		return b.String()
+	case Sentence:
+		r, size := utf8.DecodeRuneInString(name)
+		return string(unicode.ToTitle(r)) + name[size:]
+	case Upper:
+		return strings.ToUpper(name)
+	}
+	return name
+}
+
+// Option configures a Generator
+type Option func(*Generator)
+
+// WithLength limits names to between min and max characters, 3 and 12 by default.
+// A max of 0 or less leaves names unbounded.
+func WithLength(min, max int) Option {
+	return func(g *Generator) {
+		g.minLength, g.maxLength = min, max
+	}
+}
+
+// WithCapitalization sets how names are cased, Title by default
+func WithCapitalization(c Capitalization) Option {
+	return func(g *Generator) {
+		g.capitalization = c
+	}
+}
+
+// AllowTrainingNames lets the generator return names it was trained on, which are
+// rejected by default
+func AllowTrainingNames() Option {
+	return func(g *Generator) {
+		g.allowTrained = true
+	}
+}
+
+// WithAttempts sets how many names are generated for each one returned before
+// giving up with ErrNoName, 100 by default
+func WithAttempts(n int) Option {
+	return func(g *Generator) {
+		g.attempts = n
+	}
+}
+
+// WithRand generates names from prng instead of the chain's PRNG. prng must be safe
+// for concurrent use if the generator is used from several goroutines.
+func WithRand(prng gomarkov.PRNG) Option {
+	return func(g *Generator) {
+		g.prng = prng
+	}
+}
+
+// Generator generates names from a character level chain. It is safe for concurrent use.
+type Generator struct {
+	chain          *gomarkov.Chain
+	minLength      int
+	maxLength      int
+	capitalization Capitalization
+	allowTrained   bool
+	attempts       int
+	prng           gomarkov.PRNG
+
+	lock    sync.RWMutex
+	trained map[string]struct{}
+}
+
+// New creates a Generator training chain, which should be empty or already trained
+// on characters of names. Names chain was trained on before are not known to the
+// novelty filter.
+func New(chain *gomarkov.Chain, opts ...Option) *Generator {
+	g := &Generator{
+		chain:     chain,
+		minLength: 3,
+		maxLength: 12,
+		attempts:  100,
+		trained:   make(map[string]struct{}),
+	}
+	for _, opt := range opts {
+		opt(g)
+	}
+	if g.attempts < 1 {
+		g.attempts = 1
+	}
+	return g
+}
+
+// Train adds example names to the chain, in lower case unless the capitalization is
+// AsTrained. Blank names are ignored.
+func (g *Generator) Train(names ...string) error {
+	sequences := make([][]string, 0, len(names))
+	g.lock.Lock()
+	for _, name := range names {
+		name = g.normalize(name)
+		if name == "" {
+			continue
+		}
+		g.trained[name] = struct{}{}
+		sequences = append(sequences, strings.Split(name, ""))
+	}
+	g.lock.Unlock()
+	return g.chain.AddBatch(sequences)
+}
+
+func (g *Generator) normalize(name string) string {
+	name = strings.TrimSpace(name)
+	if g.capitalization != AsTrained {
+		name = strings.ToLower(name)
+	}
+	return name
+}
+
+// Generate returns a name satisfying the constraints
+func (g *Generator) Generate() (string, error) {
+	for i := 0; i < g.attempts; i++ {
+		name, ok, err := g.attempt()
+		if err != nil {
+			return "", err
+		}
+		if ok {
+			return g.capitalization.apply(name), nil
+		}
+	}
+	return "", ErrNoName
+}
+
+// GenerateN returns n distinct names satisfying the constraints. If they can't all
+// be found, it returns those that were with ErrNoName.
+func (g *Generator) GenerateN(n int) ([]string, error) {
+	names := make([]string, 0, n)
+	seen := make(map[string]struct{}, n)
+	for i := 0; len(names) < n && i < n*g.attempts; i++ {
+		name, ok, err := g.attempt()
+		if err != nil {
+			return names, err
+		}
+		if _, dup := seen[name]; !ok || dup {
+			continue
+		}
+		seen[name] = struct{}{}
+		names = append(names, g.capitalization.apply(name))
+	}
+	if len(names) < n {
+		return names, ErrNoName
+	}
+	return names, nil
+}
+
+// attempt generates a name, reporting whether it satisfies the constraints
+func (g *Generator) attempt() (string, bool, error) {
+	seed := make(gomarkov.NGram, g.chain.Order)
+	for i := range seed {
+		seed[i] = gomarkov.StartToken
+	}
+	// One character past the limit is enough to tell a name is too long
+	limit := 0
+	if g.maxLength > 0 {
+		limit = g.maxLength + 1
+	}
+	var chars []string
+	var err error
+	if g.prng != nil {
+		chars, err = g.chain.GenerateSequenceDeterministic(seed, limit, g.prng)
+	} else {
+		chars, err = g.chain.GenerateSequence(seed, limit)
+	}
+	if errors.Is(err, gomarkov.ErrDeadEnd) {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, err
+	}
+	if len(chars) < g.minLength || (g.maxLength > 0 && len(chars) > g.maxLength) {
+		return "", false, nil
+	}
+	name := strings.Join(chars, "")
+	if strings.TrimSpace(name) != name {
+		return "", false, nil
+	}
+	if !g.allowTrained {
+		g.lock.RLock()
+		_, trained := g.trained[name]
+		g.lock.RUnlock()
+		if trained {
+			return "", false, nil
+		}
+	}
+	return name, true, nil
+}
@@ -0,0 +1,96 @@
+package namegen
+
+import (
+	"errors"
+	"math/rand"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/mb-14/gomarkov"
+)
+
+var names = []string{
+	"Bulbasaur", "Ivysaur", "Venusaur", "Charmander", "Charmeleon", "Charizard",
+	"Squirtle", "Wartortle", "Blastoise", "Caterpie", "Metapod", "Butterfree",
+	"Weedle", "Kakuna", "Beedrill", "Pidgey", "Pidgeotto", "Pidgeot", "Rattata",
+}
+
+func TestGenerator_GenerateN(t *testing.T) {
+	g := New(gomarkov.NewChain(2), WithLength(4, 9), WithRand(rand.New(rand.NewSource(1))))
+	if err := g.Train(names...); err != nil {
+		t.Fatalf("Generator.Train() error = %v", err)
+	}
+	got, err := g.GenerateN(10)
+	if err != nil {
+		t.Fatalf("Generator.GenerateN() error = %v", err)
+	}
+	seen := make(map[string]bool)
+	for _, name := range got {
+		if n := utf8.RuneCountInString(name); n < 4 || n > 9 {
+			t.Errorf("name %q has %d characters, want 4 to 9", name, n)
+		}
+		if seen[name] {
+			t.Errorf("name %q was generated twice", name)
+		}
+		seen[name] = true
+		for _, trained := range names {
+			if name == trained {
+				t.Errorf("name %q is a training name", name)
+			}
+		}
+		if first, _ := utf8.DecodeRuneInString(name); first < 'A' || first > 'Z' {
+			t.Errorf("name %q is not capitalized", name)
+		}
+	}
+}
+
+func TestGenerator_NoName(t *testing.T) {
+	// A chain trained on one name can only reproduce it
+	g := New(gomarkov.NewChain(3), WithAttempts(5))
+	g.Train("Pikachu")
+	if _, err := g.Generate(); !errors.Is(err, ErrNoName) {
+		t.Errorf("Generator.Generate() error = %v, want %v", err, ErrNoName)
+	}
+
+	g = New(gomarkov.NewChain(3), AllowTrainingNames())
+	g.Train("Pikachu")
+	if got, err := g.Generate(); got != "Pikachu" || err != nil {
+		t.Errorf("Generator.Generate() = %q, %v, want Pikachu", got, err)
+	}
+	if got, err := g.GenerateN(2); len(got) != 1 || !errors.Is(err, ErrNoName) {
+		t.Errorf("Generator.GenerateN() = %v, %v, want one name and %v", got, err, ErrNoName)
+	}
+}
+
+func TestCapitalization(t *testing.T) {
+	tests := []struct {
+		c    Capitalization
+		want string
+	}{
+		{Title, "Mount Doom-Ember"},
+		{Sentence, "Mount doom-ember"},
+		{Lower, "mount doom-ember"},
+		{Upper, "MOUNT DOOM-EMBER"},
+		{AsTrained, "mount doom-ember"},
+	}
+	for _, tt := range tests {
+		if got := tt.c.apply("mount doom-ember"); got != tt.want {
+			t.Errorf("Capitalization(%d).apply() = %q, want %q", tt.c, got, tt.want)
+		}
+	}
+}
+
+func TestGenerator_AsTrained(t *testing.T) {
+	g := New(gomarkov.NewChain(3), WithCapitalization(AsTrained), AllowTrainingNames())
+	g.Train("McDuck")
+	if got, _ := g.Generate(); got != "McDuck" {
+		t.Errorf("Generator.Generate() = %q, want McDuck", got)
+	}
+}
+
+func TestGenerator_EmptyChain(t *testing.T) {
+	g := New(gomarkov.NewChain(2))
+	if _, err := g.Generate(); !errors.Is(err, gomarkov.ErrEmptyChain) {
+		t.Errorf("Generator.Generate() error = %v, want %v", err, gomarkov.ErrEmptyChain)
+	}
+}
@@ -0,0 +1,134 @@
+package gomarkov
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Shift returns the n-gram that follows this one when next is generated, dropping
+// the first token. The result never shares memory with ngram.
+func (ngram NGram) Shift(next string) NGram {
+	if len(ngram) == 0 {
+		return NGram{}
+	}
+	shifted := make(NGram, len(ngram))
+	copy(shifted, ngram[1:])
+	shifted[len(shifted)-1] = next
+	return shifted
+}
+
+// ended reports whether the n-gram ends with the end token, after which nothing can
+// be generated. The empty n-gram of an order 0 chain never ends.
+func (ngram NGram) ended() bool {
+	return len(ngram) > 0 && ngram[len(ngram)-1] == EndToken
+}
+
+// Append returns a copy of the n-gram with next added at the end
+func (ngram NGram) Append(next string) NGram {
+	appended := make(NGram, len(ngram), len(ngram)+1)
+	copy(appended, ngram)
+	return append(appended, next)
+}
+
+// Validate checks that the n-gram can be used with a chain of the given order
+func (ngram NGram) Validate(order int) error {
+	if len(ngram) != order {
+		return fmt.Errorf("%w: got %d tokens, want %d", ErrOrderMismatch, len(ngram), order)
+	}
+	return nil
+}
+
+// FromWords returns the n-gram made of the last order words of text, padded with
+// StartToken when text is shorter, ready to generate the word that follows it. text
+// is split with tokenize, or into whitespace separated words if it is nil.
+func FromWords(text string, order int, tokenize func(string) []string) NGram {
+	if tokenize == nil {
+		tokenize = strings.Fields
+	}
+	return FromTokens(tokenize(text), order)
+}
+
+// FromTokens returns the n-gram made of the last order tokens, padded with
+// StartToken when there are fewer, ready to generate the token that follows them.
+// Servers use it to turn a client's seed into a state of the chain.
+func FromTokens(tokens []string, order int) NGram {
+	ngram := make(NGram, 0, order)
+	for i := len(tokens); i < order; i++ {
+		ngram = append(ngram, StartToken)
+	}
+	if len(tokens) > order {
+		tokens = tokens[len(tokens)-order:]
+	}
+	return append(ngram, tokens...)
+}
+
+// MarshalText encodes the n-gram as its tokens joined by "_", escaping "_" and `\`
+// in tokens with a backslash and writing empty tokens as `\0`, so distinct n-grams
+// always have distinct encodings. This makes n-grams usable as JSON map keys, and
+// encodes them as strings rather than arrays in JSON values.
+func (ngram NGram) MarshalText() ([]byte, error) {
+	var buf []byte
+	for i, token := range ngram {
+		if i > 0 {
+			buf = append(buf, '_')
+		}
+		if token == "" {
+			buf = append(buf, `\0`...)
+			continue
+		}
+		for j := 0; j < len(token); j++ {
+			if c := token[j]; c == '_' || c == '\\' {
+				buf = append(buf, '\\')
+			}
+			buf = append(buf, token[j])
+		}
+	}
+	return buf, nil
+}
+
+// sequenceKey identifies a sequence of tokens, such as to find duplicates, by its
+// MarshalText encoding, which tells any two sequences apart
+func sequenceKey(tokens []string) string {
+	text, _ := NGram(tokens).MarshalText()
+	return string(text)
+}
+
+var errNGramEscape = errors.New("Invalid escape in n-gram text")
+
+// UnmarshalText decodes an n-gram encoded by MarshalText
+func (ngram *NGram) UnmarshalText(text []byte) error {
+	decoded := NGram{}
+	if len(text) == 0 {
+		*ngram = decoded
+		return nil
+	}
+	var token []byte
+	for i := 0; i < len(text); i++ {
+		switch c := text[i]; {
+		case c == '_':
+			decoded = append(decoded, string(token))
+			token = token[:0]
+		case c == '\\':
+			i++
+			if i == len(text) {
+				return errNGramEscape
+			}
+			switch text[i] {
+			case '_', '\\':
+				token = append(token, text[i])
+			case '0':
+				// An empty token must be the whole token
+				if len(token) > 0 || (i+1 < len(text) && text[i+1] != '_') {
+					return errNGramEscape
+				}
+			default:
+				return errNGramEscape
+			}
+		default:
+			token = append(token, c)
+		}
+	}
+	*ngram = append(decoded, string(token))
+	return nil
+}
@@ -0,0 +1,160 @@
+package gomarkov
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestNGram_Shift(t *testing.T) {
+	tests := []struct {
+		name  string
+		ngram NGram
+		next  string
+		want  NGram
+	}{
+		{"Empty", NGram{}, "a", NGram{}},
+		{"Single", NGram{"a"}, "b", NGram{"b"}},
+		{"Window", NGram{"a", "b", "c"}, "d", NGram{"b", "c", "d"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			original := append(NGram{}, tt.ngram...)
+			if got := tt.ngram.Shift(tt.next); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("NGram.Shift() = %v, want %v", got, tt.want)
+			}
+			if !reflect.DeepEqual(tt.ngram, original) {
+				t.Errorf("NGram.Shift() modified the n-gram to %v", tt.ngram)
+			}
+		})
+	}
+}
+
+func TestNGram_Append(t *testing.T) {
+	ngram := make(NGram, 2, 4)
+	copy(ngram, []string{"a", "b"})
+	first, second := ngram.Append("c"), ngram.Append("d")
+	if !reflect.DeepEqual(first, NGram{"a", "b", "c"}) || !reflect.DeepEqual(second, NGram{"a", "b", "d"}) {
+		t.Errorf("NGram.Append() = %v, %v, want independent copies", first, second)
+	}
+}
+
+func TestNGram_Validate(t *testing.T) {
+	if err := (NGram{"a", "b"}).Validate(2); err != nil {
+		t.Errorf("NGram.Validate() error = %v", err)
+	}
+	if err := (NGram{"a"}).Validate(2); !errors.Is(err, ErrOrderMismatch) {
+		t.Errorf("NGram.Validate() error = %v, want %v", err, ErrOrderMismatch)
+	}
+}
+
+func TestFromWords(t *testing.T) {
+	tests := []struct {
+		name     string
+		text     string
+		order    int
+		tokenize func(string) []string
+		want     NGram
+	}{
+		{"Empty", "", 2, nil, NGram{StartToken, StartToken}},
+		{"Short", "hello", 2, nil, NGram{StartToken, "hello"}},
+		{"Exact", "hello  world", 2, nil, NGram{"hello", "world"}},
+		{"Long", "I want a cheese burger", 2, nil, NGram{"cheese", "burger"}},
+		{"Tokenizer", "abc", 2, func(s string) []string { return strings.Split(s, "") }, NGram{"b", "c"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := FromWords(tt.text, tt.order, tt.tokenize); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("FromWords() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFromTokens(t *testing.T) {
+	if got := FromTokens([]string{"a", "b"}, 0); len(got) != 0 {
+		t.Errorf("FromTokens() of order 0 = %v, want an empty n-gram", got)
+	}
+	tokens := []string{"a", "b", "c"}
+	got := FromTokens(tokens, 2)
+	got[0] = "x"
+	if tokens[1] != "b" {
+		t.Errorf("FromTokens() shares the tokens it was given")
+	}
+}
+
+func TestNGram_MarshalText(t *testing.T) {
+	tests := []struct {
+		name  string
+		ngram NGram
+		want  string
+	}{
+		{"Empty", NGram{}, ""},
+		{"Empty token", NGram{""}, `\0`},
+		{"Empty tokens", NGram{"", "a", ""}, `\0_a_\0`},
+		{"Plain", NGram{"I", "want"}, "I_want"},
+		{"Separator", NGram{"a_b", "c"}, `a\_b_c`},
+		{"Backslash", NGram{`a\`, "_"}, `a\\_\_`},
+		{"Boundary", NGram{StartToken, StartToken}, "^_^"},
+	}
+	seen := map[string]bool{}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.ngram.MarshalText()
+			if err != nil || string(got) != tt.want {
+				t.Fatalf("NGram.MarshalText() = %q, %v, want %q", got, err, tt.want)
+			}
+			if seen[string(got)] {
+				t.Errorf("NGram.MarshalText() encoding %q collides", got)
+			}
+			seen[string(got)] = true
+			var decoded NGram
+			if err := decoded.UnmarshalText(got); err != nil || !reflect.DeepEqual(decoded, tt.ngram) {
+				t.Errorf("NGram.UnmarshalText(%q) = %q, %v, want %q", got, decoded, err, tt.ngram)
+			}
+		})
+	}
+
+	for _, text := range []string{`a\`, `a\x`, `a\0`, `\0a`, `\0\_`} {
+		var decoded NGram
+		if err := decoded.UnmarshalText([]byte(text)); err == nil {
+			t.Errorf("NGram.UnmarshalText(%q) = %q, want an error", text, decoded)
+		}
+	}
+}
+
+func TestNGram_MarshalText_JSON(t *testing.T) {
+	config := map[string]NGram{"seed": {"I", "want"}}
+	data, err := json.Marshal(config)
+	if err != nil || string(data) != `{"seed":"I_want"}` {
+		t.Fatalf("json.Marshal() = %s, %v", data, err)
+	}
+	var decoded map[string]NGram
+	if err := json.Unmarshal(data, &decoded); err != nil || !reflect.DeepEqual(decoded, config) {
+		t.Errorf("json.Unmarshal() = %v, %v, want %v", decoded, err, config)
+	}
+}
+
+func Test_sequenceKey(t *testing.T) {
+	distinct := [][]string{
+		{},
+		{""},
+		{"", ""},
+		{"a", "b"},
+		{"a_b"},
+		{"a\x00b"},
+		{"a\x00", "b"},
+		{"a", "\x00b"},
+		{`a\`, "b"},
+	}
+	seen := make(map[string][]string)
+	for _, tokens := range distinct {
+		key := sequenceKey(tokens)
+		if other, ok := seen[key]; ok {
+			t.Errorf("sequenceKey(%q) = sequenceKey(%q) = %q", tokens, other, key)
+		}
+		seen[key] = tokens
+	}
+}
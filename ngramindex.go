@@ -0,0 +1,59 @@
+package gomarkov
+
+import "strings"
+
+// ngramStep is an edge of an ngramIndex: the token following a prefix node, and
+// whether it completes the n-gram
+type ngramStep struct {
+	prefix int
+	token  string
+	final  bool
+}
+
+// ngramIndex maps n-grams to state indices one token at a time, so looking up an
+// n-gram hashes its tokens in place instead of joining them into a key
+type ngramIndex struct {
+	lock  rwLock
+	steps map[ngramStep]int
+	nodes int
+}
+
+func (x *ngramIndex) find(ngram NGram) (int, bool) {
+	x.lock.RLock()
+	defer x.lock.RUnlock()
+	node := 0
+	for i, token := range ngram {
+		next, ok := x.steps[ngramStep{node, token, i == len(ngram)-1}]
+		if !ok {
+			return 0, false
+		}
+		node = next
+	}
+	return node, true
+}
+
+func (x *ngramIndex) insert(ngram NGram, state int) {
+	x.lock.Lock()
+	defer x.lock.Unlock()
+	if x.steps == nil {
+		x.steps = make(map[ngramStep]int)
+	}
+	node := 0
+	for i, token := range ngram {
+		final := i == len(ngram)-1
+		step := ngramStep{node, token, final}
+		next, ok := x.steps[step]
+		if !ok || final {
+			// Tokens often share memory with the whole input, keep our own copy
+			step.token = strings.Clone(token)
+			if final {
+				next = state
+			} else {
+				x.nodes++
+				next = x.nodes
+			}
+			x.steps[step] = next
+		}
+		node = next
+	}
+}
@@ -0,0 +1,57 @@
+package gomarkov
+
+import "testing"
+
+func Test_ngramIndex(t *testing.T) {
+	var x ngramIndex
+	x.insert(NGram{"a", "b"}, 3)
+	x.insert(NGram{"a", "c"}, 5)
+	x.insert(NGram{"b", "a"}, 7)
+	tests := []struct {
+		name   string
+		ngram  NGram
+		want   int
+		wantOk bool
+	}{
+		{"Known", NGram{"a", "b"}, 3, true},
+		{"Shared prefix", NGram{"a", "c"}, 5, true},
+		{"Reversed", NGram{"b", "a"}, 7, true},
+		{"Prefix only", NGram{"a"}, 0, false},
+		{"Unknown", NGram{"c", "a"}, 0, false},
+		{"Too long", NGram{"a", "b", "c"}, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := x.find(tt.ngram)
+			if got != tt.want || ok != tt.wantOk {
+				t.Errorf("ngramIndex.find() = %v, %v, want %v, %v", got, ok, tt.want, tt.wantOk)
+			}
+		})
+	}
+}
+
+func Test_memoryStore_lookupNGram(t *testing.T) {
+	chain := NewChain(2)
+	chain.Add([]string{"I", "want", "a", "cheese", "burger"})
+	m := chain.store.(*memoryStore)
+	index, _, _ := m.LookupState(NGram{"want", "a"}.key())
+
+	var loaded Chain
+	data, _ := chain.MarshalJSON()
+	if err := loaded.UnmarshalJSON(data); err != nil {
+		t.Fatalf("Chain.UnmarshalJSON() error = %v", err)
+	}
+	for _, c := range []*Chain{chain, &loaded} {
+		got, ok, _ := c.store.(*memoryStore).lookupNGram(NGram{"want", "a"})
+		if !ok || got != index {
+			t.Errorf("memoryStore.lookupNGram() = %v, %v, want %v, true", got, ok, index)
+		}
+	}
+
+	allocs := testing.AllocsPerRun(100, func() {
+		m.lookupNGram(NGram{"want", "a"})
+	})
+	if allocs != 0 {
+		t.Errorf("memoryStore.lookupNGram() allocates %v times, want 0", allocs)
+	}
+}
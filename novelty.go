@@ -0,0 +1,148 @@
+package gomarkov
+
+import (
+	"errors"
+	"hash/fnv"
+	"math"
+	"sync"
+)
+
+// ErrNotNovel is returned when every sequence generated reproduces the training data
+var ErrNotNovel = errors.New("Generated sequences reproduce the training data")
+
+// NoveltyOption configures a NoveltyFilter
+type NoveltyOption func(*NoveltyFilter)
+
+// NoveltyBloom keeps the filter's windows in a Bloom filter sized for about n windows
+// with a false positive rate of p, instead of an exact set of their hashes. It uses
+// far less memory for large corpora, at the cost of rejecting a fraction p of novel
+// sequences.
+func NoveltyBloom(n int, p float64) NoveltyOption {
+	return func(f *NoveltyFilter) {
+		f.set = newBloomFilter(n, p)
+	}
+}
+
+// NoveltyAttempts sets how many sequences GenerateSequence generates before giving up
+// with ErrNotNovel, 20 by default and at least 1
+func NoveltyAttempts(n int) NoveltyOption {
+	return func(f *NoveltyFilter) {
+		f.attempts = n
+	}
+}
+
+// NoveltyFilter remembers the training sequences of a chain to reject generated ones
+// that copy them. A sequence is rejected if more than overlap consecutive tokens of
+// it appear in a training sequence, or if it is a whole training sequence. It is safe
+// for concurrent use.
+type NoveltyFilter struct {
+	overlap  int
+	attempts int
+	lock     sync.RWMutex
+	set      hashSet
+}
+
+// hashSet holds 64-bit hashes of windows, exactly or approximately
+type hashSet interface {
+	add(h uint64)
+	has(h uint64) bool
+}
+
+type exactSet map[uint64]struct{}
+
+func (s exactSet) add(h uint64)      { s[h] = struct{}{} }
+func (s exactSet) has(h uint64) bool { _, ok := s[h]; return ok }
+
+// NewNoveltyFilter creates a filter rejecting sequences sharing more than overlap
+// consecutive tokens with a training sequence
+func NewNoveltyFilter(overlap int, opts ...NoveltyOption) *NoveltyFilter {
+	f := &NoveltyFilter{overlap: max(overlap, 0), attempts: 20, set: make(exactSet)}
+	for _, opt := range opts {
+		opt(f)
+	}
+	if f.attempts < 1 {
+		f.attempts = 1
+	}
+	return f
+}
+
+// windowHash hashes tokens, marking whether they are a whole sequence so a short
+// sequence doesn't match a window of a longer one
+func windowHash(tokens []string, whole bool) uint64 {
+	h := fnv.New64a()
+	if whole {
+		h.Write([]byte{0xfe})
+	}
+	for _, token := range tokens {
+		h.Write([]byte(token))
+		h.Write([]byte{0xff})
+	}
+	return h.Sum64()
+}
+
+// Add remembers a training sequence
+func (f *NoveltyFilter) Add(tokens []string) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	if len(tokens) <= f.overlap {
+		f.set.add(windowHash(tokens, true))
+		return
+	}
+	for i := 0; i+f.overlap < len(tokens); i++ {
+		f.set.add(windowHash(tokens[i:i+f.overlap+1], false))
+	}
+}
+
+// Novel reports whether a sequence doesn't copy any training sequence
+func (f *NoveltyFilter) Novel(tokens []string) bool {
+	f.lock.RLock()
+	defer f.lock.RUnlock()
+	if len(tokens) <= f.overlap {
+		return !f.set.has(windowHash(tokens, true))
+	}
+	for i := 0; i+f.overlap < len(tokens); i++ {
+		if f.set.has(windowHash(tokens[i:i+f.overlap+1], false)) {
+			return false
+		}
+	}
+	return true
+}
+
+// bloomFilter is a Bloom filter over 64-bit hashes, deriving its k probes from the
+// two halves of the hash
+type bloomFilter struct {
+	bits []uint64
+	k    int
+}
+
+func newBloomFilter(n int, p float64) *bloomFilter {
+	n = max(n, 1)
+	if p <= 0 || p >= 1 {
+		p = 0.01
+	}
+	m := int(math.Ceil(-float64(n) * math.Log(p) / (math.Ln2 * math.Ln2)))
+	k := int(math.Round(float64(m) / float64(n) * math.Ln2))
+	return &bloomFilter{bits: make([]uint64, (m+63)/64), k: max(k, 1)}
+}
+
+func (b *bloomFilter) probe(h uint64, i int) (word int, mask uint64) {
+	bit := (uint64(uint32(h)) + uint64(i)*(h>>32|1)) % uint64(64*len(b.bits))
+	return int(bit / 64), 1 << (bit % 64)
+}
+
+func (b *bloomFilter) add(h uint64) {
+	for i := 0; i < b.k; i++ {
+		word, mask := b.probe(h, i)
+		b.bits[word] |= mask
+	}
+}
+
+func (b *bloomFilter) has(h uint64) bool {
+	for i := 0; i < b.k; i++ {
+		word, mask := b.probe(h, i)
+		if b.bits[word]&mask == 0 {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,92 @@
+package gomarkov
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+func TestWithNoveltyFilter(t *testing.T) {
+	filter := NewNoveltyFilter(2)
+	chain := NewChain(1, WithNoveltyFilter(filter))
+	corpus := [][]string{
+		strings.Fields("red fish swim"),
+		strings.Fields("blue fish sink"),
+		strings.Fields("old cats nap"),
+	}
+	for i := 0; i < 10; i++ {
+		chain.AddBatch(corpus[:2])
+		chain.Add(corpus[2])
+	}
+	for _, s := range corpus {
+		if filter.Novel(s) {
+			t.Errorf("NoveltyFilter.Novel(%v) = true for a training sequence", s)
+		}
+	}
+
+	prng := rand.New(rand.NewSource(1))
+	novel := 0
+	for i := 0; i < 50; i++ {
+		got, err := chain.GenerateSequenceDeterministic(NGram{StartToken}, 0, prng)
+		if errors.Is(err, ErrNotNovel) {
+			continue
+		}
+		if err != nil {
+			t.Fatalf("Chain.GenerateSequence() error = %v", err)
+		}
+		novel++
+		joined := " " + strings.Join(got, " ") + " "
+		for _, s := range corpus {
+			for j := 0; j+3 <= len(s); j++ {
+				if window := " " + strings.Join(s[j:j+3], " ") + " "; strings.Contains(joined, window) {
+					t.Errorf("Chain.GenerateSequence() = %v, copying %q", got, window)
+				}
+			}
+		}
+	}
+	if novel == 0 {
+		t.Error("Chain.GenerateSequence() generated nothing novel")
+	}
+
+	// A chain that can only reproduce its one sequence has nothing novel to say
+	copycat := NewChain(1, WithNoveltyFilter(NewNoveltyFilter(2, NoveltyAttempts(3))))
+	copycat.Add(strings.Fields("to be or not"))
+	if _, err := copycat.GenerateSequence(NGram{StartToken}, 0); !errors.Is(err, ErrNotNovel) {
+		t.Errorf("Chain.GenerateSequence() error = %v, want %v", err, ErrNotNovel)
+	}
+	if f := NewNoveltyFilter(2, NoveltyAttempts(0)); f.attempts != 1 {
+		t.Errorf("NoveltyAttempts(0) allows %d attempts, want 1", f.attempts)
+	}
+}
+
+func TestNoveltyFilter_short(t *testing.T) {
+	f := NewNoveltyFilter(3)
+	f.Add([]string{"hi", "there"})
+	if f.Novel([]string{"hi", "there"}) {
+		t.Error("NoveltyFilter.Novel() = true for a whole training sequence")
+	}
+	if !f.Novel([]string{"hi"}) || !f.Novel([]string{"hi", "there", "you"}) {
+		t.Error("NoveltyFilter.Novel() = false for sequences only overlapping a short one")
+	}
+}
+
+func TestNoveltyBloom(t *testing.T) {
+	f := NewNoveltyFilter(0, NoveltyBloom(1000, 0.01))
+	for i := 0; i < 1000; i++ {
+		f.Add([]string{fmt.Sprint("seen", i)})
+	}
+	falsePositives := 0
+	for i := 0; i < 1000; i++ {
+		if f.Novel([]string{fmt.Sprint("seen", i)}) {
+			t.Fatalf("NoveltyFilter.Novel(seen%d) = true", i)
+		}
+		if !f.Novel([]string{fmt.Sprint("unseen", i)}) {
+			falsePositives++
+		}
+	}
+	if falsePositives > 40 {
+		t.Errorf("NoveltyBloom rejected %d of 1000 novel sequences, want about 10", falsePositives)
+	}
+}
@@ -0,0 +1,131 @@
+package gomarkov
+
+// ChainOption configures NewChain
+type ChainOption func(*chainOptions)
+
+type chainOptions struct {
+	expectedVocab  int
+	expectedStates int
+	prng           PRNG
+	withoutLocking bool
+	metrics        Instrumentation
+	lengths        *LengthDistribution
+	novelty        *NoveltyFilter
+	minLength      int
+	stop           func(tokens []string) bool
+	checkpoints    *Checkpointer
+	floatWeights   bool
+}
+
+func newChainOptions(opts []ChainOption) chainOptions {
+	var o chainOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// WithExpectedVocab presizes the state pool for about n distinct tokens, avoiding
+// repeated growth while training a large corpus
+func WithExpectedVocab(n int) ChainOption {
+	return func(o *chainOptions) {
+		o.expectedVocab = n
+	}
+}
+
+// WithExpectedStates presizes the state pool and frequency matrix for about m
+// distinct n-grams with outgoing transitions
+func WithExpectedStates(m int) ChainOption {
+	return func(o *chainOptions) {
+		o.expectedStates = m
+	}
+}
+
+// WithRand makes Generate draw from prng instead of the package-wide PRNG, so
+// results are reproducible without passing a PRNG to every call. prng must be safe
+// for concurrent use if the chain generates from several goroutines.
+func WithRand(prng PRNG) ChainOption {
+	return func(o *chainOptions) {
+		o.prng = prng
+	}
+}
+
+// WithoutLocking skips all synchronization in the chain's in-memory store, which
+// speeds up training jobs that only ever use the chain from a single goroutine.
+// Using the chain from several goroutines at once is then a data race, though
+// snapshots taken with Snapshot are locked as usual. Like the capacity hints, it
+// doesn't apply to stores created by the caller.
+func WithoutLocking() ChainOption {
+	return func(o *chainOptions) {
+		o.withoutLocking = true
+	}
+}
+
+// WithInstrumentation reports the chain's training, generation and scoring to m, for
+// example to export them as metrics. Snapshots and frozen copies of the chain report
+// to m too.
+func WithInstrumentation(m Instrumentation) ChainOption {
+	return func(o *chainOptions) {
+		o.metrics = m
+	}
+}
+
+// WithLengthMatching records the length of every sequence the chain is trained on in
+// d, and ends generated sequences so their lengths follow d rather than only the
+// chain's end transitions, which low order chains end too early or too late. A
+// sequence only ends where the chain can end it. Pass a decoded distribution to
+// match the lengths of a chain trained earlier. Snapshots and frozen copies of the
+// chain share d.
+func WithLengthMatching(d *LengthDistribution) ChainOption {
+	return func(o *chainOptions) {
+		o.lengths = d
+	}
+}
+
+// WithNoveltyFilter records every sequence the chain is trained on in f, and makes
+// GenerateSequence generate again whenever a sequence copies the training data, see
+// NoveltyFilter. Iterators from GenerateSeq yield tokens as they are generated and
+// aren't filtered. Snapshots and frozen copies of the chain share f.
+func WithNoveltyFilter(f *NoveltyFilter) ChainOption {
+	return func(o *chainOptions) {
+		o.novelty = f
+	}
+}
+
+// WithMinLength skips training sequences shorter than n tokens, such as fragments
+// left over by a sentence splitter. Empty sequences are always skipped.
+func WithMinLength(n int) ChainOption {
+	return func(o *chainOptions) {
+		o.minLength = n
+	}
+}
+
+// WithStopWhen makes GenerateSequence and GenerateSeq end a sequence as soon as stop
+// returns true for the tokens generated so far, the last one included, as well as at
+// the end token. It lets chains trained on continuous text, without sentence
+// boundaries, generate a sentence at a time. stop must not modify or retain tokens.
+func WithStopWhen(stop func(tokens []string) bool) ChainOption {
+	return func(o *chainOptions) {
+		o.stop = stop
+	}
+}
+
+// WithStopTokens makes generated sequences end after any of the given tokens, such
+// as ".", "!" and "?", see WithStopWhen. The stop token is kept in the sequence.
+func WithStopTokens(tokens ...string) ChainOption {
+	stops := make(map[string]bool, len(tokens))
+	for _, token := range tokens {
+		stops[token] = true
+	}
+	return WithStopWhen(func(generated []string) bool {
+		return stops[generated[len(generated)-1]]
+	})
+}
+
+// WithCheckpoints saves the chain with c as it trains, see Checkpointer. Close c once
+// training is done to save the last sequences too.
+func WithCheckpoints(c *Checkpointer) ChainOption {
+	return func(o *chainOptions) {
+		o.checkpoints = c
+	}
+}
@@ -0,0 +1,139 @@
+// Package password estimates the strength of passwords by how predictable they are
+// to a character level chain trained on leaked passwords, in the spirit of Markov
+// strength meters such as the one in zxcvbn. A password the chain is likely to
+// produce would be guessed early by an attacker enumerating the same model.
+//
+//	scorer := password.New(gomarkov.NewChain(3))
+//	scorer.TrainReader(leaked) // one password per line
+//	s, _ := scorer.Score("hunter2")
+//	fmt.Printf("%.0f bits, level %d\n", s.Bits, s.Level)
+//
+// Trained scorers are best shipped as compiled models, which load instantly and are
+// served straight from a memory mapping:
+//
+//	scorer.Chain().Compile(f)
+//	chain, _ := gomarkov.OpenCompiled(path)
+//	scorer = password.New(chain)
+package password
+
+import (
+	"bufio"
+	"io"
+	"math"
+	"strings"
+
+	"github.com/mb-14/gomarkov"
+)
+
+// Strength is the estimated strength of a password
+type Strength struct {
+	// Bits is the negative base 2 log of the probability of the chain producing
+	// the password, including where it ends
+	Bits float64
+	// Guesses is about how many guesses an attacker using the model needs, 2^Bits
+	Guesses float64
+	// Level rates the guesses from 0, too guessable, to 4, very unguessable, using
+	// the thresholds of zxcvbn
+	Level int
+	// Unseen is the number of character transitions the chain has never seen
+	Unseen int
+}
+
+// levels are the upper bounds of guesses for each level below 4
+var levels = [...]float64{1e3, 1e6, 1e8, 1e10}
+
+// Option configures a Scorer
+type Option func(*Scorer)
+
+// WithFloor sets the probability given to character transitions the chain has never
+// seen, 1e-4 by default. Lower floors rate unusual passwords as stronger.
+func WithFloor(p float64) Option {
+	return func(s *Scorer) {
+		s.floor = p
+	}
+}
+
+// WithMaxLength ignores characters beyond the first n when training and scoring, 64
+// by default, bounding the work done for hostile inputs
+func WithMaxLength(n int) Option {
+	return func(s *Scorer) {
+		s.maxLength = n
+	}
+}
+
+// Scorer rates passwords against a character level chain. It is safe for concurrent
+// use if the chain is.
+type Scorer struct {
+	chain     *gomarkov.Chain
+	floor     float64
+	maxLength int
+}
+
+// New creates a Scorer over chain, which may already be trained or be a compiled model
+func New(chain *gomarkov.Chain, opts ...Option) *Scorer {
+	s := &Scorer{chain: chain, floor: 1e-4, maxLength: 64}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Chain returns the chain of the scorer, for example to compile it
+func (s *Scorer) Chain() *gomarkov.Chain {
+	return s.chain
+}
+
+// chars splits a password into characters. Case and whitespace are kept, they are
+// part of the password.
+func (s *Scorer) chars(password string) []string {
+	chars := strings.Split(password, "")
+	if s.maxLength > 0 && len(chars) > s.maxLength {
+		chars = chars[:s.maxLength]
+	}
+	return chars
+}
+
+// Train adds passwords to the chain
+func (s *Scorer) Train(passwords ...string) error {
+	sequences := make([][]string, 0, len(passwords))
+	for _, password := range passwords {
+		if password != "" {
+			sequences = append(sequences, s.chars(password))
+		}
+	}
+	return s.chain.AddBatch(sequences)
+}
+
+// TrainReader adds every line of r as a password, the format of most leaked
+// password lists. Empty lines are skipped.
+func (s *Scorer) TrainReader(r io.Reader) error {
+	scanner := bufio.NewScanner(r)
+	batch := make([]string, 0, 1024)
+	for scanner.Scan() {
+		batch = append(batch, strings.TrimSuffix(scanner.Text(), "\r"))
+		if len(batch) == cap(batch) {
+			if err := s.Train(batch...); err != nil {
+				return err
+			}
+			batch = batch[:0]
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	return s.Train(batch...)
+}
+
+// Score estimates the strength of a password
+func (s *Scorer) Score(password string) (Strength, error) {
+	logProb, unseen, err := s.chain.FlooredLogProbability(s.chars(password), s.floor)
+	if err != nil {
+		return Strength{}, err
+	}
+	strength := Strength{Bits: -logProb / math.Ln2, Unseen: unseen}
+	strength.Guesses = math.Exp2(strength.Bits)
+	for strength.Level < len(levels) && strength.Guesses >= levels[strength.Level] {
+		strength.Level++
+	}
+	return strength, nil
+}
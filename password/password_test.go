@@ -0,0 +1,83 @@
+package password
+
+import (
+	"bytes"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/mb-14/gomarkov"
+)
+
+const leaked = "password\r\n123456\r\npassword1\r\n\r\n12345678\r\nqwerty\r\npassword\r\n123456789\r\nletmein\r\n"
+
+func trained(t *testing.T, opts ...Option) *Scorer {
+	t.Helper()
+	s := New(gomarkov.NewChain(2), opts...)
+	if err := s.TrainReader(strings.NewReader(leaked)); err != nil {
+		t.Fatalf("Scorer.TrainReader() error = %v", err)
+	}
+	return s
+}
+
+func TestScorer_Score(t *testing.T) {
+	s := trained(t)
+
+	weak, err := s.Score("password")
+	if err != nil {
+		t.Fatalf("Scorer.Score() error = %v", err)
+	}
+	// Three of the eight passwords start with pass, two of them end after password
+	if want := -math.Log2(3.0 / 8 * 2 / 3); math.Abs(weak.Bits-want) > 1e-9 {
+		t.Errorf("Scorer.Score(password) = %v bits, want %v", weak.Bits, want)
+	}
+	if weak.Level != 0 || weak.Unseen != 0 {
+		t.Errorf("Scorer.Score(password) = %+v, want level 0 with nothing unseen", weak)
+	}
+
+	strong, err := s.Score("Vx7#qLp0!zR")
+	if err != nil {
+		t.Fatalf("Scorer.Score() error = %v", err)
+	}
+	if strong.Level != 4 || strong.Unseen != 12 {
+		t.Errorf("Scorer.Score(random) = %+v, want level 4 with 12 unseen", strong)
+	}
+	if math.Abs(strong.Guesses-math.Exp2(strong.Bits)) > 1e-6*strong.Guesses {
+		t.Errorf("Scorer.Score() guesses %v, want 2^%v", strong.Guesses, strong.Bits)
+	}
+}
+
+func TestScorer_Score_maxLength(t *testing.T) {
+	s := trained(t, WithMaxLength(8))
+	short, _ := s.Score("password")
+	long, _ := s.Score("password" + strings.Repeat("x", 100))
+	if short != long {
+		t.Errorf("Scorer.Score() = %+v beyond the max length, want %+v", long, short)
+	}
+}
+
+func TestScorer_compiled(t *testing.T) {
+	s := trained(t)
+	var buf bytes.Buffer
+	if err := s.Chain().Compile(&buf); err != nil {
+		t.Fatalf("Chain.Compile() error = %v", err)
+	}
+	chain, err := gomarkov.LoadCompiled(buf.Bytes())
+	if err != nil {
+		t.Fatalf("LoadCompiled() error = %v", err)
+	}
+	compiled := New(chain)
+	for _, password := range []string{"password1", "letmein", "correct horse"} {
+		want, _ := s.Score(password)
+		got, err := compiled.Score(password)
+		if err != nil {
+			t.Fatalf("Scorer.Score() error = %v", err)
+		}
+		if got != want {
+			t.Errorf("compiled Scorer.Score(%q) = %+v, want %+v", password, got, want)
+		}
+	}
+	if err := compiled.Train("hunter2"); err == nil {
+		t.Error("Scorer.Train() on a compiled chain succeeded, want an error")
+	}
+}
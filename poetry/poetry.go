@@ -0,0 +1,196 @@
+// Package poetry generates poems from a chain, with a syllable budget for each line
+// and lines rhyming by a scheme. Lines are found by a constrained search through the
+// chain rather than by generating lines until one happens to fit:
+//
+//	g := poetry.New(chain, poetry.Heuristic)
+//	poem, err := g.Generate(poetry.Haiku)
+//	for _, line := range poem {
+//		fmt.Println(strings.Join(line, " "))
+//	}
+//
+// Pronunciations come from a Pronouncer, either the built-in Heuristic or a
+// Dictionary such as the CMU Pronouncing Dictionary loaded with LoadCMUDict. Words
+// the Pronouncer doesn't know are never used.
+package poetry
+
+import (
+	"errors"
+
+	"github.com/mb-14/gomarkov"
+)
+
+// ErrNoPoem is returned when no poem meeting the constraints is found
+var ErrNoPoem = errors.New("No poem satisfies the constraints")
+
+// Line constrains a line of a poem
+type Line struct {
+	Syllables int
+	// Rhyme names the line's rhyme group, lines of the same group end in different
+	// words that rhyme. Lines without a group don't need to rhyme.
+	Rhyme string
+}
+
+// Common forms
+var (
+	Haiku    = []Line{{Syllables: 5}, {Syllables: 7}, {Syllables: 5}}
+	Limerick = []Line{{8, "A"}, {8, "A"}, {5, "B"}, {5, "B"}, {8, "A"}}
+	Couplet  = []Line{{10, "A"}, {10, "A"}}
+)
+
+// Option configures a Generator
+type Option func(*Generator)
+
+// WithRand makes the generator draw from prng instead of the chain's PRNG
+func WithRand(prng gomarkov.PRNG) Option {
+	return func(g *Generator) {
+		g.prng = prng
+	}
+}
+
+// WithAttempts sets how many times a poem is started over when a line can't be
+// found, 20 by default
+func WithAttempts(n int) Option {
+	return func(g *Generator) {
+		g.attempts = n
+	}
+}
+
+// WithSearchLimit sets how many tokens the search for each line may try, 10000 by
+// default
+func WithSearchLimit(n int) Option {
+	return func(g *Generator) {
+		g.limit = n
+	}
+}
+
+// CompleteLines requires every line to be a whole sequence of the chain, ending
+// where a trained sequence could end. By default lines stop wherever their syllables
+// run out.
+func CompleteLines() Option {
+	return func(g *Generator) {
+		g.complete = true
+	}
+}
+
+// Generator writes poems from a chain
+type Generator struct {
+	chain    *gomarkov.Chain
+	pron     Pronouncer
+	prng     gomarkov.PRNG
+	attempts int
+	limit    int
+	complete bool
+}
+
+// New creates a Generator over a trained chain
+func New(chain *gomarkov.Chain, pron Pronouncer, opts ...Option) *Generator {
+	g := &Generator{chain: chain, pron: pron, attempts: 20, limit: 10000}
+	for _, opt := range opts {
+		opt(g)
+	}
+	return g
+}
+
+// Generate writes a poem of the given lines, returning the tokens of each line
+func (g *Generator) Generate(lines []Line) ([][]string, error) {
+	opts := []gomarkov.SearchOption{gomarkov.SearchLimit(g.limit)}
+	if g.prng != nil {
+		opts = append(opts, gomarkov.SearchRand(g.prng))
+	}
+	seed := make(gomarkov.NGram, g.chain.Order)
+	for i := range seed {
+		seed[i] = gomarkov.StartToken
+	}
+	for attempt := 0; attempt < g.attempts; attempt++ {
+		poem, err := g.generate(seed, lines, opts)
+		if err == nil {
+			return poem, nil
+		}
+		if !errors.Is(err, gomarkov.ErrUnsatisfiable) {
+			return nil, err
+		}
+	}
+	return nil, ErrNoPoem
+}
+
+func (g *Generator) generate(seed gomarkov.NGram, lines []Line, opts []gomarkov.SearchOption) ([][]string, error) {
+	poem := make([][]string, 0, len(lines))
+	// rhymes holds the rhyme key of each group and the words ending its lines
+	type group struct {
+		key   string
+		words map[string]bool
+	}
+	rhymes := make(map[string]*group)
+	for _, line := range lines {
+		c := &lineConstraint{pron: g.pron, budget: line.Syllables, complete: g.complete}
+		grp := rhymes[line.Rhyme]
+		if line.Rhyme != "" {
+			c.rhymes = true
+			if grp != nil {
+				c.key, c.avoid = grp.key, grp.words
+			}
+		}
+		tokens, err := g.chain.GenerateConstrained(seed, c, opts...)
+		if err != nil {
+			return nil, err
+		}
+		if line.Rhyme != "" {
+			last := normalizeWord(tokens[len(tokens)-1])
+			if grp == nil {
+				key, _ := g.pron.Rhyme(last)
+				grp = &group{key: key, words: make(map[string]bool)}
+				rhymes[line.Rhyme] = grp
+			}
+			grp.words[last] = true
+		}
+		poem = append(poem, tokens)
+	}
+	return poem, nil
+}
+
+// lineConstraint accepts lines using up exactly their syllable budget
+type lineConstraint struct {
+	pron     Pronouncer
+	budget   int
+	complete bool
+	// rhymes is set when the last word must have a rhyme key, key if it must be a
+	// particular one, and avoid holds words already used for it
+	rhymes bool
+	key    string
+	avoid  map[string]bool
+}
+
+func (c *lineConstraint) syllables(tokens []string) int {
+	n := 0
+	for _, token := range tokens {
+		s, _ := c.pron.Syllables(token)
+		n += s
+	}
+	return n
+}
+
+func (c *lineConstraint) Allow(tokens []string, next string) bool {
+	used := c.syllables(tokens)
+	if next == gomarkov.EndToken {
+		return c.complete && used == c.budget && len(tokens) > 0
+	}
+	if next == gomarkov.StartToken {
+		return false
+	}
+	n, ok := c.pron.Syllables(next)
+	if !ok || n == 0 || used+n > c.budget {
+		return false
+	}
+	if used+n < c.budget || !c.rhymes {
+		return true
+	}
+	key, ok := c.pron.Rhyme(next)
+	if !ok || c.key != "" && key != c.key {
+		return false
+	}
+	return !c.avoid[normalizeWord(next)]
+}
+
+func (c *lineConstraint) Done(tokens []string) bool {
+	return !c.complete && len(tokens) > 0 && c.syllables(tokens) == c.budget
+}
@@ -0,0 +1,135 @@
+package poetry
+
+import (
+	"errors"
+	"math/rand"
+	"strings"
+	"testing"
+
+	"github.com/mb-14/gomarkov"
+)
+
+var verse = []string{
+	"the old cat sat upon the mat",
+	"a quiet frog sang in the fog",
+	"the silver moon will set too soon",
+	"my little dog ran through the bog",
+	"a lonely bird was never heard",
+	"the summer rain fell on the plain",
+	"an autumn leaf fell in the grief of night",
+}
+
+func trained() *gomarkov.Chain {
+	chain := gomarkov.NewChain(1)
+	for _, line := range verse {
+		chain.Add(strings.Fields(line))
+	}
+	return chain
+}
+
+func countSyllables(t *testing.T, line []string) int {
+	t.Helper()
+	n := 0
+	for _, word := range line {
+		s, ok := Heuristic.Syllables(word)
+		if !ok {
+			t.Fatalf("word %q has no syllable count", word)
+		}
+		n += s
+	}
+	return n
+}
+
+func TestGenerator_Generate_haiku(t *testing.T) {
+	g := New(trained(), Heuristic, WithRand(rand.New(rand.NewSource(1))))
+	poem, err := g.Generate(Haiku)
+	if err != nil {
+		t.Fatalf("Generator.Generate() error = %v", err)
+	}
+	if len(poem) != 3 {
+		t.Fatalf("Generator.Generate() = %d lines, want 3", len(poem))
+	}
+	for i, line := range poem {
+		if got, want := countSyllables(t, line), Haiku[i].Syllables; got != want {
+			t.Errorf("line %d %v has %d syllables, want %d", i, line, got, want)
+		}
+	}
+}
+
+func TestGenerator_Generate_rhymes(t *testing.T) {
+	g := New(trained(), Heuristic, WithRand(rand.New(rand.NewSource(1))))
+	lines := []Line{{8, "A"}, {8, "A"}, {8, "B"}, {8, "B"}}
+	poem, err := g.Generate(lines)
+	if err != nil {
+		t.Fatalf("Generator.Generate() error = %v", err)
+	}
+	last := func(i int) string { return poem[i][len(poem[i])-1] }
+	for _, pair := range [][2]int{{0, 1}, {2, 3}} {
+		a, b := last(pair[0]), last(pair[1])
+		ka, _ := Heuristic.Rhyme(a)
+		kb, _ := Heuristic.Rhyme(b)
+		if ka != kb || a == b {
+			t.Errorf("lines %d and %d end in %q and %q, want different rhyming words", pair[0], pair[1], a, b)
+		}
+	}
+	for i, line := range poem {
+		if got := countSyllables(t, line); got != 8 {
+			t.Errorf("line %d %v has %d syllables, want 8", i, line, got)
+		}
+	}
+}
+
+func TestGenerator_Generate_completeLines(t *testing.T) {
+	g := New(trained(), Heuristic, CompleteLines(), WithRand(rand.New(rand.NewSource(1))))
+	poem, err := g.Generate([]Line{{8, ""}})
+	if err != nil {
+		t.Fatalf("Generator.Generate() error = %v", err)
+	}
+	p, _ := trained().TransitionProbability(gomarkov.EndToken, gomarkov.NGram{poem[0][len(poem[0])-1]})
+	if p == 0 {
+		t.Errorf("line %v can't end a sequence", poem[0])
+	}
+
+	if _, err := g.Generate([]Line{{1, ""}}); !errors.Is(err, ErrNoPoem) {
+		t.Errorf("Generator.Generate() error = %v, want %v", err, ErrNoPoem)
+	}
+}
+
+func TestHeuristic(t *testing.T) {
+	for word, want := range map[string]int{"cat": 1, "silver": 2, "little": 2, "time": 1, "Summer,": 2, "autumn": 2} {
+		if got, _ := Heuristic.Syllables(word); got != want {
+			t.Errorf("Heuristic.Syllables(%q) = %d, want %d", word, got, want)
+		}
+	}
+	a, _ := Heuristic.Rhyme("frog")
+	b, _ := Heuristic.Rhyme("Fog!")
+	if a != b {
+		t.Errorf("Heuristic.Rhyme() = %q and %q, want frog to rhyme with fog", a, b)
+	}
+}
+
+func TestDictionary(t *testing.T) {
+	d, err := LoadCMUDict(strings.NewReader(`;;; comment
+DAY  D EY1
+AWAY  AH0 W EY1
+BANANA  B AH0 N AE1 N AH0
+BANANA(2)  B AH0 N AA1 N AH0
+`))
+	if err != nil {
+		t.Fatalf("LoadCMUDict() error = %v", err)
+	}
+	if n, ok := d.Syllables("Banana"); !ok || n != 3 {
+		t.Errorf("Dictionary.Syllables(banana) = %d, %v, want 3", n, ok)
+	}
+	a, _ := d.Rhyme("day")
+	b, _ := d.Rhyme("away")
+	if a != "EY" || b != "EY" {
+		t.Errorf("Dictionary.Rhyme() = %q and %q, want EY", a, b)
+	}
+	if key, _ := d.Rhyme("banana"); key != "AE N AH" {
+		t.Errorf("Dictionary.Rhyme(banana) = %q, want %q", key, "AE N AH")
+	}
+	if _, ok := d.Syllables("unknown"); ok {
+		t.Error("Dictionary.Syllables(unknown) is known")
+	}
+}
@@ -0,0 +1,143 @@
+package poetry
+
+import (
+	"bufio"
+	"io"
+	"strings"
+	"unicode"
+)
+
+// Pronouncer knows how words are pronounced, as far as poems care
+type Pronouncer interface {
+	// Syllables returns the number of syllables of a word, with ok false if the
+	// word is unknown
+	Syllables(word string) (n int, ok bool)
+	// Rhyme returns a key that words rhyming with word share, with ok false if the
+	// word is unknown
+	Rhyme(word string) (key string, ok bool)
+}
+
+// normalizeWord lower cases a word and trims the punctuation around it
+func normalizeWord(word string) string {
+	return strings.ToLower(strings.TrimFunc(word, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '\''
+	}))
+}
+
+// Dictionary maps lower case words to their phonemes in ARPAbet, with stress digits
+// on vowels as in the CMU Pronouncing Dictionary
+type Dictionary map[string][]string
+
+// LoadCMUDict reads a dictionary in the format of the CMU Pronouncing Dictionary,
+// a word and its phonemes per line. Comments and alternative pronunciations, such
+// as WORD(2), are skipped.
+func LoadCMUDict(r io.Reader) (Dictionary, error) {
+	d := make(Dictionary)
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, ";;;") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) < 2 || strings.HasSuffix(fields[0], ")") {
+			continue
+		}
+		d[strings.ToLower(fields[0])] = fields[1:]
+	}
+	return d, scanner.Err()
+}
+
+func isVowel(phoneme string) bool {
+	last := phoneme[len(phoneme)-1]
+	return last >= '0' && last <= '9'
+}
+
+// Syllables counts the vowels of the word's pronunciation
+func (d Dictionary) Syllables(word string) (int, bool) {
+	phonemes, ok := d[normalizeWord(word)]
+	if !ok {
+		return 0, false
+	}
+	n := 0
+	for _, p := range phonemes {
+		if isVowel(p) {
+			n++
+		}
+	}
+	return n, true
+}
+
+// Rhyme returns the phonemes from the last stressed vowel on, or from the last vowel
+// if none is stressed
+func (d Dictionary) Rhyme(word string) (string, bool) {
+	phonemes, ok := d[normalizeWord(word)]
+	if !ok {
+		return "", false
+	}
+	last := -1
+	for i, p := range phonemes {
+		if isVowel(p) {
+			if last < 0 || !strings.HasSuffix(p, "0") {
+				last = i
+			}
+		}
+	}
+	if last < 0 {
+		return "", false
+	}
+	// Stress doesn't change whether words rhyme
+	key := make([]string, 0, len(phonemes)-last)
+	for _, p := range phonemes[last:] {
+		key = append(key, strings.TrimRight(p, "0123456789"))
+	}
+	return strings.Join(key, " "), true
+}
+
+// Heuristic approximates the pronunciation of English words from their spelling.
+// It is often wrong, but needs no dictionary and knows every word.
+var Heuristic Pronouncer = heuristic{}
+
+type heuristic struct{}
+
+func isVowelLetter(r rune) bool {
+	return strings.ContainsRune("aeiouy", r)
+}
+
+// vowelGroups returns the start of each run of vowels in a word, ignoring a silent
+// final e
+func vowelGroups(word []rune) []int {
+	end := len(word)
+	if end > 2 && word[end-1] == 'e' && word[end-2] != 'l' && !isVowelLetter(word[end-2]) {
+		end--
+	}
+	var groups []int
+	for i := 0; i < end; i++ {
+		if isVowelLetter(word[i]) && (i == 0 || !isVowelLetter(word[i-1])) {
+			groups = append(groups, i)
+		}
+	}
+	return groups
+}
+
+// Syllables counts the runs of vowels in the word, at least one
+func (heuristic) Syllables(word string) (int, bool) {
+	w := []rune(normalizeWord(word))
+	if len(w) == 0 {
+		return 0, false
+	}
+	return max(len(vowelGroups(w)), 1), true
+}
+
+// Rhyme returns the word from its last run of vowels on
+func (heuristic) Rhyme(word string) (string, bool) {
+	w := []rune(normalizeWord(word))
+	if len(w) == 0 {
+		return "", false
+	}
+	groups := vowelGroups(w)
+	if len(groups) == 0 {
+		return string(w), true
+	}
+	return string(w[groups[len(groups)-1]:]), true
+}
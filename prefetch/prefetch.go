@@ -0,0 +1,267 @@
+// Package prefetch predicts the next paths a visitor is likely to request, for
+// prefetching them at a CDN or in a browser. It learns from each session's path
+// visits as they arrive, conditioning on the last few paths of the session, and
+// decays old observations so routes that are no longer visited stop being
+// predicted:
+//
+//	p := prefetch.New(prefetch.WithOrder(2), prefetch.WithHalfLife(6*time.Hour))
+//	p.Visit(sessionID, r.URL.Path, time.Now())
+//	for _, next := range p.Predict(sessionID, 3, time.Now()) {
+//		if next.Probability > 0.3 {
+//			push(next.Token)
+//		}
+//	}
+//
+// Unlike gomarkov.Chain, paths are never tokenized and sessions have no start or end
+// markers, a session is simply the paths visited without a long enough pause.
+package prefetch
+
+import (
+	"math"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mb-14/gomarkov"
+)
+
+// Option configures a Predictor
+type Option func(*Predictor)
+
+// WithOrder sets how many of the last paths of a session predictions are conditioned
+// on, 1 by default. Contexts that haven't been seen back off to fewer paths.
+func WithOrder(order int) Option {
+	return func(p *Predictor) {
+		p.order = order
+	}
+}
+
+// WithHalfLife sets how long it takes for an observed transition to count half as
+// much, 24 hours by default
+func WithHalfLife(d time.Duration) Option {
+	return func(p *Predictor) {
+		p.halfLife = d
+	}
+}
+
+// WithSessionGap sets how long a session can go without a visit before the next
+// visit starts a new one, 30 minutes by default
+func WithSessionGap(d time.Duration) Option {
+	return func(p *Predictor) {
+		p.gap = d
+	}
+}
+
+// WithMinWeight sets the decayed weight below which Prune forgets a transition,
+// 0.01 by default, about 7 half-lives after a single visit
+func WithMinWeight(w float64) Option {
+	return func(p *Predictor) {
+		p.minWeight = w
+	}
+}
+
+// WithNormalizer maps paths to the form they are learnt and predicted in, Normalize
+// by default. Use it to collapse IDs in routes such as /users/123.
+func WithNormalizer(normalize func(string) string) Option {
+	return func(p *Predictor) {
+		p.normalize = normalize
+	}
+}
+
+// Normalize strips the query and fragment of a path or URL, keeping only its path
+func Normalize(path string) string {
+	if u, err := url.Parse(path); err == nil {
+		if u.Path == "" {
+			return "/"
+		}
+		return u.Path
+	}
+	if i := strings.IndexAny(path, "?#"); i >= 0 {
+		return path[:i]
+	}
+	return path
+}
+
+// Predictor learns transitions between paths with exponential decay. It is safe for
+// concurrent use.
+type Predictor struct {
+	order     int
+	halfLife  time.Duration
+	gap       time.Duration
+	minWeight float64
+	normalize func(string) string
+
+	lock     sync.Mutex
+	rows     map[string]map[string]*edge
+	sessions map[string]*session
+}
+
+// edge is the weight of a transition as of updated
+type edge struct {
+	weight  float64
+	updated time.Time
+}
+
+type session struct {
+	recent []string
+	last   time.Time
+}
+
+// New creates an empty Predictor
+func New(opts ...Option) *Predictor {
+	p := &Predictor{
+		order:     1,
+		halfLife:  24 * time.Hour,
+		gap:       30 * time.Minute,
+		minWeight: 0.01,
+		normalize: Normalize,
+		rows:      make(map[string]map[string]*edge),
+		sessions:  make(map[string]*session),
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
+}
+
+// contextKey joins the last n paths of recent into a key, separated by NUL which
+// doesn't appear in paths
+func contextKey(recent []string, n int) string {
+	return strings.Join(recent[len(recent)-n:], "\x00")
+}
+
+// decayed returns the weight of e as of now
+func (p *Predictor) decayed(e *edge, now time.Time) float64 {
+	if p.halfLife <= 0 {
+		return e.weight
+	}
+	age := now.Sub(e.updated)
+	if age <= 0 {
+		return e.weight
+	}
+	return e.weight * math.Exp2(-float64(age)/float64(p.halfLife))
+}
+
+// Visit records that a session requested path at time t, learning the transition
+// from the session's previous paths. Visits of a session must be recorded in time
+// order.
+func (p *Predictor) Visit(sessionID, path string, t time.Time) {
+	path = p.normalize(path)
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	s, ok := p.sessions[sessionID]
+	if !ok || t.Sub(s.last) > p.gap {
+		s = &session{}
+		p.sessions[sessionID] = s
+	}
+	for n := 1; n <= len(s.recent); n++ {
+		key := contextKey(s.recent, n)
+		row := p.rows[key]
+		if row == nil {
+			row = make(map[string]*edge)
+			p.rows[key] = row
+		}
+		e := row[path]
+		if e == nil {
+			e = &edge{updated: t}
+			row[path] = e
+		}
+		e.weight = p.decayed(e, t) + 1
+		if t.After(e.updated) {
+			e.updated = t
+		}
+	}
+	s.recent = append(s.recent, path)
+	if len(s.recent) > p.order {
+		s.recent = s.recent[len(s.recent)-p.order:]
+	}
+	s.last = t
+}
+
+// Predict returns the k most likely next paths of a session as of now, most likely
+// first, or all of them if k is 0 or less. Sessions that are unknown or have ended
+// have no predictions.
+func (p *Predictor) Predict(sessionID string, k int, now time.Time) []gomarkov.Prediction[string] {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	s, ok := p.sessions[sessionID]
+	if !ok || now.Sub(s.last) > p.gap {
+		return nil
+	}
+	return p.predict(s.recent, k, now)
+}
+
+// PredictAfter returns the k most likely paths to follow history as of now, see
+// Predict. Only the last paths of history up to the order matter.
+func (p *Predictor) PredictAfter(history []string, k int, now time.Time) []gomarkov.Prediction[string] {
+	if len(history) > p.order {
+		history = history[len(history)-p.order:]
+	}
+	recent := make([]string, len(history))
+	for i, path := range history {
+		recent[i] = p.normalize(path)
+	}
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	return p.predict(recent, k, now)
+}
+
+// predict uses the longest suffix of recent with transitions, the caller must hold
+// the lock
+func (p *Predictor) predict(recent []string, k int, now time.Time) []gomarkov.Prediction[string] {
+	for n := len(recent); n > 0; n-- {
+		row := p.rows[contextKey(recent, n)]
+		if len(row) == 0 {
+			continue
+		}
+		predictions := make([]gomarkov.Prediction[string], 0, len(row))
+		total := 0.0
+		for path, e := range row {
+			w := p.decayed(e, now)
+			total += w
+			predictions = append(predictions, gomarkov.Prediction[string]{Token: path, Probability: w})
+		}
+		for i := range predictions {
+			predictions[i].Probability /= total
+		}
+		sort.Slice(predictions, func(a, b int) bool {
+			if predictions[a].Probability == predictions[b].Probability {
+				return predictions[a].Token < predictions[b].Token
+			}
+			return predictions[a].Probability > predictions[b].Probability
+		})
+		if k > 0 && len(predictions) > k {
+			predictions = predictions[:k]
+		}
+		return predictions
+	}
+	return nil
+}
+
+// Prune forgets transitions whose weight has decayed below the minimum weight and
+// sessions that have ended as of now, returning how many transitions were removed.
+// Call it periodically to bound memory.
+func (p *Predictor) Prune(now time.Time) int {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	removed := 0
+	for key, row := range p.rows {
+		for path, e := range row {
+			if p.decayed(e, now) < p.minWeight {
+				delete(row, path)
+				removed++
+			}
+		}
+		if len(row) == 0 {
+			delete(p.rows, key)
+		}
+	}
+	for id, s := range p.sessions {
+		if now.Sub(s.last) > p.gap {
+			delete(p.sessions, id)
+		}
+	}
+	return removed
+}
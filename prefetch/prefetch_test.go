@@ -0,0 +1,96 @@
+package prefetch
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+var epoch = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func visit(p *Predictor, id string, start time.Time, paths ...string) {
+	for i, path := range paths {
+		p.Visit(id, path, start.Add(time.Duration(i)*time.Second))
+	}
+}
+
+func TestPredictor_Predict(t *testing.T) {
+	p := New()
+	visit(p, "a", epoch, "/", "/products", "/cart")
+	visit(p, "b", epoch, "/", "/products?page=2", "/products/1")
+	visit(p, "c", epoch, "/", "/about")
+
+	got := p.PredictAfter([]string{"/"}, 0, epoch.Add(time.Minute))
+	if len(got) != 2 || got[0].Token != "/products" || got[1].Token != "/about" {
+		t.Fatalf("Predictor.PredictAfter(/) = %v, want /products then /about", got)
+	}
+	if math.Abs(got[0].Probability-2.0/3) > 1e-6 {
+		t.Errorf("Predictor.PredictAfter(/) gives /products %v, want 2/3", got[0].Probability)
+	}
+
+	// The session is on /products, queries are stripped
+	visit(p, "d", epoch.Add(time.Minute), "/", "/products?page=3")
+	got = p.Predict("d", 0, epoch.Add(time.Minute))
+	if len(got) != 2 || got[0].Token != "/cart" || got[1].Token != "/products/1" {
+		t.Errorf("Predictor.Predict(d) = %v, want the pages after /products", got)
+	}
+	if got := p.Predict("d", 1, epoch.Add(time.Hour)); got != nil {
+		t.Errorf("Predictor.Predict() after the session ended = %v, want nil", got)
+	}
+	if got := p.Predict("unknown", 1, epoch); got != nil {
+		t.Errorf("Predictor.Predict(unknown) = %v, want nil", got)
+	}
+}
+
+func TestPredictor_order(t *testing.T) {
+	p := New(WithOrder(2))
+	visit(p, "a", epoch, "/search", "/results", "/item")
+	visit(p, "b", epoch, "/browse", "/results", "/category")
+
+	got := p.PredictAfter([]string{"/search", "/results"}, 0, epoch)
+	if len(got) != 1 || got[0].Token != "/item" {
+		t.Errorf("Predictor.PredictAfter(/search /results) = %v, want /item", got)
+	}
+	// An unseen pair backs off to the last path
+	got = p.PredictAfter([]string{"/home", "/results"}, 0, epoch)
+	if len(got) != 2 {
+		t.Errorf("Predictor.PredictAfter(/home /results) = %v, want both pages after /results", got)
+	}
+}
+
+func TestPredictor_decay(t *testing.T) {
+	p := New(WithHalfLife(time.Hour))
+	for i := 0; i < 3; i++ {
+		visit(p, "old", epoch.Add(time.Duration(i)*time.Hour), "/", "/legacy")
+	}
+	later := epoch.Add(10 * time.Hour)
+	visit(p, "new", later, "/", "/v2")
+
+	got := p.PredictAfter([]string{"/"}, 0, later)
+	if len(got) != 2 || got[0].Token != "/v2" {
+		t.Fatalf("Predictor.PredictAfter(/) = %v, want /v2 first", got)
+	}
+	if got[0].Probability < 0.95 {
+		t.Errorf("Predictor.PredictAfter(/) gives /v2 %v, want the stale route to have decayed", got[0].Probability)
+	}
+
+	if removed := p.Prune(later.Add(time.Second)); removed != 1 {
+		t.Errorf("Predictor.Prune() removed %d transitions, want 1", removed)
+	}
+	got = p.PredictAfter([]string{"/"}, 0, later)
+	if len(got) != 1 || got[0].Token != "/v2" || got[0].Probability != 1 {
+		t.Errorf("Predictor.PredictAfter(/) after pruning = %v, want only /v2", got)
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	for in, want := range map[string]string{
+		"/a/b?x=1#top":          "/a/b",
+		"https://example.com/c": "/c",
+		"https://example.com":   "/",
+	} {
+		if got := Normalize(in); got != want {
+			t.Errorf("Normalize(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package gomarkov
+
+import (
+	crand "crypto/rand"
+	"encoding/binary"
+	"math/bits"
+	"math/rand"
+)
+
+// Uint64Source is a source of uniformly distributed 64-bit values. It has the shape
+// of math/rand/v2's Source, so its generators, such as PCG and ChaCha8, and its
+// *Rand can be used directly.
+type Uint64Source interface {
+	Uint64() uint64
+}
+
+// PRNGFromSource returns a PRNG drawing from a math/rand Source. Like the Source,
+// it is not safe for concurrent use.
+func PRNGFromSource(src rand.Source) PRNG {
+	return rand.New(src)
+}
+
+// PRNGFromUint64 returns a PRNG drawing from a 64-bit source, such as one from
+// math/rand/v2. Like the source, it is not safe for concurrent use.
+func PRNGFromUint64(src Uint64Source) PRNG {
+	return uint64PRNG{src}
+}
+
+// CryptoPRNG returns a PRNG drawing from crypto/rand, for output that must not be
+// predictable from earlier output, such as generated names or tokens with security
+// implications. It is safe for concurrent use, but much slower than other sources.
+func CryptoPRNG() PRNG {
+	return uint64PRNG{cryptoSource{}}
+}
+
+// cryptoSource reads 64 bits at a time from crypto/rand
+type cryptoSource struct{}
+
+func (cryptoSource) Uint64() uint64 {
+	var b [8]byte
+	if _, err := crand.Read(b[:]); err != nil {
+		panic("crypto/rand: " + err.Error())
+	}
+	return binary.LittleEndian.Uint64(b[:])
+}
+
+type uint64PRNG struct {
+	src Uint64Source
+}
+
+// Intn maps 64 random bits onto [0,n) with Lemire's multiply and reject method,
+// which is unbiased and rarely needs more than one draw
+func (p uint64PRNG) Intn(n int) int {
+	if n <= 0 {
+		panic("invalid argument to Intn")
+	}
+	un := uint64(n)
+	hi, lo := bits.Mul64(p.src.Uint64(), un)
+	if lo < un {
+		threshold := -un % un
+		for lo < threshold {
+			hi, lo = bits.Mul64(p.src.Uint64(), un)
+		}
+	}
+	return int(hi)
+}
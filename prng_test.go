@@ -0,0 +1,92 @@
+package gomarkov
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// splitMix is a small 64-bit generator standing in for a math/rand/v2 Source
+type splitMix struct {
+	state uint64
+}
+
+func (s *splitMix) Uint64() uint64 {
+	s.state += 0x9e3779b97f4a7c15
+	z := s.state
+	z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9
+	z = (z ^ (z >> 27)) * 0x94d049bb133111eb
+	return z ^ (z >> 31)
+}
+
+// fixedSource returns the given values in turn
+type fixedSource struct {
+	values []uint64
+}
+
+func (s *fixedSource) Uint64() uint64 {
+	v := s.values[0]
+	s.values = s.values[1:]
+	return v
+}
+
+func TestPRNGFromUint64(t *testing.T) {
+	prng := PRNGFromUint64(&splitMix{1})
+	counts := make([]int, 3)
+	for i := 0; i < 30000; i++ {
+		n := prng.Intn(3)
+		if n < 0 || n >= 3 {
+			t.Fatalf("Intn(3) = %d, out of range", n)
+		}
+		counts[n]++
+	}
+	for n, count := range counts {
+		if count < 9500 || count > 10500 {
+			t.Errorf("Intn(3) returned %d %d times out of 30000", n, count)
+		}
+	}
+
+	// With n = 3, 2^64 mod 3 = 1 value maps to a low product below the threshold
+	// and must be redrawn rather than biasing the result
+	prng = PRNGFromUint64(&fixedSource{[]uint64{0, math.MaxUint64}})
+	if got := prng.Intn(3); got != 2 {
+		t.Errorf("Intn(3) = %d after rejecting a biased draw, want 2", got)
+	}
+}
+
+func TestPRNGFromSource(t *testing.T) {
+	a, b := PRNGFromSource(rand.NewSource(3)), rand.New(rand.NewSource(3))
+	for i := 0; i < 10; i++ {
+		if x, y := a.Intn(100), b.Intn(100); x != y {
+			t.Fatalf("PRNGFromSource() Intn = %d, want %d", x, y)
+		}
+	}
+}
+
+func TestChain_GenerateDeterministic_Uint64(t *testing.T) {
+	chain := NewChain(1, WithRand(PRNGFromUint64(&splitMix{5})))
+	chain.Add([]string{"I", "want", "a", "cheese", "burger"})
+	if got, err := chain.Generate(NGram{"I"}); err != nil || got != "want" {
+		t.Errorf("Chain.Generate() = %v, %v, want want", got, err)
+	}
+}
+
+func TestCryptoPRNG(t *testing.T) {
+	prng := CryptoPRNG()
+	seen := make([]bool, 4)
+	for i := 0; i < 1000; i++ {
+		n := prng.Intn(4)
+		if n < 0 || n >= 4 {
+			t.Fatalf("Intn(4) = %d, out of range", n)
+		}
+		seen[n] = true
+	}
+	for n, ok := range seen {
+		if !ok {
+			t.Errorf("Intn(4) never returned %d in 1000 draws", n)
+		}
+	}
+	if got := prng.Intn(1); got != 0 {
+		t.Errorf("Intn(1) = %d, want 0", got)
+	}
+}
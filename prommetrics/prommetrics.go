@@ -0,0 +1,166 @@
+// Package prommetrics exports the activity of chains as Prometheus metrics:
+//
+//	gomarkov_trained_sequences_total     counter of sequences added
+//	gomarkov_trained_transitions_total   counter of transitions added
+//	gomarkov_generated_tokens_total      counter of tokens generated
+//	gomarkov_scored_tokens_total         counter of tokens scored
+//	gomarkov_errors_total                counter of failed calls, by operation
+//	gomarkov_operation_duration_seconds  histogram of call latencies, by operation
+//	gomarkov_states                      gauge of the states in the chain
+//
+// Operations are "train", "generate" and "score". Register a Metrics with a
+// prometheus.Registerer and pass it to gomarkov.WithInstrumentation:
+//
+//	metrics := prommetrics.New()
+//	prometheus.MustRegister(metrics)
+//	chain := gomarkov.NewChain(2, gomarkov.WithInstrumentation(metrics))
+package prommetrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/mb-14/gomarkov"
+)
+
+// Operation label values
+const (
+	opTrain    = "train"
+	opGenerate = "generate"
+	opScore    = "score"
+)
+
+// Option configures Metrics
+type Option func(*options)
+
+type options struct {
+	namespace   string
+	constLabels prometheus.Labels
+	buckets     []float64
+}
+
+// WithNamespace replaces the "gomarkov" prefix of the metric names
+func WithNamespace(namespace string) Option {
+	return func(o *options) {
+		o.namespace = namespace
+	}
+}
+
+// WithConstLabels adds labels to every metric, for example to tell several chains
+// of the same process apart
+func WithConstLabels(labels prometheus.Labels) Option {
+	return func(o *options) {
+		o.constLabels = labels
+	}
+}
+
+// WithBuckets sets the buckets of the latency histogram, in seconds. The default
+// ranges from 10µs to about 5s, as generating a token usually takes microseconds.
+func WithBuckets(buckets []float64) Option {
+	return func(o *options) {
+		o.buckets = buckets
+	}
+}
+
+// Metrics is a gomarkov.Instrumentation and a prometheus.Collector. A single Metrics
+// may instrument several chains, in which case the states gauge follows whichever
+// chain was trained last.
+type Metrics struct {
+	sequences   prometheus.Counter
+	transitions prometheus.Counter
+	generated   prometheus.Counter
+	scored      prometheus.Counter
+	errors      *prometheus.CounterVec
+	duration    *prometheus.HistogramVec
+	states      prometheus.Gauge
+}
+
+var _ gomarkov.Instrumentation = (*Metrics)(nil)
+
+// New creates unregistered Metrics
+func New(opts ...Option) *Metrics {
+	o := options{
+		namespace: "gomarkov",
+		buckets:   prometheus.ExponentialBuckets(1e-5, 4, 10),
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	counter := func(name, help string) prometheus.Counter {
+		return prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: o.namespace, Name: name, Help: help, ConstLabels: o.constLabels,
+		})
+	}
+	m := &Metrics{
+		sequences:   counter("trained_sequences_total", "Sequences added to the chain."),
+		transitions: counter("trained_transitions_total", "Transitions added to the chain."),
+		generated:   counter("generated_tokens_total", "Tokens generated from the chain."),
+		scored:      counter("scored_tokens_total", "Tokens scored against the chain."),
+		errors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: o.namespace, Name: "errors_total", Help: "Failed calls to the chain.", ConstLabels: o.constLabels,
+		}, []string{"operation"}),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: o.namespace, Name: "operation_duration_seconds", Help: "Latency of calls to the chain.",
+			ConstLabels: o.constLabels, Buckets: o.buckets,
+		}, []string{"operation"}),
+		states: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: o.namespace, Name: "states", Help: "States in the chain.", ConstLabels: o.constLabels,
+		}),
+	}
+	// Export every operation from the start, so rates are defined before the first error
+	for _, op := range []string{opTrain, opGenerate, opScore} {
+		m.errors.WithLabelValues(op)
+		m.duration.WithLabelValues(op)
+	}
+	return m
+}
+
+func (m *Metrics) collectors() []prometheus.Collector {
+	return []prometheus.Collector{m.sequences, m.transitions, m.generated, m.scored, m.errors, m.duration, m.states}
+}
+
+// Describe implements prometheus.Collector
+func (m *Metrics) Describe(ch chan<- *prometheus.Desc) {
+	for _, c := range m.collectors() {
+		c.Describe(ch)
+	}
+}
+
+// Collect implements prometheus.Collector
+func (m *Metrics) Collect(ch chan<- prometheus.Metric) {
+	for _, c := range m.collectors() {
+		c.Collect(ch)
+	}
+}
+
+// ObserveTrain implements gomarkov.Instrumentation
+func (m *Metrics) ObserveTrain(e gomarkov.TrainEvent) {
+	m.observe(opTrain, e.Duration.Seconds(), e.Err)
+	if e.Err == nil {
+		m.sequences.Add(float64(e.Sequences))
+		m.transitions.Add(float64(e.Transitions))
+	}
+	if e.States >= 0 {
+		m.states.Set(float64(e.States))
+	}
+}
+
+// ObserveGenerate implements gomarkov.Instrumentation
+func (m *Metrics) ObserveGenerate(e gomarkov.GenerateEvent) {
+	m.observe(opGenerate, e.Duration.Seconds(), e.Err)
+	m.generated.Add(float64(e.Tokens))
+}
+
+// ObserveScore implements gomarkov.Instrumentation
+func (m *Metrics) ObserveScore(e gomarkov.ScoreEvent) {
+	m.observe(opScore, e.Duration.Seconds(), e.Err)
+	if e.Err == nil {
+		m.scored.Add(float64(e.Tokens))
+	}
+}
+
+func (m *Metrics) observe(op string, seconds float64, err error) {
+	m.duration.WithLabelValues(op).Observe(seconds)
+	if err != nil {
+		m.errors.WithLabelValues(op).Inc()
+	}
+}
@@ -0,0 +1,74 @@
+package prommetrics
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"github.com/mb-14/gomarkov"
+)
+
+func TestMetrics(t *testing.T) {
+	metrics := New(WithConstLabels(prometheus.Labels{"chain": "test"}))
+	registry := prometheus.NewPedanticRegistry()
+	registry.MustRegister(metrics)
+
+	chain := gomarkov.NewChain(1, gomarkov.WithInstrumentation(metrics))
+	chain.Add([]string{"a", "b"})
+	chain.AddBatch([][]string{{"b", "a"}, {"c"}})
+	chain.GenerateSequence(gomarkov.NGram{gomarkov.StartToken}, 5)
+	chain.Generate(gomarkov.NGram{"unknown"})
+	chain.LogProbability([]string{"a", "b"})
+
+	expected := `
+# HELP gomarkov_errors_total Failed calls to the chain.
+# TYPE gomarkov_errors_total counter
+gomarkov_errors_total{chain="test",operation="generate"} 1
+gomarkov_errors_total{chain="test",operation="score"} 0
+gomarkov_errors_total{chain="test",operation="train"} 0
+# HELP gomarkov_scored_tokens_total Tokens scored against the chain.
+# TYPE gomarkov_scored_tokens_total counter
+gomarkov_scored_tokens_total{chain="test"} 2
+# HELP gomarkov_states States in the chain.
+# TYPE gomarkov_states gauge
+gomarkov_states{chain="test"} 5
+# HELP gomarkov_trained_sequences_total Sequences added to the chain.
+# TYPE gomarkov_trained_sequences_total counter
+gomarkov_trained_sequences_total{chain="test"} 3
+# HELP gomarkov_trained_transitions_total Transitions added to the chain.
+# TYPE gomarkov_trained_transitions_total counter
+gomarkov_trained_transitions_total{chain="test"} 8
+`
+	err := testutil.GatherAndCompare(registry, strings.NewReader(expected),
+		"gomarkov_errors_total", "gomarkov_scored_tokens_total", "gomarkov_states",
+		"gomarkov_trained_sequences_total", "gomarkov_trained_transitions_total")
+	if err != nil {
+		t.Error(err)
+	}
+	if got := testutil.ToFloat64(metrics.generated); got < 1 || got > 5 {
+		t.Errorf("gomarkov_generated_tokens_total = %v, want 1 to 5", got)
+	}
+	if got := testutil.CollectAndCount(metrics.duration); got != 3 {
+		t.Errorf("gomarkov_operation_duration_seconds has %d series, want 3", got)
+	}
+	if problems, err := testutil.CollectAndLint(metrics); err != nil || len(problems) > 0 {
+		t.Errorf("linting metrics = %v, %v", problems, err)
+	}
+}
+
+func TestWithNamespace(t *testing.T) {
+	metrics := New(WithNamespace("bot"))
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(metrics)
+	families, err := registry.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, family := range families {
+		if !strings.HasPrefix(family.GetName(), "bot_") {
+			t.Errorf("metric %s doesn't use the namespace", family.GetName())
+		}
+	}
+}
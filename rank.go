@@ -0,0 +1,79 @@
+package gomarkov
+
+import (
+	"errors"
+	"sort"
+)
+
+// StateRank is the centrality of a state in the transition graph
+type StateRank struct {
+	State NGram
+	Rank  float64
+}
+
+// RankStates computes a PageRank-like centrality of every state over the chain's
+// transition graph, where edges lead from a state to the state obtained by shifting
+// the next token into it, weighted by transition probability. A walker follows an
+// edge with probability damping and otherwise jumps to a random state, as it does
+// from states without transitions. Ranks sum to 1 and are refined over iters
+// iterations. States are returned from most to least central.
+func (chain *Chain) RankStates(damping float64, iters int) ([]StateRank, error) {
+	if damping < 0 || damping > 1 {
+		return nil, errors.New("Damping must be between 0 and 1")
+	}
+	g, err := chain.transitionGraph()
+	if err != nil {
+		return nil, err
+	}
+	nodes := g.nodes()
+	if len(nodes) == 0 {
+		return nil, nil
+	}
+	index := make(map[string]int, len(nodes))
+	for i, state := range nodes {
+		index[state] = i
+	}
+	type edge struct {
+		from, to    int
+		probability float64
+	}
+	edges := make([]edge, len(g.edges))
+	outgoing := make([]bool, len(nodes))
+	for i, e := range g.edges {
+		edges[i] = edge{index[e.from], index[e.to], e.probability}
+		outgoing[index[e.from]] = true
+	}
+
+	n := float64(len(nodes))
+	rank := make([]float64, len(nodes))
+	for i := range rank {
+		rank[i] = 1 / n
+	}
+	next := make([]float64, len(nodes))
+	for iter := 0; iter < iters; iter++ {
+		dangling := 0.0
+		for i, r := range rank {
+			if !outgoing[i] {
+				dangling += r
+			}
+		}
+		jump := (1-damping)/n + damping*dangling/n
+		for i := range next {
+			next[i] = jump
+		}
+		for _, e := range edges {
+			next[e.to] += damping * rank[e.from] * e.probability
+		}
+		rank, next = next, rank
+	}
+
+	ranks := make([]StateRank, len(nodes))
+	for i, state := range nodes {
+		ngram, _ := splitKey(state, chain.Order)
+		ranks[i] = StateRank{State: ngram, Rank: rank[i]}
+	}
+	sort.SliceStable(ranks, func(a, b int) bool {
+		return ranks[a].Rank > ranks[b].Rank
+	})
+	return ranks, nil
+}
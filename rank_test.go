@@ -0,0 +1,54 @@
+package gomarkov
+
+import (
+	"math"
+	"testing"
+)
+
+func TestChain_RankStates(t *testing.T) {
+	chain := NewChain(1)
+	chain.Add([]string{"a", "hub", "b"})
+	chain.Add([]string{"c", "hub", "d"})
+	chain.Add([]string{"e", "hub", "a"})
+
+	ranks, err := chain.RankStates(0.85, 50)
+	if err != nil {
+		t.Fatalf("Chain.RankStates() error = %v", err)
+	}
+	if len(ranks) != 8 {
+		t.Fatalf("Chain.RankStates() ranked %d states, want 8", len(ranks))
+	}
+	total := 0.0
+	for i, r := range ranks {
+		total += r.Rank
+		if i > 0 && r.Rank > ranks[i-1].Rank {
+			t.Errorf("Chain.RankStates() is not sorted at %d", i)
+		}
+	}
+	if math.Abs(total-1) > 1e-9 {
+		t.Errorf("Chain.RankStates() ranks sum to %v, want 1", total)
+	}
+	// Every sequence passes through the end, the hub is the busiest inner state
+	if ranks[0].State[0] != EndToken {
+		t.Errorf("Chain.RankStates() ranks %v first, want %v", ranks[0].State, NGram{EndToken})
+	}
+	for _, r := range ranks[2:] {
+		if r.State[0] == "hub" {
+			t.Errorf("Chain.RankStates() ranks hub below %v", ranks[:2])
+		}
+	}
+
+	uniform, _ := chain.RankStates(0, 10)
+	for _, r := range uniform {
+		if math.Abs(r.Rank-1.0/8) > 1e-12 {
+			t.Errorf("Chain.RankStates() without damping = %v, want uniform ranks", uniform)
+			break
+		}
+	}
+	if _, err := chain.RankStates(1.5, 10); err == nil {
+		t.Error("Chain.RankStates() accepted a damping above 1")
+	}
+	if ranks, err := NewChain(2).RankStates(0.85, 10); ranks != nil || err != nil {
+		t.Errorf("Chain.RankStates() of an empty chain = %v, %v", ranks, err)
+	}
+}
@@ -0,0 +1,177 @@
+package gomarkov
+
+import (
+	"container/heap"
+	"math"
+)
+
+// Reachable reports whether the chain can generate to within maxDepth tokens of
+// from, to included, which tells why a token never shows up in generated text.
+// Only the states reachable from from are visited, breadth first. to may be
+// EndToken, asking whether a sequence can end that soon.
+func (chain *Chain) Reachable(from NGram, to string, maxDepth int) (bool, error) {
+	if len(from) != chain.Order {
+		return false, ErrOrderMismatch
+	}
+	if from.ended() || maxDepth < 1 {
+		return false, nil
+	}
+	index, ok, err := chain.lookupState(from)
+	if err != nil {
+		return false, err
+	}
+	if !ok {
+		if chain.empty() {
+			return false, ErrEmptyChain
+		}
+		return false, &UnknownNGramError{NGram: append(NGram{}, from...)}
+	}
+	type state struct {
+		ngram NGram
+		index int
+	}
+	layer := []state{{append(NGram{}, from...), index}}
+	seen := map[string]bool{from.key(): true}
+	for depth := 0; depth < maxDepth && len(layer) > 0; depth++ {
+		var next []state
+		for _, s := range layer {
+			transitions, err := chain.rowTransitions(s.index)
+			if err != nil {
+				return false, err
+			}
+			for _, t := range transitions {
+				if t.Probability == 0 {
+					continue
+				}
+				if t.Next == to {
+					return true, nil
+				}
+				shifted := s.ngram.Shift(t.Next)
+				key := shifted.key()
+				if t.Next == EndToken || seen[key] {
+					continue
+				}
+				seen[key] = true
+				if index, ok, err := chain.lookupState(shifted); err != nil {
+					return false, err
+				} else if ok {
+					next = append(next, state{shifted, index})
+				}
+			}
+		}
+		layer = next
+	}
+	return false, nil
+}
+
+// Path is a sequence of tokens found by PathsTo
+type Path struct {
+	// Tokens run from the start of a sequence up to and including the target
+	Tokens []string
+	// Probability is the probability of generating Tokens from the start
+	Probability float64
+}
+
+// pathItem is a partial path queued by PathsTo, or a complete one if done
+type pathItem struct {
+	ngram   NGram
+	tokens  []string
+	logProb float64
+	done    bool
+}
+
+// pathQueue is a max-heap of paths by probability
+type pathQueue []*pathItem
+
+func (q pathQueue) Len() int            { return len(q) }
+func (q pathQueue) Less(a, b int) bool  { return q[a].logProb > q[b].logProb }
+func (q pathQueue) Swap(a, b int)       { q[a], q[b] = q[b], q[a] }
+func (q *pathQueue) Push(x interface{}) { *q = append(*q, x.(*pathItem)) }
+func (q *pathQueue) Pop() interface{} {
+	old := *q
+	item := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return item
+}
+
+// PathsTo returns the k most probable ways of generating target from the start of a
+// sequence, most probable first, each ending at target's first appearance. It steers
+// goal-directed generation and shows how the chain gets to a token. Paths are
+// searched best first, skipping states that can't lead to target, and the search
+// stops with fewer than k paths once it has extended as many as SearchLimit allows.
+func (chain *Chain) PathsTo(target string, k int, opts ...SearchOption) ([]Path, error) {
+	o := searchOptions{limit: 100000}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if k < 1 {
+		return nil, nil
+	}
+	rows, err := chain.stateRows()
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return nil, ErrEmptyChain
+	}
+
+	// Find the states that can lead to target, walking the graph backwards
+	leadsTo := make(map[string][]string)
+	useful := make(map[string]bool)
+	var queue []string
+	for key, row := range rows {
+		for _, t := range row.transitions {
+			if t.Probability == 0 {
+				continue
+			}
+			if t.Next == target {
+				if !useful[key] {
+					useful[key] = true
+					queue = append(queue, key)
+				}
+			} else if t.Next != EndToken {
+				to := row.ngram.Shift(t.Next).key()
+				leadsTo[to] = append(leadsTo[to], key)
+			}
+		}
+	}
+	for len(queue) > 0 {
+		key := queue[len(queue)-1]
+		queue = queue[:len(queue)-1]
+		for _, from := range leadsTo[key] {
+			if !useful[from] {
+				useful[from] = true
+				queue = append(queue, from)
+			}
+		}
+	}
+
+	start := NGram(boundary(StartToken, chain.Order))
+	if !useful[start.key()] {
+		return nil, nil
+	}
+	var paths []Path
+	q := &pathQueue{{ngram: start}}
+	for tried := 0; q.Len() > 0 && len(paths) < k && tried < o.limit; tried++ {
+		item := heap.Pop(q).(*pathItem)
+		if item.done {
+			paths = append(paths, Path{Tokens: item.tokens, Probability: math.Exp(item.logProb)})
+			continue
+		}
+		for _, t := range rows[item.ngram.key()].transitions {
+			if t.Probability == 0 {
+				continue
+			}
+			next := &pathItem{
+				ngram:   item.ngram.Shift(t.Next),
+				tokens:  append(item.tokens[:len(item.tokens):len(item.tokens)], t.Next),
+				logProb: item.logProb + math.Log(t.Probability),
+				done:    t.Next == target,
+			}
+			if next.done || (t.Next != EndToken && useful[next.ngram.key()]) {
+				heap.Push(q, next)
+			}
+		}
+	}
+	return paths, nil
+}
@@ -0,0 +1,90 @@
+package gomarkov
+
+import (
+	"errors"
+	"math"
+	"reflect"
+	"testing"
+)
+
+var reachCorpus = [][]string{
+	{"the", "cat", "sat"}, {"the", "cat", "sat"}, {"the", "cat", "sat"},
+	{"the", "dog", "sat"},
+	{"a", "dog", "ran"}, {"a", "dog", "ran"},
+}
+
+func TestChain_Reachable(t *testing.T) {
+	chain := NewChain(1)
+	chain.AddBatch(reachCorpus)
+	tests := []struct {
+		name     string
+		from     NGram
+		to       string
+		maxDepth int
+		want     bool
+	}{
+		{"Next token", NGram{StartToken}, "the", 1, true},
+		{"Within depth", NGram{StartToken}, "ran", 3, true},
+		{"Too deep", NGram{StartToken}, "ran", 2, false},
+		{"End", NGram{"cat"}, EndToken, 2, true},
+		{"Never follows", NGram{"cat"}, "the", 10, false},
+		{"Unknown token", NGram{StartToken}, "bird", 10, false},
+		{"No depth", NGram{StartToken}, "the", 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := chain.Reachable(tt.from, tt.to, tt.maxDepth)
+			if err != nil {
+				t.Fatalf("Chain.Reachable() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Chain.Reachable() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+	if _, err := chain.Reachable(NGram{"bird"}, "sat", 3); !errors.Is(err, ErrUnknownNGram) {
+		t.Errorf("Chain.Reachable() from an unknown state error = %v, want ErrUnknownNGram", err)
+	}
+	if _, err := chain.Reachable(NGram{"a", "b"}, "sat", 3); !errors.Is(err, ErrOrderMismatch) {
+		t.Errorf("Chain.Reachable() from a bigram error = %v, want ErrOrderMismatch", err)
+	}
+}
+
+func TestChain_PathsTo(t *testing.T) {
+	chain := NewChain(1)
+	chain.AddBatch(reachCorpus)
+	got, err := chain.PathsTo("dog", 3)
+	if err != nil {
+		t.Fatalf("Chain.PathsTo() error = %v", err)
+	}
+	want := []Path{
+		{Tokens: []string{"a", "dog"}, Probability: 1.0 / 3},
+		{Tokens: []string{"the", "dog"}, Probability: 4.0 / 6 * 0.25},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("Chain.PathsTo() = %v, want %v", got, want)
+	}
+	for i := range got {
+		if !reflect.DeepEqual(got[i].Tokens, want[i].Tokens) || math.Abs(got[i].Probability-want[i].Probability) > 1e-9 {
+			t.Errorf("Chain.PathsTo()[%d] = %v, want %v", i, got[i], want[i])
+		}
+	}
+
+	if got, _ := chain.PathsTo("sat", 1); len(got) != 1 || !reflect.DeepEqual(got[0].Tokens, []string{"the", "cat", "sat"}) {
+		t.Errorf("Chain.PathsTo(sat, 1) = %v, want [the cat sat]", got)
+	}
+	if got, err := chain.PathsTo("bird", 3); err != nil || len(got) != 0 {
+		t.Errorf("Chain.PathsTo() of an unknown token = %v, %v", got, err)
+	}
+
+	// Paths go around cycles as long as they are probable enough
+	loop := NewChain(1)
+	loop.AddBatch([][]string{{"x", "x", "y"}})
+	got, err = loop.PathsTo("y", 2)
+	if err != nil || len(got) != 2 || !reflect.DeepEqual(got[1].Tokens, []string{"x", "x", "y"}) {
+		t.Errorf("Chain.PathsTo() around a cycle = %v, %v", got, err)
+	}
+	if _, err := NewChain(1).PathsTo("x", 1); !errors.Is(err, ErrEmptyChain) {
+		t.Errorf("Chain.PathsTo() of an empty chain error = %v, want ErrEmptyChain", err)
+	}
+}
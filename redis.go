@@ -0,0 +1,151 @@
+package gomarkov
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// RedisClient is the subset of Redis commands needed to back a chain.
+// Adapt your client of choice (go-redis, redigo, ...) to this interface.
+type RedisClient interface {
+	// Incr increments the integer stored at key and returns the new value
+	Incr(key string) (int64, error)
+	// HGet returns the value of a hash field and whether the field exists
+	HGet(key, field string) (string, bool, error)
+	// HSetNX sets a hash field only if it does not exist yet and reports whether it was set
+	HSetNX(key, field, value string) (bool, error)
+	// HIncrBy increments the integer value of a hash field
+	HIncrBy(key, field string, incr int64) (int64, error)
+	// HGetAll returns all fields and values of a hash
+	HGetAll(key string) (map[string]string, error)
+}
+
+// redisStore keeps the state pool and transition counts in Redis hashes:
+//
+//	<prefix>:states   state -> index
+//	<prefix>:tokens   index -> state
+//	<prefix>:seq      last assigned index
+//	<prefix>:row:<i>  next state index -> count, for current state i
+type redisStore struct {
+	client RedisClient
+	prefix string
+}
+
+// NewRedisStore creates a Store keeping the model in Redis under the given key prefix
+func NewRedisStore(client RedisClient, prefix string) Store {
+	return &redisStore{client: client, prefix: prefix}
+}
+
+// NewRedisChain creates a chain whose model lives in Redis under the given key prefix.
+// Every process sharing a prefix trains and generates against the same model, so they
+// must all use the same order.
+func NewRedisChain(order int, client RedisClient, prefix string) *Chain {
+	return NewChainWithStore(order, NewRedisStore(client, prefix))
+}
+
+func (r *redisStore) key(name string) string {
+	return r.prefix + ":" + name
+}
+
+func (r *redisStore) rowKey(current int) string {
+	return r.key("row:" + strconv.Itoa(current))
+}
+
+func (r *redisStore) AddState(state string) (int, error) {
+	index, ok, err := r.LookupState(state)
+	if err != nil || ok {
+		return index, err
+	}
+	seq, err := r.client.Incr(r.key("seq"))
+	if err != nil {
+		return 0, err
+	}
+	index = int(seq - 1)
+	// Publish the reverse mapping first so that any process able to see the
+	// new index can also resolve it back to its state.
+	if _, err := r.client.HSetNX(r.key("tokens"), strconv.Itoa(index), state); err != nil {
+		return 0, err
+	}
+	set, err := r.client.HSetNX(r.key("states"), state, strconv.Itoa(index))
+	if err != nil {
+		return 0, err
+	}
+	if set {
+		return index, nil
+	}
+	// Another process registered the state first, use its index instead
+	index, ok, err = r.LookupState(state)
+	if err == nil && !ok {
+		err = fmt.Errorf("State %q vanished from redis", state)
+	}
+	return index, err
+}
+
+func (r *redisStore) LookupState(state string) (int, bool, error) {
+	value, ok, err := r.client.HGet(r.key("states"), state)
+	if err != nil || !ok {
+		return 0, false, err
+	}
+	index, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, false, err
+	}
+	return index, true, nil
+}
+
+func (r *redisStore) LookupIndex(index int) (string, bool, error) {
+	return r.client.HGet(r.key("tokens"), strconv.Itoa(index))
+}
+
+func (r *redisStore) IncrementTransition(current, next, delta int) error {
+	count, err := r.client.HIncrBy(r.rowKey(current), strconv.Itoa(next), int64(delta))
+	if err == nil && int64(int(count)) != count {
+		// Redis counts are 64-bit, on 32-bit platforms they may not fit an int
+		return ErrCountOverflow
+	}
+	return err
+}
+
+func (r *redisStore) GetRow(current int) (map[int]int, error) {
+	fields, err := r.client.HGetAll(r.rowKey(current))
+	if err != nil {
+		return nil, err
+	}
+	arr := make(map[int]int, len(fields))
+	for field, value := range fields {
+		next, err := strconv.Atoi(field)
+		if err != nil {
+			return nil, err
+		}
+		count, err := strconv.Atoi(value)
+		if err != nil {
+			return nil, err
+		}
+		arr[next] = count
+	}
+	return arr, nil
+}
+
+func (r *redisStore) IterateRows(fn func(current int, row map[int]int) bool) error {
+	tokens, err := r.client.HGetAll(r.key("tokens"))
+	if err != nil {
+		return err
+	}
+	for field := range tokens {
+		current, err := strconv.Atoi(field)
+		if err != nil {
+			return err
+		}
+		row, err := r.GetRow(current)
+		if err != nil {
+			return err
+		}
+		if len(row) == 0 {
+			continue
+		}
+		if !fn(current, row) {
+			break
+		}
+	}
+	return nil
+}
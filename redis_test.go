@@ -0,0 +1,159 @@
+package gomarkov
+
+import (
+	"strconv"
+	"sync"
+	"testing"
+)
+
+// fakeRedis is an in-memory stand-in for a Redis server
+type fakeRedis struct {
+	counters map[string]int64
+	hashes   map[string]map[string]string
+	sync.Mutex
+}
+
+func newFakeRedis() *fakeRedis {
+	return &fakeRedis{
+		counters: make(map[string]int64),
+		hashes:   make(map[string]map[string]string),
+	}
+}
+
+func (f *fakeRedis) hash(key string) map[string]string {
+	if f.hashes[key] == nil {
+		f.hashes[key] = make(map[string]string)
+	}
+	return f.hashes[key]
+}
+
+func (f *fakeRedis) Incr(key string) (int64, error) {
+	f.Lock()
+	defer f.Unlock()
+	f.counters[key]++
+	return f.counters[key], nil
+}
+
+func (f *fakeRedis) HGet(key, field string) (string, bool, error) {
+	f.Lock()
+	defer f.Unlock()
+	value, ok := f.hash(key)[field]
+	return value, ok, nil
+}
+
+func (f *fakeRedis) HSetNX(key, field, value string) (bool, error) {
+	f.Lock()
+	defer f.Unlock()
+	h := f.hash(key)
+	if _, ok := h[field]; ok {
+		return false, nil
+	}
+	h[field] = value
+	return true, nil
+}
+
+func (f *fakeRedis) HIncrBy(key, field string, incr int64) (int64, error) {
+	f.Lock()
+	defer f.Unlock()
+	h := f.hash(key)
+	value, _ := strconv.ParseInt(h[field], 10, 64)
+	value += incr
+	h[field] = strconv.FormatInt(value, 10)
+	return value, nil
+}
+
+func (f *fakeRedis) HGetAll(key string) (map[string]string, error) {
+	f.Lock()
+	defer f.Unlock()
+	out := make(map[string]string)
+	for k, v := range f.hash(key) {
+		out[k] = v
+	}
+	return out, nil
+}
+
+func TestRedisChain_Shared(t *testing.T) {
+	client := newFakeRedis()
+	trainer := NewRedisChain(2, client, "bot")
+	trainer.Add([]string{"I", "want", "a", "cheese", "burger"})
+	trainer.Add([]string{"I", "want", "a", "chilled", "sprite"})
+	trainer.Add([]string{"I", "want", "to", "go", "to", "the", "movies"})
+
+	// A second instance on the same prefix sees the first one's training
+	reader := NewRedisChain(2, client, "bot")
+	tests := []struct {
+		name    string
+		next    string
+		current NGram
+		want    float64
+	}{
+		{"Shared transition", "a", NGram{"I", "want"}, 2.0 / 3.0},
+		{"Start transition", "I", NGram{StartToken, StartToken}, 1},
+		{"Unknown state", "a", NGram{"no", "such"}, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := reader.TransitionProbability(tt.next, tt.current)
+			if err != nil {
+				t.Fatalf("Chain.TransitionProbability() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Chain.TransitionProbability() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	next, err := reader.Generate(NGram{"cheese", "burger"})
+	if err != nil || next != EndToken {
+		t.Errorf("Chain.Generate() = %q, %v, want %q", next, err, EndToken)
+	}
+
+	isolated := NewRedisChain(2, client, "other")
+	if _, err := isolated.Generate(NGram{"I", "want"}); err == nil {
+		t.Errorf("Chain.Generate() on a different prefix should not see other models")
+	}
+}
+
+func TestRedisChain_ConcurrentAdd(t *testing.T) {
+	client := newFakeRedis()
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			chain := NewRedisChain(1, client, "bot")
+			if err := chain.Add([]string{"hello", "world"}); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	chain := NewRedisChain(1, client, "bot")
+	got, err := chain.TransitionProbability("world", NGram{"hello"})
+	if err != nil || got != 1 {
+		t.Errorf("Chain.TransitionProbability() = %v, %v, want 1", got, err)
+	}
+	if states := len(client.hashes["bot:states"]); states != 4 {
+		t.Errorf("expected 4 distinct states, got %d", states)
+	}
+}
+
+func TestRedisChain_MarshalJSON(t *testing.T) {
+	redisChain := NewRedisChain(1, newFakeRedis(), "bot")
+	redisChain.Add([]string{"test", "data"})
+	redisChain.Add([]string{"test", "node"})
+
+	data, err := redisChain.MarshalJSON()
+	if err != nil {
+		t.Fatalf("Chain.MarshalJSON() error = %v", err)
+	}
+	chain := NewChain(1)
+	if err := chain.UnmarshalJSON(data); err != nil {
+		t.Fatalf("Chain.UnmarshalJSON() error = %v", err)
+	}
+	got, err := chain.TransitionProbability("node", NGram{"test"})
+	if err != nil || got != 0.5 {
+		t.Errorf("Chain.TransitionProbability() = %v, %v, want 0.5", got, err)
+	}
+}
@@ -0,0 +1,70 @@
+package gomarkov
+
+import (
+	"sort"
+	"strings"
+)
+
+// RankedResponse is a candidate response scored by RankResponses
+type RankedResponse struct {
+	Response string
+	// Index is the position of the response among the candidates
+	Index int
+	// Score is the mean natural log probability of the response's transitions,
+	// counting unseen ones at the floor probability. Higher is more in style.
+	Score float64
+	// Unseen is the number of transitions the chain has never seen
+	Unseen int
+}
+
+// RankOption configures RankResponses
+type RankOption func(*rankOptions)
+
+type rankOptions struct {
+	tokenize func(string) []string
+	floor    float64
+}
+
+// RankTokenizer splits responses into tokens, strings.Fields by default. It should
+// match how the chain was trained.
+func RankTokenizer(tokenize func(string) []string) RankOption {
+	return func(o *rankOptions) {
+		o.tokenize = tokenize
+	}
+}
+
+// RankFloor sets the probability given to transitions the chain has never seen, so
+// an unseen word lowers a response's score instead of ruling it out. The default is
+// 1e-4.
+func RankFloor(p float64) RankOption {
+	return func(o *rankOptions) {
+		o.floor = p
+	}
+}
+
+// RankResponses orders candidate responses, such as those from templates or
+// retrieval, by how likely they are under the chain, best first. Trained on a
+// persona's messages, the chain then picks the candidate closest to its style.
+// Scores are averaged per transition so long responses aren't penalized for their
+// length. Ties keep the order of the candidates.
+func (chain *Chain) RankResponses(candidates []string, opts ...RankOption) ([]RankedResponse, error) {
+	o := rankOptions{tokenize: strings.Fields, floor: 1e-4}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	ranked := make([]RankedResponse, len(candidates))
+	for i, response := range candidates {
+		r := RankedResponse{Response: response, Index: i}
+		tokens := o.tokenize(response)
+		logProb, unseen, err := chain.FlooredLogProbability(tokens, o.floor)
+		if err != nil {
+			return nil, err
+		}
+		r.Score, r.Unseen = logProb/float64(len(tokens)+1), unseen
+		ranked[i] = r
+	}
+	sort.SliceStable(ranked, func(a, b int) bool {
+		return ranked[a].Score > ranked[b].Score
+	})
+	return ranked, nil
+}
@@ -0,0 +1,49 @@
+package gomarkov
+
+import (
+	"math"
+	"testing"
+)
+
+func TestChain_RankResponses(t *testing.T) {
+	chain := NewChain(1)
+	chain.Add([]string{"ahoy", "matey"})
+	chain.Add([]string{"ahoy", "there", "matey"})
+	chain.Add([]string{"shiver", "me", "timbers"})
+
+	candidates := []string{
+		"hello there friend",
+		"ahoy matey",
+		"shiver me timbers",
+		"ahoy there matey",
+	}
+	ranked, err := chain.RankResponses(candidates)
+	if err != nil {
+		t.Fatalf("Chain.RankResponses() error = %v", err)
+	}
+	if len(ranked) != len(candidates) {
+		t.Fatalf("Chain.RankResponses() returned %d responses, want %d", len(ranked), len(candidates))
+	}
+	// Both four transition responses have probability 1/3 and tie, ahoy matey has the
+	// same probability over three transitions
+	wantOrder := []int{2, 3, 1, 0}
+	for i, r := range ranked {
+		if r.Index != wantOrder[i] || r.Response != candidates[r.Index] {
+			t.Errorf("Chain.RankResponses()[%d] = %+v, want candidate %d", i, r, wantOrder[i])
+		}
+	}
+	if want := math.Log(1.0/3) / 4; math.Abs(ranked[0].Score-want) > 1e-9 {
+		t.Errorf("Chain.RankResponses() best score = %v, want %v", ranked[0].Score, want)
+	}
+	if last := ranked[3]; last.Unseen != 4 || math.Abs(last.Score-math.Log(1e-4)) > 1e-9 {
+		t.Errorf("Chain.RankResponses() worst = %+v, want 4 unseen transitions at the floor", last)
+	}
+
+	ranked, err = chain.RankResponses([]string{"hello there matey"}, RankFloor(0.5))
+	if err != nil {
+		t.Fatalf("Chain.RankResponses() error = %v", err)
+	}
+	if r := ranked[0]; r.Unseen != 2 || r.Score <= math.Log(0.5) {
+		t.Errorf("Chain.RankResponses(RankFloor) = %+v, want 2 unseen above log 0.5", r)
+	}
+}
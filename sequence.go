@@ -0,0 +1,139 @@
+package gomarkov
+
+import (
+	"math"
+	"time"
+)
+
+// GenerateSequence generates tokens following seed until the end token, or until
+// maxLength tokens have been generated if maxLength is positive. The seed and end
+// token are not included. Sequences also end where WithStopWhen says so. It uses the
+// PRNG set with WithRand or a package-wide one.
+func (chain *Chain) GenerateSequence(seed NGram, maxLength int) ([]string, error) {
+	prng := chain.prng
+	if prng == nil {
+		prng = defaultPrng
+	}
+	return chain.GenerateSequenceDeterministic(seed, maxLength, prng)
+}
+
+// GenerateSequenceDeterministic is GenerateSequence using the given PRNG. If a token
+// can't be generated, the error is a *SequenceError holding the tokens generated so far.
+// With WithNoveltyFilter, sequences copying the training data are generated again,
+// and the error is ErrNotNovel if every attempt does.
+func (chain *Chain) GenerateSequenceDeterministic(seed NGram, maxLength int, prng PRNG) ([]string, error) {
+	if chain.novelty == nil {
+		return chain.generate(seed, maxLength, prng, nil)
+	}
+	var prefix []string
+	for _, token := range seed {
+		if token != StartToken {
+			prefix = append(prefix, token)
+		}
+	}
+	for attempt := 0; attempt < chain.novelty.attempts; attempt++ {
+		tokens, err := chain.generate(seed, maxLength, prng, nil)
+		if err != nil {
+			return tokens, err
+		}
+		if chain.novelty.Novel(append(prefix[:len(prefix):len(prefix)], tokens...)) {
+			return tokens, nil
+		}
+	}
+	return nil, ErrNotNovel
+}
+
+// generate generates tokens following seed, passing each to yield if it isn't nil
+// and stopping early if yield returns false. It returns the generated tokens.
+func (chain *Chain) generate(seed NGram, maxLength int, prng PRNG, yield func(string) bool) (tokens []string, err error) {
+	if chain.metrics != nil {
+		defer func(start time.Time) {
+			chain.observeGenerate(start, len(tokens), err)
+		}(time.Now())
+	}
+	if len(seed) != chain.Order {
+		return nil, ErrOrderMismatch
+	}
+	current := seed
+	if current.ended() {
+		return tokens, nil
+	}
+	// offset counts the tokens of the seed when matching lengths
+	offset := 0
+	for _, token := range seed {
+		if token != StartToken {
+			offset++
+		}
+	}
+	for maxLength <= 0 || len(tokens) < maxLength {
+		var next string
+		if chain.lengths != nil {
+			next, err = chain.nextMatchingLength(current, offset+len(tokens), prng)
+		} else {
+			next, err = chain.next(current, prng)
+		}
+		if err != nil {
+			return tokens, &SequenceError{
+				Op:       "generate",
+				Position: len(tokens),
+				NGram:    append(NGram{}, current...),
+				Partial:  tokens,
+				Err:      err,
+			}
+		}
+		if next == EndToken {
+			break
+		}
+		tokens = append(tokens, next)
+		if yield != nil && !yield(next) {
+			break
+		}
+		if chain.stop != nil && chain.stop(tokens) {
+			break
+		}
+		current = current.Shift(next)
+	}
+	return tokens, nil
+}
+
+// LogProbability returns the natural log of the probability of the chain generating
+// exactly tokens, from the start of a sequence to its end. Sequences the chain can't
+// produce have a log probability of negative infinity. If a transition can't be
+// scored, the error is a *SequenceError holding the tokens scored so far.
+func (chain *Chain) LogProbability(tokens []string) (float64, error) {
+	logProb, _, err := chain.FlooredLogProbability(tokens, 0)
+	return logProb, err
+}
+
+// FlooredLogProbability is LogProbability with every transition probability raised
+// to at least floor, so text with a few transitions the chain has never seen still
+// gets a comparable score, as classifiers and detectors need. It also returns how
+// many transitions were unseen, those of probability 0.
+func (chain *Chain) FlooredLogProbability(tokens []string, floor float64) (logProb float64, unseen int, err error) {
+	if chain.metrics != nil {
+		defer chain.observeScore(time.Now(), len(tokens), &err)
+	}
+	current := NGram(boundary(StartToken, chain.Order))
+	for i := 0; i <= len(tokens); i++ {
+		next := EndToken
+		if i < len(tokens) {
+			next = tokens[i]
+		}
+		p, err := chain.TransitionProbability(next, current)
+		if err != nil {
+			return 0, 0, &SequenceError{
+				Op:       "score",
+				Position: i,
+				NGram:    append(NGram{}, current...),
+				Partial:  tokens[:i:i],
+				Err:      err,
+			}
+		}
+		if p == 0 {
+			unseen++
+		}
+		logProb += math.Log(math.Max(p, floor))
+		current = current.Shift(next)
+	}
+	return logProb, unseen, nil
+}
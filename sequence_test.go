@@ -0,0 +1,131 @@
+package gomarkov
+
+import (
+	"errors"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// failingStore fails every lookup of one state
+type failingStore struct {
+	Store
+	fail string
+}
+
+var errLookup = errors.New("lookup failed")
+
+func (f failingStore) LookupState(state string) (int, bool, error) {
+	if state == f.fail {
+		return 0, false, errLookup
+	}
+	return f.Store.LookupState(state)
+}
+
+func TestChain_GenerateSequence(t *testing.T) {
+	chain := NewChain(2)
+	chain.Add([]string{"I", "want", "a", "cheese", "burger"})
+	got, err := chain.GenerateSequence(NGram{StartToken, StartToken}, 0)
+	if err != nil {
+		t.Fatalf("Chain.GenerateSequence() error = %v", err)
+	}
+	if want := []string{"I", "want", "a", "cheese", "burger"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Chain.GenerateSequence() = %v, want %v", got, want)
+	}
+	if got, _ := chain.GenerateSequence(NGram{StartToken, StartToken}, 2); len(got) != 2 {
+		t.Errorf("Chain.GenerateSequence() with a limit of 2 = %v", got)
+	}
+	if _, err := chain.GenerateSequence(NGram{"I"}, 0); !errors.Is(err, ErrOrderMismatch) {
+		t.Errorf("Chain.GenerateSequence() error = %v, want %v", err, ErrOrderMismatch)
+	}
+}
+
+func TestChain_GenerateSequence_Stop(t *testing.T) {
+	text := strings.Fields("the cat sat . a dog ran ! so it goes .")
+	chain := NewChain(2, WithStopTokens(".", "!", "?"))
+	chain.Add(text)
+	tests := []struct {
+		seed NGram
+		want []string
+	}{
+		{NGram{StartToken, StartToken}, []string{"the", "cat", "sat", "."}},
+		{NGram{"sat", "."}, []string{"a", "dog", "ran", "!"}},
+		{NGram{"ran", "!"}, []string{"so", "it", "goes", "."}},
+	}
+	for _, tt := range tests {
+		got, err := chain.GenerateSequence(tt.seed, 0)
+		if err != nil || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Chain.GenerateSequence(%v) = %v, %v, want %v", tt.seed, got, err, tt.want)
+		}
+	}
+	short := NewChain(2, WithStopWhen(func(tokens []string) bool { return len(tokens) == 2 }))
+	short.Add(text)
+	if got, _ := short.GenerateSequence(NGram{StartToken, StartToken}, 0); !reflect.DeepEqual(got, []string{"the", "cat"}) {
+		t.Errorf("Chain.GenerateSequence() with WithStopWhen = %v, want [the cat]", got)
+	}
+}
+
+func TestChain_GenerateSequence_Error(t *testing.T) {
+	chain := NewChainWithStore(2, failingStore{NewMemoryStore(), "want_a"})
+	chain.Add([]string{"I", "want"})
+	chain.Add([]string{"you", "want", "a", "burger"})
+
+	got, err := chain.GenerateSequence(NGram{"you", "want"}, 0)
+	var seqErr *SequenceError
+	if !errors.As(err, &seqErr) {
+		t.Fatalf("Chain.GenerateSequence() error = %v, want a SequenceError", err)
+	}
+	want := &SequenceError{Op: "generate", Position: 1, NGram: NGram{"want", "a"}, Partial: []string{"a"}, Err: errLookup}
+	if !reflect.DeepEqual(seqErr, want) || !reflect.DeepEqual(got, want.Partial) {
+		t.Errorf("Chain.GenerateSequence() = %v, %+v, want %+v", got, seqErr, want)
+	}
+	if !errors.Is(err, errLookup) {
+		t.Errorf("SequenceError does not unwrap to the cause")
+	}
+	if msg := "Cannot generate token 1 after [want a]: lookup failed"; err.Error() != msg {
+		t.Errorf("SequenceError.Error() = %q, want %q", err.Error(), msg)
+	}
+}
+
+func TestChain_LogProbability(t *testing.T) {
+	chain := NewChain(1)
+	chain.Add([]string{"I", "want", "a", "burger"})
+	chain.Add([]string{"I", "want", "a", "sprite"})
+	got, err := chain.LogProbability([]string{"I", "want", "a", "burger"})
+	if err != nil {
+		t.Fatalf("Chain.LogProbability() error = %v", err)
+	}
+	if want := math.Log(0.5); math.Abs(got-want) > 1e-12 {
+		t.Errorf("Chain.LogProbability() = %v, want %v", got, want)
+	}
+	if got, _ := chain.LogProbability([]string{"I", "want", "tea"}); !math.IsInf(got, -1) {
+		t.Errorf("Chain.LogProbability() of an unseen sequence = %v, want -Inf", got)
+	}
+
+	failing := NewChainWithStore(1, failingStore{NewMemoryStore(), "a"})
+	failing.Add([]string{"I", "want", "a", "burger"})
+	_, err = failing.LogProbability([]string{"I", "want", "a", "burger"})
+	var seqErr *SequenceError
+	if !errors.As(err, &seqErr) || seqErr.Op != "score" || seqErr.Position != 2 ||
+		!reflect.DeepEqual(seqErr.Partial, []string{"I", "want"}) {
+		t.Errorf("Chain.LogProbability() error = %+v, want a SequenceError at token 2", err)
+	}
+}
+
+func TestChain_FlooredLogProbability(t *testing.T) {
+	chain := NewChain(1)
+	chain.Add([]string{"I", "want", "a", "burger"})
+	chain.Add([]string{"I", "want", "a", "sprite"})
+	got, unseen, err := chain.FlooredLogProbability([]string{"I", "want", "a", "burger", "burger"}, 1e-3)
+	if err != nil {
+		t.Fatalf("Chain.FlooredLogProbability() error = %v", err)
+	}
+	if want := math.Log(0.5) + math.Log(1e-3); math.Abs(got-want) > 1e-12 || unseen != 1 {
+		t.Errorf("Chain.FlooredLogProbability() = %v, %d, want %v, 1", got, unseen, want)
+	}
+	logProb, _ := chain.LogProbability([]string{"I", "want", "a", "burger"})
+	if got, unseen, _ := chain.FlooredLogProbability([]string{"I", "want", "a", "burger"}, 1e-3); got != logProb || unseen != 0 {
+		t.Errorf("Chain.FlooredLogProbability() of a seen sequence = %v, %d, want %v, 0", got, unseen, logProb)
+	}
+}
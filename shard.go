@@ -0,0 +1,159 @@
+package gomarkov
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// MarshalShards serializes the chain into n independent JSON documents, each holding
+// the states and rows of a contiguous range of state indices. Shards are encoded
+// concurrently and can be decoded concurrently with UnmarshalShards. Float weights,
+// see WithFloatWeights, are saved with the rows they belong to.
+func (chain Chain) MarshalShards(n int) ([][]byte, error) {
+	if n < 1 {
+		return nil, errors.New("Shard count must be positive")
+	}
+	spoolMap, freqMat, weights, err := chain.exportModel()
+	if err != nil {
+		return nil, err
+	}
+	size := 0
+	for _, index := range spoolMap {
+		size = max(size, index+1)
+	}
+	shardOf := func(index int) int {
+		return index * n / max(size, 1)
+	}
+	spools := make([]map[string]int, n)
+	mats := make([]map[int]sparseArray, n)
+	weightMats := make([]map[int]map[int]float64, n)
+	for k := range spools {
+		spools[k] = make(map[string]int)
+		mats[k] = make(map[int]sparseArray)
+		if weights != nil {
+			weightMats[k] = make(map[int]map[int]float64)
+		}
+	}
+	for state, index := range spoolMap {
+		spools[shardOf(index)][state] = index
+	}
+	for current, row := range freqMat {
+		mats[shardOf(current)][current] = row
+	}
+	for current, row := range weights {
+		weightMats[shardOf(current)][current] = row
+	}
+
+	shards := make([][]byte, n)
+	errs := make([]error, n)
+	var wg sync.WaitGroup
+	for k := 0; k < n; k++ {
+		wg.Add(1)
+		go func(k int) {
+			defer wg.Done()
+			obj := chainJSON{Version: formatVersion, Order: chain.Order, Shard: k, Shards: n}
+			if obj.SpoolMap, errs[k] = json.Marshal(spools[k]); errs[k] != nil {
+				return
+			}
+			if obj.FreqMat, errs[k] = json.Marshal(mats[k]); errs[k] != nil {
+				return
+			}
+			if weightMats[k] != nil {
+				if obj.Weights, errs[k] = json.Marshal(weightMats[k]); errs[k] != nil {
+					return
+				}
+			}
+			obj.Checksum = checksum(obj)
+			shards[k], errs[k] = json.Marshal(obj)
+		}(k)
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+	return shards, nil
+}
+
+// UnmarshalShards rebuilds a chain from every shard written by MarshalShards, in any
+// order, decoding them concurrently
+func UnmarshalShards(shards [][]byte) (*Chain, error) {
+	type decoded struct {
+		obj      chainJSON
+		spoolMap map[string]int
+		freqMat  map[int]sparseArray
+		weights  map[int]map[int]float64
+		err      error
+	}
+	results := make([]decoded, len(shards))
+	var wg sync.WaitGroup
+	for i := range shards {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			r := &results[i]
+			r.obj, r.spoolMap, r.freqMat, r.err = decodeModel(shards[i])
+			if r.err == nil && len(r.obj.Weights) > 0 {
+				r.err = json.Unmarshal(r.obj.Weights, &r.weights)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if len(shards) == 0 {
+		return nil, errors.New("No shards to decode")
+	}
+	seen := make([]bool, len(shards))
+	states, rows := 0, 0
+	for i, r := range results {
+		if r.err != nil {
+			return nil, fmt.Errorf("Shard %d: %v", i, r.err)
+		}
+		if r.obj.Shards != len(shards) || r.obj.Shard < 0 || r.obj.Shard >= len(shards) {
+			return nil, fmt.Errorf("Shard %d: expected one of %d shards", i, len(shards))
+		}
+		if (r.weights == nil) != (results[0].weights == nil) {
+			return nil, fmt.Errorf("Shard %d: float weights must be saved in every shard or none", i)
+		}
+		if seen[r.obj.Shard] {
+			return nil, fmt.Errorf("Shard %d: duplicate shard %d", i, r.obj.Shard)
+		}
+		if r.obj.Order != results[0].obj.Order {
+			return nil, fmt.Errorf("Shard %d: order %d does not match %d", i, r.obj.Order, results[0].obj.Order)
+		}
+		seen[r.obj.Shard] = true
+		states += len(r.spoolMap)
+		rows += len(r.freqMat)
+	}
+
+	spoolMap := make(map[string]int, states)
+	freqMat := make(map[int]sparseArray, rows)
+	var weights map[int]map[int]float64
+	if results[0].weights != nil {
+		weights = make(map[int]map[int]float64, rows)
+	}
+	for _, r := range results {
+		for state, index := range r.spoolMap {
+			spoolMap[state] = index
+		}
+		for current, row := range r.freqMat {
+			freqMat[current] = row
+		}
+		for current, row := range r.weights {
+			weights[current] = row
+		}
+	}
+	if weights != nil {
+		if err := validateWeights(freqMat, weights); err != nil {
+			return nil, err
+		}
+	}
+	chain := new(Chain)
+	if err := chain.load(results[0].obj.Order, spoolMap, freqMat, weights); err != nil {
+		return nil, err
+	}
+	return chain, nil
+}
@@ -0,0 +1,77 @@
+package gomarkov
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestChain_MarshalShards(t *testing.T) {
+	chain := NewChain(2)
+	chain.Add([]string{"I", "want", "a", "cheese", "burger"})
+	chain.Add([]string{"I", "want", "a", "chilled", "sprite"})
+	chain.Add([]string{"I", "want", "to", "go", "to", "the", "movies"})
+	want := transitionCounts(t, chain)
+
+	tests := []struct {
+		name   string
+		shards int
+	}{
+		{"One shard", 1},
+		{"Few shards", 3},
+		{"More shards than states", 100},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			shards, err := chain.MarshalShards(tt.shards)
+			if err != nil {
+				t.Fatalf("Chain.MarshalShards() error = %v", err)
+			}
+			if len(shards) != tt.shards {
+				t.Fatalf("Chain.MarshalShards() returned %d shards, want %d", len(shards), tt.shards)
+			}
+			// Shards may be decoded in any order
+			reversed := make([][]byte, len(shards))
+			for i, shard := range shards {
+				reversed[len(shards)-1-i] = shard
+			}
+			loaded, err := UnmarshalShards(reversed)
+			if err != nil {
+				t.Fatalf("UnmarshalShards() error = %v", err)
+			}
+			if loaded.Order != 2 {
+				t.Errorf("UnmarshalShards() order = %d, want 2", loaded.Order)
+			}
+			if got := transitionCounts(t, loaded); !reflect.DeepEqual(got, want) {
+				t.Errorf("UnmarshalShards() = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestUnmarshalShards_Invalid(t *testing.T) {
+	chain := NewChain(1)
+	chain.Add([]string{"test", "data"})
+	shards, _ := chain.MarshalShards(2)
+	whole, _ := chain.MarshalJSON()
+
+	tests := []struct {
+		name   string
+		shards [][]byte
+	}{
+		{"No shards", [][]byte{}},
+		{"Missing shard", shards[:1]},
+		{"Duplicate shard", [][]byte{shards[0], shards[0]}},
+		{"Not a shard", [][]byte{whole}},
+		{"Invalid json", [][]byte{shards[0], []byte("{")}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := UnmarshalShards(tt.shards); err == nil {
+				t.Errorf("UnmarshalShards() expected an error")
+			}
+		})
+	}
+	if _, err := chain.MarshalShards(0); err == nil {
+		t.Errorf("Chain.MarshalShards(0) expected an error")
+	}
+}
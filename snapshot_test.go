@@ -0,0 +1,64 @@
+package gomarkov
+
+import (
+	"reflect"
+	"sync"
+	"testing"
+)
+
+func TestChain_Snapshot(t *testing.T) {
+	chain := NewChain(1)
+	chain.Add([]string{"test", "data"})
+
+	snap, err := chain.Snapshot()
+	if err != nil {
+		t.Fatalf("Chain.Snapshot() error = %v", err)
+	}
+	want := transitionCounts(t, chain)
+
+	chain.Add([]string{"test", "node"})
+	chain.Add([]string{"new", "words"})
+	if got := transitionCounts(t, snap); !reflect.DeepEqual(got, want) {
+		t.Errorf("Snapshot changed after training the original: got %v, want %v", got, want)
+	}
+
+	snap.Add([]string{"test", "snapshot"})
+	got, _ := chain.TransitionProbability("snapshot", NGram{"test"})
+	if got != 0 {
+		t.Errorf("Training the snapshot changed the original")
+	}
+	got, _ = chain.TransitionProbability("node", NGram{"test"})
+	if got != 0.5 {
+		t.Errorf("Chain.TransitionProbability() = %v, want 0.5", got)
+	}
+}
+
+func TestChain_Snapshot_WhileTraining(t *testing.T) {
+	chain := NewChain(1)
+	chain.Add([]string{"seed"})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 200; i++ {
+			chain.Add([]string{"test", "data", "more"})
+		}
+	}()
+	for i := 0; i < 20; i++ {
+		snap, err := chain.Snapshot()
+		if err != nil {
+			t.Fatalf("Chain.Snapshot() error = %v", err)
+		}
+		if _, err := snap.MarshalJSON(); err != nil {
+			t.Fatalf("Chain.MarshalJSON() error = %v", err)
+		}
+	}
+	wg.Wait()
+}
+
+func TestChain_Snapshot_Unsupported(t *testing.T) {
+	chain := NewRedisChain(1, newFakeRedis(), "bot")
+	if _, err := chain.Snapshot(); err == nil {
+		t.Errorf("Chain.Snapshot() expected an error for redis backed chains")
+	}
+}
@@ -1,33 +1,120 @@
 package gomarkov
 
-import "sync"
-
+// spool assigns dense indices to states. It is safe for concurrent use, and
+// copies returned by clone share their maps until either side adds a state.
 type spool struct {
 	stringMap map[string]int
-	intMap    map[int]string
-	sync.RWMutex
+	// next is the index of the next state added, past every index in stringMap even
+	// if a loaded model left gaps
+	next int
+	// intMap is only built once an index is looked up, as chains that are trained
+	// and serialized never need it
+	intMap map[int]string
+	// shared is set while the maps may be referenced by a clone
+	shared bool
+	lock   rwLock
+}
+
+func newSpool(stringMap map[string]int, intMap map[int]string) *spool {
+	s := &spool{stringMap: stringMap, intMap: intMap}
+	for _, index := range stringMap {
+		s.next = max(s.next, index+1)
+	}
+	return s
 }
 
 func (s *spool) add(str string) int {
-	s.RLock()
+	s.lock.RLock()
 	index, ok := s.stringMap[str]
-	s.RUnlock()
+	s.lock.RUnlock()
 	if ok {
 		return index
 	}
-	s.Lock()
-	defer s.Unlock()
+	s.lock.Lock()
+	defer s.lock.Unlock()
 	index, ok = s.stringMap[str]
 	if ok {
 		return index
 	}
-	index = len(s.stringMap)
+	if s.shared {
+		s.unshare()
+	}
+	index = s.next
+	s.next++
 	s.stringMap[str] = index
-	s.intMap[index] = str
+	if s.intMap != nil {
+		s.intMap[index] = str
+	}
 	return index
 }
 
+// unshare gives s its own copy of the maps, it must hold the write lock
+func (s *spool) unshare() {
+	stringMap := make(map[string]int, len(s.stringMap)+1)
+	for k, v := range s.stringMap {
+		stringMap[k] = v
+	}
+	var intMap map[int]string
+	if s.intMap != nil {
+		intMap = make(map[int]string, len(s.intMap)+1)
+		for k, v := range s.intMap {
+			intMap[k] = v
+		}
+	}
+	s.stringMap, s.intMap, s.shared = stringMap, intMap, false
+}
+
 func (s *spool) get(str string) (int, bool) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
 	index, ok := s.stringMap[str]
 	return index, ok
 }
+
+func (s *spool) lookup(index int) (string, bool) {
+	s.lock.RLock()
+	if s.intMap != nil {
+		str, ok := s.intMap[index]
+		s.lock.RUnlock()
+		return str, ok
+	}
+	s.lock.RUnlock()
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.intMap == nil {
+		s.buildIntMap()
+	}
+	str, ok := s.intMap[index]
+	return str, ok
+}
+
+// buildIntMap inverts stringMap, it must hold the write lock
+func (s *spool) buildIntMap() {
+	s.intMap = make(map[int]string, len(s.stringMap))
+	for k, v := range s.stringMap {
+		s.intMap[v] = k
+	}
+}
+
+func (s *spool) len() int {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	return len(s.stringMap)
+}
+
+// rangeStates calls fn for every state without building intMap
+func (s *spool) rangeStates(fn func(state string, index int)) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	for state, index := range s.stringMap {
+		fn(state, index)
+	}
+}
+
+// clone returns a spool with the same states, sharing the maps copy-on-write
+func (s *spool) clone() *spool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.shared = true
+	return &spool{stringMap: s.stringMap, intMap: s.intMap, next: s.next, shared: true}
+}
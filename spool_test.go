@@ -1,50 +1,93 @@
 package gomarkov
 
-import "testing"
+import (
+	"strconv"
+	"sync"
+	"testing"
+)
 
-func Test_spool_add(t *testing.T) {
-	type args struct {
-		str string
-	}
-	tests := []struct {
-		name string
-		s    *spool
-		args args
-		want int
+func Test_spool_clone(t *testing.T) {
+	s := newSpool(make(map[string]int), make(map[int]string))
+	s.add("a")
+	s.add("b")
+	c := s.clone()
+	s.add("c")
+	c.add("d")
+
+	for _, tt := range []struct {
+		s      *spool
+		state  string
+		want   int
+		wantOk bool
 	}{
-		// TODO: Add test cases.
+		{s, "b", 1, true},
+		{s, "c", 2, true},
+		{s, "d", 0, false},
+		{c, "b", 1, true},
+		{c, "c", 0, false},
+		{c, "d", 2, true},
+	} {
+		if got, ok := tt.s.get(tt.state); got != tt.want || ok != tt.wantOk {
+			t.Errorf("spool.get(%v) = %v, %v, want %v, %v", tt.state, got, ok, tt.want, tt.wantOk)
+		}
 	}
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			if got := tt.s.add(tt.args.str); got != tt.want {
-				t.Errorf("spool.add() = %v, want %v", got, tt.want)
-			}
-		})
+	if got, _ := c.lookup(2); got != "d" {
+		t.Errorf("spool.lookup(2) = %v, want d", got)
 	}
 }
 
-func Test_spool_get(t *testing.T) {
-	type args struct {
-		str string
-	}
-	tests := []struct {
-		name  string
-		s     *spool
-		args  args
-		want  int
-		want1 bool
-	}{
-		// TODO: Add test cases.
+func Test_spool_add_Sparse(t *testing.T) {
+	s := newSpool(map[string]int{"^": 0, "a": 5, "$": 2}, nil)
+	if got := s.add("d"); got != 6 {
+		t.Errorf("spool.add() = %v, want 6 past the highest loaded index", got)
 	}
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			got, got1 := tt.s.get(tt.args.str)
-			if got != tt.want {
-				t.Errorf("spool.get() got = %v, want %v", got, tt.want)
-			}
-			if got1 != tt.want1 {
-				t.Errorf("spool.get() got1 = %v, want %v", got1, tt.want1)
+	if got := s.clone().add("e"); got != 7 {
+		t.Errorf("cloned spool.add() = %v, want 7", got)
+	}
+}
+
+func Test_spool_ConcurrentAdd(t *testing.T) {
+	s := newSpool(make(map[string]int), make(map[int]string))
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for n := 0; n < 100; n++ {
+				index := s.add(strconv.Itoa(n))
+				if state, ok := s.lookup(index); !ok || state != strconv.Itoa(n) {
+					t.Errorf("spool.lookup(%v) = %v, %v, want %v", index, state, ok, n)
+				}
+				if n == 50 {
+					s.clone()
+				}
 			}
-		})
+		}()
+	}
+	wg.Wait()
+	if len(s.stringMap) != 100 || len(s.intMap) != 100 {
+		t.Errorf("spool holds %d states, want 100", len(s.stringMap))
+	}
+}
+
+func Test_spool_LazyIntMap(t *testing.T) {
+	chain := NewChain(1)
+	chain.Add([]string{"I", "want", "a", "cheese", "burger"})
+	s := chain.store.(*memoryStore).statePool
+	if _, err := chain.MarshalJSON(); err != nil {
+		t.Fatalf("Chain.MarshalJSON() error = %v", err)
+	}
+	if s.intMap != nil {
+		t.Errorf("spool.intMap built by training and serializing")
+	}
+	if _, err := chain.Generate(NGram{"I"}); err != nil {
+		t.Fatalf("Chain.Generate() error = %v", err)
+	}
+	if len(s.intMap) != len(s.stringMap) {
+		t.Errorf("spool.intMap has %d states, want %d", len(s.intMap), len(s.stringMap))
+	}
+	index := s.add("fries")
+	if got, ok := s.lookup(index); !ok || got != "fries" {
+		t.Errorf("spool.lookup() = %v, %v after adding, want fries", got, ok)
 	}
 }
@@ -0,0 +1,479 @@
+package gomarkov
+
+import (
+	"errors"
+	"sync"
+)
+
+// Store holds the state pool and transition counts backing a chain.
+// States are n-gram keys or single tokens, each identified by a dense integer index.
+type Store interface {
+	// AddState returns the index of a state, assigning a new one if it hasn't been seen
+	AddState(state string) (int, error)
+	// LookupState returns the index of a state and whether it exists
+	LookupState(state string) (int, bool, error)
+	// LookupIndex returns the state with the given index and whether it exists
+	LookupIndex(index int) (string, bool, error)
+	// IncrementTransition adds delta to the transition count between two states. It
+	// returns ErrCountOverflow rather than let a count or its row's total wrap around.
+	IncrementTransition(current, next, delta int) error
+	// GetRow returns the transition counts out of a state, keyed by next state index.
+	// The returned map must not be modified.
+	GetRow(current int) (map[int]int, error)
+	// IterateRows calls fn for every state that has outgoing transitions, stopping
+	// early if fn returns false. fn must not modify the store or retain row.
+	IterateRows(fn func(current int, row map[int]int) bool) error
+}
+
+// memoryStripes is the number of independently locked shards of a memoryStore's
+// rows, letting concurrent Adds to different states proceed in parallel
+const memoryStripes = 64
+
+var errNegativeIndex = errors.New("State indices must not be negative")
+
+// rwLock is a sync.RWMutex that can be disabled for stores only ever used from a
+// single goroutine, see WithoutLocking
+type rwLock struct {
+	disabled bool
+	mu       sync.RWMutex
+}
+
+func (l *rwLock) Lock() {
+	if !l.disabled {
+		l.mu.Lock()
+	}
+}
+
+func (l *rwLock) Unlock() {
+	if !l.disabled {
+		l.mu.Unlock()
+	}
+}
+
+func (l *rwLock) RLock() {
+	if !l.disabled {
+		l.mu.RLock()
+	}
+}
+
+func (l *rwLock) RUnlock() {
+	if !l.disabled {
+		l.mu.RUnlock()
+	}
+}
+
+// memoryStore keeps the whole chain in process memory
+type memoryStore struct {
+	statePool *spool
+	// ngrams caches the state index of n-grams added or looked up through the chain
+	ngrams ngramIndex
+	// stripes hold the rows, row i in stripe i % memoryStripes
+	stripes [memoryStripes]*memoryStripe
+}
+
+// memoryStripe holds a share of a memoryStore's rows under its own lock. State
+// indices are dense, so rows are kept in a slice rather than a map.
+type memoryStripe struct {
+	// rows holds row i of the store at rows[i/memoryStripes]
+	rows []memoryRow
+	// epoch is incremented by every snapshot, see memoryRow.epoch
+	epoch int
+	lock  rwLock
+}
+
+// memoryRow holds the transitions out of a state
+type memoryRow struct {
+	counts sparseArray
+	// dense replaces counts for rows with more than denseRowThreshold successors
+	dense *denseRow
+	// sum is the total count of the row, kept up to date as counts are added
+	sum int
+	// cdf caches the sampling distribution of the row, reset when it changes
+	cdf *cumulativeDist
+	// epoch is the stripe's epoch when the row was last copied. Rows from an earlier
+	// epoch may be shared with a snapshot and are copied before writing.
+	epoch int
+}
+
+func (r *memoryRow) sparse() sparseArray {
+	if r.dense != nil {
+		return r.dense.sparse()
+	}
+	return r.counts
+}
+
+func (r *memoryRow) count(next int) int {
+	if r.dense != nil {
+		return r.dense.get(next)
+	}
+	return r.counts[next]
+}
+
+// NewMemoryStore creates an empty in-memory Store, the default used by NewChain
+func NewMemoryStore() Store {
+	return newMemoryStore()
+}
+
+func newMemoryStore() *memoryStore {
+	return newMemoryStoreSized(0, 0)
+}
+
+// newMemoryStoreSized creates a memoryStore with room for the given number of
+// states in the pool and rows in the frequency matrix
+func newMemoryStoreSized(states, rows int) *memoryStore {
+	m := &memoryStore{
+		statePool: newSpool(make(map[string]int, states), nil),
+	}
+	perStripe := rows / memoryStripes
+	for i := range m.stripes {
+		m.stripes[i] = &memoryStripe{rows: make([]memoryRow, 0, perStripe)}
+	}
+	return m
+}
+
+// loadMemoryStore creates a memoryStore holding a decoded model
+func loadMemoryStore(spoolMap map[string]int, intMap map[int]string, freqMat map[int]sparseArray) *memoryStore {
+	m := newMemoryStoreSized(0, len(spoolMap))
+	m.statePool = newSpool(spoolMap, intMap)
+	for current, row := range freqMat {
+		r := m.stripe(current).grow(current)
+		r.sum = row.sum()
+		if len(row) > denseRowThreshold {
+			r.dense = newDenseRow(row)
+		} else {
+			r.counts = row
+		}
+	}
+	return m
+}
+
+// disableLocking turns off every lock of the store, see WithoutLocking
+func (m *memoryStore) disableLocking() {
+	m.statePool.lock.disabled = true
+	m.ngrams.lock.disabled = true
+	for _, s := range m.stripes {
+		s.lock.disabled = true
+	}
+}
+
+func (m *memoryStore) stripe(current int) *memoryStripe {
+	return m.stripes[m.stripeIndex(current)]
+}
+
+func (m *memoryStore) stripeIndex(current int) int {
+	i := current % memoryStripes
+	if i < 0 {
+		i += memoryStripes
+	}
+	return i
+}
+
+// row returns the row of a state, or nil if it has none. The caller must hold the
+// stripe's lock for as long as it uses the row.
+func (s *memoryStripe) row(current int) *memoryRow {
+	i := current / memoryStripes
+	if current < 0 || i >= len(s.rows) {
+		return nil
+	}
+	return &s.rows[i]
+}
+
+// grow returns the row of a state, extending the stripe to hold it. The caller must
+// hold the write lock.
+func (s *memoryStripe) grow(current int) *memoryRow {
+	i := current / memoryStripes
+	if i >= len(s.rows) {
+		if i < cap(s.rows) {
+			s.rows = s.rows[:i+1]
+		} else {
+			s.rows = append(s.rows, make([]memoryRow, i+1-len(s.rows))...)
+		}
+	}
+	return &s.rows[i]
+}
+
+func (m *memoryStore) AddState(state string) (int, error) {
+	return m.statePool.add(state), nil
+}
+
+func (m *memoryStore) LookupState(state string) (int, bool, error) {
+	index, ok := m.statePool.get(state)
+	return index, ok, nil
+}
+
+// addNGram is AddState for an n-gram, without joining it into a key once it's known
+func (m *memoryStore) addNGram(ngram NGram) (int, error) {
+	if len(ngram) <= 1 {
+		return m.statePool.add(ngram.key()), nil
+	}
+	if index, ok := m.ngrams.find(ngram); ok {
+		return index, nil
+	}
+	index := m.statePool.add(ngram.key())
+	m.ngrams.insert(ngram, index)
+	return index, nil
+}
+
+// lookupNGram is LookupState for an n-gram, see addNGram
+func (m *memoryStore) lookupNGram(ngram NGram) (int, bool, error) {
+	if len(ngram) <= 1 {
+		index, ok := m.statePool.get(ngram.key())
+		return index, ok, nil
+	}
+	if index, ok := m.ngrams.find(ngram); ok {
+		return index, true, nil
+	}
+	index, ok := m.statePool.get(ngram.key())
+	if ok {
+		m.ngrams.insert(ngram, index)
+	}
+	return index, ok, nil
+}
+
+func (m *memoryStore) stateCount() int {
+	return m.statePool.len()
+}
+
+func (m *memoryStore) rangeStates(fn func(state string, index int)) {
+	m.statePool.rangeStates(fn)
+}
+
+func (m *memoryStore) LookupIndex(index int) (string, bool, error) {
+	state, ok := m.statePool.lookup(index)
+	return state, ok, nil
+}
+
+func (m *memoryStore) IncrementTransition(current, next, delta int) error {
+	if current < 0 {
+		return errNegativeIndex
+	}
+	s := m.stripe(current)
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if !s.fits(current, delta) {
+		return ErrCountOverflow
+	}
+	s.increment(current, next, delta)
+	return nil
+}
+
+// incrementRows adds a batch of counts, locking each stripe once
+func (m *memoryStore) incrementRows(rows map[int]sparseArray) error {
+	var byStripe [memoryStripes][]int
+	for current := range rows {
+		if current < 0 {
+			return errNegativeIndex
+		}
+		i := m.stripeIndex(current)
+		byStripe[i] = append(byStripe[i], current)
+	}
+	for i, currents := range byStripe {
+		if len(currents) == 0 {
+			continue
+		}
+		s := m.stripes[i]
+		s.lock.Lock()
+		// Check the whole stripe first, so a failing batch leaves it untouched
+		for _, current := range currents {
+			total, ok := 0, true
+			for _, delta := range rows[current] {
+				if total, ok = addCount(total, delta); !ok {
+					break
+				}
+			}
+			if !ok || !s.fits(current, total) {
+				s.lock.Unlock()
+				return ErrCountOverflow
+			}
+		}
+		for _, current := range currents {
+			for next, delta := range rows[current] {
+				s.increment(current, next, delta)
+			}
+		}
+		s.lock.Unlock()
+	}
+	return nil
+}
+
+// fits reports whether delta can be added to the counts of a row without its total
+// overflowing, the caller must hold the lock. Counts never exceed their row's total.
+func (s *memoryStripe) fits(current, delta int) bool {
+	sum := 0
+	if r := s.row(current); r != nil {
+		sum = r.sum
+	}
+	_, ok := addCount(sum, delta)
+	return ok
+}
+
+// increment adds delta to a count of the stripe, the caller must hold the write lock
+func (s *memoryStripe) increment(current, next, delta int) {
+	r := s.grow(current)
+	r.sum += delta
+	r.cdf = nil
+	shared := r.epoch != s.epoch
+	r.epoch = s.epoch
+	if r.dense != nil {
+		if shared {
+			r.dense = r.dense.clone()
+		}
+		r.dense.add(next, delta)
+		return
+	}
+	if shared {
+		// The row is shared with a snapshot, copy it before writing
+		row := make(sparseArray, len(r.counts)+1)
+		for k, v := range r.counts {
+			row[k] = v
+		}
+		r.counts = row
+	}
+	if r.counts == nil {
+		r.counts = make(sparseArray, 0)
+	}
+	r.counts[next] += delta
+	if len(r.counts) > denseRowThreshold {
+		r.dense = newDenseRow(r.counts)
+		r.counts = nil
+	}
+}
+
+func (m *memoryStore) distribution(current int) (distribution, error) {
+	return m.cumulative(current), nil
+}
+
+func (m *memoryStore) cumulative(current int) *cumulativeDist {
+	s := m.stripe(current)
+	s.lock.RLock()
+	if r := s.row(current); r != nil && r.cdf != nil {
+		d := r.cdf
+		s.lock.RUnlock()
+		return d
+	}
+	s.lock.RUnlock()
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	r := s.row(current)
+	if r == nil {
+		return sparseArray(nil).cumulative()
+	}
+	if r.cdf == nil {
+		if r.dense != nil {
+			r.cdf = r.dense.cumulative()
+		} else {
+			r.cdf = r.counts.cumulative()
+		}
+	}
+	return r.cdf
+}
+
+// lockAll write locks every stripe, in order so concurrent callers can't deadlock
+func (m *memoryStore) lockAll() func() {
+	for _, s := range m.stripes {
+		s.lock.Lock()
+	}
+	return func() {
+		for _, s := range m.stripes {
+			s.lock.Unlock()
+		}
+	}
+}
+
+// snapshot returns a store sharing all rows with m. Both stores copy a shared row
+// the first time they write to it, so neither sees the other's later changes.
+func (m *memoryStore) snapshot() Store {
+	defer m.lockAll()()
+	snap := newMemoryStore()
+	snap.statePool = m.statePool.clone()
+	for i, s := range m.stripes {
+		s.epoch++
+		snap.stripes[i].rows = append(make([]memoryRow, 0, len(s.rows)), s.rows...)
+		snap.stripes[i].epoch = s.epoch
+	}
+	return snap
+}
+
+// GetRow returns a copy of the row, so it stays consistent while training continues
+func (m *memoryStore) GetRow(current int) (map[int]int, error) {
+	s := m.stripe(current)
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	r := s.row(current)
+	if r == nil {
+		return nil, nil
+	}
+	if r.dense != nil {
+		return r.dense.sparse(), nil
+	}
+	if r.counts == nil {
+		return nil, nil
+	}
+	copied := make(map[int]int, len(r.counts))
+	for next, count := range r.counts {
+		copied[next] = count
+	}
+	return copied, nil
+}
+
+// transitionCount returns a single count and the total of its row, read together
+// under the row's lock and without copying the row
+func (m *memoryStore) transitionCount(current, next int) (count, sum int) {
+	s := m.stripe(current)
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	r := s.row(current)
+	if r == nil {
+		return 0, 0
+	}
+	return r.count(next), r.sum
+}
+
+// rangeRows is IterateRows reading the rows in place, so dense rows aren't converted
+// to maps, see Chain.iterateRows
+func (m *memoryStore) rangeRows(fn func(current int, row rowReader) bool) {
+	for _, s := range m.stripes {
+		s.lock.RLock()
+	}
+	defer func() {
+		for _, s := range m.stripes {
+			s.lock.RUnlock()
+		}
+	}()
+	for i, s := range m.stripes {
+		for j := range s.rows {
+			r := &s.rows[j]
+			if r.counts == nil && r.dense == nil {
+				continue
+			}
+			if !fn(j*memoryStripes+i, rowReader{r.counts, r.dense}) {
+				return
+			}
+		}
+	}
+}
+
+// IterateRows read locks every stripe for the whole iteration, so fn sees a
+// consistent chain even while other goroutines train it
+func (m *memoryStore) IterateRows(fn func(current int, row map[int]int) bool) error {
+	for _, s := range m.stripes {
+		s.lock.RLock()
+	}
+	defer func() {
+		for _, s := range m.stripes {
+			s.lock.RUnlock()
+		}
+	}()
+	for i, s := range m.stripes {
+		for j := range s.rows {
+			r := &s.rows[j]
+			if r.counts == nil && r.dense == nil {
+				continue
+			}
+			if !fn(j*memoryStripes+i, r.sparse()) {
+				return nil
+			}
+		}
+	}
+	return nil
+}
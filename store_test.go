@@ -0,0 +1,417 @@
+package gomarkov
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"math"
+	"math/rand"
+	"reflect"
+	"strconv"
+	"sync"
+	"testing"
+)
+
+func Test_memoryStore_IncrementTransition(t *testing.T) {
+	type increment struct {
+		current, next, delta int
+	}
+	tests := []struct {
+		name       string
+		increments []increment
+		want       map[int]sparseArray
+	}{
+		{"No increments", []increment{}, map[int]sparseArray{}},
+		{"Single increment", []increment{{0, 1, 1}}, map[int]sparseArray{0: {1: 1}}},
+		{"Accumulated deltas", []increment{{0, 1, 1}, {0, 1, 2}, {0, 2, 1}}, map[int]sparseArray{0: {1: 3, 2: 1}}},
+		{"Several rows", []increment{{0, 1, 1}, {1, 2, 5}}, map[int]sparseArray{0: {1: 1}, 1: {2: 5}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := newMemoryStore()
+			for _, inc := range tt.increments {
+				if err := m.IncrementTransition(inc.current, inc.next, inc.delta); err != nil {
+					t.Fatalf("memoryStore.IncrementTransition() error = %v", err)
+				}
+			}
+			got := map[int]sparseArray{}
+			m.IterateRows(func(current int, row map[int]int) bool {
+				got[current] = row
+				return true
+			})
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("memoryStore rows = %v, want %v", got, tt.want)
+			}
+			for current, row := range tt.want {
+				if _, got := m.transitionCount(current, 0); got != row.sum() {
+					t.Errorf("memoryStore.transitionCount(%v) sum = %v, want %v", current, got, row.sum())
+				}
+			}
+		})
+	}
+}
+
+func Test_memoryStore_IterateRows(t *testing.T) {
+	m := newMemoryStore()
+	m.IncrementTransition(0, 1, 1)
+	m.IncrementTransition(1, 2, 1)
+	m.IncrementTransition(2, 3, 1)
+
+	visited := 0
+	m.IterateRows(func(current int, row map[int]int) bool {
+		visited++
+		return true
+	})
+	if visited != 3 {
+		t.Errorf("memoryStore.IterateRows() visited %d rows, want 3", visited)
+	}
+
+	visited = 0
+	m.IterateRows(func(current int, row map[int]int) bool {
+		visited++
+		return false
+	})
+	if visited != 1 {
+		t.Errorf("memoryStore.IterateRows() did not stop early, visited %d rows", visited)
+	}
+}
+
+func TestNewChainWithStore(t *testing.T) {
+	store := NewMemoryStore()
+	chain := NewChainWithStore(1, store)
+	chain.Add([]string{"Test"})
+
+	index, ok, err := store.LookupState("Test")
+	if err != nil || !ok {
+		t.Fatalf("Store.LookupState() = %v, %v, %v", index, ok, err)
+	}
+	row, err := store.GetRow(index)
+	if err != nil {
+		t.Fatalf("Store.GetRow() error = %v", err)
+	}
+	end, _, _ := store.LookupState(EndToken)
+	if !reflect.DeepEqual(row, map[int]int{end: 1}) {
+		t.Errorf("Store.GetRow() = %v, want %v", row, map[int]int{end: 1})
+	}
+}
+
+func Test_memoryStore_cumulative(t *testing.T) {
+	m := newMemoryStore()
+	m.IncrementTransition(0, 1, 1)
+	if got := m.cumulative(0).sum(); got != 1 {
+		t.Errorf("memoryStore.cumulative().sum() = %v, want 1", got)
+	}
+	m.IncrementTransition(0, 2, 2)
+	if got := m.cumulative(0).sum(); got != 3 {
+		t.Errorf("memoryStore.cumulative() not invalidated, sum = %v, want 3", got)
+	}
+}
+
+func Test_memoryStore_transitionCount_Loaded(t *testing.T) {
+	chain := NewChain(1)
+	chain.Add([]string{"a", "b"})
+	chain.Add([]string{"a", "c"})
+	data, err := chain.MarshalJSON()
+	if err != nil {
+		t.Fatalf("Chain.MarshalJSON() error = %v", err)
+	}
+	var loaded Chain
+	if err := loaded.UnmarshalJSON(data); err != nil {
+		t.Fatalf("Chain.UnmarshalJSON() error = %v", err)
+	}
+	snap, err := loaded.Snapshot()
+	if err != nil {
+		t.Fatalf("Chain.Snapshot() error = %v", err)
+	}
+	loaded.Add([]string{"a", "d"})
+	for _, tt := range []struct {
+		chain *Chain
+		want  float64
+	}{{&loaded, 1.0 / 3}, {snap, 0.5}} {
+		if got, _ := tt.chain.TransitionProbability("b", NGram{"a"}); got != tt.want {
+			t.Errorf("Chain.TransitionProbability() = %v, want %v", got, tt.want)
+		}
+	}
+}
+
+func TestChain_ConcurrentAdd(t *testing.T) {
+	chain := NewChain(2)
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for n := 0; n < 100; n++ {
+				chain.Add([]string{"I", "want", strconv.Itoa(n % 10), strconv.Itoa(i)})
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	want := NewChain(2)
+	for i := 0; i < 8; i++ {
+		for n := 0; n < 100; n++ {
+			want.Add([]string{"I", "want", strconv.Itoa(n % 10), strconv.Itoa(i)})
+		}
+	}
+	if !reflect.DeepEqual(transitionCounts(t, chain), transitionCounts(t, want)) {
+		t.Errorf("Concurrent Chain.Add() lost or duplicated transitions")
+	}
+}
+
+func TestChain_GenerateWhileTraining(t *testing.T) {
+	chain := NewChain(1)
+	chain.Add([]string{"I", "want", "a", "cheese", "burger"})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for n := 0; n < 200; n++ {
+			chain.Add([]string{"I", "want", "a", strconv.Itoa(n)})
+		}
+	}()
+	for n := 0; n < 200; n++ {
+		if _, err := chain.Generate(NGram{"a"}); err != nil {
+			t.Fatalf("Chain.Generate() error = %v", err)
+		}
+		if p, err := chain.TransitionProbability("a", NGram{"want"}); err != nil || p != 1 {
+			t.Fatalf("Chain.TransitionProbability() = %v, %v, want 1", p, err)
+		}
+		if _, err := chain.MarshalJSON(); err != nil {
+			t.Fatalf("Chain.MarshalJSON() error = %v", err)
+		}
+	}
+	wg.Wait()
+}
+
+// TestChain_ConcurrentAPI runs every kind of chain method at once, so the race
+// detector can check the whole surface rather than a single code path
+func TestChain_ConcurrentAPI(t *testing.T) {
+	chain := NewChain(2)
+	chain.Add([]string{"I", "want", "a", "cheese", "burger"})
+	readers := []func() error{
+		func() error {
+			_, err := chain.Generate(NGram{"I", "want"})
+			return err
+		},
+		func() error {
+			_, err := chain.TransitionProbability("a", NGram{"I", "want"})
+			return err
+		},
+		func() error {
+			_, err := chain.MarshalJSON()
+			return err
+		},
+		func() error {
+			_, err := chain.MarshalCanonicalJSON()
+			return err
+		},
+		func() error {
+			_, err := chain.Vocabulary()
+			return err
+		},
+		func() error {
+			_ = chain.String()
+			return chain.Dump(io.Discard, 5)
+		},
+		func() error {
+			snap, err := chain.Snapshot()
+			if err != nil {
+				return err
+			}
+			_, err = snap.Generate(NGram{"I", "want"})
+			return err
+		},
+		func() error {
+			frozen, err := chain.Freeze()
+			if err != nil {
+				return err
+			}
+			_, err = frozen.Generate(NGram{"I", "want"})
+			return err
+		},
+	}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, len(readers)+2)
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for n := 0; n < 100; n++ {
+			if err := chain.Add([]string{"I", "want", "a", strconv.Itoa(n)}); err != nil {
+				errs <- err
+				return
+			}
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for n := 0; n < 20; n++ {
+			if err := chain.AddBatch([][]string{{"I", "want", "to", strconv.Itoa(n)}}); err != nil {
+				errs <- err
+				return
+			}
+		}
+	}()
+	for _, read := range readers {
+		wg.Add(1)
+		go func(read func() error) {
+			defer wg.Done()
+			for n := 0; n < 20; n++ {
+				if err := read(); err != nil {
+					errs <- err
+					return
+				}
+			}
+		}(read)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Errorf("concurrent chain method error = %v", err)
+	}
+}
+
+func TestChain_ConcurrentGenerate(t *testing.T) {
+	chain := NewChain(1)
+	chain.Add([]string{"I", "want", "a", "cheese", "burger"})
+	chain.Add([]string{"I", "want", "a", "chilled", "sprite"})
+	// A frozen chain takes no locks, so only the PRNG could race
+	frozen, err := chain.Freeze()
+	if err != nil {
+		t.Fatalf("Chain.Freeze() error = %v", err)
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for n := 0; n < 100; n++ {
+				if _, err := frozen.Generate(NGram{"a"}); err != nil {
+					t.Errorf("Chain.Generate() error = %v", err)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func Test_memoryStore_NegativeIndex(t *testing.T) {
+	m := newMemoryStore()
+	if err := m.IncrementTransition(-1, 0, 1); err != errNegativeIndex {
+		t.Errorf("memoryStore.IncrementTransition() error = %v, want %v", err, errNegativeIndex)
+	}
+	if row, _ := m.GetRow(-1); row != nil {
+		t.Errorf("memoryStore.GetRow() = %v, want nil", row)
+	}
+}
+
+func Test_memoryStore_Overflow(t *testing.T) {
+	m := newMemoryStore()
+	if err := m.IncrementTransition(0, 1, math.MaxInt-1); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.IncrementTransition(0, 2, 2); !errors.Is(err, ErrCountOverflow) {
+		t.Errorf("memoryStore.IncrementTransition() error = %v, want %v", err, ErrCountOverflow)
+	}
+	if err := m.IncrementTransition(0, 2, 1); err != nil {
+		t.Errorf("memoryStore.IncrementTransition() up to the limit error = %v", err)
+	}
+
+	// A failing batch leaves the stripe untouched
+	batch := map[int]sparseArray{0: {1: 1}, memoryStripes: {3: 1}}
+	if err := m.incrementRows(batch); !errors.Is(err, ErrCountOverflow) {
+		t.Errorf("memoryStore.incrementRows() error = %v, want %v", err, ErrCountOverflow)
+	}
+	if row, _ := m.GetRow(memoryStripes); row != nil {
+		t.Errorf("memoryStore.incrementRows() applied part of a failing batch: %v", row)
+	}
+	if err := m.incrementRows(map[int]sparseArray{5: {1: math.MaxInt, 2: 1}}); !errors.Is(err, ErrCountOverflow) {
+		t.Errorf("memoryStore.incrementRows() with an overflowing row error = %v, want %v", err, ErrCountOverflow)
+	}
+
+	chain := NewChain(1)
+	chain.AddWeighted([]string{"a"}, math.MaxInt)
+	if err := chain.Add([]string{"a"}); !errors.Is(err, ErrCountOverflow) {
+		t.Errorf("Chain.Add() error = %v, want %v", err, ErrCountOverflow)
+	}
+	if p, _ := chain.TransitionProbability("a", NGram{StartToken}); p != 1 {
+		t.Errorf("P(a | ^) after an overflow = %v, want 1", p)
+	}
+}
+
+// TestChain_GenerateDeterministic_Distribution draws from a row whose last key in
+// sampling order has a count of 1, with every store and sampler
+func TestChain_GenerateDeterministic_Distribution(t *testing.T) {
+	train := func(chain *Chain) *Chain {
+		for _, next := range []string{"a", "b", "c", "c"} {
+			if err := chain.Add([]string{"x", next}); err != nil {
+				t.Fatalf("Chain.Add() error = %v", err)
+			}
+		}
+		return chain
+	}
+	want := map[string]float64{"a": 0.25, "b": 0.25, "c": 0.5}
+	const draws = 20000
+	check := func(t *testing.T, generate func(prng PRNG) (string, error)) {
+		prng := rand.New(rand.NewSource(1))
+		got := make(map[string]int)
+		for i := 0; i < draws; i++ {
+			next, err := generate(prng)
+			if err != nil {
+				t.Fatalf("Chain.GenerateDeterministic() error = %v", err)
+			}
+			got[next]++
+		}
+		for next, p := range want {
+			if share := float64(got[next]) / draws; math.Abs(share-p) > 0.02 {
+				t.Errorf("Chain.GenerateDeterministic() drew %q %v of the time, want %v; got %v", next, share, p, got)
+			}
+		}
+	}
+
+	var compiled bytes.Buffer
+	if err := train(NewChain(1)).Compile(&compiled); err != nil {
+		t.Fatalf("Chain.Compile() error = %v", err)
+	}
+	chains := map[string]func() (*Chain, error){
+		"Memory":   func() (*Chain, error) { return train(NewChain(1)), nil },
+		"Compact":  func() (*Chain, error) { return train(NewChainWithStore(1, NewCompactMemoryStore())), nil },
+		"Weighted": func() (*Chain, error) { return train(NewChain(1, WithFloatWeights())), nil },
+		"Redis":    func() (*Chain, error) { return train(NewRedisChain(1, newFakeRedis(), "bot")), nil },
+		"Frozen":   func() (*Chain, error) { return train(NewChain(1)).Freeze() },
+		"Live": func() (*Chain, error) {
+			live, err := NewLiveChain(train(NewChain(1)))
+			if err != nil {
+				return nil, err
+			}
+			return live.View(), nil
+		},
+		"Compiled": func() (*Chain, error) { return LoadCompiled(compiled.Bytes()) },
+		"Lazy": func() (*Chain, error) {
+			return LoadCompiledLazy(bytes.NewReader(compiled.Bytes()), int64(compiled.Len()), 1)
+		},
+	}
+	for name, load := range chains {
+		t.Run(name, func(t *testing.T) {
+			chain, err := load()
+			if err != nil {
+				t.Fatalf("loading the chain: %v", err)
+			}
+			check(t, func(prng PRNG) (string, error) {
+				return chain.GenerateDeterministic(NGram{"x"}, prng)
+			})
+		})
+	}
+	t.Run("ChainOf", func(t *testing.T) {
+		chain := NewChainOf[string](1)
+		for _, next := range []string{"a", "b", "c", "c"} {
+			chain.Add([]string{"x", next})
+		}
+		check(t, func(prng PRNG) (string, error) {
+			next, _, err := chain.GenerateDeterministic([]string{"x"}, prng)
+			return next, err
+		})
+	})
+}
@@ -0,0 +1,39 @@
+package stream
+
+import (
+	"context"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// kafkaSource reads messages with a consumer group reader, committing their offsets
+type kafkaSource struct {
+	reader *kafka.Reader
+}
+
+// Kafka returns a Source reading from reader, which should belong to a consumer group
+// so trained messages are committed and consumption resumes where it stopped. The
+// reader is not closed by the trainer.
+func Kafka(reader *kafka.Reader) Source {
+	return kafkaSource{reader}
+}
+
+func (k kafkaSource) Fetch(ctx context.Context) (Message, error) {
+	msg, err := k.reader.FetchMessage(ctx)
+	if err != nil {
+		return Message{}, err
+	}
+	return Message{Value: msg.Value, Handle: msg}, nil
+}
+
+func (k kafkaSource) Commit(ctx context.Context, msgs []Message) error {
+	if k.reader.Config().GroupID == "" {
+		// Readers outside a consumer group can't commit offsets
+		return nil
+	}
+	committed := make([]kafka.Message, len(msgs))
+	for i, msg := range msgs {
+		committed[i] = msg.Handle.(kafka.Message)
+	}
+	return k.reader.CommitMessages(ctx, committed...)
+}
@@ -0,0 +1,47 @@
+package stream
+
+import (
+	"context"
+
+	"github.com/nats-io/nats.go"
+)
+
+// natsSource reads messages from a synchronous subscription, acking them if it is a
+// JetStream subscription
+type natsSource struct {
+	sub *nats.Subscription
+	ack bool
+}
+
+// NATS returns a Source reading from a synchronous core NATS subscription, such as
+// one created with SubscribeSync or QueueSubscribeSync. Core NATS doesn't redeliver
+// messages, so nothing is committed.
+func NATS(sub *nats.Subscription) Source {
+	return natsSource{sub: sub}
+}
+
+// JetStream returns a Source reading from a synchronous JetStream subscription with
+// explicit acks, acking messages once they have been trained on
+func JetStream(sub *nats.Subscription) Source {
+	return natsSource{sub: sub, ack: true}
+}
+
+func (n natsSource) Fetch(ctx context.Context) (Message, error) {
+	msg, err := n.sub.NextMsgWithContext(ctx)
+	if err != nil {
+		return Message{}, err
+	}
+	return Message{Value: msg.Data, Handle: msg}, nil
+}
+
+func (n natsSource) Commit(ctx context.Context, msgs []Message) error {
+	if !n.ack {
+		return nil
+	}
+	for _, msg := range msgs {
+		if err := msg.Handle.(*nats.Msg).Ack(nats.Context(ctx)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
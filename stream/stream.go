@@ -0,0 +1,251 @@
+// Package stream trains chains continuously from a message stream such as a Kafka
+// topic or a NATS subject. A Trainer tokenizes each message, adds them to the chain in
+// batches, commits them to the source once they've been trained on and periodically
+// checkpoints the model:
+//
+//	reader := kafka.NewReader(kafka.ReaderConfig{Brokers: brokers, GroupID: "bot", Topic: "chat"})
+//	trainer := stream.NewTrainer(chain, stream.Kafka(reader),
+//		stream.WithCheckpoint(time.Minute, stream.FileCheckpoint("model.json")))
+//	err := trainer.Run(ctx)
+//
+// Messages are fetched at most a buffer ahead of training, so a chain that can't keep
+// up slows consumption instead of growing memory. Messages are delivered at least
+// once: those trained on but not yet committed when the process dies are trained on
+// again.
+package stream
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/mb-14/gomarkov"
+)
+
+// Message is a unit of text received from a Source
+type Message struct {
+	Value []byte
+	// Handle is whatever the source needs to commit the message
+	Handle any
+}
+
+// Source delivers the messages to train on. It is only used from one goroutine at a time.
+type Source interface {
+	// Fetch blocks until a message is available or ctx is done
+	Fetch(ctx context.Context) (Message, error)
+	// Commit acknowledges messages once they have been trained on, in the order they
+	// were fetched
+	Commit(ctx context.Context, msgs []Message) error
+}
+
+// Option configures a Trainer
+type Option func(*Trainer)
+
+// WithTokenizer splits messages into tokens, strings.Fields by default. Messages
+// without tokens are committed without training.
+func WithTokenizer(tokenize func(string) []string) Option {
+	return func(t *Trainer) {
+		t.tokenize = tokenize
+	}
+}
+
+// WithBatch trains once size messages have been received, or interval after the
+// first message of a batch, whichever comes first. The defaults are 100 and a second.
+func WithBatch(size int, interval time.Duration) Option {
+	return func(t *Trainer) {
+		t.batchSize, t.batchInterval = size, interval
+	}
+}
+
+// WithBuffer sets how many messages may be fetched ahead of training, the batch size
+// by default
+func WithBuffer(n int) Option {
+	return func(t *Trainer) {
+		t.buffer = n
+	}
+}
+
+// WithCheckpoint calls save with a snapshot of the chain every interval, and once
+// more when the trainer stops, if any message was trained on since the last one
+func WithCheckpoint(interval time.Duration, save func(*gomarkov.Chain) error) Option {
+	return func(t *Trainer) {
+		t.checkpointInterval, t.checkpoint = interval, save
+	}
+}
+
+// FileCheckpoint returns a checkpoint function for WithCheckpoint saving the chain's
+// JSON to path. The file is replaced atomically, so it always holds a whole model.
+func FileCheckpoint(path string) func(*gomarkov.Chain) error {
+	return func(chain *gomarkov.Chain) error {
+		data, err := chain.MarshalJSON()
+		if err != nil {
+			return err
+		}
+		f, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+		if err != nil {
+			return err
+		}
+		defer os.Remove(f.Name())
+		if _, err := f.Write(data); err != nil {
+			f.Close()
+			return err
+		}
+		if err := f.Close(); err != nil {
+			return err
+		}
+		return os.Rename(f.Name(), path)
+	}
+}
+
+// Trainer trains a chain from a Source
+type Trainer struct {
+	chain              *gomarkov.Chain
+	source             Source
+	tokenize           func(string) []string
+	batchSize          int
+	batchInterval      time.Duration
+	buffer             int
+	checkpointInterval time.Duration
+	checkpoint         func(*gomarkov.Chain) error
+}
+
+// NewTrainer creates a Trainer adding the messages of source to chain
+func NewTrainer(chain *gomarkov.Chain, source Source, opts ...Option) *Trainer {
+	t := &Trainer{
+		chain:         chain,
+		source:        source,
+		tokenize:      strings.Fields,
+		batchSize:     100,
+		batchInterval: time.Second,
+	}
+	for _, opt := range opts {
+		opt(t)
+	}
+	if t.batchSize < 1 {
+		t.batchSize = 1
+	}
+	if t.buffer < 1 {
+		t.buffer = t.batchSize
+	}
+	return t
+}
+
+// fetched is a message or the error that ended fetching
+type fetched struct {
+	msg Message
+	err error
+}
+
+// Run trains the chain until ctx is done or the source fails. The current batch is
+// then trained on, committed and checkpointed before Run returns, while messages
+// fetched ahead of it are left uncommitted. It returns nil once ctx is done, and
+// otherwise the first error of the source, the chain or the checkpoint.
+func (t *Trainer) Run(ctx context.Context) error {
+	fetchCtx, stop := context.WithCancel(ctx)
+	defer stop()
+	messages := make(chan fetched, t.buffer)
+	go t.fetch(fetchCtx, messages)
+
+	var batch []Message
+	var flushTimer, checkpointTimer <-chan time.Time
+	if t.checkpoint != nil && t.checkpointInterval > 0 {
+		ticker := time.NewTicker(t.checkpointInterval)
+		defer ticker.Stop()
+		checkpointTimer = ticker.C
+	}
+	dirty := false
+	// Finishing must not be cut short by ctx, which is likely what stopped the trainer
+	finish := func(err error) error {
+		stop()
+		trained, ferr := t.flush(context.WithoutCancel(ctx), batch)
+		if ferr != nil && err == nil {
+			err = ferr
+		}
+		dirty = dirty || trained
+		if dirty && t.checkpoint != nil {
+			if cerr := t.save(); cerr != nil && err == nil {
+				err = cerr
+			}
+		}
+		return err
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return finish(nil)
+		case f := <-messages:
+			if f.err != nil {
+				if ctx.Err() != nil {
+					return finish(nil)
+				}
+				return finish(f.err)
+			}
+			batch = append(batch, f.msg)
+			if len(batch) == 1 {
+				flushTimer = time.After(t.batchInterval)
+			}
+			if len(batch) < t.batchSize {
+				continue
+			}
+		case <-flushTimer:
+		case <-checkpointTimer:
+			if dirty {
+				if err := t.save(); err != nil {
+					return finish(err)
+				}
+				dirty = false
+			}
+			continue
+		}
+		trained, err := t.flush(ctx, batch)
+		dirty = dirty || trained
+		if err != nil {
+			batch = nil
+			return finish(err)
+		}
+		batch, flushTimer = nil, nil
+	}
+}
+
+// fetch sends messages from the source until ctx is done or fetching fails
+func (t *Trainer) fetch(ctx context.Context, messages chan<- fetched) {
+	for {
+		msg, err := t.source.Fetch(ctx)
+		select {
+		case messages <- fetched{msg, err}:
+		case <-ctx.Done():
+			return
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// flush trains on a batch of messages and commits them. trained reports whether the
+// chain took the batch, even if committing it then failed, so it is checkpointed.
+func (t *Trainer) flush(ctx context.Context, batch []Message) (trained bool, err error) {
+	if len(batch) == 0 {
+		return false, nil
+	}
+	sequences := make([][]string, 0, len(batch))
+	for _, msg := range batch {
+		if tokens := t.tokenize(string(msg.Value)); len(tokens) > 0 {
+			sequences = append(sequences, tokens)
+		}
+	}
+	if err := t.chain.AddBatch(sequences); err != nil {
+		return false, err
+	}
+	return true, t.source.Commit(ctx, batch)
+}
+
+func (t *Trainer) save() error {
+	snapshot, err := t.chain.Snapshot()
+	if err != nil {
+		return err
+	}
+	return t.checkpoint(snapshot)
+}
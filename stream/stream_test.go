@@ -0,0 +1,169 @@
+package stream
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mb-14/gomarkov"
+)
+
+// fakeSource delivers queued messages, then blocks until the context is done or
+// fails with err if it is set
+type fakeSource struct {
+	lock      sync.Mutex
+	queue     []string
+	err       error
+	commitErr error
+	committed []string
+	commits   int
+}
+
+func (f *fakeSource) Fetch(ctx context.Context) (Message, error) {
+	f.lock.Lock()
+	if len(f.queue) > 0 {
+		value := f.queue[0]
+		f.queue = f.queue[1:]
+		f.lock.Unlock()
+		return Message{Value: []byte(value), Handle: value}, nil
+	}
+	err := f.err
+	f.lock.Unlock()
+	if err != nil {
+		return Message{}, err
+	}
+	<-ctx.Done()
+	return Message{}, ctx.Err()
+}
+
+func (f *fakeSource) Commit(ctx context.Context, msgs []Message) error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.commits++
+	if f.commitErr != nil {
+		return f.commitErr
+	}
+	for _, msg := range msgs {
+		f.committed = append(f.committed, msg.Handle.(string))
+	}
+	return nil
+}
+
+func (f *fakeSource) committedCount() int {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	return len(f.committed)
+}
+
+func TestTrainer_Run(t *testing.T) {
+	source := &fakeSource{queue: []string{"a b", "", "b c", "c a", "a c"}}
+	chain := gomarkov.NewChain(1)
+	var checkpoints []*gomarkov.Chain
+	trainer := NewTrainer(chain, source,
+		WithBatch(2, time.Hour),
+		WithCheckpoint(time.Hour, func(c *gomarkov.Chain) error {
+			checkpoints = append(checkpoints, c)
+			return nil
+		}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- trainer.Run(ctx) }()
+	// Two full batches are trained, the last message waits for the batch interval
+	for source.committedCount() < 4 {
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatalf("Trainer.Run() error = %v", err)
+	}
+
+	if len(source.committed) != 5 || source.commits != 3 {
+		t.Errorf("committed %v in %d commits, want every message in 3", source.committed, source.commits)
+	}
+	if p, _ := chain.TransitionProbability("c", gomarkov.NGram{"b"}); p != 0.5 {
+		t.Errorf("P(c | b) = %v, want 0.5", p)
+	}
+	if len(checkpoints) != 1 {
+		t.Fatalf("saved %d checkpoints, want 1 when stopping", len(checkpoints))
+	}
+	if stats, _ := checkpoints[0].Stats(); stats.Transitions != 10 {
+		t.Errorf("checkpoint has %d transitions, want 10", stats.Transitions)
+	}
+}
+
+func TestTrainer_Run_BatchInterval(t *testing.T) {
+	source := &fakeSource{queue: []string{"a b"}}
+	chain := gomarkov.NewChain(1)
+	trainer := NewTrainer(chain, source, WithBatch(100, 10*time.Millisecond))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go trainer.Run(ctx)
+	deadline := time.Now().Add(5 * time.Second)
+	for source.committedCount() == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("partial batch was never trained on")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestTrainer_Run_SourceError(t *testing.T) {
+	errBroken := errors.New("broken")
+	source := &fakeSource{queue: []string{"a b"}, err: errBroken}
+	chain := gomarkov.NewChain(1)
+	err := NewTrainer(chain, source).Run(context.Background())
+	if !errors.Is(err, errBroken) {
+		t.Errorf("Trainer.Run() error = %v, want %v", err, errBroken)
+	}
+	if len(source.committed) != 1 {
+		t.Errorf("messages received before the error were not committed")
+	}
+}
+
+func TestTrainer_Run_CommitError(t *testing.T) {
+	errCommit := errors.New("commit failed")
+	source := &fakeSource{queue: []string{"a b", "b c"}, commitErr: errCommit}
+	chain := gomarkov.NewChain(1)
+	var checkpoints []*gomarkov.Chain
+	trainer := NewTrainer(chain, source,
+		WithBatch(2, time.Hour),
+		WithCheckpoint(time.Hour, func(c *gomarkov.Chain) error {
+			checkpoints = append(checkpoints, c)
+			return nil
+		}))
+	if err := trainer.Run(context.Background()); !errors.Is(err, errCommit) {
+		t.Errorf("Trainer.Run() error = %v, want %v", err, errCommit)
+	}
+	// The chain trained on the batch, so the checkpoint must hold it
+	if len(checkpoints) != 1 {
+		t.Fatalf("saved %d checkpoints, want 1 for the trained batch", len(checkpoints))
+	}
+	if stats, _ := checkpoints[0].Stats(); stats.Transitions != 6 {
+		t.Errorf("checkpoint has %d transitions, want 6", stats.Transitions)
+	}
+}
+
+func TestFileCheckpoint(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "model.json")
+	chain := gomarkov.NewChain(1)
+	chain.Add([]string{"a", "b"})
+	if err := FileCheckpoint(path)(chain); err != nil {
+		t.Fatalf("FileCheckpoint() error = %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var loaded gomarkov.Chain
+	if err := loaded.UnmarshalJSON(data); err != nil {
+		t.Fatalf("checkpoint doesn't load: %v", err)
+	}
+	if entries, _ := os.ReadDir(filepath.Dir(path)); len(entries) != 1 {
+		t.Errorf("checkpoint left %d files behind", len(entries))
+	}
+}
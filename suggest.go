@@ -0,0 +1,127 @@
+package gomarkov
+
+import (
+	"errors"
+	"sort"
+	"strings"
+)
+
+// suggestBackoff discounts the score of suggestions each time the context is
+// shortened, as in stupid backoff
+const suggestBackoff = 0.4
+
+// Suggestion is a candidate next token
+type Suggestion struct {
+	Token string
+	// Score is the token's probability after the longest context it was found in,
+	// discounted by 0.4 for every token the context is shorter than the order
+	Score float64
+	// Context is the number of tokens of context the suggestion was found with
+	Context int
+}
+
+// SuggestOption configures Suggest
+type SuggestOption func(*suggestOptions)
+
+type suggestOptions struct {
+	prefix   string
+	foldCase bool
+}
+
+// SuggestPrefix only suggests tokens starting with partial, such as the word being
+// typed, ignoring case if foldCase is set
+func SuggestPrefix(partial string, foldCase bool) SuggestOption {
+	return func(o *suggestOptions) {
+		o.prefix, o.foldCase = partial, foldCase
+	}
+}
+
+func (o suggestOptions) match(token string) bool {
+	if len(token) < len(o.prefix) {
+		return false
+	}
+	if o.foldCase {
+		return strings.EqualFold(token[:len(o.prefix)], o.prefix)
+	}
+	return strings.HasPrefix(token, o.prefix)
+}
+
+// Suggest returns up to n candidates for the token following prefixWords, best first.
+// The last Order words are the context, and a shorter prefix is the start of a
+// sequence. When the context is unknown or doesn't yield n candidates, Suggest backs
+// off to states ending with ever shorter suffixes of it, down to the overall next
+// token frequencies. Backing off scans every transition of the chain, so it is much
+// slower than a context the chain knows. A non-positive n returns every candidate.
+func (chain *Chain) Suggest(prefixWords []string, n int, opts ...SuggestOption) ([]Suggestion, error) {
+	var o suggestOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	context := make(NGram, 0, chain.Order)
+	context = append(context, boundary(StartToken, max(chain.Order-len(prefixWords), 0))...)
+	context = append(context, prefixWords[max(len(prefixWords)-chain.Order, 0):]...)
+
+	best := make(map[string]Suggestion)
+	add := func(counts map[string]int, length int) {
+		total := 0
+		for _, count := range counts {
+			total += count
+		}
+		discount := 1.0
+		for i := length; i < chain.Order; i++ {
+			discount *= suggestBackoff
+		}
+		for token, count := range counts {
+			if token == StartToken || token == EndToken || !o.match(token) {
+				continue
+			}
+			s := Suggestion{token, discount * float64(count) / float64(total), length}
+			if s.Score > best[token].Score {
+				best[token] = s
+			}
+		}
+	}
+
+	row, err := chain.Row(context)
+	if err != nil && !errors.Is(err, ErrUnknownNGram) {
+		return nil, err
+	}
+	add(row, chain.Order)
+	if len(best) < n || n <= 0 {
+		// Count the next tokens of every state sharing each suffix of the context
+		levels := make([]map[string]int, chain.Order)
+		for i := range levels {
+			levels[i] = make(map[string]int)
+		}
+		err := chain.ForEachTransition(func(current NGram, t Transition) bool {
+			for length := 0; length < chain.Order; length++ {
+				if length > 0 && current[chain.Order-length] != context[chain.Order-length] {
+					break
+				}
+				levels[length][t.Next] += t.Count
+			}
+			return true
+		})
+		if err != nil {
+			return nil, err
+		}
+		for length := chain.Order - 1; length >= 0; length-- {
+			add(levels[length], length)
+		}
+	}
+
+	suggestions := make([]Suggestion, 0, len(best))
+	for _, s := range best {
+		suggestions = append(suggestions, s)
+	}
+	sort.Slice(suggestions, func(a, b int) bool {
+		if suggestions[a].Score == suggestions[b].Score {
+			return suggestions[a].Token < suggestions[b].Token
+		}
+		return suggestions[a].Score > suggestions[b].Score
+	})
+	if n > 0 && len(suggestions) > n {
+		suggestions = suggestions[:n]
+	}
+	return suggestions, nil
+}
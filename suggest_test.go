@@ -0,0 +1,110 @@
+package gomarkov
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+func suggestChain() *Chain {
+	chain := NewChain(2)
+	chain.Add([]string{"i", "want", "to", "go"})
+	chain.Add([]string{"i", "want", "to", "go"})
+	chain.Add([]string{"i", "want", "to", "eat"})
+	chain.Add([]string{"you", "have", "to", "gamble"})
+	chain.Add([]string{"we", "want", "tea"})
+	return chain
+}
+
+func suggestedTokens(suggestions []Suggestion) []string {
+	out := make([]string, len(suggestions))
+	for i, s := range suggestions {
+		out[i] = s.Token
+	}
+	return out
+}
+
+func TestChain_Suggest(t *testing.T) {
+	chain := suggestChain()
+
+	got, err := chain.Suggest([]string{"i", "want", "to"}, 2)
+	if err != nil {
+		t.Fatalf("Chain.Suggest() error = %v", err)
+	}
+	if want := []string{"go", "eat"}; !reflect.DeepEqual(suggestedTokens(got), want) {
+		t.Errorf("Chain.Suggest() = %v, want %v", suggestedTokens(got), want)
+	}
+	if math.Abs(got[0].Score-2.0/3) > 1e-9 || got[0].Context != 2 {
+		t.Errorf("Chain.Suggest() = %+v, want score 2/3 with context 2", got[0])
+	}
+
+	// A short prefix is the start of a sentence
+	got, err = chain.Suggest([]string{"you"}, 1)
+	if err != nil {
+		t.Fatalf("Chain.Suggest() error = %v", err)
+	}
+	if want := []string{"have"}; !reflect.DeepEqual(suggestedTokens(got), want) {
+		t.Errorf("Chain.Suggest(you) = %v, want %v", suggestedTokens(got), want)
+	}
+}
+
+func TestChain_Suggest_backoff(t *testing.T) {
+	chain := suggestChain()
+
+	// "they have" was never seen, but states ending in "have" were
+	got, err := chain.Suggest([]string{"they", "have"}, 0)
+	if err != nil {
+		t.Fatalf("Chain.Suggest() error = %v", err)
+	}
+	if len(got) == 0 || got[0].Token != "to" || got[0].Context != 1 {
+		t.Fatalf("Chain.Suggest(they have) = %+v, want to with context 1 first", got)
+	}
+	if math.Abs(got[0].Score-0.4) > 1e-9 {
+		t.Errorf("Chain.Suggest(they have) scored %v, want 0.4", got[0].Score)
+	}
+	for i, s := range got {
+		if s.Token == EndToken || s.Token == StartToken {
+			t.Errorf("Chain.Suggest() suggested %q", s.Token)
+		}
+		if i > 0 && s.Score > got[i-1].Score {
+			t.Errorf("Chain.Suggest() is not sorted at %d", i)
+		}
+	}
+
+	// The exact context only knows tea, the rest backs off to "want"
+	got, err = chain.Suggest([]string{"we", "want"}, 2)
+	if err != nil {
+		t.Fatalf("Chain.Suggest() error = %v", err)
+	}
+	if want := []string{"tea", "to"}; !reflect.DeepEqual(suggestedTokens(got), want) {
+		t.Errorf("Chain.Suggest(we want) = %v, want %v", suggestedTokens(got), want)
+	}
+}
+
+func TestChain_Suggest_prefix(t *testing.T) {
+	chain := suggestChain()
+
+	got, err := chain.Suggest([]string{"i", "want", "to"}, 3, SuggestPrefix("g", false))
+	if err != nil {
+		t.Fatalf("Chain.Suggest() error = %v", err)
+	}
+	// go follows the exact context, gamble only follows "to"
+	if want := []string{"go", "gamble"}; !reflect.DeepEqual(suggestedTokens(got), want) {
+		t.Errorf("Chain.Suggest(g) = %v, want %v", suggestedTokens(got), want)
+	}
+
+	got, err = chain.Suggest([]string{"i", "want", "to"}, 3, SuggestPrefix("E", false))
+	if err != nil {
+		t.Fatalf("Chain.Suggest() error = %v", err)
+	}
+	if len(got) != 0 {
+		t.Errorf("Chain.Suggest(E) = %v, want none", suggestedTokens(got))
+	}
+	got, err = chain.Suggest([]string{"i", "want", "to"}, 3, SuggestPrefix("E", true))
+	if err != nil {
+		t.Fatalf("Chain.Suggest() error = %v", err)
+	}
+	if want := []string{"eat"}; !reflect.DeepEqual(suggestedTokens(got), want) {
+		t.Errorf("Chain.Suggest(E, fold) = %v, want %v", suggestedTokens(got), want)
+	}
+}
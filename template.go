@@ -0,0 +1,177 @@
+package gomarkov
+
+import (
+	"errors"
+	"math"
+	"sort"
+	"strings"
+)
+
+// Slot marks a token of a template for FillTemplate to fill. It is the separator
+// of state keys, which tokens of a chain shouldn't contain anyway.
+const Slot = "_"
+
+// FillOption configures FillTemplate
+type FillOption func(*fillOptions)
+
+type fillOptions struct {
+	prng      PRNG
+	openEnded bool
+}
+
+// FillSample draws the slots at random from their probability given the whole
+// template, instead of picking the most probable fill
+func FillSample(prng PRNG) FillOption {
+	return func(o *fillOptions) {
+		o.prng = prng
+	}
+}
+
+// FillOpenEnded lets the template stop before the end of a sequence, so the
+// last tokens don't need to be where the chain ends sequences
+func FillOpenEnded() FillOption {
+	return func(o *fillOptions) {
+		o.openEnded = true
+	}
+}
+
+// fillCell is a state reachable at a position of the template
+type fillCell struct {
+	state NGram
+	key   string
+	// logAlpha is the log probability of the best path to the cell, or of all
+	// paths when sampling
+	logAlpha float64
+	in       []fillEdge
+}
+
+// fillEdge is a transition into a cell
+type fillEdge struct {
+	prev  *fillCell
+	token string
+	logP  float64
+}
+
+// FillTemplate fills the slots of a template, tokens equal to Slot, with tokens
+// consistent with both the tokens before and after them. The template is a whole
+// sequence from start to end, unless FillOpenEnded is given. By default the most
+// probable fill is returned, see FillSample. If no fill has a non-zero probability,
+// the error is ErrUnsatisfiable.
+func (chain *Chain) FillTemplate(template []string, opts ...FillOption) ([]string, error) {
+	var o fillOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	start := NGram(boundary(StartToken, chain.Order))
+	cells := map[string]*fillCell{"": {state: start}}
+	add := func(next map[string]*fillCell, prev *fillCell, token string, p float64) {
+		if p <= 0 {
+			return
+		}
+		state := prev.state.Shift(token)
+		key := strings.Join(state, "\x00")
+		edge := fillEdge{prev, token, math.Log(p)}
+		cell, ok := next[key]
+		if !ok {
+			cell = &fillCell{state: state, key: key, logAlpha: math.Inf(-1)}
+			next[key] = cell
+		}
+		cell.in = append(cell.in, edge)
+		cell.logAlpha = o.combine(cell.logAlpha, prev.logAlpha+edge.logP)
+	}
+	for _, token := range template {
+		next := make(map[string]*fillCell)
+		for _, cell := range cells {
+			if token != Slot {
+				p, err := chain.TransitionProbability(token, cell.state)
+				if err != nil {
+					return nil, err
+				}
+				add(next, cell, token, p)
+				continue
+			}
+			row, err := chain.Row(cell.state)
+			if err != nil && !errors.Is(err, ErrUnknownNGram) {
+				return nil, err
+			}
+			sum := 0
+			for _, count := range row {
+				sum += count
+			}
+			for candidate, count := range row {
+				if candidate != StartToken && candidate != EndToken {
+					add(next, cell, candidate, float64(count)/float64(sum))
+				}
+			}
+		}
+		if len(next) == 0 {
+			return nil, ErrUnsatisfiable
+		}
+		cells = next
+	}
+
+	// end holds the cells the template can finish in, as edges out of them
+	end := &fillCell{logAlpha: math.Inf(-1)}
+	for _, cell := range cells {
+		p := 1.0
+		if !o.openEnded {
+			var err error
+			if p, err = chain.TransitionProbability(EndToken, cell.state); err != nil {
+				return nil, err
+			}
+		}
+		if p > 0 {
+			end.in = append(end.in, fillEdge{cell, EndToken, math.Log(p)})
+		}
+	}
+	if len(end.in) == 0 {
+		return nil, ErrUnsatisfiable
+	}
+	filled := make([]string, len(template))
+	for cell, i := o.choose(end.in).prev, len(template)-1; i >= 0; i-- {
+		edge := o.choose(cell.in)
+		filled[i] = edge.token
+		cell = edge.prev
+	}
+	return filled, nil
+}
+
+// combine adds the log probability of a path to a cell, keeping the best one or
+// summing them when sampling
+func (o fillOptions) combine(logAlpha, logP float64) float64 {
+	if o.prng == nil {
+		return math.Max(logAlpha, logP)
+	}
+	if math.IsInf(logAlpha, -1) {
+		return logP
+	}
+	hi, lo := math.Max(logAlpha, logP), math.Min(logAlpha, logP)
+	return hi + math.Log1p(math.Exp(lo-hi))
+}
+
+// choose picks the edge a path into a cell came through, the best one or one drawn
+// by its probability when sampling
+func (o fillOptions) choose(in []fillEdge) fillEdge {
+	// Sort first so ties and draws only depend on the PRNG
+	sort.Slice(in, func(a, b int) bool {
+		if in[a].token != in[b].token {
+			return in[a].token < in[b].token
+		}
+		return in[a].prev.key < in[b].prev.key
+	})
+	best := in[0]
+	for _, edge := range in[1:] {
+		if edge.prev.logAlpha+edge.logP > best.prev.logAlpha+best.logP {
+			best = edge
+		}
+	}
+	if o.prng == nil {
+		return best
+	}
+	top := best.prev.logAlpha + best.logP
+	weights := make([]float64, len(in))
+	for i, edge := range in {
+		weights[i] = math.Exp(edge.prev.logAlpha + edge.logP - top)
+	}
+	return in[drawFloat(o.prng, weights)]
+}
@@ -0,0 +1,75 @@
+package gomarkov
+
+import (
+	"errors"
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func templateChain() *Chain {
+	chain := NewChain(1)
+	for i := 0; i < 3; i++ {
+		chain.Add(strings.Fields("i love green tea"))
+	}
+	chain.Add(strings.Fields("i love green apples"))
+	chain.Add(strings.Fields("you love red apples"))
+	chain.Add(strings.Fields("i hate red tea"))
+	return chain
+}
+
+func TestChain_FillTemplate(t *testing.T) {
+	chain := templateChain()
+
+	got, err := chain.FillTemplate([]string{"i", "love", Slot, "tea"})
+	if err != nil {
+		t.Fatalf("Chain.FillTemplate() error = %v", err)
+	}
+	if want := strings.Fields("i love green tea"); !reflect.DeepEqual(got, want) {
+		t.Errorf("Chain.FillTemplate() = %v, want %v", got, want)
+	}
+
+	// hate is less likely than love after i, but the red after it decides, and
+	// ties go to the first token
+	got, err = chain.FillTemplate([]string{Slot, Slot, "red", Slot})
+	if err != nil {
+		t.Fatalf("Chain.FillTemplate() error = %v", err)
+	}
+	if want := strings.Fields("i hate red apples"); !reflect.DeepEqual(got, want) {
+		t.Errorf("Chain.FillTemplate() = %v, want %v", got, want)
+	}
+
+	if _, err := chain.FillTemplate([]string{"green", Slot}); !errors.Is(err, ErrUnsatisfiable) {
+		t.Errorf("Chain.FillTemplate() error = %v, want %v", err, ErrUnsatisfiable)
+	}
+	// Sequences never end after love, unless the template is open ended
+	if _, err := chain.FillTemplate([]string{Slot, "love"}); !errors.Is(err, ErrUnsatisfiable) {
+		t.Errorf("Chain.FillTemplate() error = %v, want %v", err, ErrUnsatisfiable)
+	}
+	got, err = chain.FillTemplate([]string{Slot, "love"}, FillOpenEnded())
+	if err != nil || got[0] != "i" {
+		t.Errorf("Chain.FillTemplate(FillOpenEnded) = %v, %v, want i love", got, err)
+	}
+}
+
+func TestChain_FillTemplate_sample(t *testing.T) {
+	chain := templateChain()
+	prng := rand.New(rand.NewSource(1))
+	counts := make(map[string]int)
+	for i := 0; i < 2000; i++ {
+		got, err := chain.FillTemplate([]string{"i", Slot, Slot, "tea"}, FillSample(prng))
+		if err != nil {
+			t.Fatalf("Chain.FillTemplate() error = %v", err)
+		}
+		counts[strings.Join(got, " ")]++
+	}
+	// The fills have probability 4/5·4/5·3/4, 4/5·1/5·1/2 and 1/5·1·1/2, so i hate
+	// red tea is drawn with probability 0.1/0.66
+	if len(counts) != 3 {
+		t.Fatalf("Chain.FillTemplate(FillSample) drew %v, want three fills", counts)
+	}
+	if n := counts["i hate red tea"]; n < 230 || n > 380 {
+		t.Errorf("Chain.FillTemplate(FillSample) drew i hate red tea %d times in 2000, want about 303", n)
+	}
+}
@@ -0,0 +1,211 @@
+package gomarkov
+
+import (
+	"fmt"
+	"math"
+)
+
+// Termination describes how walks from a state end, see Chain.Termination
+type Termination struct {
+	// Probability is the probability that a walk reaches EndToken, rather than
+	// cycling forever or running into a state without transitions
+	Probability float64
+	// ExpectedLength is the expected number of tokens generated before EndToken,
+	// +Inf unless Probability is 1
+	ExpectedLength float64
+	// StdDev is the standard deviation of the number of tokens, +Inf unless
+	// Probability is 1
+	StdDev float64
+}
+
+// terminationIterations limits the sweeps of Termination, which converges slowly
+// only for chains whose sequences run on for a very long time
+const terminationIterations = 100000
+
+// termEdge leads to the state with index to, or to the end if to is -1
+type termEdge struct {
+	to int
+	p  float64
+}
+
+// Termination computes the probability that walks from a state, such as the start
+// of a sequence, end, and the mean and spread of the number of tokens they generate
+// until then, telling whether a chain produces sequences of a reasonable length
+// before deploying it. EstimateHittingTime estimates the same by simulation, while
+// Termination solves for it by absorption analysis, and so also sees the rare cycles
+// a chain can never leave. It takes time proportional to the number of transitions
+// reachable from state times the number of sweeps needed to converge.
+func (chain *Chain) Termination(state NGram) (Termination, error) {
+	if len(state) != chain.Order {
+		return Termination{}, ErrOrderMismatch
+	}
+	if state.ended() {
+		return Termination{Probability: 1}, nil
+	}
+	index, ok, err := chain.lookupState(state)
+	if err != nil {
+		return Termination{}, err
+	}
+	if !ok {
+		if chain.empty() {
+			return Termination{}, ErrEmptyChain
+		}
+		return Termination{}, &UnknownNGramError{NGram: append(NGram{}, state...)}
+	}
+
+	// Collect the states reachable from state, breadth first
+	states := []NGram{append(NGram{}, state...)}
+	indices := []int{index}
+	seen := map[string]int{state.key(): 0}
+	var edges [][]termEdge
+	for i := 0; i < len(states); i++ {
+		var out []termEdge
+		if indices[i] >= 0 {
+			transitions, err := chain.rowTransitions(indices[i])
+			if err != nil {
+				return Termination{}, err
+			}
+			for _, t := range transitions {
+				if t.Probability == 0 {
+					continue
+				}
+				if t.Next == EndToken {
+					out = append(out, termEdge{-1, t.Probability})
+					continue
+				}
+				next := states[i].Shift(t.Next)
+				to, ok := seen[next.key()]
+				if !ok {
+					to = len(states)
+					index, found, err := chain.lookupState(next)
+					if err != nil {
+						return Termination{}, err
+					}
+					if !found {
+						index = -1
+					}
+					seen[next.key()] = to
+					states, indices = append(states, next), append(indices, index)
+				}
+				out = append(out, termEdge{to, t.Probability})
+			}
+		}
+		edges = append(edges, out)
+	}
+
+	// Walks surely end from states that can't reach a state unable to end
+	canEnd := make([]bool, len(states))
+	reverse := make([][]int, len(states))
+	var queue []int
+	for i, out := range edges {
+		for _, e := range out {
+			if e.to < 0 {
+				if !canEnd[i] {
+					canEnd[i] = true
+					queue = append(queue, i)
+				}
+			} else {
+				reverse[e.to] = append(reverse[e.to], i)
+			}
+		}
+	}
+	backwardReach(reverse, canEnd, queue)
+	queue = nil
+	mayNotEnd := make([]bool, len(states))
+	for i := range states {
+		if !canEnd[i] {
+			mayNotEnd[i] = true
+			queue = append(queue, i)
+		}
+	}
+	backwardReach(reverse, mayNotEnd, queue)
+
+	if mayNotEnd[0] {
+		prob, err := solveTermination(edges, func(i int, out []termEdge, x []float64) float64 {
+			if !canEnd[i] {
+				return 0
+			}
+			if !mayNotEnd[i] {
+				return 1
+			}
+			p := 0.0
+			for _, e := range out {
+				if e.to < 0 {
+					p += e.p
+				} else {
+					p += e.p * x[e.to]
+				}
+			}
+			return p
+		})
+		if err != nil {
+			return Termination{}, err
+		}
+		return Termination{Probability: prob[0], ExpectedLength: math.Inf(1), StdDev: math.Inf(1)}, nil
+	}
+
+	// N(s), the number of tokens from s, is 0 at the end and 1 + N(s') otherwise
+	mean, err := solveTermination(edges, func(i int, out []termEdge, x []float64) float64 {
+		m := 0.0
+		for _, e := range out {
+			if e.to >= 0 {
+				m += e.p * (1 + x[e.to])
+			}
+		}
+		return m
+	})
+	if err != nil {
+		return Termination{}, err
+	}
+	// E[N(s)²] follows from the means, as (1 + N)² = 1 + 2N + N²
+	square, err := solveTermination(edges, func(i int, out []termEdge, x []float64) float64 {
+		m := 0.0
+		for _, e := range out {
+			if e.to >= 0 {
+				m += e.p * (1 + 2*mean[e.to] + x[e.to])
+			}
+		}
+		return m
+	})
+	if err != nil {
+		return Termination{}, err
+	}
+	variance := math.Max(square[0]-mean[0]*mean[0], 0)
+	return Termination{Probability: 1, ExpectedLength: mean[0], StdDev: math.Sqrt(variance)}, nil
+}
+
+// backwardReach marks every state that can reach a marked one, given the states
+// leading into each. queue holds the marked states to start from.
+func backwardReach(reverse [][]int, marked []bool, queue []int) {
+	for len(queue) > 0 {
+		i := queue[len(queue)-1]
+		queue = queue[:len(queue)-1]
+		for _, from := range reverse[i] {
+			if !marked[from] {
+				marked[from] = true
+				queue = append(queue, from)
+			}
+		}
+	}
+}
+
+// solveTermination finds the fixed point of update, one value per state, by
+// Gauss-Seidel sweeps starting from 0. States are swept from the last found to the
+// first, so chains without cycles are solved by the first sweep.
+func solveTermination(edges [][]termEdge, update func(i int, out []termEdge, x []float64) float64) ([]float64, error) {
+	x := make([]float64, len(edges))
+	for sweep := 0; sweep < terminationIterations; sweep++ {
+		converged := true
+		for i := len(edges) - 1; i >= 0; i-- {
+			v := update(i, edges[i], x)
+			if math.Abs(v-x[i]) > 1e-12*math.Max(1, math.Abs(v)) {
+				converged = false
+			}
+			x[i] = v
+		}
+		if converged {
+			return x, nil
+		}
+	}
+	return nil, fmt.Errorf("Termination did not converge within %d sweeps", terminationIterations)
+}
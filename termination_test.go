@@ -0,0 +1,64 @@
+package gomarkov
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestChain_Termination(t *testing.T) {
+	tests := []struct {
+		name   string
+		order  int
+		corpus [][]string
+		state  NGram
+		want   Termination
+	}{
+		{"From the start", 1, [][]string{{"a", "b"}, {"a"}}, NGram{StartToken}, Termination{1, 1.5, 0.5}},
+		{"From a state", 1, [][]string{{"a", "b"}, {"a"}}, NGram{"a"}, Termination{1, 0.5, 0.5}},
+		{"Cycle", 1, [][]string{{"x", "x"}}, NGram{StartToken}, Termination{1, 2, math.Sqrt2}},
+		{"Order 2", 2, [][]string{{"a", "b"}, {"a"}}, NGram{StartToken, StartToken}, Termination{1, 1.5, 0.5}},
+		{"Ended", 1, [][]string{{"a"}}, NGram{EndToken}, Termination{1, 0, 0}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chain := NewChain(tt.order)
+			chain.AddBatch(tt.corpus)
+			got, err := chain.Termination(tt.state)
+			if err != nil {
+				t.Fatalf("Chain.Termination() error = %v", err)
+			}
+			if math.Abs(got.Probability-tt.want.Probability) > 1e-9 || math.Abs(got.ExpectedLength-tt.want.ExpectedLength) > 1e-9 || math.Abs(got.StdDev-tt.want.StdDev) > 1e-9 {
+				t.Errorf("Chain.Termination() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+
+	// Half the walks enter a cycle without an exit
+	store := NewMemoryStore()
+	index := func(state string) int {
+		i, _ := store.AddState(state)
+		return i
+	}
+	store.IncrementTransition(index(StartToken), index("a"), 1)
+	store.IncrementTransition(index("a"), index(EndToken), 1)
+	store.IncrementTransition(index(StartToken), index("c"), 1)
+	store.IncrementTransition(index("c"), index("d"), 1)
+	store.IncrementTransition(index("d"), index("c"), 1)
+	got, err := NewChainWithStore(1, store).Termination(NGram{StartToken})
+	if err != nil || math.Abs(got.Probability-0.5) > 1e-9 || !math.IsInf(got.ExpectedLength, 1) {
+		t.Errorf("Chain.Termination() with a closed cycle = %+v, %v", got, err)
+	}
+
+	chain := NewChain(1)
+	if _, err := chain.Termination(NGram{StartToken}); !errors.Is(err, ErrEmptyChain) {
+		t.Errorf("Chain.Termination() of an empty chain error = %v, want ErrEmptyChain", err)
+	}
+	chain.Add([]string{"a"})
+	if _, err := chain.Termination(NGram{"z"}); !errors.Is(err, ErrUnknownNGram) {
+		t.Errorf("Chain.Termination() of an unknown state error = %v, want ErrUnknownNGram", err)
+	}
+	if _, err := chain.Termination(NGram{"a", "b"}); !errors.Is(err, ErrOrderMismatch) {
+		t.Errorf("Chain.Termination() of a bigram error = %v, want ErrOrderMismatch", err)
+	}
+}
@@ -0,0 +1,145 @@
+package gomarkov
+
+import "sort"
+
+// Transition is a token that can follow a state, with its count and probability
+type Transition struct {
+	Next        string
+	Count       int
+	Probability float64
+}
+
+// rowIndices returns the indices of every state with outgoing transitions, in
+// ascending order, so they can be visited without holding the store's locks
+func (chain *Chain) rowIndices() ([]int, error) {
+	var indices []int
+	err := chain.iterateRows(func(current int, row rowReader) bool {
+		indices = append(indices, current)
+		return true
+	})
+	sort.Ints(indices)
+	return indices, err
+}
+
+// transitions returns the state with the given index, split into its n-gram, and
+// its transitions ordered from most to least likely. ok is false if the state has
+// no transitions or can't be split.
+func (chain *Chain) transitions(current int) (ngram NGram, transitions []Transition, ok bool, err error) {
+	key, ok, err := chain.store.LookupIndex(current)
+	if err != nil || !ok {
+		return nil, nil, false, err
+	}
+	if ngram, ok = splitKey(key, chain.Order); !ok {
+		return nil, nil, false, nil
+	}
+	transitions, err = chain.rowTransitions(current)
+	return ngram, transitions, len(transitions) > 0, err
+}
+
+// rowTransitions returns the transitions out of the state with the given index,
+// ordered from most to least likely
+func (chain *Chain) rowTransitions(current int) ([]Transition, error) {
+	if ws, ok := chain.store.(WeightedStore); ok {
+		return chain.weightedTransitions(ws, current)
+	}
+	row, err := chain.getRow(current)
+	if err != nil || len(row) == 0 {
+		return nil, err
+	}
+	var transitions []Transition
+	sum := float64(row.sum())
+	for _, p := range row.orderedPairs() {
+		next, found, err := chain.store.LookupIndex(p[0])
+		if err != nil {
+			return nil, err
+		}
+		if !found {
+			continue
+		}
+		t := Transition{Next: next, Count: p[1]}
+		if sum > 0 {
+			t.Probability = float64(p[1]) / sum
+		}
+		transitions = append(transitions, t)
+	}
+	return transitions, nil
+}
+
+// ForEachTransition calls fn with every transition of the chain, each state with
+// its successors from most to least likely, stopping early if fn returns false.
+// States are visited one at a time, so the chain can be trained meanwhile, and
+// states whose tokens contain the n-gram separator are skipped as they can't be split.
+func (chain *Chain) ForEachTransition(fn func(NGram, Transition) bool) error {
+	indices, err := chain.rowIndices()
+	if err != nil {
+		return err
+	}
+	for _, current := range indices {
+		ngram, transitions, ok, err := chain.transitions(current)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			continue
+		}
+		for _, t := range transitions {
+			if !fn(ngram, t) {
+				return nil
+			}
+		}
+	}
+	return nil
+}
+
+// Row returns the transition counts out of current, keyed by the next token. The
+// map is a copy and can be modified.
+func (chain *Chain) Row(current NGram) (map[string]int, error) {
+	if len(current) != chain.Order {
+		return nil, ErrOrderMismatch
+	}
+	index, ok, err := chain.lookupState(current)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, &UnknownNGramError{NGram: append(NGram{}, current...)}
+	}
+	row, err := chain.store.GetRow(index)
+	if err != nil {
+		return nil, err
+	}
+	counts := make(map[string]int, len(row))
+	for next, count := range row {
+		token, ok, err := chain.store.LookupIndex(next)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			counts[token] = count
+		}
+	}
+	return counts, nil
+}
+
+// stateRow is a state along with its transitions, see stateRows
+type stateRow struct {
+	ngram       NGram
+	transitions []Transition
+}
+
+// stateRows returns every state with transitions, keyed by state, for the searches
+// that need the whole transition graph at hand
+func (chain *Chain) stateRows() (map[string]*stateRow, error) {
+	rows := make(map[string]*stateRow)
+	err := chain.ForEachTransition(func(ngram NGram, t Transition) bool {
+		key := ngram.key()
+		row, ok := rows[key]
+		if !ok {
+			row = &stateRow{ngram: ngram}
+			rows[key] = row
+		}
+		row.transitions = append(row.transitions, t)
+		return true
+	})
+	return rows, err
+}
@@ -0,0 +1,67 @@
+package gomarkov
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestChain_Row(t *testing.T) {
+	chain := NewChain(1)
+	chain.Add([]string{"test", "data"})
+	chain.Add([]string{"test", "data"})
+	chain.Add([]string{"test", "node"})
+	tests := []struct {
+		name    string
+		current NGram
+		want    map[string]int
+		wantErr error
+	}{
+		{"Start", NGram{StartToken}, map[string]int{"test": 3}, nil},
+		{"Branching", NGram{"test"}, map[string]int{"data": 2, "node": 1}, nil},
+		{"End", NGram{"data"}, map[string]int{EndToken: 2}, nil},
+		{"Unknown", NGram{"missing"}, nil, ErrUnknownNGram},
+		{"Order mismatch", NGram{"test", "data"}, nil, ErrOrderMismatch},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := chain.Row(tt.current)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Chain.Row() error = %v, want %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Chain.Row() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestChain_ForEachTransition(t *testing.T) {
+	chain := NewChain(1)
+	chain.Add([]string{"a", "b"})
+	chain.Add([]string{"a", "a"})
+	var got []string
+	err := chain.ForEachTransition(func(current NGram, tr Transition) bool {
+		got = append(got, fmt.Sprintf("%v->%s:%d", current, tr.Next, tr.Count))
+		return true
+	})
+	if err != nil {
+		t.Fatalf("Chain.ForEachTransition() error = %v", err)
+	}
+	if len(got) != 5 {
+		t.Errorf("Chain.ForEachTransition() visited %v, want 5 transitions", got)
+	}
+	if got[0] != "[^]->a:2" {
+		t.Errorf("Chain.ForEachTransition() visited %s first, want [^]->a:2", got[0])
+	}
+
+	visited := 0
+	chain.ForEachTransition(func(NGram, Transition) bool {
+		visited++
+		return false
+	})
+	if visited != 1 {
+		t.Errorf("Chain.ForEachTransition() kept going after fn returned false")
+	}
+}
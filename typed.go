@@ -0,0 +1,210 @@
+package gomarkov
+
+import "sync"
+
+// Token indices reserved for the boundaries of sequences in a ChainOf
+const (
+	startIndex = iota
+	endIndex
+	firstTokenIndex
+)
+
+// ChainOf is a markov chain over tokens of any comparable type, such as integer
+// event IDs, runes or structs, without converting them to strings. Sequences are
+// wrapped in boundary markers like Chain does with StartToken and EndToken.
+type ChainOf[T comparable] struct {
+	Order int
+	// tokens interns each token to an index, tokens[i] being token i
+	tokenIndex map[T]int
+	tokens     []T
+	// states maps an n-gram of token indices to a state one token at a time, keyed
+	// by the prefix's node and the next token index
+	states map[[2]int]int
+	nodes  int
+	// rows holds the counts of next token indices out of each state
+	rows map[int]sparseArray
+	lock sync.RWMutex
+	// cdfs caches the sampling distribution of each row, reset when it changes.
+	// Generating fills it under the read lock, so it has a lock of its own.
+	cdfs    map[int]*cumulativeDist
+	cdfLock sync.RWMutex
+}
+
+// NewChainOf creates a chain over tokens of type T. It panics if order is negative.
+func NewChainOf[T comparable](order int) *ChainOf[T] {
+	if err := validateOrder(order); err != nil {
+		panic("gomarkov: " + err.Error())
+	}
+	return &ChainOf[T]{
+		Order:      order,
+		tokenIndex: make(map[T]int),
+		tokens:     make([]T, firstTokenIndex),
+		states:     make(map[[2]int]int),
+		rows:       make(map[int]sparseArray),
+		cdfs:       make(map[int]*cumulativeDist),
+	}
+}
+
+// Add adds the transition counts to the chain for a given sequence of tokens.
+// Empty sequences are skipped, see Chain.Add.
+func (chain *ChainOf[T]) Add(input []T) {
+	if len(input) == 0 {
+		return
+	}
+	chain.lock.Lock()
+	defer chain.lock.Unlock()
+	indices := make([]int, 0, len(input)+2*chain.Order)
+	for i := 0; i < chain.Order; i++ {
+		indices = append(indices, startIndex)
+	}
+	for _, token := range input {
+		index, ok := chain.tokenIndex[token]
+		if !ok {
+			index = len(chain.tokens)
+			chain.tokenIndex[token] = index
+			chain.tokens = append(chain.tokens, token)
+		}
+		indices = append(indices, index)
+	}
+	for i := 0; i < max(chain.Order, 1); i++ {
+		indices = append(indices, endIndex)
+	}
+	for i := 0; i+chain.Order < len(indices); i++ {
+		state := chain.addState(indices[i : i+chain.Order])
+		if chain.rows[state] == nil {
+			chain.rows[state] = make(sparseArray)
+		}
+		chain.rows[state][indices[i+chain.Order]]++
+		delete(chain.cdfs, state)
+	}
+}
+
+func (chain *ChainOf[T]) addState(indices []int) int {
+	node := 0
+	for _, index := range indices {
+		next, ok := chain.states[[2]int{node, index}]
+		if !ok {
+			chain.nodes++
+			next = chain.nodes
+			chain.states[[2]int{node, index}] = next
+		}
+		node = next
+	}
+	return node
+}
+
+// state returns the state of an n-gram of tokens. N-grams shorter than the order
+// are taken to be at the start of a sequence.
+func (chain *ChainOf[T]) state(current []T) (int, bool, error) {
+	if len(current) > chain.Order {
+		return 0, false, ErrOrderMismatch
+	}
+	node := 0
+	for i := len(current); i < chain.Order; i++ {
+		next, ok := chain.states[[2]int{node, startIndex}]
+		if !ok {
+			return 0, false, nil
+		}
+		node = next
+	}
+	for _, token := range current {
+		index, ok := chain.tokenIndex[token]
+		if !ok {
+			return 0, false, nil
+		}
+		next, ok := chain.states[[2]int{node, index}]
+		if !ok {
+			return 0, false, nil
+		}
+		node = next
+	}
+	return node, true, nil
+}
+
+// TransitionProbability returns the probability of next following current. A
+// current n-gram shorter than the order is the start of a sequence, so an empty
+// one gives the probability of next starting a sequence.
+func (chain *ChainOf[T]) TransitionProbability(next T, current []T) (float64, error) {
+	chain.lock.RLock()
+	defer chain.lock.RUnlock()
+	state, ok, err := chain.state(current)
+	if err != nil || !ok {
+		return 0, err
+	}
+	index, ok := chain.tokenIndex[next]
+	if !ok {
+		return 0, nil
+	}
+	row := chain.rows[state]
+	if len(row) == 0 {
+		return 0, nil
+	}
+	return float64(row[index]) / float64(row.sum()), nil
+}
+
+// EndProbability returns the probability of the sequence ending after current
+func (chain *ChainOf[T]) EndProbability(current []T) (float64, error) {
+	chain.lock.RLock()
+	defer chain.lock.RUnlock()
+	state, ok, err := chain.state(current)
+	if err != nil || !ok {
+		return 0, err
+	}
+	row := chain.rows[state]
+	if len(row) == 0 {
+		return 0, nil
+	}
+	return float64(row[endIndex]) / float64(row.sum()), nil
+}
+
+// cumulative returns the cached sampling distribution of a state's row, the caller
+// must hold the read lock
+func (chain *ChainOf[T]) cumulative(state int) *cumulativeDist {
+	chain.cdfLock.RLock()
+	d := chain.cdfs[state]
+	chain.cdfLock.RUnlock()
+	if d != nil {
+		return d
+	}
+	chain.cdfLock.Lock()
+	defer chain.cdfLock.Unlock()
+	if d = chain.cdfs[state]; d == nil {
+		d = chain.rows[state].cumulative()
+		chain.cdfs[state] = d
+	}
+	return d
+}
+
+// Generate returns a token following current, with ok false if the sequence ends
+// there instead
+func (chain *ChainOf[T]) Generate(current []T) (next T, ok bool, err error) {
+	return chain.GenerateDeterministic(current, defaultPrng)
+}
+
+// GenerateDeterministic is Generate using the given PRNG, see Chain.GenerateDeterministic
+func (chain *ChainOf[T]) GenerateDeterministic(current []T, prng PRNG) (next T, ok bool, err error) {
+	chain.lock.RLock()
+	defer chain.lock.RUnlock()
+	state, known, err := chain.state(current)
+	if err != nil {
+		return next, false, err
+	}
+	if !known {
+		if len(chain.rows) == 0 {
+			return next, false, ErrEmptyChain
+		}
+		return next, false, ErrUnknownNGram
+	}
+	index, drawn := chain.cumulative(state).draw(prng)
+	if !drawn {
+		if len(chain.rows) == 0 {
+			// The empty state of an order 0 chain is always known
+			return next, false, ErrEmptyChain
+		}
+		return next, false, ErrDeadEnd
+	}
+	if index == endIndex {
+		return next, false, nil
+	}
+	return chain.tokens[index], true, nil
+}
@@ -0,0 +1,90 @@
+package gomarkov
+
+import (
+	"errors"
+	"math/rand"
+	"testing"
+)
+
+func TestChainOf(t *testing.T) {
+	type event struct {
+		kind string
+		code int
+	}
+	chain := NewChainOf[event](1)
+	login, view, logout := event{"login", 1}, event{"view", 2}, event{"logout", 3}
+	chain.Add([]event{login, view, view, logout})
+	chain.Add([]event{login, logout})
+
+	tests := []struct {
+		name    string
+		next    event
+		current []event
+		want    float64
+	}{
+		{"Start", login, nil, 1},
+		{"Branch", view, []event{login}, 0.5},
+		{"Repeat", view, []event{view}, 0.5},
+		{"Unknown token", event{"buy", 4}, []event{view}, 0},
+		{"Unknown state", view, []event{event{"buy", 4}}, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := chain.TransitionProbability(tt.next, tt.current)
+			if err != nil || got != tt.want {
+				t.Errorf("ChainOf.TransitionProbability() = %v, %v, want %v", got, err, tt.want)
+			}
+		})
+	}
+	if got, _ := chain.EndProbability([]event{logout}); got != 1 {
+		t.Errorf("ChainOf.EndProbability() = %v, want 1", got)
+	}
+	if _, err := chain.TransitionProbability(view, []event{login, view}); !errors.Is(err, ErrOrderMismatch) {
+		t.Errorf("ChainOf.TransitionProbability() error = %v, want %v", err, ErrOrderMismatch)
+	}
+}
+
+func TestChainOf_Generate(t *testing.T) {
+	chain := NewChainOf[int](2)
+	chain.Add([]int{1, 2, 3, 4})
+	prng := rand.New(rand.NewSource(1))
+	var got []int
+	for {
+		current := got
+		if len(current) > chain.Order {
+			current = current[len(current)-chain.Order:]
+		}
+		next, ok, err := chain.GenerateDeterministic(current, prng)
+		if err != nil {
+			t.Fatalf("ChainOf.GenerateDeterministic() error = %v", err)
+		}
+		if !ok {
+			break
+		}
+		got = append(got, next)
+	}
+	if len(got) != 4 || got[0] != 1 || got[3] != 4 {
+		t.Errorf("ChainOf.GenerateDeterministic() generated %v, want [1 2 3 4]", got)
+	}
+
+	if _, _, err := chain.Generate([]int{9}); !errors.Is(err, ErrUnknownNGram) {
+		t.Errorf("ChainOf.Generate() error = %v, want %v", err, ErrUnknownNGram)
+	}
+	// Training replaces the cached distribution of the rows it changes
+	cached := NewChainOf[int](1)
+	cached.Add([]int{1})
+	if next, _, _ := cached.GenerateDeterministic(nil, &sequenceRand{[]int{0}}); next != 1 {
+		t.Errorf("ChainOf.GenerateDeterministic() = %v, want 1", next)
+	}
+	cached.Add([]int{2})
+	cached.Add([]int{2})
+	if next, _, _ := cached.GenerateDeterministic(nil, &sequenceRand{[]int{0}}); next != 2 {
+		t.Errorf("ChainOf.GenerateDeterministic() after training = %v, want 2", next)
+	}
+
+	empty := NewChainOf[rune](1)
+	empty.Add(nil)
+	if _, _, err := empty.Generate(nil); !errors.Is(err, ErrEmptyChain) {
+		t.Errorf("ChainOf.Generate() error = %v, want %v", err, ErrEmptyChain)
+	}
+}
@@ -0,0 +1,98 @@
+package gomarkov
+
+import (
+	"sort"
+	"strings"
+)
+
+// tokenIndices returns the state indices of every token the chain can generate.
+// Each token follows some state, so they are the targets of all transitions, less
+// the end token.
+func (chain *Chain) tokenIndices() (map[int]struct{}, error) {
+	tokens := make(map[int]struct{})
+	err := chain.iterateRows(func(current int, row rowReader) bool {
+		row.each(func(next, count int) {
+			tokens[next] = struct{}{}
+		})
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	end, ok, err := chain.store.LookupState(EndToken)
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		delete(tokens, end)
+	}
+	return tokens, nil
+}
+
+// Vocabulary returns every token the chain has been trained on, in sorted order
+func (chain *Chain) Vocabulary() ([]string, error) {
+	indices, err := chain.tokenIndices()
+	if err != nil {
+		return nil, err
+	}
+	vocab := make([]string, 0, len(indices))
+	for index := range indices {
+		token, ok, err := chain.store.LookupIndex(index)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			vocab = append(vocab, token)
+		}
+	}
+	sort.Strings(vocab)
+	return vocab, nil
+}
+
+// TokenID returns the index the chain's store assigned to a token, which stays the
+// same for as long as the store holds the chain. Only tokens of the vocabulary have
+// an index, not the boundary tokens or the n-gram states of chains of order 2 and
+// above. The error is that of the store, such as a Redis store failing to look the
+// token up, which shouldn't be mistaken for an unknown token.
+func (chain *Chain) TokenID(token string) (int, bool, error) {
+	id, ok, err := chain.store.LookupState(token)
+	if err != nil || !ok {
+		return 0, false, err
+	}
+	if ok, err = chain.isToken(id, token); err != nil || !ok {
+		return 0, false, err
+	}
+	return id, true, nil
+}
+
+// TokenByID returns the token with the given index, see TokenID
+func (chain *Chain) TokenByID(id int) (string, bool, error) {
+	token, ok, err := chain.store.LookupIndex(id)
+	if err != nil || !ok {
+		return "", false, err
+	}
+	if ok, err = chain.isToken(id, token); err != nil || !ok {
+		return "", false, err
+	}
+	return token, true, nil
+}
+
+// isToken reports whether the state with the given index and key is a token of the
+// vocabulary. Keys that can't be an n-gram of the chain's order are decided without
+// looking at the transitions, so only tokens holding the separator cost a scan.
+func (chain *Chain) isToken(index int, key string) (bool, error) {
+	switch {
+	case key == StartToken || key == EndToken:
+		return false, nil
+	case chain.Order == 0 && key != "", chain.Order == 1:
+		return true, nil
+	case chain.Order >= 2 && strings.Count(key, "_") < chain.Order-1:
+		return true, nil
+	}
+	indices, err := chain.tokenIndices()
+	if err != nil {
+		return false, err
+	}
+	_, ok := indices[index]
+	return ok, nil
+}
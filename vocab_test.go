@@ -0,0 +1,44 @@
+package gomarkov
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestChain_Vocabulary(t *testing.T) {
+	for _, order := range []int{1, 2} {
+		chain := NewChain(order)
+		chain.Add([]string{"I", "want", "a", "cheese", "burger"})
+		chain.Add([]string{"I", "want", "a", "chilled", "sprite"})
+		got, err := chain.Vocabulary()
+		if err != nil {
+			t.Fatalf("Chain.Vocabulary() error = %v", err)
+		}
+		want := []string{"I", "a", "burger", "cheese", "chilled", "sprite", "want"}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("Chain.Vocabulary() order %d = %v, want %v", order, got, want)
+		}
+		for _, token := range want {
+			id, ok, err := chain.TokenID(token)
+			if err != nil || !ok {
+				t.Fatalf("Chain.TokenID(%q) = %v, %v, %v", token, id, ok, err)
+			}
+			if got, ok, _ := chain.TokenByID(id); !ok || got != token {
+				t.Errorf("Chain.TokenByID(%d) = %q, %v, want %q", id, got, ok, token)
+			}
+		}
+	}
+	chain := NewChain(2)
+	chain.Add([]string{"snake_case", "a", "b"})
+	for _, state := range []string{"a_b", "^_^", StartToken, EndToken} {
+		if id, ok, err := chain.TokenID(state); ok || err != nil {
+			t.Errorf("Chain.TokenID(%q) = %v, %v, %v, want no token", state, id, ok, err)
+		}
+	}
+	if id, ok, err := chain.TokenID("snake_case"); !ok || err != nil {
+		t.Errorf("Chain.TokenID(snake_case) = %v, %v, %v, want a token", id, ok, err)
+	}
+	if _, ok, _ := NewChain(1).TokenID("missing"); ok {
+		t.Errorf("Chain.TokenID() found a token in an empty chain")
+	}
+}
@@ -0,0 +1,108 @@
+package gomarkov
+
+import "math"
+
+// Walk takes up to steps random transitions from start and returns the tokens
+// visited, not including start. Unlike GenerateSequence, which treats the chain as
+// a text generator, the walk includes EndToken when it reaches it, where it stops
+// since nothing follows the end of a sequence. If a transition can't be taken, the
+// error is a *SequenceError holding the tokens visited so far.
+func (chain *Chain) Walk(start NGram, steps int, prng PRNG) ([]string, error) {
+	var path []string
+	err := chain.walk(start, steps, prng, func(token string) bool {
+		path = append(path, token)
+		return true
+	})
+	if seqErr, ok := err.(*SequenceError); ok {
+		seqErr.Partial = path
+	}
+	return path, err
+}
+
+// walk calls visit with each token of a random walk, stopping early if it returns false
+func (chain *Chain) walk(start NGram, steps int, prng PRNG, visit func(string) bool) error {
+	if len(start) != chain.Order {
+		return ErrOrderMismatch
+	}
+	current := append(NGram(nil), start...)
+	for step := 0; step < steps && !current.ended(); step++ {
+		next, err := chain.next(current, prng)
+		if err != nil {
+			return &SequenceError{
+				Op:       "walk",
+				Position: step,
+				NGram:    append(NGram{}, current...),
+				Err:      err,
+			}
+		}
+		if !visit(next) {
+			return nil
+		}
+		if len(current) == 0 {
+			if next == EndToken {
+				return nil
+			}
+			continue
+		}
+		// Shift in place, the walk may be long
+		copy(current, current[1:])
+		current[len(current)-1] = next
+	}
+	return nil
+}
+
+// HittingTime estimates by simulation how many steps a walk from start takes to
+// first reach a token
+type HittingTime struct {
+	// Mean is the mean number of steps of the walks that reached the token, NaN if
+	// none did
+	Mean float64
+	// StdErr is the standard error of Mean
+	StdErr float64
+	// Reached is the fraction of walks that reached the token within the step limit
+	Reached float64
+}
+
+// EstimateHittingTime simulates trials random walks of at most maxSteps from start
+// and measures how many steps they take to first reach target. Walks that end first
+// never reach it, unless target is EndToken.
+func (chain *Chain) EstimateHittingTime(start NGram, target string, trials, maxSteps int, prng PRNG) (HittingTime, error) {
+	var hits int
+	var sum, sumSq float64
+	for i := 0; i < trials; i++ {
+		steps, hit := 0, false
+		err := chain.walk(start, maxSteps, prng, func(token string) bool {
+			steps++
+			hit = token == target
+			return !hit
+		})
+		if err != nil {
+			return HittingTime{}, err
+		}
+		if hit {
+			hits++
+			sum += float64(steps)
+			sumSq += float64(steps) * float64(steps)
+		}
+	}
+	if trials <= 0 {
+		return HittingTime{Mean: math.NaN()}, nil
+	}
+	h := HittingTime{Mean: math.NaN(), Reached: float64(hits) / float64(trials)}
+	if hits > 0 {
+		n := float64(hits)
+		h.Mean = sum / n
+		if hits > 1 {
+			variance := math.Max((sumSq-sum*sum/n)/(n-1), 0)
+			h.StdErr = math.Sqrt(variance / n)
+		}
+	}
+	return h, nil
+}
+
+// EstimateAbsorption estimates by simulation the probability that a walk from start
+// reaches EndToken within maxSteps transitions, simulating trials walks
+func (chain *Chain) EstimateAbsorption(start NGram, trials, maxSteps int, prng PRNG) (float64, error) {
+	h, err := chain.EstimateHittingTime(start, EndToken, trials, maxSteps, prng)
+	return h.Reached, err
+}
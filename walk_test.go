@@ -0,0 +1,66 @@
+package gomarkov
+
+import (
+	"errors"
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// walkChain alternates between a and b, ending after b two times out of three
+func walkChain() *Chain {
+	var sequences [][]string
+	for i := 0; i < 500; i++ {
+		sequences = append(sequences, []string{"a", "b"}, []string{"a", "b", "a", "b"})
+	}
+	chain := NewChain(1)
+	chain.AddBatch(sequences)
+	return chain
+}
+
+func TestChain_Walk(t *testing.T) {
+	chain := walkChain()
+	prng := rand.New(rand.NewSource(1))
+
+	path, err := chain.Walk(NGram{"a"}, 1000, prng)
+	if err != nil {
+		t.Fatalf("Chain.Walk() error = %v", err)
+	}
+	if len(path) < 2 || path[len(path)-1] != EndToken {
+		t.Errorf("Chain.Walk() = %v, want a walk ending with %q", path, EndToken)
+	}
+	if path, _ := chain.Walk(NGram{"a"}, 1, prng); len(path) != 1 || path[0] != "b" {
+		t.Errorf("Chain.Walk() of one step = %v, want [b]", path)
+	}
+	if path, _ := chain.Walk(NGram{EndToken}, 10, prng); len(path) != 0 {
+		t.Errorf("Chain.Walk() from the end = %v, want nothing", path)
+	}
+
+	var seqErr *SequenceError
+	if _, err := chain.Walk(NGram{"c"}, 10, prng); !errors.As(err, &seqErr) || !errors.Is(err, ErrUnknownNGram) {
+		t.Errorf("Chain.Walk() error = %v, want a *SequenceError for an unknown n-gram", err)
+	}
+}
+
+func TestChain_EstimateHittingTime(t *testing.T) {
+	chain := walkChain()
+	prng := rand.New(rand.NewSource(1))
+
+	// From a, the walk goes to b and then ends, or returns to a a third of the time,
+	// taking 3 steps to end on average
+	h, err := chain.EstimateHittingTime(NGram{"a"}, EndToken, 10000, 1000, prng)
+	if err != nil {
+		t.Fatalf("Chain.EstimateHittingTime() error = %v", err)
+	}
+	if h.Reached != 1 || math.Abs(h.Mean-3) > 4*h.StdErr || h.StdErr == 0 {
+		t.Errorf("Chain.EstimateHittingTime() = %+v, want a mean of about 3", h)
+	}
+	if h, _ := chain.EstimateHittingTime(NGram{"a"}, "missing", 100, 1000, prng); !math.IsNaN(h.Mean) || h.Reached != 0 {
+		t.Errorf("Chain.EstimateHittingTime() of an unreachable token = %+v", h)
+	}
+
+	p, err := chain.EstimateAbsorption(NGram{"a"}, 10000, 2, prng)
+	if err != nil || math.Abs(p-2.0/3) > 0.03 {
+		t.Errorf("Chain.EstimateAbsorption() within 2 steps = %v, %v, want about 2/3", p, err)
+	}
+}
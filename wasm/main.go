@@ -0,0 +1,209 @@
+//go:build js && wasm
+
+// Command wasm exposes chains to JavaScript when compiled to WebAssembly:
+//
+//	GOOS=js GOARCH=wasm go build -o gomarkov.wasm ./wasm
+//
+// Once loaded with Go's wasm_exec.js, it defines a global gomarkov object. Chains are
+// referred to by numeric handles, released with free:
+//
+//	const id = gomarkov.newChain(2)
+//	gomarkov.train(id, ["I", "want", "a", "cheese", "burger"])
+//	gomarkov.generate(id, [], 20)          // ["I", "want", ...]
+//	gomarkov.score(id, ["I", "want"])      // natural log probability
+//	const json = gomarkov.toJSON(id)
+//	gomarkov.free(id)
+//
+// Models trained elsewhere are loaded with loadJSON, or loadCompiled for a Uint8Array
+// holding a model written by Chain.Compile, which is served without decoding and so
+// keeps memory use close to the model's size. freeze returns the handle of a compact
+// read-only copy of a chain. Functions return an Error instead of throwing on failure.
+package main
+
+import (
+	"syscall/js"
+
+	"github.com/mb-14/gomarkov"
+)
+
+// chains holds every chain handed out to JavaScript, by handle. JavaScript calls
+// into Go one at a time, so it needs no lock.
+var (
+	chains     = map[int]*gomarkov.Chain{}
+	nextHandle = 1
+)
+
+func main() {
+	api := map[string]interface{}{
+		"newChain":     js.FuncOf(newChain),
+		"loadJSON":     js.FuncOf(loadJSON),
+		"loadCompiled": js.FuncOf(loadCompiled),
+		"train":        js.FuncOf(withChain(train)),
+		"generate":     js.FuncOf(withChain(generate)),
+		"score":        js.FuncOf(withChain(score)),
+		"probability":  js.FuncOf(withChain(probability)),
+		"stats":        js.FuncOf(withChain(stats)),
+		"toJSON":       js.FuncOf(withChain(toJSON)),
+		"freeze":       js.FuncOf(withChain(freeze)),
+		"free":         js.FuncOf(free),
+	}
+	js.Global().Set("gomarkov", js.ValueOf(api))
+	// Keep the functions callable for the lifetime of the page
+	select {}
+}
+
+func register(chain *gomarkov.Chain) int {
+	handle := nextHandle
+	nextHandle++
+	chains[handle] = chain
+	return handle
+}
+
+func jsError(msg string) js.Value {
+	return js.Global().Get("Error").New(msg)
+}
+
+// withChain resolves the handle passed as the first argument
+func withChain(fn func(chain *gomarkov.Chain, args []js.Value) interface{}) func(js.Value, []js.Value) interface{} {
+	return func(this js.Value, args []js.Value) interface{} {
+		if len(args) == 0 || args[0].Type() != js.TypeNumber {
+			return jsError("gomarkov: expected a chain handle")
+		}
+		chain, ok := chains[args[0].Int()]
+		if !ok {
+			return jsError("gomarkov: unknown chain handle")
+		}
+		return fn(chain, args[1:])
+	}
+}
+
+// stringsArg converts a JavaScript array of strings
+func stringsArg(args []js.Value, i int) []string {
+	if i >= len(args) || args[i].Type() != js.TypeObject {
+		return nil
+	}
+	tokens := make([]string, args[i].Length())
+	for j := range tokens {
+		tokens[j] = args[i].Index(j).String()
+	}
+	return tokens
+}
+
+func stringsValue(tokens []string) js.Value {
+	values := make([]interface{}, len(tokens))
+	for i, token := range tokens {
+		values[i] = token
+	}
+	return js.ValueOf(values)
+}
+
+func newChain(this js.Value, args []js.Value) interface{} {
+	if len(args) == 0 || args[0].Type() != js.TypeNumber || args[0].Int() < 1 {
+		return jsError("gomarkov: expected a positive order")
+	}
+	return register(gomarkov.NewChain(args[0].Int()))
+}
+
+func loadJSON(this js.Value, args []js.Value) interface{} {
+	if len(args) == 0 || args[0].Type() != js.TypeString {
+		return jsError("gomarkov: expected a JSON string")
+	}
+	var chain gomarkov.Chain
+	if err := chain.UnmarshalJSON([]byte(args[0].String())); err != nil {
+		return jsError(err.Error())
+	}
+	return register(&chain)
+}
+
+func loadCompiled(this js.Value, args []js.Value) interface{} {
+	if len(args) == 0 || args[0].Type() != js.TypeObject {
+		return jsError("gomarkov: expected a Uint8Array")
+	}
+	data := make([]byte, args[0].Length())
+	js.CopyBytesToGo(data, args[0])
+	chain, err := gomarkov.LoadCompiled(data)
+	if err != nil {
+		return jsError(err.Error())
+	}
+	return register(chain)
+}
+
+func train(chain *gomarkov.Chain, args []js.Value) interface{} {
+	if err := chain.Add(stringsArg(args, 0)); err != nil {
+		return jsError(err.Error())
+	}
+	return nil
+}
+
+// generate takes the seed, padded with start tokens, and a maximum length
+func generate(chain *gomarkov.Chain, args []js.Value) interface{} {
+	current := gomarkov.FromTokens(stringsArg(args, 0), chain.Order)
+	maxLength := 0
+	if len(args) > 1 && args[1].Type() == js.TypeNumber {
+		maxLength = args[1].Int()
+	}
+	tokens, err := chain.GenerateSequence(current, maxLength)
+	if err != nil {
+		return jsError(err.Error())
+	}
+	return stringsValue(tokens)
+}
+
+func score(chain *gomarkov.Chain, args []js.Value) interface{} {
+	logProb, err := chain.LogProbability(stringsArg(args, 0))
+	if err != nil {
+		return jsError(err.Error())
+	}
+	return logProb
+}
+
+func probability(chain *gomarkov.Chain, args []js.Value) interface{} {
+	if len(args) == 0 || args[0].Type() != js.TypeString {
+		return jsError("gomarkov: expected the next token")
+	}
+	p, err := chain.TransitionProbability(args[0].String(), stringsArg(args, 1))
+	if err != nil {
+		return jsError(err.Error())
+	}
+	return p
+}
+
+func stats(chain *gomarkov.Chain, args []js.Value) interface{} {
+	s, err := chain.Stats()
+	if err != nil {
+		return jsError(err.Error())
+	}
+	return js.ValueOf(map[string]interface{}{
+		"order":       s.Order,
+		"states":      s.States,
+		"transitions": s.Transitions,
+		"vocabulary":  s.Vocabulary,
+	})
+}
+
+func toJSON(chain *gomarkov.Chain, args []js.Value) interface{} {
+	data, err := chain.MarshalJSON()
+	if err != nil {
+		return jsError(err.Error())
+	}
+	return string(data)
+}
+
+// freeze returns the handle of a frozen copy of the chain, leaving the original
+func freeze(chain *gomarkov.Chain, args []js.Value) interface{} {
+	frozen, err := chain.Freeze()
+	if err != nil {
+		return jsError(err.Error())
+	}
+	return register(frozen)
+}
+
+func free(this js.Value, args []js.Value) interface{} {
+	if len(args) > 0 && args[0].Type() == js.TypeNumber {
+		if chain, ok := chains[args[0].Int()]; ok {
+			chain.Close()
+			delete(chains, args[0].Int())
+		}
+	}
+	return nil
+}
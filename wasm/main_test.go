@@ -0,0 +1,60 @@
+//go:build js && wasm
+
+package main
+
+import (
+	"syscall/js"
+	"testing"
+)
+
+func call(fn func(js.Value, []js.Value) interface{}, args ...interface{}) js.Value {
+	values := make([]js.Value, len(args))
+	for i, arg := range args {
+		values[i] = js.ValueOf(arg)
+	}
+	return js.ValueOf(fn(js.Undefined(), values))
+}
+
+func tokens(v js.Value) []string {
+	out := make([]string, v.Length())
+	for i := range out {
+		out[i] = v.Index(i).String()
+	}
+	return out
+}
+
+func TestAPI(t *testing.T) {
+	id := call(newChain, 2)
+	if id.Type() != js.TypeNumber {
+		t.Fatalf("newChain() = %v, want a handle", id)
+	}
+	sentence := []interface{}{"I", "want", "a", "cheese", "burger"}
+	if err := call(withChain(train), id, sentence); !err.IsNull() && !err.IsUndefined() {
+		t.Fatalf("train() = %v", err)
+	}
+	if got := tokens(call(withChain(generate), id, []interface{}{"a", "cheese"}, 10)); len(got) != 1 || got[0] != "burger" {
+		t.Errorf("generate() = %v, want [burger]", got)
+	}
+	if got := call(withChain(score), id, sentence).Float(); got != 0 {
+		t.Errorf("score() = %v, want 0", got)
+	}
+	if got := call(withChain(stats), id).Get("vocabulary").Int(); got != 5 {
+		t.Errorf("stats().vocabulary = %v, want 5", got)
+	}
+
+	loaded := call(loadJSON, call(withChain(toJSON), id).String())
+	frozen := call(withChain(freeze), loaded)
+	if got := call(withChain(probability), frozen, "a", []interface{}{"I", "want"}).Float(); got != 1 {
+		t.Errorf("probability() of a reloaded, frozen chain = %v, want 1", got)
+	}
+
+	for _, v := range []js.Value{call(newChain, 0), call(loadJSON, "{"), call(withChain(score), 999, sentence)} {
+		if !v.InstanceOf(js.Global().Get("Error")) {
+			t.Errorf("invalid call returned %v, want an Error", v)
+		}
+	}
+	call(free, id)
+	if _, ok := chains[id.Int()]; ok {
+		t.Errorf("free() kept the chain")
+	}
+}
@@ -0,0 +1,460 @@
+package gomarkov
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+)
+
+// WeightedStore is a Store holding float64 transition weights instead of integer
+// counts, so that scaling, decay and fractional training weights aren't truncated.
+// The Store methods see the weights rounded to counts, see NewWeightedMemoryStore.
+type WeightedStore interface {
+	Store
+	// AddWeight adds delta to the weight of the transition between two states. A
+	// weight falling to 0 or below removes the transition.
+	AddWeight(current, next int, delta float64) error
+	// GetWeights returns the transition weights out of a state, keyed by next state
+	// index. The returned map must not be modified.
+	GetWeights(current int) (map[int]float64, error)
+	// IterateWeights calls fn for every state that has outgoing transitions, stopping
+	// early if fn returns false. fn must not modify the store or retain row.
+	IterateWeights(fn func(current int, row map[int]float64) bool) error
+	// ScaleWeights multiplies every weight by factor
+	ScaleWeights(factor float64) error
+}
+
+// weightedMemoryStore keeps a float-weighted chain in process memory
+type weightedMemoryStore struct {
+	statePool *spool
+	lock      rwLock
+	rows      map[int]*weightedRow
+}
+
+// weightedRow holds the transitions out of a state
+type weightedRow struct {
+	weights map[int]float64
+	// sum is the total weight of the row, kept up to date as weights are added
+	sum float64
+	// dist caches the sampling distribution of the row, reset when it changes
+	dist *weightedDist
+}
+
+// NewWeightedMemoryStore creates an empty in-memory WeightedStore, used by NewChain
+// with WithFloatWeights. Sampling and probabilities use the exact weights, while
+// GetRow and IterateRows round them to the nearest count, weights below 1 counting
+// as 1 so that integer code still sees every transition.
+func NewWeightedMemoryStore() WeightedStore {
+	return newWeightedMemoryStore(0)
+}
+
+func newWeightedMemoryStore(states int) *weightedMemoryStore {
+	return &weightedMemoryStore{
+		statePool: newSpool(make(map[string]int, states), nil),
+		rows:      make(map[int]*weightedRow),
+	}
+}
+
+// loadWeightedMemoryStore creates a weightedMemoryStore holding a decoded model
+func loadWeightedMemoryStore(spoolMap map[string]int, intMap map[int]string, weights map[int]map[int]float64) *weightedMemoryStore {
+	w := newWeightedMemoryStore(0)
+	w.statePool = newSpool(spoolMap, intMap)
+	for current, row := range weights {
+		r := &weightedRow{weights: row}
+		for _, weight := range row {
+			r.sum += weight
+		}
+		w.rows[current] = r
+	}
+	return w
+}
+
+// disableLocking turns off every lock of the store, see WithoutLocking
+func (w *weightedMemoryStore) disableLocking() {
+	w.statePool.lock.disabled = true
+	w.lock.disabled = true
+}
+
+func (w *weightedMemoryStore) AddState(state string) (int, error) {
+	return w.statePool.add(state), nil
+}
+
+func (w *weightedMemoryStore) LookupState(state string) (int, bool, error) {
+	index, ok := w.statePool.get(state)
+	return index, ok, nil
+}
+
+func (w *weightedMemoryStore) LookupIndex(index int) (string, bool, error) {
+	state, ok := w.statePool.lookup(index)
+	return state, ok, nil
+}
+
+func (w *weightedMemoryStore) stateCount() int {
+	return w.statePool.len()
+}
+
+func (w *weightedMemoryStore) rangeStates(fn func(state string, index int)) {
+	w.statePool.rangeStates(fn)
+}
+
+func (w *weightedMemoryStore) IncrementTransition(current, next, delta int) error {
+	return w.AddWeight(current, next, float64(delta))
+}
+
+func (w *weightedMemoryStore) AddWeight(current, next int, delta float64) error {
+	if current < 0 {
+		return errNegativeIndex
+	}
+	if math.IsNaN(delta) || math.IsInf(delta, 0) {
+		return fmt.Errorf("Transition weight must be finite, got %v", delta)
+	}
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	r := w.rows[current]
+	if r == nil {
+		if delta <= 0 {
+			return nil
+		}
+		r = &weightedRow{weights: make(map[int]float64)}
+		w.rows[current] = r
+	}
+	if math.IsInf(r.sum+delta, 0) {
+		return ErrCountOverflow
+	}
+	r.dist = nil
+	weight := r.weights[next] + delta
+	if weight > 0 {
+		r.weights[next] = weight
+		r.sum += delta
+		return nil
+	}
+	delete(r.weights, next)
+	if len(r.weights) == 0 {
+		delete(w.rows, current)
+		return nil
+	}
+	// Summing again keeps rounding errors from piling up as transitions come and go
+	r.sum = 0
+	for _, weight := range r.weights {
+		r.sum += weight
+	}
+	return nil
+}
+
+func (w *weightedMemoryStore) GetWeights(current int) (map[int]float64, error) {
+	w.lock.RLock()
+	defer w.lock.RUnlock()
+	r := w.rows[current]
+	if r == nil {
+		return nil, nil
+	}
+	copied := make(map[int]float64, len(r.weights))
+	for next, weight := range r.weights {
+		copied[next] = weight
+	}
+	return copied, nil
+}
+
+func (w *weightedMemoryStore) IterateWeights(fn func(current int, row map[int]float64) bool) error {
+	w.lock.RLock()
+	defer w.lock.RUnlock()
+	for current, r := range w.rows {
+		if !fn(current, r.weights) {
+			return nil
+		}
+	}
+	return nil
+}
+
+func (w *weightedMemoryStore) ScaleWeights(factor float64) error {
+	if !(factor > 0) || math.IsInf(factor, 0) {
+		return fmt.Errorf("Scale factor must be positive and finite, got %v", factor)
+	}
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	for _, r := range w.rows {
+		if math.IsInf(r.sum*factor, 0) {
+			return ErrCountOverflow
+		}
+	}
+	for current, r := range w.rows {
+		r.sum = 0
+		for next, weight := range r.weights {
+			if weight *= factor; weight > 0 {
+				r.weights[next] = weight
+				r.sum += weight
+			} else {
+				// Shrinking by a tiny factor can underflow to 0
+				delete(r.weights, next)
+			}
+		}
+		if len(r.weights) == 0 {
+			delete(w.rows, current)
+		}
+		r.dist = nil
+	}
+	return nil
+}
+
+// GetRow returns the weights of a row rounded to counts, see NewWeightedMemoryStore
+func (w *weightedMemoryStore) GetRow(current int) (map[int]int, error) {
+	w.lock.RLock()
+	defer w.lock.RUnlock()
+	r := w.rows[current]
+	if r == nil {
+		return nil, nil
+	}
+	return r.rounded(), nil
+}
+
+func (w *weightedMemoryStore) IterateRows(fn func(current int, row map[int]int) bool) error {
+	w.lock.RLock()
+	defer w.lock.RUnlock()
+	for current, r := range w.rows {
+		if !fn(current, r.rounded()) {
+			return nil
+		}
+	}
+	return nil
+}
+
+// rounded returns the weights of the row rounded to counts
+func (r *weightedRow) rounded() sparseArray {
+	counts := make(sparseArray, len(r.weights))
+	for next, weight := range r.weights {
+		counts[next] = roundWeight(weight)
+	}
+	return counts
+}
+
+// roundWeight rounds a positive weight to the nearest count of at least 1
+func roundWeight(weight float64) int {
+	if weight >= math.MaxInt {
+		return math.MaxInt
+	}
+	return max(int(math.Round(weight)), 1)
+}
+
+// transitionWeight returns a single weight and the total of its row
+func (w *weightedMemoryStore) transitionWeight(current, next int) (weight, sum float64) {
+	w.lock.RLock()
+	defer w.lock.RUnlock()
+	r := w.rows[current]
+	if r == nil {
+		return 0, 0
+	}
+	return r.weights[next], r.sum
+}
+
+func (w *weightedMemoryStore) distribution(current int) (distribution, error) {
+	w.lock.RLock()
+	if r := w.rows[current]; r != nil && r.dist != nil {
+		d := r.dist
+		w.lock.RUnlock()
+		return d, nil
+	}
+	w.lock.RUnlock()
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	r := w.rows[current]
+	if r == nil {
+		return &weightedDist{}, nil
+	}
+	if r.dist == nil {
+		r.dist = newWeightedDist(r.weights)
+	}
+	return r.dist, nil
+}
+
+// snapshot returns a copy of the store. Unlike the default store's snapshots it
+// copies every row up front.
+func (w *weightedMemoryStore) snapshot() Store {
+	w.lock.RLock()
+	defer w.lock.RUnlock()
+	snap := newWeightedMemoryStore(0)
+	snap.statePool = w.statePool.clone()
+	for current, r := range w.rows {
+		weights := make(map[int]float64, len(r.weights))
+		for next, weight := range r.weights {
+			weights[next] = weight
+		}
+		snap.rows[current] = &weightedRow{weights: weights, sum: r.sum}
+	}
+	return snap
+}
+
+// weightedDist holds the keys of a row from the heaviest to the lightest, along with
+// the running total of their weights
+type weightedDist struct {
+	keys   []int
+	totals []float64
+}
+
+func newWeightedDist(row map[int]float64) *weightedDist {
+	d := &weightedDist{keys: orderedWeights(row), totals: make([]float64, len(row))}
+	total := 0.0
+	for i, next := range d.keys {
+		total += row[next]
+		d.totals[i] = total
+	}
+	return d
+}
+
+func (d *weightedDist) draw(prng PRNG) (int, bool) {
+	if len(d.keys) == 0 {
+		return 0, false
+	}
+	u := uniform(prng) * d.totals[len(d.totals)-1]
+	i := sort.Search(len(d.totals), func(i int) bool { return d.totals[i] > u })
+	// Guard against the running total rounding below the sum
+	return d.keys[min(i, len(d.keys)-1)], true
+}
+
+// orderedWeights returns the keys of a row from the heaviest to the lightest, using
+// the key as a tie-breaker like orderedPairs
+func orderedWeights(row map[int]float64) []int {
+	keys := make([]int, 0, len(row))
+	for next := range row {
+		keys = append(keys, next)
+	}
+	sort.Slice(keys, func(a, b int) bool {
+		if row[keys[a]] == row[keys[b]] {
+			return keys[a] < keys[b]
+		}
+		return row[keys[a]] > row[keys[b]]
+	})
+	return keys
+}
+
+// WithFloatWeights backs the chain with NewWeightedMemoryStore instead of integer
+// counts, so it can be trained with AddWeightedFloat and decayed with Scale.
+// TrainMixture then gives each source its exact share.
+func WithFloatWeights() ChainOption {
+	return func(o *chainOptions) {
+		o.floatWeights = true
+	}
+}
+
+// weighted returns the chain's store if it holds float weights
+func (chain *Chain) weighted() (WeightedStore, error) {
+	ws, ok := chain.store.(WeightedStore)
+	if !ok {
+		return nil, errors.New("Chain store does not hold float weights")
+	}
+	return ws, nil
+}
+
+// AddWeightedFloat adds the transitions of a sequence with a fractional weight, such
+// as an importance weight, to a chain holding float weights, see WithFloatWeights.
+// A weight of 0 adds nothing. The sequence counts once towards the length
+// distribution and checkpoints whatever its weight.
+func (chain *Chain) AddWeightedFloat(input []string, weight float64) (err error) {
+	ws, err := chain.weighted()
+	if err != nil {
+		return err
+	}
+	if weight < 0 || math.IsNaN(weight) || math.IsInf(weight, 0) {
+		return fmt.Errorf("Sequence weight must be finite and not negative, got %v", weight)
+	}
+	if weight == 0 || chain.skips(input) {
+		return nil
+	}
+	if chain.metrics != nil {
+		defer chain.observeTrain(time.Now(), 1, chain.transitionCount(input), &err)
+	}
+	if chain.observing() {
+		defer chain.observeSequences(&err, 1, input)
+	}
+	buf := chain.padded(input)
+	defer releaseTokens(buf)
+	tokens := *buf
+	for i := 0; i+chain.Order < len(tokens); i++ {
+		currentIndex, err := chain.addState(tokens[i : i+chain.Order])
+		if err != nil {
+			return err
+		}
+		nextIndex, err := ws.AddState(tokens[i+chain.Order])
+		if err != nil {
+			return err
+		}
+		if err := ws.AddWeight(currentIndex, nextIndex, weight); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Scale multiplies every transition weight of a chain holding float weights by
+// factor, see WithFloatWeights. Probabilities are unchanged, but what the chain is
+// trained on next carries more weight against what it has already learned: scaling by
+// 0.5 before each day's training makes older days count half as much as the day after.
+func (chain *Chain) Scale(factor float64) error {
+	ws, err := chain.weighted()
+	if err != nil {
+		return err
+	}
+	return ws.ScaleWeights(factor)
+}
+
+// exportWeights collects the transition weights of a chain holding float weights
+func exportWeights(ws WeightedStore) (map[int]map[int]float64, error) {
+	weights := make(map[int]map[int]float64)
+	err := ws.IterateWeights(func(current int, row map[int]float64) bool {
+		copied := make(map[int]float64, len(row))
+		for next, weight := range row {
+			copied[next] = weight
+		}
+		weights[current] = copied
+		return true
+	})
+	return weights, err
+}
+
+// validateWeights checks that decoded weights are positive and finite, and that they
+// hold the same transitions as the frequency matrix they were saved with
+func validateWeights(freqMat map[int]sparseArray, weights map[int]map[int]float64) error {
+	if len(weights) != len(freqMat) {
+		return errors.New("Transition weights don't match the frequency matrix")
+	}
+	for current, row := range weights {
+		counts, ok := freqMat[current]
+		if !ok || len(counts) != len(row) {
+			return errors.New("Transition weights don't match the frequency matrix")
+		}
+		for next, weight := range row {
+			if _, ok := counts[next]; !ok {
+				return errors.New("Transition weights don't match the frequency matrix")
+			}
+			if !(weight > 0) || math.IsInf(weight, 0) {
+				return fmt.Errorf("Transition weight from %d to %d must be positive and finite, got %v", current, next, weight)
+			}
+		}
+	}
+	return nil
+}
+
+// weightedTransitions is transitions for a chain holding float weights, with exact
+// probabilities and successors ordered by weight
+func (chain *Chain) weightedTransitions(ws WeightedStore, current int) ([]Transition, error) {
+	row, err := ws.GetWeights(current)
+	if err != nil || len(row) == 0 {
+		return nil, err
+	}
+	sum := 0.0
+	for _, weight := range row {
+		sum += weight
+	}
+	var transitions []Transition
+	for _, index := range orderedWeights(row) {
+		next, found, err := ws.LookupIndex(index)
+		if err != nil {
+			return nil, err
+		}
+		if found {
+			transitions = append(transitions, Transition{Next: next, Count: roundWeight(row[index]), Probability: row[index] / sum})
+		}
+	}
+	return transitions, nil
+}
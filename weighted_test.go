@@ -0,0 +1,257 @@
+package gomarkov
+
+import (
+	"math"
+	"math/rand"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestChain_AddWeightedFloat(t *testing.T) {
+	chain := NewChain(1, WithFloatWeights())
+	chain.AddWeightedFloat([]string{"a"}, 0.25)
+	chain.AddWeightedFloat([]string{"b"}, 0.5)
+	chain.Add([]string{"a"})
+	prob, known, err := chain.LookupTransition("a", NGram{StartToken})
+	if err != nil || !known || math.Abs(prob-1.25/1.75) > 1e-12 {
+		t.Errorf("Chain.LookupTransition() = %v, %v, %v, want %v, true, <nil>", prob, known, err, 1.25/1.75)
+	}
+	// Weights are rounded for integer code, without losing any transition
+	want := map[string]map[string]int{StartToken: {"a": 1, "b": 1}, "a": {EndToken: 1}, "b": {EndToken: 1}}
+	if got := transitionCounts(t, chain); !reflect.DeepEqual(got, want) {
+		t.Errorf("rounded counts = %v, want %v", got, want)
+	}
+	var transitions []Transition
+	chain.ForEachTransition(func(current NGram, tr Transition) bool {
+		if current[0] == StartToken {
+			transitions = append(transitions, tr)
+		}
+		return true
+	})
+	if len(transitions) != 2 || transitions[0].Next != "a" || math.Abs(transitions[1].Probability-0.5/1.75) > 1e-12 {
+		t.Errorf("Chain.ForEachTransition() from the start = %v", transitions)
+	}
+
+	errs := []struct {
+		name   string
+		chain  *Chain
+		weight float64
+	}{
+		{"Integer counts", NewChain(1), 0.5},
+		{"Negative weight", NewChain(1, WithFloatWeights()), -0.5},
+		{"NaN weight", NewChain(1, WithFloatWeights()), math.NaN()},
+	}
+	for _, tt := range errs {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.chain.AddWeightedFloat([]string{"a"}, tt.weight); err == nil {
+				t.Errorf("Chain.AddWeightedFloat() succeeded")
+			}
+		})
+	}
+}
+
+func TestChain_Scale(t *testing.T) {
+	chain := NewChain(1, WithFloatWeights())
+	chain.Add([]string{"old"})
+	if err := chain.Scale(0.25); err != nil {
+		t.Fatalf("Chain.Scale() error = %v", err)
+	}
+	chain.Add([]string{"new"})
+	if got, _ := chain.TransitionProbability("new", NGram{StartToken}); math.Abs(got-0.8) > 1e-12 {
+		t.Errorf("P(new | ^) = %v, want 0.8", got)
+	}
+	for _, factor := range []float64{0, -1, math.Inf(1), math.NaN()} {
+		if err := chain.Scale(factor); err == nil {
+			t.Errorf("Chain.Scale(%v) succeeded", factor)
+		}
+	}
+	if err := NewChain(1).Scale(0.5); err == nil {
+		t.Errorf("Chain.Scale() of integer counts succeeded")
+	}
+}
+
+func TestWeightedMemoryStore_AddWeight(t *testing.T) {
+	store := NewWeightedMemoryStore()
+	store.AddWeight(0, 1, 1.5)
+	store.AddWeight(0, 2, 0.5)
+	store.AddWeight(0, 1, -1.5)
+	if got, _ := store.GetWeights(0); !reflect.DeepEqual(got, map[int]float64{2: 0.5}) {
+		t.Errorf("GetWeights() = %v, want map[2:0.5]", got)
+	}
+	store.AddWeight(0, 2, -1)
+	rows := 0
+	store.IterateRows(func(current int, row map[int]int) bool {
+		rows++
+		return true
+	})
+	if rows != 0 {
+		t.Errorf("IterateRows() visited %d rows after every weight was removed", rows)
+	}
+	if err := store.AddWeight(0, 1, math.Inf(1)); err == nil {
+		t.Errorf("AddWeight() of an infinite weight succeeded")
+	}
+}
+
+func TestChain_Generate_FloatWeights(t *testing.T) {
+	chain := NewChain(1, WithFloatWeights())
+	chain.AddWeightedFloat([]string{"common"}, 0.9)
+	chain.AddWeightedFloat([]string{"rare"}, 0.1)
+	prng := rand.New(rand.NewSource(1))
+	rare := 0
+	const draws = 20000
+	for i := 0; i < draws; i++ {
+		next, err := chain.GenerateDeterministic(NGram{StartToken}, prng)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if next == "rare" {
+			rare++
+		}
+	}
+	if got := float64(rare) / draws; math.Abs(got-0.1) > 0.01 {
+		t.Errorf("drew rare %v of the time, want 0.1", got)
+	}
+}
+
+func TestChain_MarshalJSON_FloatWeights(t *testing.T) {
+	chain := NewChain(1, WithFloatWeights())
+	chain.AddWeightedFloat([]string{"a", "b"}, 0.3)
+	chain.AddWeightedFloat([]string{"a", "c"}, 0.6)
+	data, err := chain.MarshalJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var restored Chain
+	if err := restored.UnmarshalJSON(data); err != nil {
+		t.Fatalf("Chain.UnmarshalJSON() error = %v", err)
+	}
+	if got, _ := restored.TransitionProbability("c", NGram{"a"}); math.Abs(got-2.0/3) > 1e-12 {
+		t.Errorf("restored P(c | a) = %v, want 2/3", got)
+	}
+	if err := restored.Scale(2); err != nil {
+		t.Errorf("restored chain doesn't hold float weights: %v", err)
+	}
+	canonical, err := chain.MarshalCanonicalJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := restored.UnmarshalJSON(canonical); err != nil {
+		t.Fatalf("Chain.UnmarshalJSON() of canonical JSON error = %v", err)
+	}
+	if got, _ := restored.TransitionProbability("b", NGram{"a"}); math.Abs(got-1.0/3) > 1e-12 {
+		t.Errorf("canonical P(b | a) = %v, want 1/3", got)
+	}
+
+	tampered := strings.Replace(string(data), "0.6", "0.7", 1)
+	if err := restored.UnmarshalJSON([]byte(tampered)); err == nil {
+		t.Errorf("Chain.UnmarshalJSON() of tampered weights succeeded")
+	}
+	invalid := []string{
+		`{"version":4,"order":1,"spool_map":{"^":0,"a":1,"$":2},"freq_mat":{"0":{"1":1},"1":{"2":1}},"weights":{"0":{"1":0.5}},"checksum":"crc32c:af460ff4"}`,
+		`{"version":4,"order":1,"spool_map":{"^":0,"a":1,"$":2},"freq_mat":{"0":{"1":1},"1":{"2":1}},"weights":{"0":{"2":0.5},"1":{"2":0.5}},"checksum":"crc32c:67c1b862"}`,
+		`{"version":4,"order":1,"spool_map":{"^":0,"a":1,"$":2},"freq_mat":{"0":{"1":1},"1":{"2":1}},"weights":{"0":{"1":-0.5},"1":{"2":0.5}},"checksum":"crc32c:22f8fcb1"}`,
+	}
+	for _, data := range invalid {
+		if err := restored.UnmarshalJSON([]byte(data)); err == nil || strings.Contains(err.Error(), "checksum") {
+			t.Errorf("Chain.UnmarshalJSON(%s) error = %v, want invalid weights", data, err)
+		}
+	}
+}
+
+func TestChain_MarshalShards_FloatWeights(t *testing.T) {
+	chain := NewChain(1, WithFloatWeights())
+	chain.AddWeightedFloat([]string{"a", "b"}, 0.99)
+	chain.AddWeightedFloat([]string{"a", "c"}, 0.01)
+	shards, err := chain.MarshalShards(3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	restored, err := UnmarshalShards(shards)
+	if err != nil {
+		t.Fatalf("UnmarshalShards() error = %v", err)
+	}
+	if got, _ := restored.TransitionProbability("c", NGram{"a"}); math.Abs(got-0.01) > 1e-12 {
+		t.Errorf("restored P(c | a) = %v, want 0.01", got)
+	}
+	if err := restored.Scale(2); err != nil {
+		t.Errorf("restored chain doesn't hold float weights: %v", err)
+	}
+
+	counts := NewChain(1)
+	counts.Add([]string{"a"})
+	plain, err := counts.MarshalShards(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := UnmarshalShards([][]byte{shards[0], shards[1], plain[0]}); err == nil {
+		t.Errorf("UnmarshalShards() of shards with and without weights succeeded")
+	}
+}
+
+// trainingStore adds a transition whenever its rows have been read, like an Add
+// landing between reads of a chain being trained
+type trainingStore struct {
+	WeightedStore
+	added int
+}
+
+func (s *trainingStore) IterateRows(fn func(current int, row map[int]int) bool) error {
+	err := s.WeightedStore.IterateRows(fn)
+	a, _ := s.AddState("a")
+	next, _ := s.AddState(strconv.Itoa(s.added))
+	s.added++
+	s.AddWeight(a, next, 0.5)
+	return err
+}
+
+func TestChain_MarshalJSON_FloatWeights_WhileTraining(t *testing.T) {
+	chain := NewChainWithStore(1, &trainingStore{WeightedStore: NewWeightedMemoryStore()})
+	chain.AddWeightedFloat([]string{"a", "b"}, 0.5)
+	var restored Chain
+	data, err := chain.MarshalJSON()
+	if err != nil {
+		t.Fatalf("Chain.MarshalJSON() error = %v", err)
+	}
+	if err := restored.UnmarshalJSON(data); err != nil {
+		t.Errorf("Chain.UnmarshalJSON() of a chain marshaled while training error = %v", err)
+	}
+	if data, err = chain.MarshalCanonicalJSON(); err != nil {
+		t.Fatalf("Chain.MarshalCanonicalJSON() error = %v", err)
+	}
+	if err := restored.UnmarshalJSON(data); err != nil {
+		t.Errorf("Chain.UnmarshalJSON() of canonical JSON marshaled while training error = %v", err)
+	}
+	shards, err := chain.MarshalShards(2)
+	if err != nil {
+		t.Fatalf("Chain.MarshalShards() error = %v", err)
+	}
+	if _, err := UnmarshalShards(shards); err != nil {
+		t.Errorf("UnmarshalShards() of shards marshaled while training error = %v", err)
+	}
+}
+
+func TestChain_FloatWeights_CountFormats(t *testing.T) {
+	chain := NewChain(1, WithFloatWeights())
+	chain.AddWeightedFloat([]string{"a", "b"}, 0.99)
+	chain.AddWeightedFloat([]string{"a", "c"}, 0.01)
+	var buf strings.Builder
+	formats := map[string]func() error{
+		"Freeze":          func() error { _, err := chain.Freeze(); return err },
+		"NewLiveChain":    func() error { _, err := NewLiveChain(chain); return err },
+		"Compile":         func() error { return chain.Compile(&buf) },
+		"ExportCSV":       func() error { return chain.ExportCSV(&buf) },
+		"ExportARPA":      func() error { return chain.ExportARPA(&buf) },
+		"ExportMarkovify": func() error { return chain.ExportMarkovify(&buf) },
+		"Dump":            func() error { return chain.Dump(&buf, 0) },
+	}
+	for name, export := range formats {
+		if err := export(); err == nil {
+			t.Errorf("%s() of a chain holding float weights succeeded", name)
+		}
+	}
+	if buf.Len() != 0 {
+		t.Errorf("wrote %q, want nothing", buf.String())
+	}
+}